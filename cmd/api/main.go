@@ -2,92 +2,273 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
 	"github.com/lucasgarciaf/df-backend-go/config"
-	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/admins"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/health"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/metrics"
+	"github.com/lucasgarciaf/df-backend-go/internal/migrations"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"github.com/lucasgarciaf/df-backend-go/internal/realtime"
 	"github.com/lucasgarciaf/df-backend-go/internal/router"
+	apiserver "github.com/lucasgarciaf/df-backend-go/internal/server"
+	"github.com/lucasgarciaf/df-backend-go/internal/storage"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"github.com/lucasgarciaf/df-backend-go/internal/webhooks"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 func main() {
-	// Load environment variables
-	log.Printf("Load environment variables")
-	err := godotenv.Load()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
-		log.Printf("No .env file found: %v", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
+	logger := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	// Anything still using the standard log package ends up in the same stream
+	slog.SetDefault(logger)
 
-	// Initialize Keycloak
-	if err := middleware.InitKeycloak(); err != nil {
-		log.Fatalf("Failed to initialize Keycloak: %v", err)
+	// Cancelled on SIGINT/SIGTERM; used for startup work and to trigger shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	backend, err := storage.ParseBackend(cfg.Storage)
+	if err != nil {
+		fatal(logger, "invalid configuration", err)
 	}
 
-	// Connect to MongoDB
-	clientOptions := options.Client().ApplyURI(config.MongoDBURI)
-	client, err := mongo.Connect(context.TODO(), clientOptions)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		fatal(logger, "failed to set up tracing", err)
 	}
 
-	db := client.Database(config.DatabaseName)
+	m := metrics.New()
 
-	err = client.Ping(context.TODO(), nil)
+	idp, err := newIdentityProvider(ctx, cfg, logger, m)
 	if err != nil {
-		log.Fatalf("Failed to ping MongoDB: %v", err)
+		fatal(logger, "failed to initialize identity provider", err)
 	}
 
-	fmt.Println("Successfully connected and pinged MongoDB!")
+	checks := health.NewRegistry(cfg.Health.CacheTTL, logger)
+	if keycloak, ok := idp.(*identity.Keycloak); ok {
+		checks.Register(health.CheckFunc("keycloak", keycloak.Ready), cfg.Health.CheckTimeout)
+	}
 
-	// // Ensure MongoDB disconnection on shutdown
-	// defer func() {
-	// 	if err = client.Disconnect(context.TODO()); err != nil {
-	// 		log.Fatalf("Failed to disconnect MongoDB: %v", err)
-	// 	}
-	// }()
+	var client *mongo.Client
+	var repos storage.Repositories
+	switch backend {
+	case storage.BackendMemory:
+		logger.Warn("using in-memory storage; data is lost when the server stops")
+		repos = storage.NewMemory()
+		if cfg.IdentityProvider == "local" {
+			createDevAdmin(ctx, cfg.DevAdmin, repos, idp, logger)
+		}
+	case storage.BackendMongo:
+		client = connectMongo(ctx, cfg.Mongo, logger)
+		db := client.Database(cfg.Mongo.Database)
+		repos = storage.NewMongo(db, logger)
+		checks.Register(health.CheckFunc("mongo", func(ctx context.Context) error {
+			return client.Ping(ctx, readpref.Primary())
+		}), cfg.Health.CheckTimeout)
+		checks.Register(health.CheckFunc("migrations", migrations.NewMigrator(db, migrations.All, logger).Ready), cfg.Health.CheckTimeout)
+	}
+
+	repos = storage.Instrument(repos, m)
+	m.Register(metrics.NewBusiness(repos.Lessons, cfg.Mongo.OperationTimeout))
 
-	fmt.Println("Starting the application...")
+	if !cfg.DevMode {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	// Access logging and panic recovery come from the router's own middleware
+	r := gin.New()
 
-	// Create a new gin router
-	r := gin.Default()
+	// Shared by the notifier and the inbox routes, so that new notifications
+	// reach the streams open on this instance straight away
+	inbox := notifications.NewInbox(repos.Inbox)
+	// Carries the schedule changes the dispatcher publishes to the WebSockets
+	// open on this instance
+	hub := realtime.NewHub()
+	// Imports calendars for the import routes and keeps synced ones up to date
+	importer := calendar.NewImporter(availability.NewAvailabilityService(repos.Availability, audit.NewLog(repos.Audit, logger), repos.Outbox),
+		repos.Instructors, repos.Syncs, cfg.Calendar, logger)
 
 	// Setup the router
-	router.SetupRouter(r, db)
+	err = router.SetupRouter(r, router.Dependencies{Config: cfg, Repos: repos, Identity: idp, Logger: logger, Metrics: m, Health: checks, Inbox: inbox, Realtime: hub, Importer: importer})
+	if err != nil {
+		fatal(logger, "failed to set up router", err)
+	}
 
-	// Start the server in a goroutine
-	server := &http.Server{
-		Addr:    ":8081",
-		Handler: r,
+	// Delivers the domain events the services store in the outbox, queuing
+	// webhooks for the deliverer to send, pushing schedule changes to
+	// WebSockets and notifying people of their lessons
+	dispatcher := events.NewDispatcher(repos.Outbox, cfg.Outbox, logger)
+	deliverer := webhooks.NewDeliverer(repos.Webhooks, repos.Deliveries, cfg.Webhooks, logger)
+	dispatcher.AddPublisher(deliverer)
+	dispatcher.AddPublisher(realtime.NewPublisher(hub))
+	sms, err := notifications.NewSMSProvider(cfg.Notifications, logger)
+	if err != nil {
+		fatal(logger, "failed to set up text messages", err)
 	}
+	notifier, err := notifications.NewNotifier(inbox, repos.Lessons, repos.Students, repos.Instructors,
+		notifications.NewMailer(cfg.Notifications.SMTP, logger), sms, cfg.Notifications, logger)
+	if err != nil {
+		fatal(logger, "failed to set up notifications", err)
+	}
+	notifier.Register(dispatcher)
+	var workers sync.WaitGroup
+	workers.Add(4)
+	go func() {
+		defer workers.Done()
+		dispatcher.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		deliverer.Run(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		notifier.RunReminders(ctx)
+	}()
+	go func() {
+		defer workers.Done()
+		importer.Run(ctx)
+	}()
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+
+	server, err := apiserver.New(cfg, r, logger)
+	if err != nil {
+		fatal(logger, "failed to set up server", err)
+	}
+	// Notification streams and WebSockets never go idle, so they are ended
+	// for Shutdown
+	server.RegisterOnShutdown(inbox.Close)
+	server.RegisterOnShutdown(hub.Close)
 
+	// Start the server in a goroutine
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+		if cfg.Server.TLS.Enabled() {
+			// The certificate comes from server.TLSConfig
+			serverErr <- server.ListenAndServeTLS("", "")
+		} else {
+			serverErr <- server.ListenAndServe()
 		}
 	}()
-	fmt.Println("Server running on :8081")
+	logger.Info("server running", slog.String("addr", cfg.Addr()), slog.Bool("tls", cfg.Server.TLS.Enabled()))
 
-	// Wait for interrupt signal to gracefully shutdown the server with
-	// a timeout of 5 seconds.
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	fmt.Println("Shutting down server...")
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "failed to start server", err)
+		}
+	case <-ctx.Done():
+	}
+	stop()
+	logger.Info("shutting down server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Stop accepting new connections and wait for in-flight requests to
+	// finish before closing the database they depend on.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("server forced to shut down", slog.Any("error", err))
+	}
+	// Undelivered events and webhooks are picked up again on the next start
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+	}
+	if client != nil {
+		if err := client.Disconnect(shutdownCtx); err != nil {
+			logger.Error("failed to disconnect MongoDB", slog.Any("error", err))
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", slog.Any("error", err))
+	}
+
+	logger.Info("server exiting")
+}
+
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
+
+func newIdentityProvider(ctx context.Context, cfg config.Config, logger *slog.Logger, m *metrics.Metrics) (identity.Provider, error) {
+	switch cfg.IdentityProvider {
+	case "local":
+		logger.Info("using the local identity provider; tokens are signed with JWT_SECRET_KEY")
+		return identity.NewLocal(cfg.JWT.SecretKey, cfg.JWT.TokenExpiry), nil
+	case "keycloak":
+		keycloak := identity.NewKeycloak(cfg.Keycloak, logger, m)
+		if err := keycloak.Ping(ctx); err != nil {
+			return nil, err
+		}
+		logger.Info("logged in to Keycloak", slog.String("realm", cfg.Keycloak.Realm))
+		return keycloak, nil
+	default:
+		return nil, fmt.Errorf("unknown identity provider %q, expected \"keycloak\" or \"local\"", cfg.IdentityProvider)
 	}
+}
 
-	fmt.Println("Server exiting")
+// connectMongo connects to MongoDB and applies pending migrations. The
+// client-wide timeout gives every operation a deadline when the request
+// context does not already carry a shorter one.
+func connectMongo(ctx context.Context, cfg config.MongoConfig, logger *slog.Logger) *mongo.Client {
+	clientOptions := options.Client().ApplyURI(cfg.URI).SetTimeout(cfg.OperationTimeout).SetMonitor(otelmongo.NewMonitor())
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		fatal(logger, "failed to connect to MongoDB", err)
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		fatal(logger, "failed to ping MongoDB", err)
+	}
+
+	logger.Info("connected to MongoDB", slog.String("database", cfg.Database))
+
+	if cfg.MigrateOnStartup {
+		applied, err := migrations.NewMigrator(client.Database(cfg.Database), migrations.All, logger).Up(ctx)
+		if err != nil {
+			fatal(logger, "failed to apply migrations", err)
+		}
+		if len(applied) > 0 {
+			logger.Info("applied migrations", slog.Any("versions", applied))
+		}
+	}
+	return client
+}
+
+// createDevAdmin gives a fresh in-memory deployment an admin to log in with,
+// since admins can only be registered by other admins.
+func createDevAdmin(ctx context.Context, cfg config.DevAdminConfig, repos storage.Repositories, idp identity.Provider, logger *slog.Logger) {
+	admin := admins.Admin{Username: "admin", FirstName: "Dev", LastName: "Admin", Email: cfg.Email}
+	if _, err := admins.NewAdminService(repos.Admins, idp, audit.NewLog(repos.Audit, logger), logger).CreateAdmin(ctx, admin, cfg.Password); err != nil {
+		fatal(logger, "failed to create dev admin", err)
+	}
+	logger.Info("created dev admin", slog.String("email", cfg.Email))
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/migrations"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [up|status]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := "up"
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
+	}
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI).SetTimeout(cfg.Mongo.OperationTimeout))
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	migrator := migrations.NewMigrator(client.Database(cfg.Mongo.Database), migrations.All, logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format))
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+			return
+		}
+		fmt.Printf("Applied migrations: %v\n", applied)
+	case "status":
+		records, err := migrator.Applied(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		applied := make(map[int]bool, len(records))
+		for _, record := range records {
+			applied[record.Version] = true
+			fmt.Printf("%4d  applied %s  %s\n", record.Version, record.AppliedAt.Format("2006-01-02 15:04:05"), record.Description)
+		}
+		for _, migration := range migrations.All {
+			if !applied[migration.Version] {
+				fmt.Printf("%4d  pending              %s\n", migration.Version, migration.Description)
+			}
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
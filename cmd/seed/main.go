@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/admins"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/integrity"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/metrics"
+	"github.com/lucasgarciaf/df-backend-go/internal/migrations"
+	"github.com/lucasgarciaf/df-backend-go/internal/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All seeded users share this email domain so --reset can find them in Keycloak.
+const emailDomain = "seed.drivefluency.test"
+
+type services struct {
+	students     *students.StudentService
+	instructors  *instructors.InstructorService
+	admins       *admins.AdminService
+	courses      *courses.CourseService
+	lessons      *lessons.LessonService
+	availability *availability.AvailabilityService
+	vehicles     *vehicles.VehicleService
+}
+
+func main() {
+	seed := flag.Int64("seed", 42, "random seed; the same seed produces the same dataset")
+	reset := flag.Bool("reset", false, "delete existing documents and seeded Keycloak users first")
+	password := flag.String("password", "Password123!", "password given to every seeded user")
+	flag.Parse()
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.Mongo.URI).SetTimeout(cfg.Mongo.OperationTimeout))
+	if err != nil {
+		log.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	defer client.Disconnect(ctx)
+	db := client.Database(cfg.Mongo.Database)
+
+	if _, err := migrations.NewMigrator(db, migrations.All, logger).Up(ctx); err != nil {
+		log.Fatalf("Failed to apply migrations: %v", err)
+	}
+
+	idp := identity.NewKeycloak(cfg.Keycloak, logger, metrics.New())
+
+	if *reset {
+		if err := resetData(ctx, db, idp); err != nil {
+			log.Fatalf("Failed to reset data: %v", err)
+		}
+	}
+
+	s := newServices(storage.NewMongo(db, logger), idp, logger)
+	rng := rand.New(rand.NewSource(*seed))
+	if err := seedData(ctx, s, rng, *password); err != nil {
+		log.Fatalf("Failed to seed data: %v", err)
+	}
+	fmt.Printf("Seeded demo data with seed %d; every user's password is %q\n", *seed, *password)
+}
+
+func newServices(repos storage.Repositories, idp identity.Provider, logger *slog.Logger) services {
+	auditor := audit.NewLog(repos.Audit, logger)
+	// Demo data should not notify anyone, so no events are emitted
+	emitter := events.Discard
+	checker := integrity.NewChecker(repos.Lessons, repos.Courses, repos.Instructors, repos.Students, repos.Vehicles, integrity.PolicyBlock, auditor, emitter)
+
+	return services{
+		students:     students.NewStudentService(repos.Students, checker, idp, auditor, emitter, logger),
+		instructors:  instructors.NewInstructorService(repos.Instructors, checker, idp, auditor, emitter, logger),
+		admins:       admins.NewAdminService(repos.Admins, idp, auditor, logger),
+		courses:      courses.NewCourseService(repos.Courses, checker, auditor),
+		lessons:      lessons.NewLessonService(repos.Lessons, checker, auditor, emitter),
+		availability: availability.NewAvailabilityService(repos.Availability, auditor, emitter),
+		vehicles:     vehicles.NewVehicleService(repos.Vehicles, checker, auditor, emitter),
+	}
+}
+
+func resetData(ctx context.Context, db *mongo.Database, idp identity.Provider) error {
+	// Everything goes, including notifications, calendar links and webhook
+	// deliveries that would otherwise point at deleted documents
+	for _, name := range storage.Collections {
+		result, err := db.Collection(name).DeleteMany(ctx, bson.M{})
+		if err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+		fmt.Printf("Deleted %d documents from %s\n", result.DeletedCount, name)
+	}
+
+	deleted, err := idp.DeleteUsers(ctx, emailDomain)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %d Keycloak users\n", deleted)
+	return nil
+}
+
+func seedData(ctx context.Context, s services, rng *rand.Rand, password string) error {
+	people := shuffledPeople(rng)
+	next := func() person {
+		p := people[0]
+		people = people[1:]
+		return p
+	}
+
+	admin := next()
+	if _, err := s.admins.CreateAdmin(ctx, admins.Admin{
+		Username:  admin.username(),
+		FirstName: admin.first,
+		LastName:  admin.last,
+		Email:     admin.email(),
+	}, password); err != nil {
+		return fmt.Errorf("failed to create admin %s: %w", admin.email(), err)
+	}
+
+	instructorIDs := make([]primitive.ObjectID, 3)
+	for i := range instructorIDs {
+		p := next()
+		id, err := s.instructors.CreateInstructor(ctx, instructors.Instructor{
+			Username:  p.username(),
+			FirstName: p.first,
+			LastName:  p.last,
+			Email:     p.email(),
+		}, password)
+		if err != nil {
+			return fmt.Errorf("failed to create instructor %s: %w", p.email(), err)
+		}
+		instructorIDs[i] = id
+	}
+
+	studentIDs := make([]primitive.ObjectID, 8)
+	for i := range studentIDs {
+		p := next()
+		id, err := s.students.CreateStudent(ctx, students.Student{
+			Username:  p.username(),
+			FirstName: p.first,
+			LastName:  p.last,
+			Email:     p.email(),
+			Age:       17 + rng.Intn(40),
+		}, password)
+		if err != nil {
+			return fmt.Errorf("failed to create student %s: %w", p.email(), err)
+		}
+		studentIDs[i] = id
+	}
+
+	courseIDs := make([]primitive.ObjectID, len(courseCatalog))
+	for i, course := range courseCatalog {
+		id, err := s.courses.CreateCourse(ctx, course)
+		if err != nil {
+			return fmt.Errorf("failed to create course %s: %w", course.Title, err)
+		}
+		courseIDs[i] = id
+	}
+
+	vehicleIDs := make([]primitive.ObjectID, len(fleet))
+	plates := map[string]bool{}
+	for i, vehicle := range fleet {
+		for vehicle.LicensePlate == "" || plates[vehicle.LicensePlate] {
+			vehicle.LicensePlate = licensePlate(rng)
+		}
+		plates[vehicle.LicensePlate] = true
+		vehicle.Year = 2015 + rng.Intn(9)
+		id, err := s.vehicles.CreateVehicle(ctx, vehicle)
+		if err != nil {
+			return fmt.Errorf("failed to create vehicle %s: %w", vehicle.LicensePlate, err)
+		}
+		vehicleIDs[i] = id
+	}
+
+	// Every instructor works weekday mornings and afternoons for the next two
+	// weeks; lessons are booked on the hour inside those windows.
+	start := nextMonday(time.Now())
+	for day := 0; day < 14; day++ {
+		date := start.AddDate(0, 0, day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		for _, instructorID := range instructorIDs {
+			for _, window := range [][2]int{{9, 12}, {13, 17}} {
+				_, err := s.availability.CreateAvailability(ctx, availability.Availability{
+					InstructorID: instructorID,
+					StartTime:    date.Add(time.Duration(window[0]) * time.Hour),
+					EndTime:      date.Add(time.Duration(window[1]) * time.Hour),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create availability: %w", err)
+				}
+			}
+		}
+	}
+
+	// Each instructor teaches in their own vehicle, so keeping instructors and
+	// students to one lesson at a time keeps the vehicles free too
+	hours := []int{9, 10, 11, 13, 14, 15, 16}
+	busy := map[string]bool{}
+	for booked := 0; booked < 16; {
+		day := rng.Intn(14)
+		date := start.AddDate(0, 0, day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		instructor := rng.Intn(len(instructorIDs))
+		schedule := date.Add(time.Duration(hours[rng.Intn(len(hours))]) * time.Hour)
+		instructorSlot := fmt.Sprintf("instructor-%d-%s", instructor, schedule)
+		if busy[instructorSlot] {
+			continue
+		}
+		var free []int
+		for student := range studentIDs {
+			if !busy[fmt.Sprintf("student-%d-%s", student, schedule)] {
+				free = append(free, student)
+			}
+		}
+		if len(free) == 0 {
+			continue
+		}
+		student := free[rng.Intn(len(free))]
+		busy[instructorSlot] = true
+		busy[fmt.Sprintf("student-%d-%s", student, schedule)] = true
+		booked++
+
+		course := rng.Intn(len(courseIDs))
+		_, err := s.lessons.CreateLesson(ctx, lessons.Lesson{
+			CourseID:     courseIDs[course],
+			InstructorID: instructorIDs[instructor],
+			StudentID:    studentIDs[student],
+			VehicleID:    vehicleIDs[instructor%len(vehicleIDs)],
+			Title:        courseCatalog[course].Title,
+			Description:  "Seeded lesson",
+			Schedule:     schedule,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create lesson: %w", err)
+		}
+	}
+	return nil
+}
+
+type person struct {
+	first, last string
+}
+
+func (p person) username() string {
+	return strings.ToLower(p.first + "." + p.last)
+}
+
+func (p person) email() string {
+	return p.username() + "@" + emailDomain
+}
+
+var firstNames = []string{"Ana", "Bruno", "Carla", "Diego", "Elena", "Felipe", "Gabriela", "Hugo", "Irene", "Javier", "Lucia", "Martin", "Nora", "Pablo"}
+var lastNames = []string{"Alvarez", "Benitez", "Castro", "Dominguez", "Fernandez", "Garcia", "Herrera", "Iglesias", "Lopez", "Morales", "Navarro", "Ortega"}
+
+// shuffledPeople returns unique first/last name pairs in an order determined by rng.
+func shuffledPeople(rng *rand.Rand) []person {
+	people := make([]person, 0, len(firstNames))
+	lasts := rng.Perm(len(lastNames))
+	for i, first := range firstNames {
+		people = append(people, person{first: first, last: lastNames[lasts[i%len(lasts)]]})
+	}
+	rng.Shuffle(len(people), func(i, j int) { people[i], people[j] = people[j], people[i] })
+	return people
+}
+
+var courseCatalog = []courses.Course{
+	{Title: "Beginner Driving", Description: "Vehicle controls, moving off and stopping, basic manoeuvres", Duration: 20},
+	{Title: "Highway Confidence", Description: "Joining, overtaking and leaving motorways safely", Duration: 6},
+	{Title: "Test Preparation", Description: "Mock tests and examiner-style route practice", Duration: 10},
+}
+
+var fleet = []vehicles.Vehicle{
+	{Make: "Toyota", Model: "Yaris", Type: "car"},
+	{Make: "Volkswagen", Model: "Golf", Type: "car"},
+	{Make: "Renault", Model: "Clio", Type: "car"},
+}
+
+func licensePlate(rng *rand.Rand) string {
+	const letters = "BCDFGHJKLMNPRSTVWXYZ"
+	plate := make([]byte, 3)
+	for i := range plate {
+		plate[i] = letters[rng.Intn(len(letters))]
+	}
+	return fmt.Sprintf("%04d%s", rng.Intn(10000), plate)
+}
+
+func nextMonday(now time.Time) time.Time {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	offset := (int(time.Monday) - int(day.Weekday()) + 7) % 7
+	if offset == 0 {
+		offset = 7
+	}
+	return day.AddDate(0, 0, offset)
+}
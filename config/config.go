@@ -1,28 +1,701 @@
+// Package config loads the typed configuration shared by the API and the
+// command-line tools. Values come from, in increasing order of precedence:
+// built-in defaults, a .env style file, environment variables and flags.
 package config
 
 import (
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/netip"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
-)
 
-var (
-	MongoDBURI           = getEnv("MONGODB_URI", "mongodb://localhost:27017")
-	DatabaseName         = getEnv("DATABASE_NAME", "drivefluency")
-	KeycloakURL          = getEnv("KEYCLOAK_URL", "http://localhost:8080")
-	KeycloakRealm        = getEnv("KEYCLOAK_REALM", "myrealm")
-	KeycloakClientID     = getEnv("KEYCLOAK_CLIENT_ID", "myclient")
-	KeycloakClientSecret = getEnv("KEYCLOAK_CLIENT_SECRET", "mysecret")
-	JWTSecretKey         = []byte(getEnv("JWT_SECRET_KEY", "myjwtsecret"))
-	TokenExpiry          = time.Hour * 24 // Token expiry duration set to 24 hours
+	"github.com/joho/godotenv"
 )
 
-func getEnv(key, defaultValue string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+const defaultJWTSecret = "myjwtsecret"
+
+type Config struct {
+	// Port the HTTP server listens on
+	Port int
+	// Dev mode runs the API without Mongo or Keycloak by defaulting to the backends below
+	DevMode bool
+	// Where documents are stored: "mongo" or "memory"
+	Storage string
+	// Who owns credentials and issues tokens: "keycloak" or "local" (HS256 tokens signed with JWTSecretKey)
+	IdentityProvider string
+	// What to do with an instructor's upcoming lessons when the instructor is deleted: "block" or "cancel"
+	InstructorDeletePolicy string
+	// How long a lesson lasts; lessons only record when they start
+	LessonDuration time.Duration
+	// How long in-flight requests are given to finish when the server shuts down
+	ShutdownTimeout time.Duration
+
+	Server        ServerConfig
+	Log           LogConfig
+	Tracing       TracingConfig
+	Health        HealthConfig
+	RateLimit     RateLimitConfig
+	CORS          CORSConfig
+	Outbox        OutboxConfig
+	Webhooks      WebhookConfig
+	Notifications NotificationConfig
+	Realtime      RealtimeConfig
+	Calendar      CalendarConfig
+	Scheduling    SchedulingConfig
+	Mongo         MongoConfig
+	Keycloak      KeycloakConfig
+	JWT           JWTConfig
+	// Admin created at startup when running on in-memory storage with the local identity provider
+	DevAdmin DevAdminConfig
+}
+
+type ServerConfig struct {
+	// Deadline for reading a request's headers, so slow clients cannot hold connections open
+	ReadHeaderTimeout time.Duration
+	// How long an idle keep-alive connection is kept
+	IdleTimeout time.Duration
+	// Largest request body accepted, in bytes
+	MaxBodyBytes int
+	// Proxies, as IPs or CIDRs, whose X-Forwarded-For header is believed; empty trusts none
+	TrustedProxies []string
+	// max-age of the Strict-Transport-Security header sent over HTTPS; 0 leaves it out
+	HSTSMaxAge time.Duration
+	TLS        TLSConfig
+}
+
+// TLSConfig makes the API serve HTTPS itself. The files are reloaded when
+// they change, so renewed certificates are picked up without a restart.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+type LogConfig struct {
+	// Records below this level are dropped: debug, info, warn or error
+	Level slog.Level
+	// Output encoding: "json" or "text"
+	Format string
+}
+
+type TracingConfig struct {
+	// Export spans over OTLP/HTTP; when off, trace context is still propagated
+	Enabled bool
+	// Base URL of the OTLP/HTTP collector, such as http://localhost:4318
+	Endpoint    string
+	ServiceName string
+	// Fraction of new traces that are sampled, between 0 and 1; incoming sampled traces are always followed
+	SampleRatio float64
+}
+
+type HealthConfig struct {
+	// Deadline for each readiness check
+	CheckTimeout time.Duration
+	// How long a check result is reused before the dependency is asked again
+	CacheTTL time.Duration
+}
+
+// RateLimitConfig throttles the unauthenticated login and registration endpoints.
+type RateLimitConfig struct {
+	// Requests each client IP may make per Window; 0 disables the limit
+	PerIP int
+	// Login attempts each account may make per Window, from any IP; 0 disables the limit
+	PerAccount int
+	Window     time.Duration
+	// Failed logins after which an account is locked; 0 disables lockouts
+	LockoutThreshold int
+	// Length of the first lock, doubled with each further failure up to LockoutMax
+	LockoutBase time.Duration
+	LockoutMax  time.Duration
+}
+
+// OutboxConfig controls how domain events are delivered from the outbox.
+type OutboxConfig struct {
+	// How often the outbox is checked for events to deliver
+	PollInterval time.Duration
+	// Events delivered per check
+	BatchSize int
+	// Deliveries tried before an event is given up on
+	MaxAttempts int
+}
+
+// WebhookConfig controls how webhooks are delivered to their subscribers.
+type WebhookConfig struct {
+	// How often deliveries that are due are looked for
+	PollInterval time.Duration
+	// How long a subscriber has to respond
+	Timeout time.Duration
+	// Wait before the first retry, doubled with each further failure
+	RetryBase time.Duration
+	// Attempts made before a delivery is given up on
+	MaxAttempts int
+	// Whether subscription URLs may point to private networks and loopback
+	// addresses; off outside dev mode, so the delivery log cannot be used to
+	// read internal services
+	AllowPrivateURLs bool
+}
+
+// NotificationConfig controls how students and instructors are told about
+// their lessons.
+type NotificationConfig struct {
+	// How long before a lesson reminders are sent
+	Reminders []time.Duration
+	// How often lessons due a reminder are looked for
+	ReminderInterval time.Duration
+	// IANA time zone lesson times are written in, such as Europe/Madrid
+	TimeZone string
+	// How often an open inbox stream looks for notifications added by other
+	// instances, and sends a keep-alive
+	StreamPoll time.Duration
+	SMTP       SMTPConfig
+	// Who sends text messages: "log" writes them to the log instead, "twilio" sends them
+	SMSProvider string
+	Twilio      TwilioConfig
+}
+
+// SMTPConfig is the mail server emails are sent through. Without a host,
+// emails are written to the log instead.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type TwilioConfig struct {
+	APIURL     string
+	AccountSID string
+	AuthToken  string
+	// Number or messaging service messages are sent from
+	From string
+}
+
+// RealtimeConfig controls the WebSocket connections schedule updates are
+// pushed over.
+type RealtimeConfig struct {
+	// How often connections are pinged, which keeps proxies from closing them
+	PingInterval time.Duration
+	// Most topics one connection may subscribe to
+	MaxSubscriptions int
+}
+
+// CalendarConfig controls the iCalendar feeds of people's lessons and the
+// import of instructors' availability from their calendars.
+type CalendarConfig struct {
+	// Public URL of the API that feed links start with, such as
+	// https://api.example.com; empty uses the host the request was sent to
+	BaseURL string
+	// How long past lessons stay in the feeds
+	History time.Duration
+	// How far ahead imported calendars become availability
+	ImportHorizon time.Duration
+	// How often calendar URLs are synced
+	SyncInterval time.Duration
+	// How long fetching a calendar URL may take
+	FetchTimeout time.Duration
+	// Whether calendar URLs may point to private networks and loopback
+	// addresses; off outside dev mode, so instructors cannot make the API
+	// fetch internal services
+	AllowPrivateURLs bool
+}
+
+// SchedulingConfig controls the lesson slots suggested to staff.
+type SchedulingConfig struct {
+	// Granularity of suggested start times, such as every half hour
+	SlotStep time.Duration
+	// Longest span a suggestion request may search
+	MaxRange time.Duration
+}
+
+// CORSConfig decides which browser origins may call the API.
+type CORSConfig struct {
+	// Origins such as https://app.example.com, or https://*.example.com for any
+	// subdomain; "*" allows every origin. Empty disables CORS.
+	AllowedOrigins []string
+	AllowedMethods []string
+	// Request headers a caller may send; "*" allows any
+	AllowedHeaders []string
+	// Response headers scripts may read
+	ExposedHeaders []string
+	// How long browsers may cache a preflight response
+	MaxAge time.Duration
+	// Let browsers send cookies and HTTP authentication; not allowed together with the "*" origin
+	AllowCredentials bool
+}
+
+type MongoConfig struct {
+	URI      string
+	Database string
+	// Deadline applied to each individual MongoDB operation
+	OperationTimeout time.Duration
+	// Apply pending schema migrations when the API starts
+	MigrateOnStartup bool
+}
+
+type KeycloakConfig struct {
+	// Base URL of the Keycloak server, without a trailing slash
+	URL          string
+	Realm        string
+	ClientID     string
+	ClientSecret string
+	// Deadline applied to each Keycloak call
+	Timeout time.Duration
+}
+
+type JWTConfig struct {
+	SecretKey   []byte
+	TokenExpiry time.Duration
+}
+
+type DevAdminConfig struct {
+	Email    string
+	Password string
+}
+
+// Addr returns the address the HTTP server listens on.
+func (c Config) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// Load reads the configuration and validates it. args are command-line flags
+// (usually os.Args[1:]); tools with flags of their own pass nil. The file is
+// taken from -config, then CONFIG_FILE, then ./.env if it exists.
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	file := fs.String("config", "", "path to a .env style configuration file")
+	fs.Int("port", 0, "port the HTTP server listens on (PORT)")
+	fs.Bool("dev", false, "run with in-memory storage and the local identity provider (DEV_MODE)")
+	fs.String("storage", "", "storage backend: mongo or memory (STORAGE)")
+	fs.String("identity-provider", "", "identity provider: keycloak or local (IDENTITY_PROVIDER)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	values, err := readFile(*file)
+	if err != nil {
+		return Config{}, err
+	}
+	for _, entry := range os.Environ() {
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			values[key] = value
+		}
+	}
+	flagKeys := map[string]string{"port": "PORT", "dev": "DEV_MODE", "storage": "STORAGE", "identity-provider": "IDENTITY_PROVIDER"}
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			values[key] = f.Value.String()
+		}
+	})
+
+	return Parse(values)
+}
+
+func readFile(path string) (map[string]string, error) {
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path == "" {
+		if _, err := os.Stat(".env"); err != nil {
+			return map[string]string{}, nil
+		}
+		path = ".env"
+	}
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// Parse builds a Config from raw key/value pairs and validates it, reporting
+// every problem at once. Keys missing from values take their defaults.
+func Parse(values map[string]string) (Config, error) {
+	r := reader{values: values}
+	var c Config
+
+	c.Port = r.int("PORT", 8081)
+	c.DevMode = r.bool("DEV_MODE", false)
+	c.Storage = r.string("STORAGE", c.devDefault("memory", "mongo"))
+	c.IdentityProvider = r.string("IDENTITY_PROVIDER", c.devDefault("local", "keycloak"))
+	c.InstructorDeletePolicy = r.string("INSTRUCTOR_DELETE_POLICY", "block")
+	c.LessonDuration = r.duration("LESSON_DURATION", time.Hour)
+	c.ShutdownTimeout = r.duration("SHUTDOWN_TIMEOUT", 15*time.Second)
+
+	c.Server = ServerConfig{
+		ReadHeaderTimeout: r.duration("READ_HEADER_TIMEOUT", 10*time.Second),
+		IdleTimeout:       r.duration("IDLE_TIMEOUT", 2*time.Minute),
+		MaxBodyBytes:      r.int("MAX_BODY_BYTES", 1<<20),
+		TrustedProxies:    r.list("TRUSTED_PROXIES", nil),
+		HSTSMaxAge:        r.duration("HSTS_MAX_AGE", 180*24*time.Hour),
+		TLS: TLSConfig{
+			CertFile: r.string("TLS_CERT_FILE", ""),
+			KeyFile:  r.string("TLS_KEY_FILE", ""),
+		},
+	}
+	c.Log = LogConfig{
+		Level:  r.level("LOG_LEVEL", slog.LevelInfo),
+		Format: r.string("LOG_FORMAT", c.devDefault("text", "json")),
+	}
+	c.Tracing = TracingConfig{
+		Enabled:     r.bool("TRACING_ENABLED", false),
+		Endpoint:    r.string("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"),
+		ServiceName: r.string("OTEL_SERVICE_NAME", "df-backend-go"),
+		SampleRatio: r.float("TRACING_SAMPLE_RATIO", 1),
+	}
+	c.Health = HealthConfig{
+		CheckTimeout: r.duration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		CacheTTL:     r.duration("HEALTH_CACHE_TTL", 5*time.Second),
+	}
+	c.RateLimit = RateLimitConfig{
+		PerIP:            r.int("RATE_LIMIT_PER_IP", 20),
+		PerAccount:       r.int("RATE_LIMIT_PER_ACCOUNT", 10),
+		Window:           r.duration("RATE_LIMIT_WINDOW", time.Minute),
+		LockoutThreshold: r.int("LOGIN_LOCKOUT_THRESHOLD", 5),
+		LockoutBase:      r.duration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LockoutMax:       r.duration("LOGIN_LOCKOUT_MAX", time.Hour),
+	}
+	c.Outbox = OutboxConfig{
+		PollInterval: r.duration("OUTBOX_POLL_INTERVAL", time.Second),
+		BatchSize:    r.int("OUTBOX_BATCH_SIZE", 100),
+		MaxAttempts:  r.int("OUTBOX_MAX_ATTEMPTS", 10),
+	}
+	c.Webhooks = WebhookConfig{
+		PollInterval: r.duration("WEBHOOK_POLL_INTERVAL", time.Second),
+		Timeout:      r.duration("WEBHOOK_TIMEOUT", 10*time.Second),
+		RetryBase:    r.duration("WEBHOOK_RETRY_BASE", 30*time.Second),
+		MaxAttempts:  r.int("WEBHOOK_MAX_ATTEMPTS", 10),
+
+		AllowPrivateURLs: r.bool("WEBHOOK_ALLOW_PRIVATE_URLS", c.DevMode),
+	}
+	c.Notifications = NotificationConfig{
+		Reminders:        r.durations("NOTIFICATION_REMINDERS", []time.Duration{24 * time.Hour, 2 * time.Hour}),
+		ReminderInterval: r.duration("NOTIFICATION_REMINDER_INTERVAL", time.Minute),
+		TimeZone:         r.string("NOTIFICATION_TIME_ZONE", "UTC"),
+		StreamPoll:       r.duration("NOTIFICATION_STREAM_POLL", 15*time.Second),
+		SMTP: SMTPConfig{
+			Host:     r.string("SMTP_HOST", ""),
+			Port:     r.int("SMTP_PORT", 587),
+			Username: r.string("SMTP_USERNAME", ""),
+			Password: r.string("SMTP_PASSWORD", ""),
+			From:     r.string("SMTP_FROM", "DriveFluency <no-reply@drivefluency.local>"),
+		},
+		SMSProvider: r.string("SMS_PROVIDER", "log"),
+		Twilio: TwilioConfig{
+			APIURL:     strings.TrimRight(r.string("TWILIO_API_URL", "https://api.twilio.com"), "/"),
+			AccountSID: r.string("TWILIO_ACCOUNT_SID", ""),
+			AuthToken:  r.string("TWILIO_AUTH_TOKEN", ""),
+			From:       r.string("TWILIO_FROM", ""),
+		},
+	}
+	c.Realtime = RealtimeConfig{
+		PingInterval:     r.duration("REALTIME_PING_INTERVAL", 30*time.Second),
+		MaxSubscriptions: r.int("REALTIME_MAX_SUBSCRIPTIONS", 20),
+	}
+	c.Calendar = CalendarConfig{
+		BaseURL:          r.string("CALENDAR_BASE_URL", ""),
+		History:          r.duration("CALENDAR_HISTORY", 30*24*time.Hour),
+		ImportHorizon:    r.duration("CALENDAR_IMPORT_HORIZON", 90*24*time.Hour),
+		SyncInterval:     r.duration("CALENDAR_SYNC_INTERVAL", time.Hour),
+		FetchTimeout:     r.duration("CALENDAR_FETCH_TIMEOUT", 30*time.Second),
+		AllowPrivateURLs: r.bool("CALENDAR_ALLOW_PRIVATE_URLS", c.DevMode),
 	}
-	if defaultValue == "" {
-		log.Fatalf("Environment variable %s is not set and no default value provided", key)
+	c.Scheduling = SchedulingConfig{
+		SlotStep: r.duration("SCHEDULING_SLOT_STEP", 30*time.Minute),
+		MaxRange: r.duration("SCHEDULING_MAX_RANGE", 31*24*time.Hour),
+	}
+	c.CORS = CORSConfig{
+		AllowedOrigins:   r.list("CORS_ALLOWED_ORIGINS", c.devList([]string{"*"}, nil)),
+		AllowedMethods:   r.list("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE"}),
+		AllowedHeaders:   r.list("CORS_ALLOWED_HEADERS", []string{"Accept", "Authorization", "Content-Type", "X-Requested-With", "X-Request-ID"}),
+		ExposedHeaders:   r.list("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}),
+		MaxAge:           r.duration("CORS_MAX_AGE", 10*time.Minute),
+		AllowCredentials: r.bool("CORS_ALLOW_CREDENTIALS", false),
+	}
+	c.Mongo = MongoConfig{
+		URI:              r.string("MONGODB_URI", "mongodb://localhost:27017"),
+		Database:         r.string("DATABASE_NAME", "drivefluency"),
+		OperationTimeout: r.duration("MONGO_OPERATION_TIMEOUT", 5*time.Second),
+		MigrateOnStartup: r.bool("MIGRATE_ON_STARTUP", true),
+	}
+	c.Keycloak = KeycloakConfig{
+		URL:          strings.TrimRight(r.string("KEYCLOAK_URL", "http://localhost:8080"), "/"),
+		Realm:        r.string("KEYCLOAK_REALM", "myrealm"),
+		ClientID:     r.string("KEYCLOAK_CLIENT_ID", "myclient"),
+		ClientSecret: r.string("KEYCLOAK_CLIENT_SECRET", "mysecret"),
+		Timeout:      r.duration("KEYCLOAK_TIMEOUT", 10*time.Second),
+	}
+	c.JWT = JWTConfig{
+		SecretKey:   []byte(r.string("JWT_SECRET_KEY", defaultJWTSecret)),
+		TokenExpiry: r.duration("TOKEN_EXPIRY", 24*time.Hour),
+	}
+	c.DevAdmin = DevAdminConfig{
+		Email:    r.string("DEV_ADMIN_EMAIL", "admin@drivefluency.local"),
+		Password: r.string("DEV_ADMIN_PASSWORD", "admin"),
+	}
+
+	errs := append(r.errs, c.Validate())
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return c, nil
+}
+
+func (c Config) devDefault(dev, prod string) string {
+	if c.DevMode {
+		return dev
+	}
+	return prod
+}
+
+func (c Config) devList(dev, prod []string) []string {
+	if c.DevMode {
+		return dev
+	}
+	return prod
+}
+
+// Validate checks that the configuration is usable, returning one error per problem.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Port > 0 && c.Port < 65536, "PORT must be between 1 and 65535, got %d", c.Port)
+	check(c.Storage == "mongo" || c.Storage == "memory", "STORAGE must be \"mongo\" or \"memory\", got %q", c.Storage)
+	check(c.IdentityProvider == "keycloak" || c.IdentityProvider == "local",
+		"IDENTITY_PROVIDER must be \"keycloak\" or \"local\", got %q", c.IdentityProvider)
+	check(c.InstructorDeletePolicy == "block" || c.InstructorDeletePolicy == "cancel",
+		"INSTRUCTOR_DELETE_POLICY must be \"block\" or \"cancel\", got %q", c.InstructorDeletePolicy)
+	check(c.Log.Format == "json" || c.Log.Format == "text", "LOG_FORMAT must be \"json\" or \"text\", got %q", c.Log.Format)
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "READ_HEADER_TIMEOUT must be positive")
+	check(c.Server.IdleTimeout > 0, "IDLE_TIMEOUT must be positive")
+	check(c.Server.MaxBodyBytes > 0, "MAX_BODY_BYTES must be positive")
+	check(c.Server.HSTSMaxAge >= 0, "HSTS_MAX_AGE must not be negative")
+	for _, proxy := range c.Server.TrustedProxies {
+		check(isIPOrCIDR(proxy), "TRUSTED_PROXIES must hold IP addresses or CIDR ranges, got %q", proxy)
+	}
+	if c.Server.TLS.Enabled() {
+		check(c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != "", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	check(c.Health.CheckTimeout > 0, "HEALTH_CHECK_TIMEOUT must be positive")
+	check(c.Health.CacheTTL >= 0, "HEALTH_CACHE_TTL must not be negative")
+	check(c.RateLimit.PerIP >= 0, "RATE_LIMIT_PER_IP must not be negative")
+	check(c.RateLimit.PerAccount >= 0, "RATE_LIMIT_PER_ACCOUNT must not be negative")
+	check(c.RateLimit.Window > 0, "RATE_LIMIT_WINDOW must be positive")
+	check(c.RateLimit.LockoutThreshold >= 0, "LOGIN_LOCKOUT_THRESHOLD must not be negative")
+	check(c.RateLimit.LockoutBase > 0, "LOGIN_LOCKOUT_BASE must be positive")
+	check(c.RateLimit.LockoutMax >= c.RateLimit.LockoutBase, "LOGIN_LOCKOUT_MAX must not be shorter than LOGIN_LOCKOUT_BASE")
+	for _, origin := range c.CORS.AllowedOrigins {
+		check(isOriginPattern(origin), "CORS_ALLOWED_ORIGINS must hold \"*\" or origins such as https://app.example.com or https://*.example.com, got %q", origin)
+		check(origin != "*" || !c.CORS.AllowCredentials, "CORS_ALLOWED_ORIGINS must not contain \"*\" when CORS_ALLOW_CREDENTIALS is true")
+	}
+	check(c.CORS.MaxAge >= 0, "CORS_MAX_AGE must not be negative")
+	check(c.Outbox.PollInterval > 0, "OUTBOX_POLL_INTERVAL must be positive")
+	check(c.Outbox.BatchSize > 0, "OUTBOX_BATCH_SIZE must be positive")
+	check(c.Outbox.MaxAttempts > 0, "OUTBOX_MAX_ATTEMPTS must be positive")
+	check(c.Webhooks.PollInterval > 0, "WEBHOOK_POLL_INTERVAL must be positive")
+	check(c.Webhooks.Timeout > 0, "WEBHOOK_TIMEOUT must be positive")
+	check(c.Webhooks.RetryBase > 0, "WEBHOOK_RETRY_BASE must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "WEBHOOK_MAX_ATTEMPTS must be positive")
+	for _, reminder := range c.Notifications.Reminders {
+		check(reminder > 0, "NOTIFICATION_REMINDERS must hold positive durations, got %v", reminder)
+	}
+	check(c.Notifications.ReminderInterval > 0, "NOTIFICATION_REMINDER_INTERVAL must be positive")
+	check(c.Notifications.StreamPoll > 0, "NOTIFICATION_STREAM_POLL must be positive")
+	check(c.Realtime.PingInterval > 0, "REALTIME_PING_INTERVAL must be positive")
+	check(c.Realtime.MaxSubscriptions > 0, "REALTIME_MAX_SUBSCRIPTIONS must be positive")
+	check(c.LessonDuration > 0, "LESSON_DURATION must be positive")
+	check(c.Calendar.History >= 0, "CALENDAR_HISTORY must not be negative")
+	check(c.Calendar.ImportHorizon > 0, "CALENDAR_IMPORT_HORIZON must be positive")
+	check(c.Calendar.SyncInterval > 0, "CALENDAR_SYNC_INTERVAL must be positive")
+	check(c.Calendar.FetchTimeout > 0, "CALENDAR_FETCH_TIMEOUT must be positive")
+	check(c.Scheduling.SlotStep > 0, "SCHEDULING_SLOT_STEP must be positive")
+	check(c.Scheduling.MaxRange > 0, "SCHEDULING_MAX_RANGE must be positive")
+	if c.Calendar.BaseURL != "" {
+		check(isHTTPURL(c.Calendar.BaseURL), "CALENDAR_BASE_URL must be an absolute http(s) URL, got %q", c.Calendar.BaseURL)
+	}
+	_, err := time.LoadLocation(c.Notifications.TimeZone)
+	check(err == nil, "NOTIFICATION_TIME_ZONE must be an IANA time zone such as Europe/Madrid, got %q", c.Notifications.TimeZone)
+	if c.Notifications.SMTP.Host != "" {
+		check(c.Notifications.SMTP.Port > 0 && c.Notifications.SMTP.Port < 65536, "SMTP_PORT must be between 1 and 65535, got %d", c.Notifications.SMTP.Port)
+		_, err := mail.ParseAddress(c.Notifications.SMTP.From)
+		check(err == nil, "SMTP_FROM must be an email address, got %q", c.Notifications.SMTP.From)
+	}
+	check(c.Notifications.SMSProvider == "log" || c.Notifications.SMSProvider == "twilio",
+		"SMS_PROVIDER must be \"log\" or \"twilio\", got %q", c.Notifications.SMSProvider)
+	if c.Notifications.SMSProvider == "twilio" {
+		check(isHTTPURL(c.Notifications.Twilio.APIURL), "TWILIO_API_URL must be an absolute http(s) URL, got %q", c.Notifications.Twilio.APIURL)
+		check(c.Notifications.Twilio.AccountSID != "" && c.Notifications.Twilio.AuthToken != "" && c.Notifications.Twilio.From != "",
+			"TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM must be set when SMS_PROVIDER=twilio")
+	}
+	check(c.Mongo.OperationTimeout > 0, "MONGO_OPERATION_TIMEOUT must be positive")
+	check(c.Keycloak.Timeout > 0, "KEYCLOAK_TIMEOUT must be positive")
+	check(c.JWT.TokenExpiry > 0, "TOKEN_EXPIRY must be positive")
+
+	if c.Tracing.Enabled {
+		check(isHTTPURL(c.Tracing.Endpoint), "OTEL_EXPORTER_OTLP_ENDPOINT must be an absolute http(s) URL, got %q", c.Tracing.Endpoint)
+		check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME must not be empty")
+		check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+	if c.Storage == "mongo" {
+		check(strings.HasPrefix(c.Mongo.URI, "mongodb://") || strings.HasPrefix(c.Mongo.URI, "mongodb+srv://"),
+			"MONGODB_URI must start with mongodb:// or mongodb+srv://")
+		check(c.Mongo.Database != "", "DATABASE_NAME must not be empty")
+	}
+	if c.IdentityProvider == "keycloak" {
+		check(isHTTPURL(c.Keycloak.URL), "KEYCLOAK_URL must be an absolute http(s) URL, got %q", c.Keycloak.URL)
+		check(c.Keycloak.Realm != "", "KEYCLOAK_REALM must not be empty")
+		check(c.Keycloak.ClientID != "", "KEYCLOAK_CLIENT_ID must not be empty")
+		check(c.Keycloak.ClientSecret != "", "KEYCLOAK_CLIENT_SECRET must not be empty")
+	}
+	if c.IdentityProvider == "local" {
+		check(len(c.JWT.SecretKey) > 0, "JWT_SECRET_KEY must not be empty")
+		check(c.DevMode || string(c.JWT.SecretKey) != defaultJWTSecret,
+			"JWT_SECRET_KEY must be changed from its default when IDENTITY_PROVIDER=local outside dev mode")
+	}
+	return errors.Join(errs...)
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isIPOrCIDR(s string) bool {
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return true
+	}
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+// isOriginPattern accepts "*" and scheme://host[:port] origins whose host may
+// start with a "*." wildcard label, without any path, query or user info.
+func isOriginPattern(s string) bool {
+	if s == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(s, "://*.", "://wildcard.", 1))
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.Path == "" && u.RawQuery == "" && u.Fragment == "" && !strings.Contains(u.Host, "*")
+}
+
+// reader converts raw values, remembering every conversion error.
+type reader struct {
+	values map[string]string
+	errs   []error
+}
+
+func (r *reader) string(key, defaultValue string) string {
+	if value, ok := r.values[key]; ok {
+		return value
 	}
 	return defaultValue
 }
+
+// list splits a comma-separated value, dropping blank items. An empty value gives an empty list.
+func (r *reader) list(key string, defaultValue []string) []string {
+	value, ok := r.values[key]
+	if !ok {
+		return defaultValue
+	}
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func (r *reader) int(key string, defaultValue int) int {
+	value, ok := r.values[key]
+	if !ok {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+	}
+	return n
+}
+
+func (r *reader) bool(key string, defaultValue bool) bool {
+	value, ok := r.values[key]
+	if !ok {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+	}
+	return b
+}
+
+func (r *reader) float(key string, defaultValue float64) float64 {
+	value, ok := r.values[key]
+	if !ok {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a number, got %q", key, value))
+	}
+	return f
+}
+
+func (r *reader) duration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := r.values[key]
+	if !ok {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be a duration such as 10s or 24h, got %q", key, value))
+	}
+	return d
+}
+
+// durations reads a comma-separated list of durations.
+func (r *reader) durations(key string, defaultValue []time.Duration) []time.Duration {
+	if _, ok := r.values[key]; !ok {
+		return defaultValue
+	}
+	var ds []time.Duration
+	for _, item := range r.list(key, nil) {
+		d, err := time.ParseDuration(item)
+		if err != nil {
+			r.errs = append(r.errs, fmt.Errorf("%s must be a list of durations such as 24h,2h, got %q", key, item))
+			continue
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+func (r *reader) level(key string, defaultValue slog.Level) slog.Level {
+	value, ok := r.values[key]
+	if !ok {
+		return defaultValue
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s must be debug, info, warn or error, got %q", key, value))
+	}
+	return level
+}
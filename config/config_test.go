@@ -0,0 +1,131 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDefaults(t *testing.T) {
+	cfg, err := Parse(map[string]string{})
+	if err != nil {
+		t.Fatalf("Expected the defaults to be valid but got %v", err)
+	}
+	if cfg.Addr() != ":8081" || cfg.Storage != "mongo" || cfg.IdentityProvider != "keycloak" {
+		t.Fatalf("Unexpected defaults %+v", cfg)
+	}
+	if cfg.Mongo.OperationTimeout != 5*time.Second || !cfg.Mongo.MigrateOnStartup {
+		t.Fatalf("Unexpected Mongo defaults %+v", cfg.Mongo)
+	}
+}
+
+func TestParseDevMode(t *testing.T) {
+	cfg, err := Parse(map[string]string{"DEV_MODE": "true"})
+	if err != nil {
+		t.Fatalf("Expected dev mode to be valid but got %v", err)
+	}
+	if cfg.Storage != "memory" || cfg.IdentityProvider != "local" {
+		t.Fatalf("Expected dev mode to default to memory/local but got %s/%s", cfg.Storage, cfg.IdentityProvider)
+	}
+
+	cfg, err = Parse(map[string]string{"DEV_MODE": "true", "STORAGE": "mongo"})
+	if err != nil || cfg.Storage != "mongo" {
+		t.Fatalf("Expected an explicit STORAGE to win over dev mode but got %q, %v", cfg.Storage, err)
+	}
+}
+
+func TestParseTrimsKeycloakURL(t *testing.T) {
+	cfg, err := Parse(map[string]string{"KEYCLOAK_URL": "http://keycloak:8080/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Keycloak.URL != "http://keycloak:8080" {
+		t.Fatalf("Expected the trailing slash to be trimmed but got %q", cfg.Keycloak.URL)
+	}
+}
+
+func TestParseReportsEveryError(t *testing.T) {
+	_, err := Parse(map[string]string{
+		"PORT":                     "80a",
+		"TOKEN_EXPIRY":             "forever",
+		"STORAGE":                  "postgres",
+		"INSTRUCTOR_DELETE_POLICY": "ignore",
+		"KEYCLOAK_URL":             "keycloak:8080",
+		"LOG_LEVEL":                "verbose",
+		"TLS_CERT_FILE":            "tls.crt",
+		"TRUSTED_PROXIES":          "10.0.0.0/8,proxy.local",
+		"OUTBOX_BATCH_SIZE":        "0",
+		"WEBHOOK_TIMEOUT":          "0s",
+		"NOTIFICATION_REMINDERS":   "24h,soon",
+		"SMS_PROVIDER":             "pigeon",
+		"CALENDAR_BASE_URL":        "api.example.com",
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{"PORT must be an integer", "TOKEN_EXPIRY must be a duration", "STORAGE must be", "INSTRUCTOR_DELETE_POLICY must be", "KEYCLOAK_URL must be an absolute", "LOG_LEVEL must be", "TLS_CERT_FILE and TLS_KEY_FILE", "TRUSTED_PROXIES must hold", "OUTBOX_BATCH_SIZE must be positive", "WEBHOOK_TIMEOUT must be positive", "NOTIFICATION_REMINDERS must be a list of durations", "SMS_PROVIDER must be", "CALENDAR_BASE_URL must be an absolute"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestParseCORSOrigins(t *testing.T) {
+	cfg, err := Parse(map[string]string{"CORS_ALLOWED_ORIGINS": " https://app.example.com, https://*.example.com:8443 ,"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"https://app.example.com", "https://*.example.com:8443"}; !slices.Equal(cfg.CORS.AllowedOrigins, want) {
+		t.Fatalf("Expected origins %v but got %v", want, cfg.CORS.AllowedOrigins)
+	}
+
+	for _, origin := range []string{"app.example.com", "https://app.example.com/", "https://app.*.com", "ftp://example.com"} {
+		if _, err := Parse(map[string]string{"CORS_ALLOWED_ORIGINS": origin}); err == nil || !strings.Contains(err.Error(), "CORS_ALLOWED_ORIGINS") {
+			t.Errorf("Expected origin %q to be rejected but got %v", origin, err)
+		}
+	}
+	if _, err := Parse(map[string]string{"CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"}); err == nil {
+		t.Fatal("Expected credentials to be refused for every origin")
+	}
+}
+
+func TestValidateLocalProviderSecret(t *testing.T) {
+	if _, err := Parse(map[string]string{"IDENTITY_PROVIDER": "local"}); err == nil || !strings.Contains(err.Error(), "JWT_SECRET_KEY") {
+		t.Fatalf("Expected the default JWT secret to be rejected outside dev mode but got %v", err)
+	}
+	if _, err := Parse(map[string]string{"IDENTITY_PROVIDER": "local", "JWT_SECRET_KEY": "s3cret"}); err != nil {
+		t.Fatalf("Expected a custom JWT secret to be accepted but got %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.env")
+	if err := os.WriteFile(file, []byte("PORT=9000\nDATABASE_NAME=from-file\nSTORAGE=memory\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DATABASE_NAME", "from-env")
+	t.Setenv("STORAGE", "mongo")
+
+	cfg, err := Load([]string{"-config", file, "-storage", "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Port != 9000 {
+		t.Errorf("Expected PORT from the file but got %d", cfg.Port)
+	}
+	if cfg.Mongo.Database != "from-env" {
+		t.Errorf("Expected the environment to override the file but got %q", cfg.Mongo.Database)
+	}
+	if cfg.Storage != "memory" {
+		t.Errorf("Expected the flag to override the environment but got %q", cfg.Storage)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load([]string{"-config", filepath.Join(t.TempDir(), "missing.env")}); err == nil {
+		t.Fatal("Expected an error for a missing config file")
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/admins"
+	"github.com/lucasgarciaf/df-backend-go/internal/ratelimit"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AdminHandler struct {
 	service *admins.AdminService
+	logins  *ratelimit.Logins
 }
 
-func NewAdminHandler(service *admins.AdminService) *AdminHandler {
-	return &AdminHandler{service: service}
+func NewAdminHandler(service *admins.AdminService, logins *ratelimit.Logins) *AdminHandler {
+	return &AdminHandler{service: service, logins: logins}
 }
 
 func (h *AdminHandler) Register(c *gin.Context) {
@@ -25,7 +28,7 @@ func (h *AdminHandler) Register(c *gin.Context) {
 		Password  string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	admin := admins.Admin{
@@ -34,9 +37,9 @@ func (h *AdminHandler) Register(c *gin.Context) {
 		LastName:  req.LastName,
 		Email:     req.Email,
 	}
-	id, err := h.service.CreateAdmin(admin, req.Password)
+	id, err := h.service.CreateAdmin(c.Request.Context(), admin, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"id": id.Hex()})
@@ -48,12 +51,20 @@ func (h *AdminHandler) Login(c *gin.Context) {
 		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&credentials); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	token, err := h.service.Authenticate(credentials.Email, credentials.Password)
+	// Locked out and throttled accounts are refused before the identity provider is asked
+	decision, err := h.logins.Allow(c.Request.Context(), credentials.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		decision.WriteHeaders(c.Writer.Header())
+		c.Error(err)
+		return
+	}
+	token, err := h.service.Authenticate(c.Request.Context(), credentials.Email, credentials.Password)
+	h.logins.Record(c.Request.Context(), credentials.Email, err)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"token": token})
@@ -64,11 +75,11 @@ func (h *AdminHandler) Logout(c *gin.Context) {
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	if err := h.service.Logout(req.RefreshToken); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
@@ -77,12 +88,12 @@ func (h *AdminHandler) Logout(c *gin.Context) {
 func (h *AdminHandler) CreateAdmin(c *gin.Context) {
 	var admin admins.Admin
 	if err := c.ShouldBindJSON(&admin); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	id, err := h.service.CreateAdmin(admin, admin.PasswordHash)
+	id, err := h.service.CreateAdmin(c.Request.Context(), admin, admin.PasswordHash)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, id)
@@ -91,12 +102,12 @@ func (h *AdminHandler) CreateAdmin(c *gin.Context) {
 func (h *AdminHandler) GetAdminByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	admin, err := h.service.GetAdminByID(id)
+	admin, err := h.service.GetAdminByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, admin)
@@ -104,9 +115,9 @@ func (h *AdminHandler) GetAdminByID(c *gin.Context) {
 
 func (h *AdminHandler) GetAdminByEmail(c *gin.Context) {
 	email := c.Param("email")
-	admin, err := h.service.GetAdminByEmail(email)
+	admin, err := h.service.GetAdminByEmail(c.Request.Context(), email)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, admin)
@@ -115,17 +126,17 @@ func (h *AdminHandler) GetAdminByEmail(c *gin.Context) {
 func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var admin admins.Admin
 	if err := c.ShouldBindJSON(&admin); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	admin.ID = id
-	if err := h.service.UpdateAdmin(admin); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateAdmin(c.Request.Context(), admin); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -134,11 +145,11 @@ func (h *AdminHandler) UpdateAdmin(c *gin.Context) {
 func (h *AdminHandler) DeleteAdmin(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteAdmin(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteAdmin(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
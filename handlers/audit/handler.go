@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditHandler struct {
+	repo audit.AuditRepository
+}
+
+func NewAuditHandler(repo audit.AuditRepository) *AuditHandler {
+	return &AuditHandler{repo: repo}
+}
+
+type listQuery struct {
+	Actor      string    `form:"actor"`
+	Resource   string    `form:"resource"`
+	ResourceID string    `form:"resource_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit      int       `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// ListEntries returns the audit entries matching the query, newest first. from
+// and to are RFC 3339 timestamps; to is exclusive.
+func (h *AuditHandler) ListEntries(c *gin.Context) {
+	var query listQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	filter := audit.Filter{
+		ActorID:  query.Actor,
+		Resource: query.Resource,
+		From:     query.From,
+		To:       query.To,
+		Limit:    query.Limit,
+	}
+	if query.ResourceID != "" {
+		id, err := primitive.ObjectIDFromHex(query.ResourceID)
+		if err != nil {
+			c.Error(apperr.Invalid("invalid_id", "resource_id must be a 24 character hex string"))
+			return
+		}
+		filter.ResourceID = id
+	}
+
+	entries, err := h.repo.ListEntries(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -19,12 +20,14 @@ func NewAvailabilityHandler(service *availability.AvailabilityService) *Availabi
 func (h *AvailabilityHandler) CreateAvailability(c *gin.Context) {
 	var availability availability.Availability
 	if err := c.ShouldBindJSON(&availability); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	id, err := h.service.CreateAvailability(availability)
+	// Only calendar imports tie windows to events
+	availability.Import = nil
+	id, err := h.service.CreateAvailability(c.Request.Context(), availability)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, id)
@@ -33,12 +36,12 @@ func (h *AvailabilityHandler) CreateAvailability(c *gin.Context) {
 func (h *AvailabilityHandler) GetAvailabilityByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	availability, err := h.service.GetAvailabilityByID(id)
+	availability, err := h.service.GetAvailabilityByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, availability)
@@ -47,17 +50,18 @@ func (h *AvailabilityHandler) GetAvailabilityByID(c *gin.Context) {
 func (h *AvailabilityHandler) UpdateAvailability(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var availability availability.Availability
 	if err := c.ShouldBindJSON(&availability); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	availability.ID = id
-	if err := h.service.UpdateAvailability(availability); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	availability.Import = nil
+	if err := h.service.UpdateAvailability(c.Request.Context(), availability); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -66,11 +70,11 @@ func (h *AvailabilityHandler) UpdateAvailability(c *gin.Context) {
 func (h *AvailabilityHandler) DeleteAvailability(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteAvailability(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteAvailability(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
@@ -1,30 +1,37 @@
 package availability
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.ErrorHandler(logging.Discard()))
 	return r
 }
 
 func TestCreateAvailability(t *testing.T) {
 	r := setupRouter()
-	availabilityService := availability.NewAvailabilityService(&MockAvailabilityRepository{})
+	availabilityService := availability.NewAvailabilityService(&MockAvailabilityRepository{}, audit.Discard, events.Discard)
 	availabilityHandler := NewAvailabilityHandler(availabilityService)
 
 	r.POST("/availability", availabilityHandler.CreateAvailability)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/availability", nil) // Add appropriate body content for the request
+	req, _ := http.NewRequest("POST", "/availability", strings.NewReader(`{"InstructorID":"60c72b2f9b1d8b6a8f8a53e2","StartTime":"2030-03-04T09:00:00Z","EndTime":"2030-03-04T12:00:00Z"}`))
 
 	r.ServeHTTP(w, req)
 
@@ -35,18 +42,22 @@ func TestCreateAvailability(t *testing.T) {
 
 type MockAvailabilityRepository struct{}
 
-func (m *MockAvailabilityRepository) CreateAvailability(availability availability.Availability) (primitive.ObjectID, error) {
+func (m *MockAvailabilityRepository) CreateAvailability(ctx context.Context, availability availability.Availability) (primitive.ObjectID, error) {
 	return primitive.NewObjectID(), nil
 }
 
-func (m *MockAvailabilityRepository) GetAvailabilityByID(id primitive.ObjectID) (*availability.Availability, error) {
+func (m *MockAvailabilityRepository) GetAvailabilityByID(ctx context.Context, id primitive.ObjectID) (*availability.Availability, error) {
 	return &availability.Availability{}, nil
 }
 
-func (m *MockAvailabilityRepository) UpdateAvailability(availability availability.Availability) error {
+func (m *MockAvailabilityRepository) ListAvailability(ctx context.Context, filter availability.Filter) ([]availability.Availability, error) {
+	return []availability.Availability{}, nil
+}
+
+func (m *MockAvailabilityRepository) UpdateAvailability(ctx context.Context, availability availability.Availability) error {
 	return nil
 }
 
-func (m *MockAvailabilityRepository) DeleteAvailability(id primitive.ObjectID) error {
+func (m *MockAvailabilityRepository) DeleteAvailability(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
@@ -0,0 +1,101 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const contentType = "text/calendar; charset=utf-8"
+
+type CalendarHandler struct {
+	service *calendar.Service
+	cfg     config.CalendarConfig
+}
+
+func NewCalendarHandler(service *calendar.Service, cfg config.CalendarConfig) *CalendarHandler {
+	return &CalendarHandler{service: service, cfg: cfg}
+}
+
+// CreateFeed gives the caller a new secret feed link, revoking the old one.
+func (h *CalendarHandler) CreateFeed(c *gin.Context) {
+	token, err := h.service.CreateFeed(c.Request.Context(), middleware.Principal(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"url": h.baseURL(c) + "/ical/" + token + ".ics"})
+}
+
+// DeleteFeed revokes the caller's feed link.
+func (h *CalendarHandler) DeleteFeed(c *gin.Context) {
+	if err := h.service.DeleteFeed(c.Request.Context(), middleware.Principal(c)); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Feed serves /ical/{token}.ics. The token is the only credential, since
+// calendar apps cannot send one.
+func (h *CalendarHandler) Feed(c *gin.Context) {
+	token, ok := strings.CutSuffix(c.Param("file"), ".ics")
+	if !ok {
+		c.Error(apperr.ResourceNotFound("calendar feed"))
+		return
+	}
+	cal, err := h.service.Feed(c.Request.Context(), token)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// Subscribers poll; a feed is only ever seen by whoever holds its link
+	c.Header("Cache-Control", "private, max-age=300")
+	render(c, cal)
+}
+
+// LessonICS downloads a single lesson of the caller's as an .ics file.
+func (h *CalendarHandler) LessonICS(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
+		return
+	}
+	cal, err := h.service.LessonCalendar(c.Request.Context(), middleware.Principal(c), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="lesson-%s.ics"`, id.Hex()))
+	render(c, cal)
+}
+
+func render(c *gin.Context, cal *calendar.Calendar) {
+	var body bytes.Buffer
+	if err := cal.Encode(&body); err != nil {
+		c.Error(apperr.Internal(err))
+		return
+	}
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+// baseURL is where feed links point: CALENDAR_BASE_URL, or else the host the
+// request was sent to.
+func (h *CalendarHandler) baseURL(c *gin.Context) string {
+	if h.cfg.BaseURL != "" {
+		return strings.TrimSuffix(h.cfg.BaseURL, "/")
+	}
+	scheme := "http"
+	if middleware.IsHTTPS(c) {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
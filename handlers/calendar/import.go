@@ -0,0 +1,123 @@
+package calendar
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type importQuery struct {
+	DryRun bool `form:"dry_run"`
+}
+
+type importURLRequest struct {
+	URL string `json:"url" binding:"required"`
+	// Sync imports the URL again every CALENDAR_SYNC_INTERVAL
+	Sync bool `json:"sync"`
+}
+
+type ImportHandler struct {
+	importer *calendar.Importer
+}
+
+func NewImportHandler(importer *calendar.Importer) *ImportHandler {
+	return &ImportHandler{importer: importer}
+}
+
+// Import imports a calendar into an instructor's availability. The calendar
+// is uploaded as the "file" field of a multipart form or as a text/calendar
+// body, or given as a JSON {"url": ...} to fetch. With ?dry_run=true nothing
+// changes and the result previews the import.
+func (h *ImportHandler) Import(c *gin.Context) {
+	instructorID, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	var query importQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	ctx := c.Request.Context()
+	var result *calendar.ImportResult
+	var err error
+	switch c.ContentType() {
+	case "multipart/form-data":
+		file, ferr := c.FormFile("file")
+		if ferr != nil {
+			c.Error(apperr.Invalid("invalid_request", "the form must have a file field holding the calendar"))
+			return
+		}
+		f, ferr := file.Open()
+		if ferr != nil {
+			c.Error(apperr.Invalid("invalid_request", ferr.Error()))
+			return
+		}
+		defer f.Close()
+		result, err = h.importer.ImportFile(ctx, middleware.Principal(c), instructorID, f, query.DryRun)
+	case "text/calendar":
+		result, err = h.importer.ImportFile(ctx, middleware.Principal(c), instructorID, c.Request.Body, query.DryRun)
+	case "application/json":
+		var req importURLRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(apperr.Invalid("invalid_request", err.Error()))
+			return
+		}
+		result, err = h.importer.ImportURL(ctx, middleware.Principal(c), instructorID, req.URL, req.Sync, query.DryRun)
+	default:
+		c.Error(apperr.Invalid("unsupported_media_type", "send the calendar as multipart/form-data or text/calendar, or its URL as application/json"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ListSyncs lists the calendar URLs synced into an instructor's availability.
+func (h *ImportHandler) ListSyncs(c *gin.Context) {
+	instructorID, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	syncs, err := h.importer.ListSyncs(c.Request.Context(), middleware.Principal(c), instructorID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if syncs == nil {
+		syncs = []calendar.Sync{}
+	}
+	c.JSON(http.StatusOK, syncs)
+}
+
+// DeleteSync stops syncing a calendar URL, keeping what it imported.
+func (h *ImportHandler) DeleteSync(c *gin.Context) {
+	instructorID, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	id, ok := objectID(c, "sync_id")
+	if !ok {
+		return
+	}
+	if err := h.importer.DeleteSync(c.Request.Context(), middleware.Principal(c), instructorID, id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func objectID(c *gin.Context, param string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param(param))
+	if err != nil {
+		c.Error(apperr.Invalid("invalid_id", param+" must be a 24 character hex string"))
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
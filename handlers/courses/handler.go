@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -19,12 +20,12 @@ func NewCourseHandler(service *courses.CourseService) *CourseHandler {
 func (h *CourseHandler) CreateCourse(c *gin.Context) {
 	var course courses.Course
 	if err := c.ShouldBindJSON(&course); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	id, err := h.service.CreateCourse(course)
+	id, err := h.service.CreateCourse(c.Request.Context(), course)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, id)
@@ -33,12 +34,12 @@ func (h *CourseHandler) CreateCourse(c *gin.Context) {
 func (h *CourseHandler) GetCourseByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	course, err := h.service.GetCourseByID(id)
+	course, err := h.service.GetCourseByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, course)
@@ -47,17 +48,17 @@ func (h *CourseHandler) GetCourseByID(c *gin.Context) {
 func (h *CourseHandler) UpdateCourse(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var course courses.Course
 	if err := c.ShouldBindJSON(&course); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	course.ID = id
-	if err := h.service.UpdateCourse(course); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateCourse(c.Request.Context(), course); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -66,11 +67,11 @@ func (h *CourseHandler) UpdateCourse(c *gin.Context) {
 func (h *CourseHandler) DeleteCourse(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteCourse(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteCourse(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
@@ -1,30 +1,37 @@
 package courses
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.ErrorHandler(logging.Discard()))
 	return r
 }
 
 func TestCreateCourse(t *testing.T) {
 	r := setupRouter()
-	courseService := courses.NewCourseService(&MockCourseRepository{})
+	courseService := courses.NewCourseService(&MockCourseRepository{}, &MockDeletionGuard{}, audit.Discard)
 	courseHandler := NewCourseHandler(courseService)
 
 	r.POST("/courses", courseHandler.CreateCourse)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/courses", nil) // Add appropriate body content for the request
+	req, _ := http.NewRequest("POST", "/courses", strings.NewReader(`{"Title":"Beginner Driving","Description":"Basics","Duration":20}`))
 
 	r.ServeHTTP(w, req)
 
@@ -33,20 +40,46 @@ func TestCreateCourse(t *testing.T) {
 	}
 }
 
+func TestDeleteCourseReferencedByLessons(t *testing.T) {
+	r := setupRouter()
+	guard := &MockDeletionGuard{err: apperr.Validation("dangling_references", "course is still referenced by lessons")}
+	courseService := courses.NewCourseService(&MockCourseRepository{}, guard, audit.Discard)
+	courseHandler := NewCourseHandler(courseService)
+
+	r.DELETE("/courses/:id", courseHandler.DeleteCourse)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/courses/60c72b2f9b1d8b6a8f8a53e0", nil)
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %v but got %v", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
 type MockCourseRepository struct{}
 
-func (m *MockCourseRepository) CreateCourse(course courses.Course) (primitive.ObjectID, error) {
+func (m *MockCourseRepository) CreateCourse(ctx context.Context, course courses.Course) (primitive.ObjectID, error) {
 	return primitive.NewObjectID(), nil
 }
 
-func (m *MockCourseRepository) GetCourseByID(id primitive.ObjectID) (*courses.Course, error) {
+func (m *MockCourseRepository) GetCourseByID(ctx context.Context, id primitive.ObjectID) (*courses.Course, error) {
 	return &courses.Course{}, nil
 }
 
-func (m *MockCourseRepository) UpdateCourse(course courses.Course) error {
+func (m *MockCourseRepository) UpdateCourse(ctx context.Context, course courses.Course) error {
 	return nil
 }
 
-func (m *MockCourseRepository) DeleteCourse(id primitive.ObjectID) error {
+func (m *MockCourseRepository) DeleteCourse(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
+
+type MockDeletionGuard struct {
+	err error
+}
+
+func (m *MockDeletionGuard) BeforeDeleteCourse(ctx context.Context, id primitive.ObjectID) error {
+	return m.err
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/health"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{registry: registry}
+}
+
+// Live reports that the process is up and serving. It checks no dependency:
+// restarting the API would not fix an unreachable database.
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+}
+
+// Ready reports whether the instance should receive traffic. The public
+// answer carries no detail; admins get the full report from Report.
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.registry.Run(c.Request.Context())
+	c.JSON(statusCode(report), gin.H{"status": report.Status})
+}
+
+// Health is the original endpoint, kept for existing monitors. It now
+// follows readiness instead of always answering healthy.
+func (h *HealthHandler) Health(c *gin.Context) {
+	report := h.registry.Run(c.Request.Context())
+	status := "healthy"
+	if report.Status != health.StatusOK {
+		status = "unhealthy"
+	}
+	c.JSON(statusCode(report), gin.H{"status": status})
+}
+
+// Report returns every check with its timing and error.
+func (h *HealthHandler) Report(c *gin.Context) {
+	report := h.registry.Run(c.Request.Context())
+	c.JSON(statusCode(report), report)
+}
+
+func statusCode(report health.Report) int {
+	if report.Status != health.StatusOK {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
@@ -4,16 +4,19 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/ratelimit"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type InstructorHandler struct {
 	service *instructors.InstructorService
+	logins  *ratelimit.Logins
 }
 
-func NewInstructorHandler(service *instructors.InstructorService) *InstructorHandler {
-	return &InstructorHandler{service: service}
+func NewInstructorHandler(service *instructors.InstructorService, logins *ratelimit.Logins) *InstructorHandler {
+	return &InstructorHandler{service: service, logins: logins}
 }
 
 func (h *InstructorHandler) Register(c *gin.Context) {
@@ -25,7 +28,7 @@ func (h *InstructorHandler) Register(c *gin.Context) {
 		Password  string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	instructor := instructors.Instructor{
@@ -34,9 +37,9 @@ func (h *InstructorHandler) Register(c *gin.Context) {
 		LastName:  req.LastName,
 		Email:     req.Email,
 	}
-	id, err := h.service.CreateInstructor(instructor, req.Password)
+	id, err := h.service.CreateInstructor(c.Request.Context(), instructor, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"id": id.Hex()})
@@ -48,12 +51,20 @@ func (h *InstructorHandler) Login(c *gin.Context) {
 		Password string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&credentials); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	token, err := h.service.Authenticate(credentials.Email, credentials.Password)
+	// Locked out and throttled accounts are refused before the identity provider is asked
+	decision, err := h.logins.Allow(c.Request.Context(), credentials.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		decision.WriteHeaders(c.Writer.Header())
+		c.Error(err)
+		return
+	}
+	token, err := h.service.Authenticate(c.Request.Context(), credentials.Email, credentials.Password)
+	h.logins.Record(c.Request.Context(), credentials.Email, err)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"token": token})
@@ -64,11 +75,11 @@ func (h *InstructorHandler) Logout(c *gin.Context) {
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	if err := h.service.Logout(req.RefreshToken); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
@@ -77,12 +88,12 @@ func (h *InstructorHandler) Logout(c *gin.Context) {
 func (h *InstructorHandler) CreateInstructor(c *gin.Context) {
 	var instructor instructors.Instructor
 	if err := c.ShouldBindJSON(&instructor); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	id, err := h.service.CreateInstructor(instructor, instructor.PasswordHash)
+	id, err := h.service.CreateInstructor(c.Request.Context(), instructor, instructor.PasswordHash)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, id)
@@ -91,12 +102,12 @@ func (h *InstructorHandler) CreateInstructor(c *gin.Context) {
 func (h *InstructorHandler) GetInstructorByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	instructor, err := h.service.GetInstructorByID(id)
+	instructor, err := h.service.GetInstructorByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, instructor)
@@ -105,17 +116,17 @@ func (h *InstructorHandler) GetInstructorByID(c *gin.Context) {
 func (h *InstructorHandler) UpdateInstructor(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var instructor instructors.Instructor
 	if err := c.ShouldBindJSON(&instructor); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	instructor.ID = id
-	if err := h.service.UpdateInstructor(instructor); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateInstructor(c.Request.Context(), instructor); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -124,11 +135,11 @@ func (h *InstructorHandler) UpdateInstructor(c *gin.Context) {
 func (h *InstructorHandler) DeleteInstructor(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteInstructor(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteInstructor(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
@@ -1,30 +1,40 @@
 package instructors
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.ErrorHandler(logging.Discard()))
 	return r
 }
 
 func TestCreateInstructor(t *testing.T) {
 	r := setupRouter()
-	instructorService := instructors.NewInstructorService(&MockInstructorRepository{})
-	instructorHandler := NewInstructorHandler(instructorService)
+	instructorService := instructors.NewInstructorService(&MockInstructorRepository{}, &MockDeletionGuard{}, identity.NewLocal([]byte("test-secret"), time.Hour), audit.Discard, events.Discard, logging.Discard())
+	instructorHandler := NewInstructorHandler(instructorService, nil)
 
 	r.POST("/instructors", instructorHandler.CreateInstructor)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/instructors", nil) // Add appropriate body content for the request
+	req, _ := http.NewRequest("POST", "/instructors", strings.NewReader(`{"username":"hcastro","firstName":"Hugo","lastName":"Castro","email":"hugo@example.com"}`))
 
 	r.ServeHTTP(w, req)
 
@@ -35,22 +45,34 @@ func TestCreateInstructor(t *testing.T) {
 
 type MockInstructorRepository struct{}
 
-func (m *MockInstructorRepository) CreateInstructor(instructor instructors.Instructor) (primitive.ObjectID, error) {
+func (m *MockInstructorRepository) CreateInstructor(ctx context.Context, instructor instructors.Instructor) (primitive.ObjectID, error) {
 	return primitive.NewObjectID(), nil
 }
 
-func (m *MockInstructorRepository) GetInstructorByID(id primitive.ObjectID) (*instructors.Instructor, error) {
+func (m *MockInstructorRepository) GetInstructorByID(ctx context.Context, id primitive.ObjectID) (*instructors.Instructor, error) {
 	return &instructors.Instructor{}, nil
 }
 
-func (m *MockInstructorRepository) GetInstructorByEmail(email string) (*instructors.Instructor, error) {
-	return &instructors.Instructor{}, nil
+func (m *MockInstructorRepository) GetInstructorByEmail(ctx context.Context, email string) (*instructors.Instructor, error) {
+	return nil, apperr.ResourceNotFound("instructor")
+}
+
+func (m *MockInstructorRepository) UpdateInstructor(ctx context.Context, instructor instructors.Instructor) error {
+	return nil
 }
 
-func (m *MockInstructorRepository) UpdateInstructor(instructor instructors.Instructor) error {
+func (m *MockInstructorRepository) DeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
 
-func (m *MockInstructorRepository) DeleteInstructor(id primitive.ObjectID) error {
+type MockDeletionGuard struct {
+	err error
+}
+
+func (m *MockDeletionGuard) BeforeDeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
+	return m.err
+}
+
+func (m *MockDeletionGuard) AfterDeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
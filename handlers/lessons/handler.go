@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -19,12 +20,12 @@ func NewLessonHandler(service *lessons.LessonService) *LessonHandler {
 func (h *LessonHandler) CreateLesson(c *gin.Context) {
 	var lesson lessons.Lesson
 	if err := c.ShouldBindJSON(&lesson); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	id, err := h.service.CreateLesson(lesson)
+	id, err := h.service.CreateLesson(c.Request.Context(), lesson)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, id)
@@ -33,12 +34,12 @@ func (h *LessonHandler) CreateLesson(c *gin.Context) {
 func (h *LessonHandler) GetLessonByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	lesson, err := h.service.GetLessonByID(id)
+	lesson, err := h.service.GetLessonByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, lesson)
@@ -47,17 +48,17 @@ func (h *LessonHandler) GetLessonByID(c *gin.Context) {
 func (h *LessonHandler) UpdateLesson(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var lesson lessons.Lesson
 	if err := c.ShouldBindJSON(&lesson); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	lesson.ID = id
-	if err := h.service.UpdateLesson(lesson); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateLesson(c.Request.Context(), lesson); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -66,11 +67,11 @@ func (h *LessonHandler) UpdateLesson(c *gin.Context) {
 func (h *LessonHandler) DeleteLesson(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteLesson(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteLesson(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
@@ -1,30 +1,38 @@
 package lessons
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.ErrorHandler(logging.Discard()))
 	return r
 }
 
 func TestCreateLesson(t *testing.T) {
 	r := setupRouter()
-	lessonService := lessons.NewLessonService(&MockLessonRepository{})
+	lessonService := lessons.NewLessonService(&MockLessonRepository{}, &MockReferenceValidator{}, audit.Discard, events.Discard)
 	lessonHandler := NewLessonHandler(lessonService)
 
 	r.POST("/lessons", lessonHandler.CreateLesson)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/lessons", nil) // Add appropriate body content for the request
+	req, _ := http.NewRequest("POST", "/lessons", strings.NewReader(`{"CourseID":"60c72b2f9b1d8b6a8f8a53e1","InstructorID":"60c72b2f9b1d8b6a8f8a53e2","Title":"Parking","Schedule":"2030-03-04T09:00:00Z"}`))
 
 	r.ServeHTTP(w, req)
 
@@ -35,7 +43,7 @@ func TestCreateLesson(t *testing.T) {
 
 func TestGetLessonByID(t *testing.T) {
 	r := setupRouter()
-	lessonService := lessons.NewLessonService(&MockLessonRepository{})
+	lessonService := lessons.NewLessonService(&MockLessonRepository{}, &MockReferenceValidator{}, audit.Discard, events.Discard)
 	lessonHandler := NewLessonHandler(lessonService)
 
 	r.GET("/lessons/:id", lessonHandler.GetLessonByID)
@@ -52,13 +60,13 @@ func TestGetLessonByID(t *testing.T) {
 
 func TestUpdateLesson(t *testing.T) {
 	r := setupRouter()
-	lessonService := lessons.NewLessonService(&MockLessonRepository{})
+	lessonService := lessons.NewLessonService(&MockLessonRepository{}, &MockReferenceValidator{}, audit.Discard, events.Discard)
 	lessonHandler := NewLessonHandler(lessonService)
 
 	r.PUT("/lessons/:id", lessonHandler.UpdateLesson)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("PUT", "/lessons/60c72b2f9b1d8b6a8f8a53e0", nil) // Add appropriate body content for the request
+	req, _ := http.NewRequest("PUT", "/lessons/60c72b2f9b1d8b6a8f8a53e0", strings.NewReader(`{"CourseID":"60c72b2f9b1d8b6a8f8a53e1","InstructorID":"60c72b2f9b1d8b6a8f8a53e2","Title":"Parking","Schedule":"2030-03-04T09:00:00Z"}`))
 
 	r.ServeHTTP(w, req)
 
@@ -69,7 +77,7 @@ func TestUpdateLesson(t *testing.T) {
 
 func TestDeleteLesson(t *testing.T) {
 	r := setupRouter()
-	lessonService := lessons.NewLessonService(&MockLessonRepository{})
+	lessonService := lessons.NewLessonService(&MockLessonRepository{}, &MockReferenceValidator{}, audit.Discard, events.Discard)
 	lessonHandler := NewLessonHandler(lessonService)
 
 	r.DELETE("/lessons/:id", lessonHandler.DeleteLesson)
@@ -84,20 +92,55 @@ func TestDeleteLesson(t *testing.T) {
 	}
 }
 
+func TestCreateLessonWithUnknownReferences(t *testing.T) {
+	r := setupRouter()
+	validator := &MockReferenceValidator{err: apperr.Validation("unknown_references", "lesson references unknown documents").
+		WithDetail("references", []string{"60c72b2f9b1d8b6a8f8a53e1"})}
+	lessonService := lessons.NewLessonService(&MockLessonRepository{}, validator, audit.Discard, events.Discard)
+	lessonHandler := NewLessonHandler(lessonService)
+
+	r.POST("/lessons", lessonHandler.CreateLesson)
+
+	w := httptest.NewRecorder()
+	body := `{"CourseID":"60c72b2f9b1d8b6a8f8a53e1","InstructorID":"60c72b2f9b1d8b6a8f8a53e2","Title":"Parking"}`
+	req, _ := http.NewRequest("POST", "/lessons", strings.NewReader(body))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %v but got %v", http.StatusUnprocessableEntity, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "60c72b2f9b1d8b6a8f8a53e1") {
+		t.Fatalf("Expected response to list the missing course, got %s", w.Body.String())
+	}
+}
+
 type MockLessonRepository struct{}
 
-func (m *MockLessonRepository) CreateLesson(lesson lessons.Lesson) (primitive.ObjectID, error) {
+func (m *MockLessonRepository) CreateLesson(ctx context.Context, lesson lessons.Lesson) (primitive.ObjectID, error) {
 	return primitive.NewObjectID(), nil
 }
 
-func (m *MockLessonRepository) GetLessonByID(id primitive.ObjectID) (*lessons.Lesson, error) {
+func (m *MockLessonRepository) GetLessonByID(ctx context.Context, id primitive.ObjectID) (*lessons.Lesson, error) {
 	return &lessons.Lesson{}, nil
 }
 
-func (m *MockLessonRepository) UpdateLesson(lesson lessons.Lesson) error {
+func (m *MockLessonRepository) ListLessons(ctx context.Context, filter lessons.Filter) ([]lessons.Lesson, error) {
+	return []lessons.Lesson{}, nil
+}
+
+func (m *MockLessonRepository) UpdateLesson(ctx context.Context, lesson lessons.Lesson) error {
 	return nil
 }
 
-func (m *MockLessonRepository) DeleteLesson(id primitive.ObjectID) error {
+func (m *MockLessonRepository) DeleteLesson(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
+
+type MockReferenceValidator struct {
+	err error
+}
+
+func (m *MockReferenceValidator) ValidateLesson(ctx context.Context, lesson lessons.Lesson) error {
+	return m.err
+}
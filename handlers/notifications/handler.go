@@ -0,0 +1,204 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationHandler serves the caller's own inbox. The recipient is always
+// the authenticated principal, so there is nothing to authorize.
+type NotificationHandler struct {
+	inbox  *notifications.Inbox
+	poll   time.Duration
+	logger *slog.Logger
+}
+
+func NewNotificationHandler(inbox *notifications.Inbox, poll time.Duration, logger *slog.Logger) *NotificationHandler {
+	return &NotificationHandler{inbox: inbox, poll: poll, logger: logger}
+}
+
+type listQuery struct {
+	Unread bool   `form:"unread"`
+	Before string `form:"before"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+// ListNotifications returns the caller's notifications, newest first. Pass
+// the ID of the last one as before to get the next page.
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	var query listQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	p := middleware.Principal(c)
+	q := notifications.Query{Email: p.Email, Role: p.Role, UnreadOnly: query.Unread, Limit: query.Limit}
+	if query.Before != "" {
+		id, err := primitive.ObjectIDFromHex(query.Before)
+		if err != nil {
+			c.Error(apperr.Invalid("invalid_id", "before must be a 24 character hex string"))
+			return
+		}
+		q.Before = id
+	}
+
+	list, err := h.inbox.List(c.Request.Context(), q)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if list == nil {
+		list = []notifications.Notification{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	p := middleware.Principal(c)
+	count, err := h.inbox.CountUnread(c.Request.Context(), p.Email, p.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
+		return
+	}
+	p := middleware.Principal(c)
+	if err := h.inbox.MarkRead(c.Request.Context(), p.Email, p.Role, id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	p := middleware.Principal(c)
+	marked, err := h.inbox.MarkAllRead(c.Request.Context(), p.Email, p.Role)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// Stream sends the caller's new notifications as server-sent events for as
+// long as the connection stays open. Each is a "notification" event whose ID
+// is the notification's, so a client that reconnects with Last-Event-ID gets
+// what it missed. An "unread" event carries the unread count whenever it
+// changes, starting with its current value.
+func (h *NotificationHandler) Stream(c *gin.Context) {
+	ctx := c.Request.Context()
+	p := middleware.Principal(c)
+	// Watching before looking for the latest notification means none added
+	// in between is missed
+	changes, stop := h.inbox.Watch(p.Email, p.Role)
+	defer stop()
+
+	var cursor primitive.ObjectID
+	if last := c.GetHeader("Last-Event-ID"); last != "" {
+		id, err := primitive.ObjectIDFromHex(last)
+		if err != nil {
+			c.Error(apperr.Invalid("invalid_id", "Last-Event-ID must be a 24 character hex string"))
+			return
+		}
+		cursor = id
+	} else {
+		latest, err := h.inbox.List(ctx, notifications.Query{Email: p.Email, Role: p.Role, Limit: 1})
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if len(latest) > 0 {
+			cursor = latest[0].ID
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Stops nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// The stream has started, so errors can only be logged
+	unread := -1
+	push := func() bool {
+		list, err := h.inbox.List(ctx, notifications.Query{Email: p.Email, Role: p.Role, After: cursor, Limit: notifications.MaxLimit})
+		if err != nil {
+			h.logger.ErrorContext(ctx, "failed to list notifications for stream", slog.Any("error", err))
+			return false
+		}
+		slices.Reverse(list)
+		for _, notification := range list {
+			if !h.send(c, notification.ID.Hex(), "notification", notification) {
+				return false
+			}
+			cursor = notification.ID
+		}
+		count, err := h.inbox.CountUnread(ctx, p.Email, p.Role)
+		if err != nil {
+			h.logger.ErrorContext(ctx, "failed to count unread notifications for stream", slog.Any("error", err))
+			return false
+		}
+		if count != unread {
+			unread = count
+			if !h.send(c, "", "unread", gin.H{"unread": count}) {
+				return false
+			}
+		}
+		c.Writer.Flush()
+		return true
+	}
+
+	ticker := time.NewTicker(h.poll)
+	defer ticker.Stop()
+	for {
+		if !push() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			// A comment, which clients ignore, keeps proxies from timing the connection out
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send writes one server-sent event.
+func (h *NotificationHandler) send(c *gin.Context, id, event string, data any) bool {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "failed to encode stream event", slog.Any("error", err))
+		return false
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(c.Writer, "id: %s\n", id); err != nil {
+			return false
+		}
+	}
+	_, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+	return err == nil
+}
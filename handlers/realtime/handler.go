@@ -0,0 +1,218 @@
+package realtime
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
+	"github.com/lucasgarciaf/df-backend-go/internal/realtime"
+)
+
+// sendBuffer is how many messages may wait for a slow client before it is
+// disconnected.
+const sendBuffer = 64
+
+// bearerProtocol is the WebSocket subprotocol browsers offer, along with their
+// access token, since they cannot set headers on a WebSocket.
+const bearerProtocol = "bearer"
+
+// writeTimeout is how long a message may take to reach the client.
+const writeTimeout = 10 * time.Second
+
+type RealtimeHandler struct {
+	broker     realtime.Broker
+	authorizer *realtime.Authorizer
+	cfg        config.RealtimeConfig
+	upgrader   websocket.Upgrader
+	logger     *slog.Logger
+}
+
+func NewRealtimeHandler(broker realtime.Broker, authorizer *realtime.Authorizer, cfg config.RealtimeConfig, logger *slog.Logger) *RealtimeHandler {
+	return &RealtimeHandler{
+		broker:     broker,
+		authorizer: authorizer,
+		cfg:        cfg,
+		upgrader: websocket.Upgrader{
+			// Only the bearer subprotocol is ever echoed back, never the token
+			Subprotocols: []string{bearerProtocol},
+			// Tokens, not cookies, authenticate the connection, so any origin may open one
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+		logger: logger,
+	}
+}
+
+// request is what clients send: {"action": "subscribe", "topic": "instructor:<id>"}.
+type request struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+}
+
+// reply acknowledges a request, or reports why it failed.
+type reply struct {
+	Type    string `json:"type"`
+	Topic   string `json:"topic,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// TokenFromProtocol moves an access token offered as the subprotocols
+// "bearer, <token>" into the Authorization header for AuthMiddleware.
+func (h *RealtimeHandler) TokenFromProtocol(c *gin.Context) {
+	if c.GetHeader("Authorization") != "" {
+		return
+	}
+	protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+	if len(protocols) == 2 && strings.TrimSpace(protocols[0]) == bearerProtocol {
+		c.Request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(protocols[1]))
+	}
+}
+
+// Serve upgrades the request to a WebSocket over which the caller subscribes
+// to schedules and receives their changes.
+func (h *RealtimeHandler) Serve(c *gin.Context) {
+	principal := middleware.Principal(c)
+	// A failed upgrade has already been answered
+	ws, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	h.session(c.Request.Context(), ws, principal)
+}
+
+func (h *RealtimeHandler) session(ctx context.Context, ws *websocket.Conn, principal *identity.Principal) {
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan any, sendBuffer)
+	overflow := make(chan struct{})
+	var overflowOnce sync.Once
+	deliver := func(msg realtime.Message) {
+		select {
+		case out <- msg:
+		default:
+			overflowOnce.Do(func() { close(overflow) })
+		}
+	}
+
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		h.read(ctx, ws, principal, deliver, out)
+	}()
+	// Closing the connection ends the reader, which unsubscribes from
+	// everything before the session is over
+	defer func() {
+		cancel()
+		ws.Close()
+		<-readerDone
+	}()
+
+	ping := time.NewTicker(h.cfg.PingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case msg := <-out:
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err = ws.WriteJSON(msg)
+		case <-ping.C:
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err = ws.WriteMessage(websocket.PingMessage, nil)
+		case <-overflow:
+			h.logger.WarnContext(ctx, "disconnecting a client that fell behind on schedule updates", slog.String("subject", principal.Subject))
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			ws.WriteJSON(reply{Type: "error", Code: "too_slow", Message: "too many updates were waiting to be sent"})
+			return
+		case <-h.broker.Done():
+			return
+		case <-readerDone:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// read handles the client's requests until the connection closes, then
+// unsubscribes from everything.
+func (h *RealtimeHandler) read(ctx context.Context, ws *websocket.Conn, principal *identity.Principal, deliver func(realtime.Message), out chan<- any) {
+	subscriptions := map[realtime.Topic]func(){}
+	defer func() {
+		for _, unsubscribe := range subscriptions {
+			unsubscribe()
+		}
+	}()
+	respond := func(r reply) bool {
+		select {
+		case out <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	fail := func(topic string, err error) bool {
+		appErr := apperr.From(err)
+		if appErr.Kind == apperr.KindInternal {
+			h.logger.ErrorContext(ctx, "failed to authorize schedule subscription", slog.String("topic", topic), slog.Any("error", err))
+		}
+		return respond(reply{Type: "error", Topic: topic, Code: appErr.Code, Message: appErr.Message})
+	}
+
+	for {
+		var req request
+		if err := ws.ReadJSON(&req); err != nil {
+			return
+		}
+		topic, err := realtime.ParseTopic(req.Topic)
+		if err != nil {
+			if !fail(req.Topic, err) {
+				return
+			}
+			continue
+		}
+
+		var r reply
+		switch req.Action {
+		case "subscribe":
+			if _, ok := subscriptions[topic]; ok {
+				r = reply{Type: "subscribed", Topic: req.Topic}
+				break
+			}
+			if len(subscriptions) >= h.cfg.MaxSubscriptions {
+				err = apperr.Invalid("too_many_subscriptions", "unsubscribe from a schedule before subscribing to another")
+				break
+			}
+			if err = h.authorizer.Authorize(ctx, principal, topic); err != nil {
+				break
+			}
+			subscriptions[topic] = h.broker.Subscribe(topic, deliver)
+			r = reply{Type: "subscribed", Topic: req.Topic}
+		case "unsubscribe":
+			if unsubscribe, ok := subscriptions[topic]; ok {
+				unsubscribe()
+				delete(subscriptions, topic)
+			}
+			r = reply{Type: "unsubscribed", Topic: req.Topic}
+		default:
+			err = apperr.Invalid("invalid_action", `action must be "subscribe" or "unsubscribe"`)
+		}
+		if err != nil {
+			if !fail(req.Topic, err) {
+				return
+			}
+			continue
+		}
+		if !respond(r) {
+			return
+		}
+	}
+}
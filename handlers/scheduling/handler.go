@@ -0,0 +1,33 @@
+package scheduling
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/scheduling"
+)
+
+type SchedulingHandler struct {
+	service *scheduling.Service
+}
+
+func NewSchedulingHandler(service *scheduling.Service) *SchedulingHandler {
+	return &SchedulingHandler{service: service}
+}
+
+// Suggest proposes ranked slots for a student's next lessons, and a plan
+// that books the requested number of them.
+func (h *SchedulingHandler) Suggest(c *gin.Context) {
+	var req scheduling.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	suggestions, err := h.service.Suggest(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
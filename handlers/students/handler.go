@@ -1,21 +1,24 @@
 package students
 
 import (
-	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/ratelimit"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type StudentHandler struct {
 	service *students.StudentService
+	logins  *ratelimit.Logins
+	logger  *slog.Logger
 }
 
-func NewStudentHandler(service *students.StudentService) *StudentHandler {
-	return &StudentHandler{service: service}
+func NewStudentHandler(service *students.StudentService, logins *ratelimit.Logins, logger *slog.Logger) *StudentHandler {
+	return &StudentHandler{service: service, logins: logins, logger: logger}
 }
 
 func (h *StudentHandler) Register(c *gin.Context) {
@@ -27,11 +30,10 @@ func (h *StudentHandler) Register(c *gin.Context) {
 		Password  string `json:"password" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("Error binding JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	log.Printf("Received registration request for user: %s", req.Email)
+	h.logger.DebugContext(c.Request.Context(), "student registration requested", slog.String("email", req.Email))
 
 	student := students.Student{
 		Username:  req.Username,
@@ -39,13 +41,11 @@ func (h *StudentHandler) Register(c *gin.Context) {
 		LastName:  req.LastName,
 		Email:     req.Email,
 	}
-	id, err := h.service.CreateStudent(student, req.Password)
+	id, err := h.service.CreateStudent(c.Request.Context(), student, req.Password)
 	if err != nil {
-		log.Printf("Error creating student: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	log.Printf("Successfully created student with ID: %s", id.Hex())
 	c.JSON(http.StatusCreated, gin.H{"id": id.Hex()})
 }
 
@@ -56,16 +56,23 @@ func (h *StudentHandler) Login(c *gin.Context) {
 		Role     string `json:"role" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&credentials); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 
-	fmt.Printf("Received login request for email: %s\n", credentials.Email)
+	h.logger.DebugContext(c.Request.Context(), "student login requested", slog.String("email", credentials.Email))
 
-	// Authenticate with Keycloak
-	token, err := h.service.AuthenticateWithKeycloak(credentials.Email, credentials.Password)
+	// Locked out and throttled accounts are refused before the identity provider is asked
+	decision, err := h.logins.Allow(c.Request.Context(), credentials.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		decision.WriteHeaders(c.Writer.Header())
+		c.Error(err)
+		return
+	}
+	token, err := h.service.Authenticate(c.Request.Context(), credentials.Email, credentials.Password)
+	h.logins.Record(c.Request.Context(), credentials.Email, err)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -76,21 +83,21 @@ func (h *StudentHandler) Login(c *gin.Context) {
 func (h *StudentHandler) GetStudentByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	student, err := h.service.GetStudentByID(id)
+	student, err := h.service.GetStudentByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, student)
 }
 
 func (h *StudentHandler) GetAllStudents(c *gin.Context) {
-	students, err := h.service.GetAllStudents()
+	students, err := h.service.GetAllStudents(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, students)
@@ -99,17 +106,17 @@ func (h *StudentHandler) GetAllStudents(c *gin.Context) {
 func (h *StudentHandler) UpdateStudent(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var student students.Student
 	if err := c.ShouldBindJSON(&student); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	student.ID = id
-	if err := h.service.UpdateStudent(student); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateStudent(c.Request.Context(), student); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -118,11 +125,11 @@ func (h *StudentHandler) UpdateStudent(c *gin.Context) {
 func (h *StudentHandler) DeleteStudent(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteStudent(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteStudent(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
@@ -1,39 +1,90 @@
 package students
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.ErrorHandler(logging.Discard()))
 	return r
 }
 
+func TestRegisterDuplicateEmail(t *testing.T) {
+	r := setupRouter()
+	studentService := students.NewStudentService(&MockStudentRepository{}, &MockDeletionGuard{}, identity.NewLocal([]byte("test-secret"), time.Hour), audit.Discard, events.Discard, logging.Discard())
+	studentHandler := NewStudentHandler(studentService, nil, logging.Discard())
+
+	r.POST("/register/student", studentHandler.Register)
+
+	w := httptest.NewRecorder()
+	body := `{"username":"jdoe","firstName":"John","lastName":"Doe","email":"john@example.com","password":"secret"}`
+	req, _ := http.NewRequest("POST", "/register/student", strings.NewReader(body))
+
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %v but got %v", http.StatusConflict, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Expected problem+json content type but got %q", ct)
+	}
+
+	var problem map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Failed to decode problem document: %v", err)
+	}
+	if problem["code"] != "email_exists" {
+		t.Fatalf("Expected code email_exists but got %v", problem["code"])
+	}
+}
+
 type MockStudentRepository struct{}
 
-func (m *MockStudentRepository) CreateStudent(student students.Student) (primitive.ObjectID, error) {
+func (m *MockStudentRepository) CreateStudent(ctx context.Context, student students.Student) (primitive.ObjectID, error) {
 	return primitive.NewObjectID(), nil
 }
 
-func (m *MockStudentRepository) GetStudentByID(id primitive.ObjectID) (*students.Student, error) {
+func (m *MockStudentRepository) GetStudentByID(ctx context.Context, id primitive.ObjectID) (*students.Student, error) {
 	return &students.Student{}, nil
 }
 
-func (m *MockStudentRepository) GetAllStudents() ([]students.Student, error) {
+func (m *MockStudentRepository) GetAllStudents(ctx context.Context) ([]students.Student, error) {
 	return []students.Student{}, nil
 }
 
-func (m *MockStudentRepository) GetStudentByEmail(email string) (*students.Student, error) {
+func (m *MockStudentRepository) GetStudentByEmail(ctx context.Context, email string) (*students.Student, error) {
 	return &students.Student{}, nil
 }
 
-func (m *MockStudentRepository) UpdateStudent(student students.Student) error {
+func (m *MockStudentRepository) UpdateStudent(ctx context.Context, student students.Student) error {
 	return nil
 }
 
-func (m *MockStudentRepository) DeleteStudent(id primitive.ObjectID) error {
+func (m *MockStudentRepository) DeleteStudent(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
+
+type MockDeletionGuard struct {
+	err error
+}
+
+func (m *MockDeletionGuard) BeforeDeleteStudent(ctx context.Context, id primitive.ObjectID) error {
+	return m.err
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -19,12 +20,12 @@ func NewVehicleHandler(service *vehicles.VehicleService) *VehicleHandler {
 func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 	var vehicle vehicles.Vehicle
 	if err := c.ShouldBindJSON(&vehicle); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
-	id, err := h.service.CreateVehicle(vehicle)
+	id, err := h.service.CreateVehicle(c.Request.Context(), vehicle)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusCreated, id)
@@ -33,12 +34,12 @@ func (h *VehicleHandler) CreateVehicle(c *gin.Context) {
 func (h *VehicleHandler) GetVehicleByID(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	vehicle, err := h.service.GetVehicleByID(id)
+	vehicle, err := h.service.GetVehicleByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, vehicle)
@@ -47,17 +48,17 @@ func (h *VehicleHandler) GetVehicleByID(c *gin.Context) {
 func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
 	var vehicle vehicles.Vehicle
 	if err := c.ShouldBindJSON(&vehicle); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
 		return
 	}
 	vehicle.ID = id
-	if err := h.service.UpdateVehicle(vehicle); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.UpdateVehicle(c.Request.Context(), vehicle); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
@@ -66,11 +67,11 @@ func (h *VehicleHandler) UpdateVehicle(c *gin.Context) {
 func (h *VehicleHandler) DeleteVehicle(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apperr.Invalid("invalid_id", "id must be a 24 character hex string"))
 		return
 	}
-	if err := h.service.DeleteVehicle(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteVehicle(c.Request.Context(), id); err != nil {
+		c.Error(err)
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
@@ -1,30 +1,37 @@
 package vehicles
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func setupRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
+	r.Use(middleware.ErrorHandler(logging.Discard()))
 	return r
 }
 
 func TestCreateVehicle(t *testing.T) {
 	r := setupRouter()
-	vehicleService := vehicles.NewVehicleService(&MockVehicleRepository{})
+	vehicleService := vehicles.NewVehicleService(&MockVehicleRepository{}, &MockDeletionGuard{}, audit.Discard, events.Discard)
 	vehicleHandler := NewVehicleHandler(vehicleService)
 
 	r.POST("/vehicles", vehicleHandler.CreateVehicle)
 
 	w := httptest.NewRecorder()
-	req, _ := http.NewRequest("POST", "/vehicles", nil) // Add appropriate body content for the request
+	req, _ := http.NewRequest("POST", "/vehicles", strings.NewReader(`{"Make":"Toyota","Model":"Yaris","Year":2021,"LicensePlate":"1234BCD"}`))
 
 	r.ServeHTTP(w, req)
 
@@ -35,18 +42,30 @@ func TestCreateVehicle(t *testing.T) {
 
 type MockVehicleRepository struct{}
 
-func (m *MockVehicleRepository) CreateVehicle(vehicle vehicles.Vehicle) (primitive.ObjectID, error) {
+func (m *MockVehicleRepository) CreateVehicle(ctx context.Context, vehicle vehicles.Vehicle) (primitive.ObjectID, error) {
 	return primitive.NewObjectID(), nil
 }
 
-func (m *MockVehicleRepository) GetVehicleByID(id primitive.ObjectID) (*vehicles.Vehicle, error) {
+func (m *MockVehicleRepository) GetVehicleByID(ctx context.Context, id primitive.ObjectID) (*vehicles.Vehicle, error) {
 	return &vehicles.Vehicle{}, nil
 }
 
-func (m *MockVehicleRepository) UpdateVehicle(vehicle vehicles.Vehicle) error {
+func (m *MockVehicleRepository) ListVehicles(ctx context.Context, filter vehicles.Filter) ([]vehicles.Vehicle, error) {
+	return nil, nil
+}
+
+func (m *MockVehicleRepository) UpdateVehicle(ctx context.Context, vehicle vehicles.Vehicle) error {
 	return nil
 }
 
-func (m *MockVehicleRepository) DeleteVehicle(id primitive.ObjectID) error {
+func (m *MockVehicleRepository) DeleteVehicle(ctx context.Context, id primitive.ObjectID) error {
 	return nil
 }
+
+type MockDeletionGuard struct {
+	err error
+}
+
+func (m *MockDeletionGuard) BeforeDeleteVehicle(ctx context.Context, id primitive.ObjectID) error {
+	return m.err
+}
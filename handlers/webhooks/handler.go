@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/webhooks"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookHandler struct {
+	service *webhooks.Service
+}
+
+func NewWebhookHandler(service *webhooks.Service) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// subscriptionRequest is the body of both create and update: an update
+// replaces every field.
+type subscriptionRequest struct {
+	URL         string        `json:"url" binding:"required"`
+	Description string        `json:"description"`
+	Events      []events.Type `json:"events"`
+	Status      string        `json:"status"`
+}
+
+func (r subscriptionRequest) subscription() webhooks.Subscription {
+	return webhooks.Subscription{URL: r.URL, Description: r.Description, Events: r.Events, Status: r.Status}
+}
+
+// createdSubscription is the only representation that includes the secret.
+type createdSubscription struct {
+	*webhooks.Subscription
+	Secret string `json:"secret"`
+}
+
+type deliveriesQuery struct {
+	Limit int `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	subscription, err := h.service.CreateSubscription(c.Request.Context(), req.subscription())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, createdSubscription{Subscription: subscription, Secret: subscription.Secret})
+}
+
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.service.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []webhooks.Subscription{}
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (h *WebhookHandler) GetSubscription(c *gin.Context) {
+	id, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	subscription, err := h.service.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (h *WebhookHandler) UpdateSubscription(c *gin.Context) {
+	id, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	subscription := req.subscription()
+	subscription.ID = id
+	if err := h.service.UpdateSubscription(c.Request.Context(), subscription); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+}
+
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteSubscription(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{"status": "deleted"})
+}
+
+// ListDeliveries returns the subscription's delivery log, newest first.
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	var query deliveriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(apperr.Invalid("invalid_request", err.Error()))
+		return
+	}
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), id, query.Limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []webhooks.Delivery{}
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayDelivery queues a delivery to be sent again. It is sent
+// asynchronously, like any other.
+func (h *WebhookHandler) ReplayDelivery(c *gin.Context) {
+	id, ok := objectID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := objectID(c, "delivery_id")
+	if !ok {
+		return
+	}
+	if err := h.service.ReplayDelivery(c.Request.Context(), id, deliveryID); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": "queued"})
+}
+
+func objectID(c *gin.Context, param string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param(param))
+	if err != nil {
+		c.Error(apperr.Invalid("invalid_id", param+" must be a 24 character hex string"))
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
@@ -0,0 +1,171 @@
+// Package apperr defines the error model shared by the domain services and the
+// HTTP layer. Every error that reaches a client is an *Error carrying a Kind,
+// which decides the HTTP status, and a stable machine-readable Code.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindUnavailable
+	KindTooManyRequests
+	KindTooLarge
+)
+
+var statusByKind = map[Kind]int{
+	KindInternal:        http.StatusInternalServerError,
+	KindInvalid:         http.StatusBadRequest,
+	KindValidation:      http.StatusUnprocessableEntity,
+	KindUnauthorized:    http.StatusUnauthorized,
+	KindForbidden:       http.StatusForbidden,
+	KindNotFound:        http.StatusNotFound,
+	KindConflict:        http.StatusConflict,
+	KindUnavailable:     http.StatusServiceUnavailable,
+	KindTooManyRequests: http.StatusTooManyRequests,
+	KindTooLarge:        http.StatusRequestEntityTooLarge,
+}
+
+// Status returns the HTTP status code used to render errors of this kind.
+func (k Kind) Status() int {
+	if status, ok := statusByKind[k]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	// Details are rendered as extension members of the problem document.
+	Details map[string]any
+	// Err is the underlying cause. It is never shown to clients.
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors of the same kind and code, so that sentinel errors such as
+// students.ErrEmailExists compare equal to copies carrying a different cause.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Kind == t.Kind && e.Code == t.Code
+}
+
+// WithDetail returns a copy of the error with an extra detail member.
+func (e *Error) WithDetail(key string, value any) *Error {
+	cp := *e
+	cp.Details = make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		cp.Details[k] = v
+	}
+	cp.Details[key] = value
+	return &cp
+}
+
+// Wrap returns a copy of the error with err as its cause.
+func (e *Error) Wrap(err error) *Error {
+	cp := *e
+	cp.Err = err
+	return &cp
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "an unexpected error occurred", Err: err}
+}
+
+func Invalid(code, message string) *Error {
+	return New(KindInvalid, code, message)
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// ResourceNotFound is returned by repositories when no document matches.
+func ResourceNotFound(resource string) *Error {
+	return NotFound(resource+"_not_found", resource+" not found")
+}
+
+// ResourceConflict is returned by repositories when a write violates a unique index.
+func ResourceConflict(resource string) *Error {
+	return Conflict(resource+"_conflict", resource+" already exists")
+}
+
+func TooManyRequests(code, message string) *Error {
+	return New(KindTooManyRequests, code, message)
+}
+
+func TooLarge(code, message string) *Error {
+	return New(KindTooLarge, code, message)
+}
+
+func Unavailable(code, message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message, Err: err}
+}
+
+// From returns err as an *Error, treating anything that is not one as internal.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal(err)
+}
+
+// KindOf reports the kind of err, or KindInternal if err is not an *Error.
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+func IsNotFound(err error) bool {
+	return err != nil && KindOf(err) == KindNotFound
+}
@@ -0,0 +1,25 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FromMongo translates a MongoDB driver error for the given resource into an
+// *Error. It returns nil when err is nil.
+func FromMongo(err error, resource string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ResourceNotFound(resource)
+	case mongo.IsDuplicateKeyError(err):
+		return ResourceConflict(resource).Wrap(err)
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err),
+		errors.Is(err, context.DeadlineExceeded), errors.Is(err, mongo.ErrClientDisconnected):
+		return Unavailable("database_unavailable", "the database is unavailable", err)
+	}
+	return Internal(err)
+}
@@ -0,0 +1,86 @@
+// Package audit keeps an append-only trail of who changed which document, when
+// and how. Domain services report every change they make to a Recorder.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Recorder is what the domain services report their changes to. before and
+// after are the document before and after the change; see Diff.
+type Recorder interface {
+	Record(ctx context.Context, action Action, resource string, id primitive.ObjectID, before, after any)
+}
+
+type discard struct{}
+
+func (discard) Record(context.Context, Action, string, primitive.ObjectID, any, any) {}
+
+// Discard is a Recorder that keeps nothing, for tests.
+var Discard Recorder = discard{}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the authenticated caller.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func actor(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+type clientIPKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the IP of the client that made
+// the request, for the entries recorded while handling it.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+func clientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+// Log is the Recorder backed by an AuditRepository. The actor, request ID and
+// client IP are taken from the context; see WithActor and WithClientIP.
+type Log struct {
+	repo   AuditRepository
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewLog(repo AuditRepository, logger *slog.Logger) *Log {
+	return &Log{repo: repo, logger: logger, now: time.Now}
+}
+
+// Record appends an entry for a change that has already been made. A failure
+// to write it is logged rather than returned, since the change itself stands.
+func (l *Log) Record(ctx context.Context, action Action, resource string, id primitive.ObjectID, before, after any) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		l.logger.ErrorContext(ctx, "failed to diff audited change", slog.String("resource", resource), slog.Any("error", err))
+	}
+	entry := Entry{
+		Time:       l.now(),
+		Actor:      actor(ctx),
+		Action:     action,
+		Resource:   resource,
+		ResourceID: id,
+		Changes:    changes,
+		RequestID:  logging.RequestID(ctx),
+		IP:         clientIP(ctx),
+	}
+	// The entry is written even if the request is cancelled right after the change
+	if _, err := l.repo.AppendEntry(context.WithoutCancel(ctx), entry); err != nil {
+		l.logger.ErrorContext(ctx, "failed to write audit entry",
+			slog.String("action", string(action)), slog.String("resource", resource), slog.String("resource_id", id.Hex()), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type document struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	Title        string             `bson:"title,omitempty"`
+	Status       string             `bson:"status,omitempty"`
+	PasswordHash string             `bson:"password_hash,omitempty"`
+	UpdatedAt    time.Time          `bson:"updated_at,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	id := primitive.NewObjectID()
+	before := &document{ID: id, Title: "Parking", Status: "scheduled", PasswordHash: "old"}
+
+	for name, tc := range map[string]struct {
+		before, after any
+		want          []Change
+	}{
+		"create": {nil, document{ID: id, Title: "Parking"}, []Change{{Field: "title", After: "Parking"}}},
+		"update only compares the fields set": {before, document{ID: id, Status: "cancelled", UpdatedAt: time.Now()},
+			[]Change{{Field: "status", Before: "scheduled", After: "cancelled"}}},
+		"unchanged":  {before, document{Title: "Parking"}, nil},
+		"delete":     {before, nil, []Change{{Field: "password_hash", Before: logging.Redacted}, {Field: "status", Before: "scheduled"}, {Field: "title", Before: "Parking"}}},
+		"nil before": {(*document)(nil), document{Status: "scheduled"}, []Change{{Field: "status", After: "scheduled"}}},
+		"redacted":   {before, document{PasswordHash: "new"}, []Change{{Field: "password_hash", Before: logging.Redacted, After: logging.Redacted}}},
+	} {
+		got, err := Diff(tc.before, tc.after)
+		if err != nil {
+			t.Fatalf("%s: Diff failed: %v", name, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: expected %+v but got %+v", name, tc.want, got)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("%s: expected %+v but got %+v", name, tc.want, got)
+			}
+		}
+	}
+}
+
+func TestLogRecord(t *testing.T) {
+	repo := NewMemoryAuditRepository()
+	log := NewLog(repo, logging.Discard())
+	ctx := WithActor(context.Background(), Actor{ID: "sub", Email: "admin@example.com", Role: "admin"})
+	ctx = WithClientIP(logging.WithRequestID(ctx, "req-1"), "203.0.113.7")
+
+	id := primitive.NewObjectID()
+	log.Record(ctx, ActionUpdate, "lesson", id, document{Status: "scheduled"}, document{Status: "cancelled"})
+
+	entries, _ := repo.ListEntries(context.Background(), Filter{})
+	if len(entries) != 1 {
+		t.Fatalf("Expected one entry but got %+v", entries)
+	}
+	entry := entries[0]
+	if entry.Actor.Email != "admin@example.com" || entry.RequestID != "req-1" || entry.IP != "203.0.113.7" ||
+		entry.ResourceID != id || entry.Time.IsZero() || len(entry.Changes) != 1 {
+		t.Fatalf("Unexpected entry %+v", entry)
+	}
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Diff compares two versions of a document field by field, as they are
+// stored. Either may be nil, for a created or deleted document. Because
+// updates only set the fields they carry, fields missing from after are taken
+// to be unchanged unless before is the only version. The ID and timestamps are
+// left out, and sensitive values such as password hashes are redacted.
+func Diff(before, after any) ([]Change, error) {
+	old, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := updated
+	if updated == nil {
+		keys = old
+	}
+	var changes []Change
+	for key := range keys {
+		if skippedField(key) {
+			continue
+		}
+		oldValue, newValue := old[key], updated[key]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if logging.SensitiveKey(key) {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+		changes = append(changes, Change{Field: key, Before: oldValue, After: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+// fields returns doc's stored fields, or nil if doc is nil or a nil pointer.
+func fields(doc any) (bson.M, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(doc); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	err = bson.Unmarshal(data, &m)
+	return m, err
+}
+
+func skippedField(key string) bool {
+	switch strings.ReplaceAll(strings.ToLower(key), "_", "") {
+	case "id", "createdat", "updatedat":
+		return true
+	}
+	return false
+}
+
+func redact(value any) any {
+	if value == nil {
+		return nil
+	}
+	return logging.Redacted
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	// ActionCancel is an update that cancels a lesson.
+	ActionCancel Action = "cancel"
+	// ActionReplay asks for a webhook delivery to be sent again.
+	ActionReplay Action = "replay"
+)
+
+// Entry records one change to one document. Entries are never updated or
+// deleted once written.
+type Entry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Time       time.Time          `bson:"time" json:"time"`
+	Actor      Actor              `bson:"actor" json:"actor"`
+	Action     Action             `bson:"action" json:"action"`
+	Resource   string             `bson:"resource" json:"resource"`
+	ResourceID primitive.ObjectID `bson:"resource_id" json:"resource_id"`
+	Changes    []Change           `bson:"changes,omitempty" json:"changes,omitempty"`
+	RequestID  string             `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	IP         string             `bson:"ip,omitempty" json:"ip,omitempty"`
+}
+
+// Actor is the caller that made the change. It is empty for unauthenticated
+// requests, such as self-registration, and for the command-line tools.
+type Actor struct {
+	ID    string `bson:"id,omitempty" json:"id,omitempty"`
+	Email string `bson:"email,omitempty" json:"email,omitempty"`
+	Role  string `bson:"role,omitempty" json:"role,omitempty"`
+}
+
+// Change is the value of one stored field before and after the change. Before
+// is nil for created documents and After is nil for deleted ones.
+type Change struct {
+	Field  string `bson:"field" json:"field"`
+	Before any    `bson:"before" json:"before,omitempty"`
+	After  any    `bson:"after" json:"after,omitempty"`
+}
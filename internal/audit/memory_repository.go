@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"context"
+	"sort"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryAuditRepository keeps entries in memory with the same semantics as
+// MongoAuditRepository. It is meant for tests and local development.
+type MemoryAuditRepository struct {
+	store *memstore.Store[Entry]
+}
+
+func NewMemoryAuditRepository() *MemoryAuditRepository {
+	return &MemoryAuditRepository{
+		store: memstore.New[Entry](nil),
+	}
+}
+
+func (r *MemoryAuditRepository) AppendEntry(ctx context.Context, entry Entry) (primitive.ObjectID, error) {
+	entry.ID = primitive.NewObjectID()
+	if _, err := r.store.Insert(entry.ID, entry); err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	return entry.ID, nil
+}
+
+func (r *MemoryAuditRepository) ListEntries(ctx context.Context, filter Filter) ([]Entry, error) {
+	entries := r.store.Find(func(entry Entry) bool {
+		switch {
+		case filter.ActorID != "" && entry.Actor.ID != filter.ActorID,
+			filter.Resource != "" && entry.Resource != filter.Resource,
+			!filter.ResourceID.IsZero() && entry.ResourceID != filter.ResourceID,
+			!filter.From.IsZero() && entry.Time.Before(filter.From),
+			!filter.To.IsZero() && !entry.Time.Before(filter.To):
+			return false
+		}
+		return true
+	})
+	// Find returns insertion order, so reversing it breaks ties like the _id sort
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	if len(entries) > filter.limit() {
+		entries = entries[:filter.limit()]
+	}
+	return entries, nil
+}
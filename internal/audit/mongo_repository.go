@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoAuditRepository struct {
+	db *mongo.Collection
+}
+
+func NewMongoAuditRepository(db *mongo.Database) *MongoAuditRepository {
+	return &MongoAuditRepository{
+		db: db.Collection("audit_log"),
+	}
+}
+
+func (r *MongoAuditRepository) AppendEntry(ctx context.Context, entry Entry) (primitive.ObjectID, error) {
+	entry.ID = primitive.NewObjectID()
+	if _, err := r.db.InsertOne(ctx, entry); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "audit_entry")
+	}
+	return entry.ID, nil
+}
+
+func (r *MongoAuditRepository) ListEntries(ctx context.Context, filter Filter) ([]Entry, error) {
+	query := bson.M{}
+	if filter.ActorID != "" {
+		query["actor.id"] = filter.ActorID
+	}
+	if filter.Resource != "" {
+		query["resource"] = filter.Resource
+	}
+	if !filter.ResourceID.IsZero() {
+		query["resource_id"] = filter.ResourceID
+	}
+	period := bson.M{}
+	if !filter.From.IsZero() {
+		period["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		period["$lt"] = filter.To
+	}
+	if len(period) > 0 {
+		query["time"] = period
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(int64(filter.limit()))
+	cursor, err := r.db.Find(ctx, query, opts)
+	if err != nil {
+		return nil, apperr.FromMongo(err, "audit_entry")
+	}
+	defer cursor.Close(ctx)
+
+	var entries []Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, apperr.FromMongo(err, "audit_entry")
+	}
+	return entries, nil
+}
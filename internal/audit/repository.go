@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultLimit and MaxLimit bound how many entries ListEntries returns.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Filter narrows down ListEntries. Zero-valued fields are ignored.
+type Filter struct {
+	ActorID    string
+	Resource   string
+	ResourceID primitive.ObjectID
+	From       time.Time
+	To         time.Time
+	// Limit caps the number of entries; zero means DefaultLimit
+	Limit int
+}
+
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return DefaultLimit
+	}
+	return min(f.Limit, MaxLimit)
+}
+
+// AuditRepository is append-only: there is deliberately no way to change or
+// remove an entry.
+type AuditRepository interface {
+	AppendEntry(ctx context.Context, entry Entry) (primitive.ObjectID, error)
+	// ListEntries returns the matching entries, newest first.
+	ListEntries(ctx context.Context, filter Filter) ([]Entry, error)
+}
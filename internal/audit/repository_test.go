@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryAuditRepository(t *testing.T) {
+	testAuditRepository(t, func(t *testing.T) AuditRepository {
+		return NewMemoryAuditRepository()
+	})
+}
+
+func TestMongoAuditRepository(t *testing.T) {
+	testAuditRepository(t, func(t *testing.T) AuditRepository {
+		return NewMongoAuditRepository(mongotest.Database(t))
+	})
+}
+
+// testAuditRepository is the contract every AuditRepository must satisfy.
+func testAuditRepository(t *testing.T, newRepo func(t *testing.T) AuditRepository) {
+	ctx := context.Background()
+	// Mongo stores times with millisecond precision
+	start := time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)
+	lessonID, courseID := primitive.NewObjectID(), primitive.NewObjectID()
+
+	seed := func(t *testing.T, repo AuditRepository) {
+		t.Helper()
+		entries := []Entry{
+			{Time: start, Actor: Actor{ID: "admin"}, Action: ActionCreate, Resource: "course", ResourceID: courseID},
+			{Time: start.Add(time.Minute), Actor: Actor{ID: "student"}, Action: ActionCreate, Resource: "lesson", ResourceID: lessonID,
+				Changes: []Change{{Field: "title", After: "Parallel parking"}}},
+			{Time: start.Add(2 * time.Minute), Actor: Actor{ID: "student"}, Action: ActionCancel, Resource: "lesson", ResourceID: lessonID,
+				Changes: []Change{{Field: "status", Before: "scheduled", After: "cancelled"}}},
+		}
+		for _, entry := range entries {
+			if _, err := repo.AppendEntry(ctx, entry); err != nil {
+				t.Fatalf("AppendEntry failed: %v", err)
+			}
+		}
+	}
+
+	t.Run("newest first", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+		entries, err := repo.ListEntries(ctx, Filter{})
+		if err != nil {
+			t.Fatalf("ListEntries failed: %v", err)
+		}
+		if len(entries) != 3 || entries[0].Action != ActionCancel || entries[2].Resource != "course" {
+			t.Fatalf("Unexpected entries %+v", entries)
+		}
+		if entries[0].ID.IsZero() || entries[0].Changes[0].After != "cancelled" {
+			t.Fatalf("Unexpected entry %+v", entries[0])
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		repo := newRepo(t)
+		seed(t, repo)
+		for name, tc := range map[string]struct {
+			filter Filter
+			want   int
+		}{
+			"actor":       {Filter{ActorID: "student"}, 2},
+			"resource":    {Filter{Resource: "course"}, 1},
+			"resource id": {Filter{ResourceID: lessonID}, 2},
+			"from":        {Filter{From: start.Add(time.Minute)}, 2},
+			"to":          {Filter{To: start.Add(time.Minute)}, 1},
+			"combined":    {Filter{ActorID: "student", To: start.Add(2 * time.Minute)}, 1},
+			"limit":       {Filter{Limit: 2}, 2},
+			"no match":    {Filter{ActorID: "nobody"}, 0},
+		} {
+			entries, err := repo.ListEntries(ctx, tc.filter)
+			if err != nil {
+				t.Fatalf("%s: ListEntries failed: %v", name, err)
+			}
+			if len(entries) != tc.want {
+				t.Fatalf("%s: expected %d entries but got %+v", name, tc.want, entries)
+			}
+		}
+	})
+
+	t.Run("ties keep insertion order reversed", func(t *testing.T) {
+		repo := newRepo(t)
+		first, _ := repo.AppendEntry(ctx, Entry{Time: start, Action: ActionCreate, Resource: "lesson", ResourceID: lessonID})
+		second, _ := repo.AppendEntry(ctx, Entry{Time: start, Action: ActionUpdate, Resource: "lesson", ResourceID: lessonID})
+		entries, _ := repo.ListEntries(ctx, Filter{})
+		if len(entries) != 2 || entries[0].ID != second || entries[1].ID != first {
+			t.Fatalf("Unexpected order %+v", entries)
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package calendar
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Whose calendar a feed is
+const (
+	OwnerStudent    = "student"
+	OwnerInstructor = "instructor"
+)
+
+// Feed is the secret link to a student's or instructor's calendar. Only a
+// hash of its token is stored, so the link is shown once, when it is created.
+type Feed struct {
+	// ID is the owner's, so each person has at most one feed
+	ID        primitive.ObjectID `bson:"_id"`
+	Owner     string             `bson:"owner"`
+	TokenHash string             `bson:"token_hash"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+// Sync imports a calendar URL into an instructor's availability on a
+// schedule, so that changes to the calendar keep showing up.
+type Sync struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	InstructorID primitive.ObjectID `bson:"instructor_id" json:"instructor_id"`
+	URL          string             `bson:"url" json:"url"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	LastSyncedAt time.Time          `bson:"last_synced_at" json:"last_synced_at"`
+	// LastError is why the last sync failed, if it did
+	LastError string `bson:"last_error" json:"last_error,omitempty"`
+}
@@ -0,0 +1,249 @@
+package calendar
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Occurrence is one concrete time span of an imported event.
+type Occurrence struct {
+	UID string
+	// Key tells occurrences apart across imports: the UID, followed by "@"
+	// and the original start for occurrences of recurring events
+	Key     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
+
+// vevent is the part of a VEVENT that imports use.
+type vevent struct {
+	uid          string
+	summary      string
+	start        time.Time
+	duration     time.Duration
+	allDay       bool
+	cancelled    bool
+	rule         *rrule
+	rdates       []time.Time
+	exdates      []time.Time
+	exdateDays   []time.Time
+	recurrenceID time.Time
+}
+
+// Occurrences expands the events of an iCalendar stream into the occurrences
+// that overlap [from, to), sorted by start. RRULE, RDATE and EXDATE are
+// applied, and RECURRENCE-ID overrides replace the occurrence they stand for.
+// Events that cannot become availability windows, such as all-day ones, are
+// left out and explained in warnings. Cancelled events are left out silently.
+func Occurrences(r io.Reader, from, to time.Time) ([]Occurrence, []string, error) {
+	cal, err := parseCalendar(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc := time.UTC
+	if p, ok := cal.get("X-WR-TIMEZONE"); ok {
+		if l, err := loadLocation(p.value); err == nil {
+			loc = l
+		}
+	}
+
+	var warnings []string
+	warn := func(uid string, format string, args ...any) {
+		if uid == "" {
+			uid = "without a UID"
+		}
+		warnings = append(warnings, fmt.Sprintf("event %s: ", uid)+fmt.Sprintf(format, args...))
+	}
+	masters := map[string]*vevent{}
+	overrides := map[string][]*vevent{}
+	var uids []string
+	for _, c := range cal.children {
+		if c.name != "VEVENT" {
+			continue
+		}
+		e, err := readEvent(c, loc)
+		if err != nil {
+			warn(e.uid, "%v", err)
+			continue
+		}
+		if _, seen := masters[e.uid]; !seen && len(overrides[e.uid]) == 0 {
+			uids = append(uids, e.uid)
+		}
+		if e.recurrenceID.IsZero() {
+			if masters[e.uid] != nil {
+				warn(e.uid, "is defined twice; the first definition is used")
+				continue
+			}
+			masters[e.uid] = e
+		} else {
+			overrides[e.uid] = append(overrides[e.uid], e)
+		}
+	}
+
+	var found []Occurrence
+	for _, uid := range uids {
+		occurrences, err := expandEvent(masters[uid], overrides[uid], from, to)
+		if err != nil {
+			warn(uid, "%v", err)
+			continue
+		}
+		found = append(found, occurrences...)
+	}
+	slices.SortFunc(found, func(a, b Occurrence) int {
+		return cmp.Or(a.Start.Compare(b.Start), strings.Compare(a.Key, b.Key))
+	})
+	return found, warnings, nil
+}
+
+func expandEvent(master *vevent, overrides []*vevent, from, to time.Time) ([]Occurrence, error) {
+	for _, e := range append([]*vevent{master}, overrides...) {
+		if e == nil || e.cancelled {
+			continue
+		}
+		switch {
+		case e.allDay:
+			return nil, errors.New("all-day events are not imported")
+		case e.duration <= 0:
+			return nil, errors.New("ends before it starts")
+		}
+	}
+
+	occurrences := map[string]Occurrence{}
+	add := func(e *vevent, key string, start time.Time) {
+		end := start.Add(e.duration)
+		if end.After(from) && start.Before(to) {
+			occurrences[key] = Occurrence{UID: e.uid, Key: key, Summary: e.summary, Start: start, End: end}
+		}
+	}
+	if master != nil && !master.cancelled {
+		if master.rule == nil && len(master.rdates) == 0 {
+			add(master, master.uid, master.start)
+		} else {
+			var starts []time.Time
+			if master.rule != nil {
+				starts = master.rule.between(master.start, from.Add(-master.duration), to)
+			} else {
+				starts = []time.Time{master.start}
+			}
+			starts = append(starts, master.rdates...)
+			for _, start := range starts {
+				if !master.excludes(start) {
+					add(master, occurrenceKey(master.uid, start), start)
+				}
+			}
+		}
+	}
+	for _, e := range overrides {
+		key := occurrenceKey(e.uid, e.recurrenceID)
+		delete(occurrences, key)
+		if !e.cancelled && (master == nil || !master.cancelled) {
+			add(e, key, e.start)
+		}
+	}
+
+	list := make([]Occurrence, 0, len(occurrences))
+	for _, o := range occurrences {
+		list = append(list, o)
+	}
+	return list, nil
+}
+
+func occurrenceKey(uid string, start time.Time) string {
+	return uid + "@" + start.UTC().Format("20060102T150405Z")
+}
+
+// excludes reports whether an EXDATE removes the occurrence starting at start.
+// Dates remove every occurrence on that day.
+func (e *vevent) excludes(start time.Time) bool {
+	for _, t := range e.exdates {
+		if t.Equal(start) {
+			return true
+		}
+	}
+	for _, day := range e.exdateDays {
+		y, m, d := start.In(e.start.Location()).Date()
+		if day.Year() == y && day.Month() == m && day.Day() == d {
+			return true
+		}
+	}
+	return false
+}
+
+func readEvent(c *component, loc *time.Location) (*vevent, error) {
+	e := &vevent{}
+	if p, ok := c.get("UID"); ok {
+		e.uid = p.value
+	}
+	if e.uid == "" {
+		return e, errors.New("has no UID")
+	}
+	if p, ok := c.get("SUMMARY"); ok {
+		e.summary = unescape(p.value)
+	}
+	if p, ok := c.get("STATUS"); ok {
+		e.cancelled = strings.EqualFold(p.value, StatusCancelled)
+	}
+
+	p, ok := c.get("DTSTART")
+	if !ok {
+		return e, errors.New("has no DTSTART")
+	}
+	var err error
+	if e.start, e.allDay, err = parseTime(p, loc); err != nil {
+		return e, err
+	}
+	// Times take the zone of DTSTART unless they say otherwise
+	loc = e.start.Location()
+	if p, ok := c.get("DTEND"); ok {
+		end, _, err := parseTime(p, loc)
+		if err != nil {
+			return e, err
+		}
+		e.duration = end.Sub(e.start)
+	} else if p, ok := c.get("DURATION"); ok {
+		if e.duration, err = parseDuration(p.value); err != nil {
+			return e, err
+		}
+	} else if e.allDay {
+		e.duration = 24 * time.Hour
+	}
+
+	if p, ok := c.get("RECURRENCE-ID"); ok {
+		if e.recurrenceID, _, err = parseTime(p, loc); err != nil {
+			return e, err
+		}
+	}
+	if p, ok := c.get("RRULE"); ok {
+		if e.rule, err = parseRRule(p.value, loc); err != nil {
+			return e, err
+		}
+	}
+	for _, p := range c.all("RDATE") {
+		if strings.EqualFold(p.params["VALUE"], "PERIOD") {
+			return e, errors.New("RDATE periods are not supported")
+		}
+		times, _, err := parseTimes(p, loc)
+		if err != nil {
+			return e, err
+		}
+		e.rdates = append(e.rdates, times...)
+	}
+	for _, p := range c.all("EXDATE") {
+		times, dates, err := parseTimes(p, loc)
+		if err != nil {
+			return e, err
+		}
+		if dates && !e.allDay {
+			e.exdateDays = append(e.exdateDays, times...)
+		} else {
+			e.exdates = append(e.exdates, times...)
+		}
+	}
+	return e, nil
+}
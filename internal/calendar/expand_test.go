@@ -0,0 +1,202 @@
+package calendar
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func vcalendar(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+func TestOccurrences(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	from := time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2030, month, day, hour, 0, 0, 0, madrid)
+	}
+
+	tests := []struct {
+		name   string
+		events []string
+		want   []time.Time
+		keys   []string
+	}{
+		{
+			name:   "single event",
+			events: []string{"BEGIN:VEVENT", "UID:once", "DTSTART:20300304T090000Z", "DTEND:20300304T110000Z", "END:VEVENT"},
+			want:   []time.Time{time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)},
+			keys:   []string{"once"},
+		},
+		{
+			// Madrid moves to summer time on 31 March 2030; the lessons stay at 10:00
+			name: "weekly rule with an exception and an override across a time change",
+			events: []string{
+				"BEGIN:VEVENT", "UID:weekly", "DTSTART;TZID=Europe/Madrid:20300310T100000", "DURATION:PT2H",
+				"RRULE:FREQ=WEEKLY;BYDAY=SU", "EXDATE;TZID=Europe/Madrid:20300317T100000", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:weekly", "RECURRENCE-ID;TZID=Europe/Madrid:20300324T100000",
+				"DTSTART;TZID=Europe/Madrid:20300324T160000", "DTEND;TZID=Europe/Madrid:20300324T180000", "END:VEVENT",
+			},
+			want: []time.Time{at(3, 10, 10), at(3, 24, 16), at(3, 31, 10)},
+			keys: []string{"weekly@20300310T090000Z", "weekly@20300324T090000Z", "weekly@20300331T080000Z"},
+		},
+		{
+			name:   "monthly rule by ordinal weekday with a count",
+			events: []string{"BEGIN:VEVENT", "UID:monthly", "DTSTART:20300101T090000Z", "DTEND:20300101T100000Z", "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=4", "END:VEVENT"},
+			// DTSTART is the first of the four, then the last Fridays of January and February
+			want: []time.Time{time.Date(2030, 3, 29, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "daily rule until a date, every other day",
+			events: []string{"BEGIN:VEVENT", "UID:daily", "DTSTART:20300326T090000Z", "DTEND:20300326T100000Z", "RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20300330T090000Z", "END:VEVENT"},
+			want:   []time.Time{time.Date(2030, 3, 26, 9, 0, 0, 0, time.UTC), time.Date(2030, 3, 28, 9, 0, 0, 0, time.UTC), time.Date(2030, 3, 30, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:   "exception by date",
+			events: []string{"BEGIN:VEVENT", "UID:dates", "DTSTART:20300330T090000Z", "DTEND:20300330T100000Z", "RRULE:FREQ=DAILY;COUNT=2", "EXDATE;VALUE=DATE:20300330", "END:VEVENT"},
+			want:   []time.Time{time.Date(2030, 3, 31, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "cancelled and all-day events",
+			events: []string{
+				"BEGIN:VEVENT", "UID:cancelled", "DTSTART:20300304T090000Z", "DTEND:20300304T100000Z", "STATUS:CANCELLED", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:holiday", "DTSTART;VALUE=DATE:20300305", "END:VEVENT",
+			},
+		},
+		{
+			name: "outside the range",
+			events: []string{
+				"BEGIN:VEVENT", "UID:before", "DTSTART:20300228T230000Z", "DTEND:20300301T000000Z", "END:VEVENT",
+				"BEGIN:VEVENT", "UID:after", "DTSTART:20300401T000000Z", "DTEND:20300401T010000Z", "END:VEVENT",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences, _, err := Occurrences(strings.NewReader(vcalendar(tt.events...)), from, to)
+			if err != nil {
+				t.Fatalf("Occurrences failed: %v", err)
+			}
+			var starts []time.Time
+			var keys []string
+			for _, o := range occurrences {
+				starts = append(starts, o.Start)
+				keys = append(keys, o.Key)
+			}
+			if !slices.EqualFunc(starts, tt.want, time.Time.Equal) {
+				t.Fatalf("Expected starts %v but got %v", tt.want, starts)
+			}
+			if tt.keys != nil && !slices.Equal(keys, tt.keys) {
+				t.Fatalf("Expected keys %v but got %v", tt.keys, keys)
+			}
+		})
+	}
+}
+
+func TestOccurrencesWarns(t *testing.T) {
+	cal := vcalendar(
+		"BEGIN:VEVENT", "DTSTART:20300304T090000Z", "DTEND:20300304T100000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:secondly", "DTSTART:20300304T090000Z", "DTEND:20300304T100000Z", "RRULE:FREQ=SECONDLY", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:holiday", "DTSTART;VALUE=DATE:20300305", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:backwards", "DTSTART:20300304T090000Z", "DTEND:20300304T080000Z", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:fine", "DTSTART:20300304T090000Z", "DTEND:20300304T100000Z", "END:VEVENT",
+	)
+	occurrences, warnings, err := Occurrences(strings.NewReader(cal), time.Date(2030, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Occurrences failed: %v", err)
+	}
+	if len(occurrences) != 1 || occurrences[0].UID != "fine" {
+		t.Fatalf("Expected just the fine event but got %+v", occurrences)
+	}
+	for _, want := range []string{"without a UID: has no UID", "secondly: RRULE FREQ=SECONDLY is not supported", "holiday: all-day", "backwards: ends before it starts"} {
+		if !slices.ContainsFunc(warnings, func(w string) bool { return strings.Contains(w, want) }) {
+			t.Errorf("Expected a warning containing %q in %q", want, warnings)
+		}
+	}
+}
+
+func TestOccurrencesRejectsOtherFiles(t *testing.T) {
+	for _, body := range []string{"", "hello", "BEGIN:VCARD\r\nEND:VCARD\r\n", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n"} {
+		if _, _, err := Occurrences(strings.NewReader(body), time.Time{}, time.Now()); err == nil {
+			t.Errorf("Expected %q to be rejected", body)
+		}
+	}
+}
+
+func TestRRule(t *testing.T) {
+	date := func(month time.Month, day int) time.Time { return time.Date(2030, month, day, 9, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		rule  string
+		start time.Time
+		want  []time.Time
+	}{
+		// 4 March 2030 is a Monday
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5", date(3, 4), []time.Time{date(3, 4), date(3, 7), date(3, 18), date(3, 21), date(4, 1)}},
+		{"FREQ=WEEKLY;BYDAY=SU;WKST=SU;COUNT=2", date(3, 4), []time.Time{date(3, 4), date(3, 10)}},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-1;COUNT=4", date(1, 31), []time.Time{date(1, 31), date(2, 1), date(2, 28), date(3, 1)}},
+		{"FREQ=MONTHLY;COUNT=3", date(1, 31), []time.Time{date(1, 31), date(3, 31), date(5, 31)}},
+		{"FREQ=YEARLY;BYMONTH=3;BYDAY=2TU;COUNT=2", date(3, 12), []time.Time{date(3, 12), time.Date(2031, 3, 11, 9, 0, 0, 0, time.UTC)}},
+		{"FREQ=DAILY;BYDAY=SA,SU;UNTIL=20300310T090000Z", date(3, 4), []time.Time{date(3, 4), date(3, 9), date(3, 10)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			r, err := parseRRule(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("parseRRule failed: %v", err)
+			}
+			got := r.between(tt.start, tt.start, tt.start.AddDate(5, 0, 0))
+			if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+				t.Fatalf("Expected %v but got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, rule := range []string{"FREQ=HOURLY", "FREQ=DAILY;BYHOUR=9", "FREQ=YEARLY;BYDAY=MO", "FREQ=WEEKLY;COUNT=0", "INTERVAL=2"} {
+		if _, err := parseRRule(rule, time.UTC); err == nil {
+			t.Errorf("Expected %q to be rejected", rule)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P1W":     7 * 24 * time.Hour,
+		"P1DT2H":  26 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+		"PT45S":   45 * time.Second,
+	}
+	for value, want := range tests {
+		if got, err := parseDuration(value); err != nil || got != want {
+			t.Errorf("parseDuration(%q) = %v, %v; expected %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "P", "1H", "PT1D", "P1H", "PTH"} {
+		if _, err := parseDuration(value); err == nil {
+			t.Errorf("Expected parseDuration(%q) to fail", value)
+		}
+	}
+}
+
+func TestParseLineUnfoldsAndUnescapes(t *testing.T) {
+	cal, err := parseCalendar(strings.NewReader(vcalendar(
+		"BEGIN:VEVENT",
+		`SUMMARY;LANGUAGE="es:ES":Clase de conducci`,
+		` ón\, aparcamiento\; rotondas`,
+		"END:VEVENT",
+	)))
+	if err != nil {
+		t.Fatalf("parseCalendar failed: %v", err)
+	}
+	p, ok := cal.children[0].get("SUMMARY")
+	if !ok || p.params["LANGUAGE"] != "es:ES" || unescape(p.value) != "Clase de conducción, aparcamiento; rotondas" {
+		t.Fatalf("Unexpected property %+v", p)
+	}
+}
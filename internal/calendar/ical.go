@@ -0,0 +1,137 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// prodID names this API as the producer of the calendars it writes.
+const prodID = "-//df-backend-go//Driving school schedule//EN"
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Calendar is an iCalendar object (RFC 5545) holding events.
+type Calendar struct {
+	// Name is shown by clients that support the X-WR-CALNAME extension
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. Times are written in UTC.
+type Event struct {
+	// UID identifies the event across every version of the calendar
+	UID string
+	// Sequence is incremented each time the event is revised
+	Sequence     int
+	Stamp        time.Time
+	Start        time.Time
+	End          time.Time
+	Summary      string
+	Description  string
+	Status       string
+	Created      time.Time
+	LastModified time.Time
+	// Transparent events do not make the calendar's owner look busy
+	Transparent bool
+}
+
+// Encode writes the calendar in the iCalendar format, with CRLF line endings
+// and long lines folded.
+func (c Calendar) Encode(w io.Writer) error {
+	e := encoder{w: bufio.NewWriter(w)}
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", prodID)
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		e.line("X-WR-CALNAME", escape(c.Name))
+	}
+	for _, event := range c.Events {
+		e.event(event)
+	}
+	e.line("END", "VCALENDAR")
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) event(event Event) {
+	e.line("BEGIN", "VEVENT")
+	e.line("UID", event.UID)
+	e.line("SEQUENCE", strconv.Itoa(event.Sequence))
+	e.line("DTSTAMP", formatTime(event.Stamp))
+	e.line("DTSTART", formatTime(event.Start))
+	e.line("DTEND", formatTime(event.End))
+	e.line("SUMMARY", escape(event.Summary))
+	if event.Description != "" {
+		e.line("DESCRIPTION", escape(event.Description))
+	}
+	if event.Status != "" {
+		e.line("STATUS", event.Status)
+	}
+	if event.Transparent {
+		e.line("TRANSP", "TRANSPARENT")
+	}
+	if !event.Created.IsZero() {
+		e.line("CREATED", formatTime(event.Created))
+	}
+	if !event.LastModified.IsZero() {
+		e.line("LAST-MODIFIED", formatTime(event.LastModified))
+	}
+	e.line("END", "VEVENT")
+}
+
+// line writes a content line, folding it so that no line is longer than 75
+// octets. Folds never split a UTF-8 character.
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+	s := name + ":" + value
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		e.write(s[:cut], "\r\n ")
+		s = s[cut:]
+		// The leading space of a continuation line counts towards its length
+		limit = 74
+	}
+	e.write(s, "\r\n")
+}
+
+func (e *encoder) write(parts ...string) {
+	for _, part := range parts {
+		if e.err == nil {
+			_, e.err = e.w.WriteString(part)
+		}
+	}
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape escapes a TEXT value.
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
@@ -0,0 +1,72 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEncode(t *testing.T) {
+	start := time.Date(2030, 3, 4, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	cal := Calendar{Name: "Driving lessons: Ana", Events: []Event{{
+		UID:         "lesson-1@df-backend-go",
+		Sequence:    2,
+		Stamp:       start.Add(-time.Hour),
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Summary:     "Parking, reversing; roundabouts",
+		Description: "Bring your licence\nand glasses",
+		Status:      StatusCancelled,
+	}}}
+	var out bytes.Buffer
+	if err := cal.Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:" + prodID + "\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"METHOD:PUBLISH\r\n" +
+		"X-WR-CALNAME:Driving lessons: Ana\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:lesson-1@df-backend-go\r\n" +
+		"SEQUENCE:2\r\n" +
+		"DTSTAMP:20300304T080000Z\r\n" +
+		"DTSTART:20300304T090000Z\r\n" +
+		"DTEND:20300304T100000Z\r\n" +
+		`SUMMARY:Parking\, reversing\; roundabouts` + "\r\n" +
+		`DESCRIPTION:Bring your licence\nand glasses` + "\r\n" +
+		"STATUS:CANCELLED\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if out.String() != want {
+		t.Fatalf("Expected\n%q\nbut got\n%q", want, out.String())
+	}
+}
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("Conducción ", 20)
+	var out bytes.Buffer
+	if err := (Calendar{Events: []Event{{UID: "1", Summary: summary}}}).Encode(&out); err != nil {
+		t.Fatal(err)
+	}
+	var unfolded strings.Builder
+	for i, line := range strings.Split(strings.TrimSuffix(out.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("Line %d is %d octets long: %q", i, len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Fatalf("Line %d splits a character: %q", i, line)
+		}
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+summary+"\n") {
+		t.Fatalf("Expected the summary to unfold intact but got %q", unfolded.String())
+	}
+}
@@ -0,0 +1,381 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SourceFile is the source of windows imported from uploaded files. Windows
+// imported from a URL have the URL as their source.
+const SourceFile = "file"
+
+// maxCalendarSize bounds the calendars fetched from URLs.
+const maxCalendarSize = 10 << 20
+
+var (
+	errNotYourAvailability = apperr.Forbidden("forbidden", "you may only import your own availability")
+	errPrivateAddress      = errors.New("the calendar URL points to a private network address")
+)
+
+// Window is an availability window in an import's preview or outcome.
+type Window struct {
+	// ID is the existing window's; windows about to be created have none
+	ID        string    `json:"id,omitempty"`
+	UID       string    `json:"uid"`
+	Summary   string    `json:"summary,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// ImportResult is what an import changed, or would change if it is a dry
+// run.
+type ImportResult struct {
+	DryRun    bool     `json:"dry_run"`
+	Created   []Window `json:"created"`
+	Updated   []Window `json:"updated"`
+	Deleted   []Window `json:"deleted"`
+	Unchanged int      `json:"unchanged"`
+	// Warnings name the events that were left out and why
+	Warnings []string `json:"warnings"`
+	// Sync is the sync set up for the URL, if one was asked for
+	Sync *Sync `json:"sync,omitempty"`
+}
+
+// Importer turns instructors' calendars into availability windows.
+//
+// Every occurrence of an event within the import horizon becomes a window
+// keyed by the event's UID, and the original start for recurring events, so
+// importing the same source again updates the windows it created earlier
+// and deletes those whose events are gone, instead of adding copies. Windows
+// from other sources, and those added by hand, are never touched.
+type Importer struct {
+	availability *availability.AvailabilityService
+	instructors  instructors.InstructorRepository
+	syncs        SyncRepository
+	cfg          config.CalendarConfig
+	client       *http.Client
+	logger       *slog.Logger
+	now          func() time.Time
+}
+
+func NewImporter(availabilityService *availability.AvailabilityService, instructorRepo instructors.InstructorRepository,
+	syncs SyncRepository, cfg config.CalendarConfig, logger *slog.Logger) *Importer {
+	dialer := &net.Dialer{Timeout: cfg.FetchTimeout}
+	if !cfg.AllowPrivateURLs {
+		// Checked on the address actually dialled, so DNS cannot point a
+		// public name at an internal service
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Importer{
+		availability: availabilityService,
+		instructors:  instructorRepo,
+		syncs:        syncs,
+		cfg:          cfg,
+		client:       &http.Client{Timeout: cfg.FetchTimeout, Transport: transport},
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+func isPublic(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// ImportFile imports an uploaded calendar into the instructor's availability.
+func (i *Importer) ImportFile(ctx context.Context, principal *identity.Principal, instructorID primitive.ObjectID, r io.Reader, dryRun bool) (_ *ImportResult, err error) {
+	ctx, span := tracer.Start(ctx, "Importer.ImportFile")
+	defer tracing.End(span, &err)
+	if err := i.authorize(ctx, principal, instructorID); err != nil {
+		return nil, err
+	}
+	return i.apply(ctx, instructorID, SourceFile, r, dryRun)
+}
+
+// ImportURL fetches a calendar and imports it into the instructor's
+// availability. With sync the URL is then imported again every
+// SyncInterval, until the sync is deleted.
+func (i *Importer) ImportURL(ctx context.Context, principal *identity.Principal, instructorID primitive.ObjectID, rawURL string, sync, dryRun bool) (_ *ImportResult, err error) {
+	ctx, span := tracer.Start(ctx, "Importer.ImportURL")
+	defer tracing.End(span, &err)
+	if err := i.authorize(ctx, principal, instructorID); err != nil {
+		return nil, err
+	}
+	source, err := normalizeURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	result, err := i.fetchAndApply(ctx, instructorID, source, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if sync && !dryRun {
+		if result.Sync, err = i.startSync(ctx, instructorID, source); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// startSync records a sync of the URL, or finds the one there already is.
+func (i *Importer) startSync(ctx context.Context, instructorID primitive.ObjectID, source string) (*Sync, error) {
+	now := i.now()
+	sync := Sync{InstructorID: instructorID, URL: source, CreatedAt: now, LastSyncedAt: now}
+	id, err := i.syncs.CreateSync(ctx, sync)
+	if apperr.KindOf(err) == apperr.KindConflict {
+		syncs, err := i.syncs.ListSyncs(ctx, instructorID)
+		if err != nil {
+			return nil, err
+		}
+		for _, existing := range syncs {
+			if existing.URL == source {
+				return &existing, i.syncs.RecordSync(ctx, existing.ID, now, "")
+			}
+		}
+		return nil, apperr.ResourceConflict("calendar sync")
+	}
+	if err != nil {
+		return nil, err
+	}
+	sync.ID = id
+	return &sync, nil
+}
+
+// ListSyncs returns the URLs synced into the instructor's availability.
+func (i *Importer) ListSyncs(ctx context.Context, principal *identity.Principal, instructorID primitive.ObjectID) (_ []Sync, err error) {
+	ctx, span := tracer.Start(ctx, "Importer.ListSyncs")
+	defer tracing.End(span, &err)
+	if err := i.authorize(ctx, principal, instructorID); err != nil {
+		return nil, err
+	}
+	return i.syncs.ListSyncs(ctx, instructorID)
+}
+
+// DeleteSync stops syncing a URL. The windows it imported stay.
+func (i *Importer) DeleteSync(ctx context.Context, principal *identity.Principal, instructorID, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "Importer.DeleteSync")
+	defer tracing.End(span, &err)
+	if err := i.authorize(ctx, principal, instructorID); err != nil {
+		return err
+	}
+	sync, err := i.syncs.GetSyncByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Someone else's sync is as good as missing
+	if sync.InstructorID != instructorID {
+		return apperr.ResourceNotFound("calendar sync")
+	}
+	return i.syncs.DeleteSync(ctx, id)
+}
+
+// Run syncs every calendar URL every SyncInterval until ctx is cancelled.
+// Every instance of the API runs its own syncs; they are idempotent, so the
+// only cost of that is the extra fetches.
+func (i *Importer) Run(ctx context.Context) {
+	ticker := time.NewTicker(i.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := i.SyncAll(ctx); err != nil && ctx.Err() == nil {
+			i.logger.ErrorContext(ctx, "failed to sync calendars", slog.Any("error", err))
+		}
+	}
+}
+
+// SyncAll imports every synced URL again. A URL that fails is recorded on
+// its sync and does not stop the others.
+func (i *Importer) SyncAll(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(ctx, "Importer.SyncAll")
+	defer tracing.End(span, &err)
+	syncs, err := i.syncs.ListSyncs(ctx, primitive.NilObjectID)
+	if err != nil {
+		return err
+	}
+	for _, sync := range syncs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var message string
+		if _, err := i.fetchAndApply(ctx, sync.InstructorID, sync.URL, false); err != nil {
+			message = err.Error()
+			i.logger.WarnContext(ctx, "failed to sync calendar", slog.String("sync_id", sync.ID.Hex()), slog.Any("error", err))
+		}
+		if err := i.syncs.RecordSync(ctx, sync.ID, i.now(), message); err != nil && !apperr.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *Importer) fetchAndApply(ctx context.Context, instructorID primitive.ObjectID, source string, dryRun bool) (*ImportResult, error) {
+	body, err := i.fetch(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return i.apply(ctx, instructorID, source, body, dryRun)
+}
+
+func (i *Importer) fetch(ctx context.Context, source string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, apperr.Invalid("invalid_url", err.Error())
+	}
+	req.Header.Set("Accept", "text/calendar")
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, apperr.Invalid("calendar_unavailable", fmt.Sprintf("fetching the calendar failed: %v", errors.Unwrap(err)))
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, apperr.Invalid("calendar_unavailable", fmt.Sprintf("fetching the calendar failed: %s", resp.Status))
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, maxCalendarSize), resp.Body}, nil
+}
+
+// apply makes the instructor's windows from source match the calendar.
+func (i *Importer) apply(ctx context.Context, instructorID primitive.ObjectID, source string, r io.Reader, dryRun bool) (*ImportResult, error) {
+	from := i.now()
+	to := from.Add(i.cfg.ImportHorizon)
+	occurrences, warnings, err := Occurrences(r, from, to)
+	if err != nil {
+		return nil, apperr.Invalid("invalid_calendar", err.Error())
+	}
+	existing, err := i.availability.ListAvailability(ctx, availability.Filter{InstructorID: instructorID, From: from, To: to, ImportSource: source})
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]availability.Availability{}
+	for _, window := range existing {
+		byKey[window.Import.Key] = window
+	}
+
+	result := &ImportResult{DryRun: dryRun, Created: []Window{}, Updated: []Window{}, Deleted: []Window{}, Warnings: warnings}
+	if result.Warnings == nil {
+		result.Warnings = []string{}
+	}
+	for _, o := range occurrences {
+		window := Window{UID: o.UID, Summary: o.Summary, StartTime: o.Start, EndTime: o.End}
+		current, found := byKey[o.Key]
+		delete(byKey, o.Key)
+		switch {
+		case !found:
+			if !dryRun {
+				id, err := i.availability.CreateAvailability(ctx, availability.Availability{
+					InstructorID: instructorID,
+					StartTime:    o.Start,
+					EndTime:      o.End,
+					Import:       &availability.Import{Source: source, UID: o.UID, Key: o.Key},
+				})
+				// A conflict means a concurrent import of the same calendar created it
+				if err != nil && apperr.KindOf(err) != apperr.KindConflict {
+					return nil, err
+				}
+				if err == nil {
+					window.ID = id.Hex()
+				}
+			}
+			result.Created = append(result.Created, window)
+		case !current.StartTime.Equal(o.Start) || !current.EndTime.Equal(o.End):
+			window.ID = current.ID.Hex()
+			if !dryRun {
+				err := i.availability.UpdateAvailability(ctx, availability.Availability{ID: current.ID, StartTime: o.Start, EndTime: o.End})
+				if err != nil && !apperr.IsNotFound(err) {
+					return nil, err
+				}
+			}
+			result.Updated = append(result.Updated, window)
+		default:
+			result.Unchanged++
+		}
+	}
+	for _, window := range existing {
+		if _, gone := byKey[window.Import.Key]; !gone {
+			continue
+		}
+		if !dryRun {
+			if err := i.availability.DeleteAvailability(ctx, window.ID); err != nil && !apperr.IsNotFound(err) {
+				return nil, err
+			}
+		}
+		result.Deleted = append(result.Deleted, Window{ID: window.ID.Hex(), UID: window.Import.UID, StartTime: window.StartTime, EndTime: window.EndTime})
+	}
+	return result, nil
+}
+
+// authorize lets admins import for any instructor and instructors for
+// themselves.
+func (i *Importer) authorize(ctx context.Context, principal *identity.Principal, instructorID primitive.ObjectID) error {
+	switch principal.Role {
+	case identity.RoleAdmin:
+		_, err := i.instructors.GetInstructorByID(ctx, instructorID)
+		return err
+	case identity.RoleInstructor:
+		instructor, err := i.instructors.GetInstructorByEmail(ctx, principal.Email)
+		if apperr.IsNotFound(err) {
+			return errNotYourAvailability
+		}
+		if err != nil {
+			return err
+		}
+		if instructor.ID != instructorID {
+			return errNotYourAvailability
+		}
+		return nil
+	}
+	return errNotYourAvailability
+}
+
+// normalizeURL accepts http(s) URLs, and webcal ones as calendar apps hand
+// them out, which are fetched over https.
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return "", apperr.Invalid("invalid_url", "url must be an absolute http(s) or webcal URL")
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "webcal", "webcals":
+		u.Scheme = "https"
+	case "http", "https":
+		u.Scheme = strings.ToLower(u.Scheme)
+	default:
+		return "", apperr.Invalid("invalid_url", "url must be an absolute http(s) or webcal URL")
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,125 @@
+package calendar
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryFeedRepository keeps calendar feeds in memory with the same semantics
+// as MongoFeedRepository, including the unique token index. It is meant for
+// tests and local development.
+type MemoryFeedRepository struct {
+	store *memstore.Store[Feed]
+	// mu makes replacing a feed atomic, like the MongoDB upsert
+	mu sync.Mutex
+}
+
+func NewMemoryFeedRepository() *MemoryFeedRepository {
+	return &MemoryFeedRepository{
+		store: memstore.New[Feed](func(a, b Feed) bool {
+			return a.TokenHash == b.TokenHash
+		}),
+	}
+}
+
+func (r *MemoryFeedRepository) SaveFeed(ctx context.Context, feed Feed) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous, replacing := r.store.Get(feed.ID)
+	r.store.Delete(feed.ID)
+	ok, err := r.store.Insert(feed.ID, feed)
+	if (err != nil || !ok) && replacing {
+		r.store.Insert(previous.ID, previous)
+	}
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !ok {
+		return apperr.ResourceConflict("calendar feed")
+	}
+	return nil
+}
+
+func (r *MemoryFeedRepository) GetFeedByTokenHash(ctx context.Context, hash string) (*Feed, error) {
+	feed, ok := r.store.FindOne(func(feed Feed) bool { return feed.TokenHash == hash })
+	if !ok {
+		return nil, apperr.ResourceNotFound("calendar feed")
+	}
+	return &feed, nil
+}
+
+func (r *MemoryFeedRepository) DeleteFeed(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("calendar feed")
+	}
+	return nil
+}
+
+// MemorySyncRepository keeps calendar syncs in memory with the same semantics
+// as MongoSyncRepository. It is meant for tests and local development.
+type MemorySyncRepository struct {
+	store *memstore.Store[Sync]
+	// mu makes recording a sync atomic, like the MongoDB update
+	mu sync.Mutex
+}
+
+func NewMemorySyncRepository() *MemorySyncRepository {
+	return &MemorySyncRepository{
+		store: memstore.New[Sync](func(a, b Sync) bool {
+			return a.InstructorID == b.InstructorID && a.URL == b.URL
+		}),
+	}
+}
+
+func (r *MemorySyncRepository) CreateSync(ctx context.Context, sync Sync) (primitive.ObjectID, error) {
+	sync.ID = primitive.NewObjectID()
+	ok, err := r.store.Insert(sync.ID, sync)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("calendar sync")
+	}
+	return sync.ID, nil
+}
+
+func (r *MemorySyncRepository) GetSyncByID(ctx context.Context, id primitive.ObjectID) (*Sync, error) {
+	sync, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("calendar sync")
+	}
+	return &sync, nil
+}
+
+func (r *MemorySyncRepository) ListSyncs(ctx context.Context, instructorID primitive.ObjectID) ([]Sync, error) {
+	return r.store.Find(func(sync Sync) bool {
+		return instructorID.IsZero() || sync.InstructorID == instructorID
+	}), nil
+}
+
+func (r *MemorySyncRepository) RecordSync(ctx context.Context, id primitive.ObjectID, at time.Time, errMessage string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sync, ok := r.store.Get(id)
+	if !ok {
+		return apperr.ResourceNotFound("calendar sync")
+	}
+	sync.LastSyncedAt = at
+	sync.LastError = errMessage
+	if _, _, err := r.store.Set(id, sync); err != nil {
+		return apperr.Internal(err)
+	}
+	return nil
+}
+
+func (r *MemorySyncRepository) DeleteSync(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("calendar sync")
+	}
+	return nil
+}
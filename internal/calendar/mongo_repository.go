@@ -0,0 +1,115 @@
+package calendar
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoFeedRepository struct {
+	db *mongo.Collection
+}
+
+func NewMongoFeedRepository(db *mongo.Database) *MongoFeedRepository {
+	return &MongoFeedRepository{
+		db: db.Collection("calendar_feeds"),
+	}
+}
+
+func (r *MongoFeedRepository) SaveFeed(ctx context.Context, feed Feed) error {
+	_, err := r.db.ReplaceOne(ctx, bson.M{"_id": feed.ID}, feed, options.Replace().SetUpsert(true))
+	if err != nil {
+		return apperr.FromMongo(err, "calendar feed")
+	}
+	return nil
+}
+
+func (r *MongoFeedRepository) GetFeedByTokenHash(ctx context.Context, hash string) (*Feed, error) {
+	var feed Feed
+	if err := r.db.FindOne(ctx, bson.M{"token_hash": hash}).Decode(&feed); err != nil {
+		return nil, apperr.FromMongo(err, "calendar feed")
+	}
+	return &feed, nil
+}
+
+func (r *MongoFeedRepository) DeleteFeed(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "calendar feed")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "calendar feed")
+	}
+	return nil
+}
+
+type MongoSyncRepository struct {
+	db *mongo.Collection
+}
+
+func NewMongoSyncRepository(db *mongo.Database) *MongoSyncRepository {
+	return &MongoSyncRepository{
+		db: db.Collection("calendar_syncs"),
+	}
+}
+
+func (r *MongoSyncRepository) CreateSync(ctx context.Context, sync Sync) (primitive.ObjectID, error) {
+	sync.ID = primitive.NewObjectID()
+	if _, err := r.db.InsertOne(ctx, sync); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "calendar sync")
+	}
+	return sync.ID, nil
+}
+
+func (r *MongoSyncRepository) GetSyncByID(ctx context.Context, id primitive.ObjectID) (*Sync, error) {
+	var sync Sync
+	if err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&sync); err != nil {
+		return nil, apperr.FromMongo(err, "calendar sync")
+	}
+	return &sync, nil
+}
+
+func (r *MongoSyncRepository) ListSyncs(ctx context.Context, instructorID primitive.ObjectID) ([]Sync, error) {
+	query := bson.M{}
+	if !instructorID.IsZero() {
+		query["instructor_id"] = instructorID
+	}
+	cursor, err := r.db.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, apperr.FromMongo(err, "calendar sync")
+	}
+	defer cursor.Close(ctx)
+
+	var syncs []Sync
+	if err := cursor.All(ctx, &syncs); err != nil {
+		return nil, apperr.FromMongo(err, "calendar sync")
+	}
+	return syncs, nil
+}
+
+func (r *MongoSyncRepository) RecordSync(ctx context.Context, id primitive.ObjectID, at time.Time, errMessage string) error {
+	result, err := r.db.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_synced_at": at, "last_error": errMessage}})
+	if err != nil {
+		return apperr.FromMongo(err, "calendar sync")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "calendar sync")
+	}
+	return nil
+}
+
+func (r *MongoSyncRepository) DeleteSync(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "calendar sync")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "calendar sync")
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLine bounds a single unfolded content line.
+const maxLine = 1 << 20
+
+var errNotCalendar = errors.New("not an iCalendar file: no VCALENDAR found")
+
+// property is a content line: NAME;PARAM=value:VALUE.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// component is a BEGIN:NAME ... END:NAME block.
+type component struct {
+	name       string
+	properties []property
+	children   []*component
+}
+
+func (c *component) get(name string) (property, bool) {
+	for _, p := range c.properties {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+func (c *component) all(name string) []property {
+	var found []property
+	for _, p := range c.properties {
+		if p.name == name {
+			found = append(found, p)
+		}
+	}
+	return found
+}
+
+// parseCalendar reads the first VCALENDAR of an iCalendar stream.
+func parseCalendar(r io.Reader) (*component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var stack []*component
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		switch p.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(p.value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, c)
+			} else if c.name != "VCALENDAR" {
+				return nil, errNotCalendar
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, p.value)
+			}
+			if len(stack) == 1 {
+				return stack[0], nil
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, errNotCalendar
+			}
+			c := stack[len(stack)-1]
+			c.properties = append(c.properties, p)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, errors.New("the calendar ends before END:VCALENDAR")
+	}
+	return nil, errNotCalendar
+}
+
+// unfold splits the stream into content lines, joining folded ones. Bare LF
+// line endings are accepted as well as CRLF.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLine)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+func parseLine(line string) (property, error) {
+	end := strings.IndexAny(line, ";:")
+	if end <= 0 {
+		return property{}, fmt.Errorf("malformed content line %q", truncate(line))
+	}
+	p := property{name: strings.ToUpper(line[:end]), params: map[string]string{}}
+	rest := line[end:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return property{}, fmt.Errorf("malformed parameter in %q", truncate(line))
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			closing := strings.IndexByte(rest[1:], '"')
+			if closing < 0 {
+				return property{}, fmt.Errorf("unterminated quote in %q", truncate(line))
+			}
+			value, rest = rest[1:closing+1], rest[closing+2:]
+		} else {
+			stop := strings.IndexAny(rest, ";:")
+			if stop < 0 {
+				return property{}, fmt.Errorf("malformed content line %q", truncate(line))
+			}
+			value, rest = rest[:stop], rest[stop:]
+		}
+		p.params[name] = value
+	}
+	if !strings.HasPrefix(rest, ":") {
+		return property{}, fmt.Errorf("malformed content line %q", truncate(line))
+	}
+	p.value = rest[1:]
+	return p, nil
+}
+
+func truncate(s string) string {
+	if len(s) > 40 {
+		return s[:40] + "..."
+	}
+	return s
+}
+
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescape(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+// parseTimes reads a DATE or DATE-TIME property, which may hold several
+// comma-separated values. Times without a zone are in loc. allDay reports
+// whether the values are dates.
+func parseTimes(p property, loc *time.Location) (times []time.Time, allDay bool, err error) {
+	if tzid, ok := p.params["TZID"]; ok {
+		if loc, err = loadLocation(tzid); err != nil {
+			return nil, false, err
+		}
+	}
+	allDay = p.params["VALUE"] == "DATE"
+	for _, value := range strings.Split(p.value, ",") {
+		t, date, err := parseTimeValue(value, loc)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", p.name, err)
+		}
+		allDay = allDay || date
+		times = append(times, t)
+	}
+	return times, allDay, nil
+}
+
+func parseTime(p property, loc *time.Location) (time.Time, bool, error) {
+	times, allDay, err := parseTimes(p, loc)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if len(times) != 1 {
+		return time.Time{}, false, fmt.Errorf("%s must hold a single value", p.name)
+	}
+	return times[0], allDay, nil
+}
+
+func parseTimeValue(value string, loc *time.Location) (t time.Time, date bool, err error) {
+	switch {
+	case len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, loc)
+		date = true
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date or time %q", value)
+	}
+	return t, date, nil
+}
+
+// loadLocation resolves a TZID. Only IANA names such as Europe/Madrid are
+// understood; the VTIMEZONE definitions some calendars use instead are not.
+func loadLocation(tzid string) (*time.Location, error) {
+	loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", tzid)
+	}
+	return loc, nil
+}
+
+// parseDuration reads a DURATION value such as PT1H30M or P1D.
+func parseDuration(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid duration %q", value)
+	s, sign := value, time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		s, sign = s[1:], -1
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, invalid
+	}
+	s = s[1:]
+	var total time.Duration
+	inTime := false
+	for s != "" {
+		if s[0] == 'T' {
+			inTime, s = true, s[1:]
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, invalid
+		}
+		n, _ := strconv.Atoi(s[:i])
+		var unit time.Duration
+		switch designator := s[i]; {
+		case !inTime && designator == 'W':
+			unit = 7 * 24 * time.Hour
+		case !inTime && designator == 'D':
+			unit = 24 * time.Hour
+		case inTime && designator == 'H':
+			unit = time.Hour
+		case inTime && designator == 'M':
+			unit = time.Minute
+		case inTime && designator == 'S':
+			unit = time.Second
+		default:
+			return 0, invalid
+		}
+		total += time.Duration(n) * unit
+		s = s[i+1:]
+	}
+	return sign * total, nil
+}
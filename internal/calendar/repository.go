@@ -0,0 +1,29 @@
+package calendar
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FeedRepository interface {
+	// SaveFeed creates the owner's feed, or replaces it and so revokes the
+	// previous token.
+	SaveFeed(ctx context.Context, feed Feed) error
+	GetFeedByTokenHash(ctx context.Context, hash string) (*Feed, error)
+	DeleteFeed(ctx context.Context, id primitive.ObjectID) error
+}
+
+type SyncRepository interface {
+	// CreateSync fails with a conflict if the instructor already syncs the URL.
+	CreateSync(ctx context.Context, sync Sync) (primitive.ObjectID, error)
+	GetSyncByID(ctx context.Context, id primitive.ObjectID) (*Sync, error)
+	// ListSyncs returns the instructor's syncs, or everyone's for a zero ID,
+	// oldest first.
+	ListSyncs(ctx context.Context, instructorID primitive.ObjectID) ([]Sync, error)
+	// RecordSync stores the outcome of a sync; errMessage is empty if it
+	// succeeded.
+	RecordSync(ctx context.Context, id primitive.ObjectID, at time.Time, errMessage string) error
+	DeleteSync(ctx context.Context, id primitive.ObjectID) error
+}
@@ -0,0 +1,166 @@
+package calendar
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryFeedRepository(t *testing.T) {
+	testFeedRepository(t, func(t *testing.T) FeedRepository {
+		return NewMemoryFeedRepository()
+	})
+}
+
+func TestMongoFeedRepository(t *testing.T) {
+	testFeedRepository(t, func(t *testing.T) FeedRepository {
+		return NewMongoFeedRepository(mongotest.Database(t))
+	})
+}
+
+// testFeedRepository is the contract every FeedRepository must satisfy.
+func testFeedRepository(t *testing.T, newRepo func(t *testing.T) FeedRepository) {
+	ctx := context.Background()
+	now := time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	t.Run("save replaces the token", func(t *testing.T) {
+		repo := newRepo(t)
+		id := primitive.NewObjectID()
+		if err := repo.SaveFeed(ctx, Feed{ID: id, Owner: OwnerStudent, TokenHash: "first", CreatedAt: now}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+		feed, err := repo.GetFeedByTokenHash(ctx, "first")
+		if err != nil {
+			t.Fatalf("GetFeedByTokenHash failed: %v", err)
+		}
+		if feed.ID != id || feed.Owner != OwnerStudent || !feed.CreatedAt.Equal(now) {
+			t.Fatalf("Unexpected feed %+v", feed)
+		}
+
+		if err := repo.SaveFeed(ctx, Feed{ID: id, Owner: OwnerStudent, TokenHash: "second", CreatedAt: now}); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+		if _, err := repo.GetFeedByTokenHash(ctx, "first"); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected the old token to be revoked but got %v", err)
+		}
+		if feed, err := repo.GetFeedByTokenHash(ctx, "second"); err != nil || feed.ID != id {
+			t.Fatalf("Expected the new token to work but got %+v, %v", feed, err)
+		}
+	})
+
+	t.Run("tokens are unique", func(t *testing.T) {
+		repo := newRepo(t)
+		first := Feed{ID: primitive.NewObjectID(), Owner: OwnerStudent, TokenHash: "same"}
+		if err := repo.SaveFeed(ctx, first); err != nil {
+			t.Fatalf("SaveFeed failed: %v", err)
+		}
+		err := repo.SaveFeed(ctx, Feed{ID: primitive.NewObjectID(), Owner: OwnerInstructor, TokenHash: "same"})
+		if apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+		if feed, err := repo.GetFeedByTokenHash(ctx, "same"); err != nil || feed.ID != first.ID {
+			t.Fatalf("Expected the first feed to keep its token but got %+v, %v", feed, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id := primitive.NewObjectID()
+		repo.SaveFeed(ctx, Feed{ID: id, Owner: OwnerInstructor, TokenHash: "token"})
+		if err := repo.DeleteFeed(ctx, id); err != nil {
+			t.Fatalf("DeleteFeed failed: %v", err)
+		}
+		if _, err := repo.GetFeedByTokenHash(ctx, "token"); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected the feed to be gone but got %v", err)
+		}
+		if err := repo.DeleteFeed(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteFeed: expected not found but got %v", err)
+		}
+	})
+}
+
+func TestMemorySyncRepository(t *testing.T) {
+	testSyncRepository(t, func(t *testing.T) SyncRepository {
+		return NewMemorySyncRepository()
+	})
+}
+
+func TestMongoSyncRepository(t *testing.T) {
+	testSyncRepository(t, func(t *testing.T) SyncRepository {
+		return NewMongoSyncRepository(mongotest.Database(t))
+	})
+}
+
+// testSyncRepository is the contract every SyncRepository must satisfy.
+func testSyncRepository(t *testing.T, newRepo func(t *testing.T) SyncRepository) {
+	ctx := context.Background()
+	now := time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)
+	instructorID := primitive.NewObjectID()
+	sample := Sync{InstructorID: instructorID, URL: "https://calendar.example.com/ana.ics", CreatedAt: now, LastSyncedAt: now}
+
+	t.Run("create, list and record", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateSync(ctx, sample)
+		if err != nil {
+			t.Fatalf("CreateSync failed: %v", err)
+		}
+		other := Sync{InstructorID: primitive.NewObjectID(), URL: sample.URL, CreatedAt: now}
+		if _, err := repo.CreateSync(ctx, other); err != nil {
+			t.Fatalf("Expected another instructor to sync the same URL but got %v", err)
+		}
+
+		syncs, err := repo.ListSyncs(ctx, instructorID)
+		if err != nil {
+			t.Fatalf("ListSyncs failed: %v", err)
+		}
+		if len(syncs) != 1 || syncs[0].ID != id || syncs[0].URL != sample.URL || !syncs[0].CreatedAt.Equal(now) {
+			t.Fatalf("Unexpected syncs %+v", syncs)
+		}
+		if all, err := repo.ListSyncs(ctx, primitive.NilObjectID); err != nil || len(all) != 2 {
+			t.Fatalf("Expected both syncs but got %+v, %v", all, err)
+		}
+
+		later := now.Add(time.Hour)
+		if err := repo.RecordSync(ctx, id, later, "fetching the calendar failed"); err != nil {
+			t.Fatalf("RecordSync failed: %v", err)
+		}
+		if sync, _ := repo.GetSyncByID(ctx, id); !sync.LastSyncedAt.Equal(later) || sync.LastError != "fetching the calendar failed" {
+			t.Fatalf("Unexpected sync after a failure %+v", sync)
+		}
+		if err := repo.RecordSync(ctx, id, later.Add(time.Hour), ""); err != nil {
+			t.Fatalf("RecordSync failed: %v", err)
+		}
+		if sync, _ := repo.GetSyncByID(ctx, id); sync.LastError != "" {
+			t.Fatalf("Expected a success to clear the error but got %+v", sync)
+		}
+	})
+
+	t.Run("URLs are unique per instructor", func(t *testing.T) {
+		repo := newRepo(t)
+		repo.CreateSync(ctx, sample)
+		if _, err := repo.CreateSync(ctx, sample); apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateSync(ctx, sample)
+		if err := repo.DeleteSync(ctx, id); err != nil {
+			t.Fatalf("DeleteSync failed: %v", err)
+		}
+		if _, err := repo.GetSyncByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected the sync to be gone but got %v", err)
+		}
+		if err := repo.RecordSync(ctx, id, now, ""); !apperr.IsNotFound(err) {
+			t.Fatalf("RecordSync: expected not found but got %v", err)
+		}
+		if err := repo.DeleteSync(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteSync: expected not found but got %v", err)
+		}
+	})
+}
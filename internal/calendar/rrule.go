@@ -0,0 +1,301 @@
+package calendar
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPeriods bounds how far a rule is followed looking for occurrences.
+const maxPeriods = 100000
+
+var weekdayNames = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// weekdayNum is a BYDAY entry such as MO, or 2MO and -1FR for the second
+// Monday and last Friday of the month.
+type weekdayNum struct {
+	n   int
+	day time.Weekday
+}
+
+// rrule is a recurrence rule (RFC 5545 section 3.3.10). The DAILY, WEEKLY,
+// MONTHLY and YEARLY frequencies are understood with INTERVAL, COUNT, UNTIL,
+// WKST, BYDAY, BYMONTHDAY and BYMONTH; rules with other parts are rejected.
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+	weekStart  time.Weekday
+}
+
+func parseRRule(value string, loc *time.Location) (*rrule, error) {
+	r := &rrule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed RRULE part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.interval, err = positive(val)
+		case "COUNT":
+			r.count, err = positive(val)
+		case "UNTIL":
+			r.until, _, err = parseTimeValue(val, loc)
+		case "WKST":
+			day, ok := weekdayNames[strings.ToUpper(val)]
+			if !ok {
+				err = fmt.Errorf("invalid WKST %q", val)
+			}
+			r.weekStart = day
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				wd, err := parseWeekdayNum(item)
+				if err != nil {
+					return nil, err
+				}
+				r.byDay = append(r.byDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, item := range strings.Split(val, ",") {
+				day, err := strconv.Atoi(item)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+				}
+				r.byMonthDay = append(r.byMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, item := range strings.Split(val, ",") {
+				month, err := strconv.Atoi(item)
+				if err != nil || month < 1 || month > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", item)
+				}
+				r.byMonth = append(r.byMonth, time.Month(month))
+			}
+		default:
+			return nil, fmt.Errorf("RRULE %s is not supported", strings.ToUpper(name))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	case "":
+		return nil, fmt.Errorf("RRULE has no FREQ")
+	default:
+		return nil, fmt.Errorf("RRULE FREQ=%s is not supported", r.freq)
+	}
+	if r.freq == "YEARLY" && len(r.byDay) > 0 && len(r.byMonth) == 0 {
+		return nil, fmt.Errorf("RRULE BYDAY in a yearly rule is only supported with BYMONTH")
+	}
+	return r, nil
+}
+
+func positive(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid RRULE number %q", s)
+	}
+	return n, nil
+}
+
+func parseWeekdayNum(s string) (weekdayNum, error) {
+	s = strings.ToUpper(s)
+	if len(s) < 2 {
+		return weekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	day, ok := weekdayNames[s[len(s)-2:]]
+	if !ok {
+		return weekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	var n int
+	if prefix := s[:len(s)-2]; prefix != "" {
+		var err error
+		if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -5 || n > 5 {
+			return weekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+	}
+	return weekdayNum{n: n, day: day}, nil
+}
+
+// between returns the starts of the occurrences in [from, to). start, the
+// DTSTART, is always the first occurrence, and every occurrence keeps its
+// wall-clock time in start's location, across daylight saving changes too.
+func (r *rrule) between(start, from, to time.Time) []time.Time {
+	var found []time.Time
+	seen := 0
+	// emit counts an occurrence and reports whether the rule goes on
+	emit := func(t time.Time) bool {
+		if (!r.until.IsZero() && t.After(r.until)) || (r.count > 0 && seen >= r.count) {
+			return false
+		}
+		seen++
+		if !t.Before(from) && t.Before(to) {
+			found = append(found, t)
+		}
+		return true
+	}
+
+	if !emit(start) {
+		return found
+	}
+	for k := 0; k < maxPeriods; k++ {
+		periodStart, days := r.period(start, k)
+		if !periodStart.Before(to) {
+			break
+		}
+		for _, day := range days {
+			t := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+			if !t.After(start) {
+				continue
+			}
+			if !t.Before(to) || !emit(t) {
+				return found
+			}
+		}
+	}
+	return found
+}
+
+// period returns the first day of the k-th period of the rule and the days in
+// it that the rule selects, in order.
+func (r *rrule) period(start time.Time, k int) (time.Time, []time.Time) {
+	y, m, d, loc := start.Year(), start.Month(), start.Day(), start.Location()
+	step := k * r.interval
+	switch r.freq {
+	case "DAILY":
+		day := time.Date(y, m, d+step, 0, 0, 0, 0, loc)
+		if r.matches(day) {
+			return day, []time.Time{day}
+		}
+		return day, nil
+	case "WEEKLY":
+		back := (int(start.Weekday()) - int(r.weekStart) + 7) % 7
+		weekStart := time.Date(y, m, d-back+7*step, 0, 0, 0, 0, loc)
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.byDay) > 0 {
+			weekdays = nil
+			for _, wd := range r.byDay {
+				weekdays = append(weekdays, wd.day)
+			}
+		}
+		var days []time.Time
+		for _, wd := range weekdays {
+			day := weekStart.AddDate(0, 0, (int(wd)-int(r.weekStart)+7)%7)
+			if len(r.byMonth) == 0 || slices.Contains(r.byMonth, day.Month()) {
+				days = append(days, day)
+			}
+		}
+		return weekStart, sortDays(days)
+	case "MONTHLY":
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+		if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, first.Month()) {
+			return first, nil
+		}
+		return first, r.daysInMonth(first, d)
+	}
+	// YEARLY
+	first := time.Date(y+step, 1, 1, 0, 0, 0, 0, loc)
+	months := r.byMonth
+	if len(months) == 0 {
+		months = []time.Month{m}
+	}
+	var days []time.Time
+	for _, month := range months {
+		days = append(days, r.daysInMonth(time.Date(first.Year(), month, 1, 0, 0, 0, 0, loc), d)...)
+	}
+	return first, sortDays(days)
+}
+
+// daysInMonth is the days of the month starting on first that BYMONTHDAY and
+// BYDAY select, or else defaultDay if the month has it.
+func (r *rrule) daysInMonth(first time.Time, defaultDay int) []time.Time {
+	length := first.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	add := func(day int) {
+		if day >= 1 && day <= length {
+			days = append(days, first.AddDate(0, 0, day-1))
+		}
+	}
+	switch {
+	case len(r.byDay) > 0:
+		for _, wd := range r.byDay {
+			for _, day := range weekdaysInMonth(first, length, wd) {
+				if len(r.byMonthDay) == 0 || slices.Contains(r.monthDays(length), day) {
+					add(day)
+				}
+			}
+		}
+	case len(r.byMonthDay) > 0:
+		for _, day := range r.monthDays(length) {
+			add(day)
+		}
+	default:
+		add(defaultDay)
+	}
+	return sortDays(days)
+}
+
+// monthDays resolves BYMONTHDAY, where -1 is the last day, for a month of
+// the given length.
+func (r *rrule) monthDays(length int) []int {
+	var days []int
+	for _, day := range r.byMonthDay {
+		if day < 0 {
+			day += length + 1
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// weekdaysInMonth is the days of the month that are wd.day, or just the
+// wd.n-th of them, counting from the end if negative.
+func weekdaysInMonth(first time.Time, length int, wd weekdayNum) []int {
+	var days []int
+	for day := 1 + (int(wd.day)-int(first.Weekday())+7)%7; day <= length; day += 7 {
+		days = append(days, day)
+	}
+	switch {
+	case wd.n == 0:
+		return days
+	case wd.n > 0 && wd.n <= len(days):
+		return days[wd.n-1 : wd.n]
+	case wd.n < 0 && -wd.n <= len(days):
+		return days[len(days)+wd.n : len(days)+wd.n+1]
+	}
+	return nil
+}
+
+// matches applies BYDAY, BYMONTHDAY and BYMONTH as filters, as they are for
+// daily rules.
+func (r *rrule) matches(day time.Time) bool {
+	if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, day.Month()) {
+		return false
+	}
+	if len(r.byMonthDay) > 0 && !slices.Contains(r.monthDays(day.AddDate(0, 1, -day.Day()).Day()), day.Day()) {
+		return false
+	}
+	if len(r.byDay) > 0 && !slices.ContainsFunc(r.byDay, func(wd weekdayNum) bool { return wd.day == day.Weekday() }) {
+		return false
+	}
+	return true
+}
+
+func sortDays(days []time.Time) []time.Time {
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(days, func(a, b time.Time) bool { return a.Equal(b) })
+}
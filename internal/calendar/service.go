@@ -0,0 +1,332 @@
+// Package calendar publishes lessons and availability as iCalendar
+// (RFC 5545) data: a feed per student and instructor that calendar apps
+// subscribe to through a secret link, and a one-off file per lesson.
+//
+// Every lesson and availability window keeps the same UID in every version of
+// a calendar and carries its update count as the SEQUENCE, so clients replace
+// the copy they have when it changes. Cancelled lessons stay in the feeds with
+// a CANCELLED status; deleted ones just disappear.
+//
+// The other way round, the Importer turns the events of instructors' own
+// calendars, uploaded or fetched from a URL, into availability windows.
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tracer = tracing.Tracer("internal/calendar")
+
+// uidDomain makes the UIDs of events unique beyond this API.
+const uidDomain = "df-backend-go"
+
+// defaultTitle stands in for lessons without a title.
+const defaultTitle = "Driving lesson"
+
+var errNoCalendar = apperr.Forbidden("no_calendar", "only students and instructors have a calendar")
+
+type Service struct {
+	feeds          FeedRepository
+	lessons        lessons.LessonRepository
+	availability   availability.AvailabilityRepository
+	students       students.StudentRepository
+	instructors    instructors.InstructorRepository
+	lessonDuration time.Duration
+	history        time.Duration
+	now            func() time.Time
+}
+
+func NewService(feeds FeedRepository, lessonRepo lessons.LessonRepository, availabilityRepo availability.AvailabilityRepository,
+	studentRepo students.StudentRepository, instructorRepo instructors.InstructorRepository, lessonDuration time.Duration, cfg config.CalendarConfig) *Service {
+	return &Service{
+		feeds:          feeds,
+		lessons:        lessonRepo,
+		availability:   availabilityRepo,
+		students:       studentRepo,
+		instructors:    instructorRepo,
+		lessonDuration: lessonDuration,
+		history:        cfg.History,
+		now:            time.Now,
+	}
+}
+
+// CreateFeed gives the caller a new feed token, revoking any previous one.
+func (s *Service) CreateFeed(ctx context.Context, principal *identity.Principal) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "Service.CreateFeed")
+	defer tracing.End(span, &err)
+	owner, id, err := s.owner(ctx, principal)
+	if err != nil {
+		return "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", apperr.Internal(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	feed := Feed{ID: id, Owner: owner, TokenHash: hashToken(token), CreatedAt: s.now()}
+	if err := s.feeds.SaveFeed(ctx, feed); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// DeleteFeed revokes the caller's feed token.
+func (s *Service) DeleteFeed(ctx context.Context, principal *identity.Principal) (err error) {
+	ctx, span := tracer.Start(ctx, "Service.DeleteFeed")
+	defer tracing.End(span, &err)
+	_, id, err := s.owner(ctx, principal)
+	if err != nil {
+		return err
+	}
+	return s.feeds.DeleteFeed(ctx, id)
+}
+
+// Feed is the calendar the token gives access to: its owner's lessons from
+// the configured history onwards and, for instructors, their availability.
+func (s *Service) Feed(ctx context.Context, token string) (_ *Calendar, err error) {
+	ctx, span := tracer.Start(ctx, "Service.Feed")
+	defer tracing.End(span, &err)
+	feed, err := s.feeds.GetFeedByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	from := s.now().Add(-s.history)
+	names := s.names()
+
+	var cal Calendar
+	var lessonList []lessons.Lesson
+	switch feed.Owner {
+	case OwnerStudent:
+		student, err := s.students.GetStudentByID(ctx, feed.ID)
+		if err != nil {
+			return nil, ownerGone(err)
+		}
+		cal.Name = "Driving lessons: " + fullName(student.FirstName, student.LastName)
+		lessonList, err = s.lessons.ListLessons(ctx, lessons.Filter{StudentID: feed.ID, From: from, IncludeCancelled: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, lesson := range lessonList {
+			with, err := names.instructor(ctx, lesson.InstructorID)
+			if err != nil {
+				return nil, err
+			}
+			cal.Events = append(cal.Events, s.lessonEvent(lesson, with))
+		}
+	case OwnerInstructor:
+		instructor, err := s.instructors.GetInstructorByID(ctx, feed.ID)
+		if err != nil {
+			return nil, ownerGone(err)
+		}
+		cal.Name = "Driving lessons: " + fullName(instructor.FirstName, instructor.LastName)
+		lessonList, err = s.lessons.ListLessons(ctx, lessons.Filter{InstructorID: feed.ID, From: from, IncludeCancelled: true})
+		if err != nil {
+			return nil, err
+		}
+		for _, lesson := range lessonList {
+			with, err := names.student(ctx, lesson.StudentID)
+			if err != nil {
+				return nil, err
+			}
+			cal.Events = append(cal.Events, s.lessonEvent(lesson, with))
+		}
+		windows, err := s.availability.ListAvailability(ctx, availability.Filter{InstructorID: feed.ID, From: from})
+		if err != nil {
+			return nil, err
+		}
+		for _, window := range windows {
+			cal.Events = append(cal.Events, availabilityEvent(window))
+		}
+	default:
+		return nil, apperr.ResourceNotFound("calendar feed")
+	}
+	return &cal, nil
+}
+
+// LessonCalendar is a calendar holding just the lesson, for adding it to a
+// calendar by hand. Students and instructors only get their own lessons;
+// other lessons are not found for them, so their IDs give nothing away.
+func (s *Service) LessonCalendar(ctx context.Context, principal *identity.Principal, id primitive.ObjectID) (_ *Calendar, err error) {
+	ctx, span := tracer.Start(ctx, "Service.LessonCalendar")
+	defer tracing.End(span, &err)
+	lesson, err := s.lessons.GetLessonByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if principal.Role != identity.RoleAdmin {
+		owner, ownerID, err := s.owner(ctx, principal)
+		if err != nil && !errors.Is(err, errNoCalendar) {
+			return nil, err
+		}
+		if err != nil || (owner == OwnerStudent && lesson.StudentID != ownerID) || (owner == OwnerInstructor && lesson.InstructorID != ownerID) {
+			return nil, apperr.ResourceNotFound("lesson")
+		}
+	}
+	with, err := s.names().instructor(ctx, lesson.InstructorID)
+	if err != nil {
+		return nil, err
+	}
+	return &Calendar{Events: []Event{s.lessonEvent(*lesson, with)}}, nil
+}
+
+func (s *Service) lessonEvent(lesson lessons.Lesson, with string) Event {
+	summary := lesson.Title
+	if summary == "" {
+		summary = defaultTitle
+	}
+	if with != "" {
+		summary += " with " + with
+	}
+	status := StatusConfirmed
+	if lesson.Status == lessons.StatusCancelled {
+		status = StatusCancelled
+	}
+	return Event{
+		UID:          "lesson-" + lesson.ID.Hex() + "@" + uidDomain,
+		Sequence:     lesson.Sequence,
+		Stamp:        stamp(lesson.CreatedAt, lesson.UpdatedAt),
+		Start:        lesson.Schedule,
+		End:          lesson.Schedule.Add(s.lessonDuration),
+		Summary:      summary,
+		Description:  lesson.Description,
+		Status:       status,
+		Created:      lesson.CreatedAt,
+		LastModified: lesson.UpdatedAt,
+	}
+}
+
+func availabilityEvent(window availability.Availability) Event {
+	return Event{
+		UID:          "availability-" + window.ID.Hex() + "@" + uidDomain,
+		Sequence:     window.Sequence,
+		Stamp:        stamp(window.CreatedAt, window.UpdatedAt),
+		Start:        window.StartTime,
+		End:          window.EndTime,
+		Summary:      "Available for lessons",
+		Status:       StatusConfirmed,
+		Created:      window.CreatedAt,
+		LastModified: window.UpdatedAt,
+		Transparent:  true,
+	}
+}
+
+// owner finds the student or instructor behind the caller.
+func (s *Service) owner(ctx context.Context, principal *identity.Principal) (string, primitive.ObjectID, error) {
+	switch principal.Role {
+	case identity.RoleStudent:
+		student, err := s.students.GetStudentByEmail(ctx, principal.Email)
+		if err != nil {
+			return "", primitive.NilObjectID, noCalendar(err)
+		}
+		return OwnerStudent, student.ID, nil
+	case identity.RoleInstructor:
+		instructor, err := s.instructors.GetInstructorByEmail(ctx, principal.Email)
+		if err != nil {
+			return "", primitive.NilObjectID, noCalendar(err)
+		}
+		return OwnerInstructor, instructor.ID, nil
+	}
+	return "", primitive.NilObjectID, errNoCalendar
+}
+
+// nameCache looks up the people on a calendar's lessons once each.
+type nameCache struct {
+	s     *Service
+	names map[primitive.ObjectID]string
+}
+
+func (s *Service) names() *nameCache {
+	return &nameCache{s: s, names: map[primitive.ObjectID]string{}}
+}
+
+func (c *nameCache) student(ctx context.Context, id primitive.ObjectID) (string, error) {
+	return c.lookup(ctx, id, func() (string, error) {
+		student, err := c.s.students.GetStudentByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return fullName(student.FirstName, student.LastName), nil
+	})
+}
+
+func (c *nameCache) instructor(ctx context.Context, id primitive.ObjectID) (string, error) {
+	return c.lookup(ctx, id, func() (string, error) {
+		instructor, err := c.s.instructors.GetInstructorByID(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return fullName(instructor.FirstName, instructor.LastName), nil
+	})
+}
+
+// lookup leaves out the name of anyone missing, such as the student of an
+// open lesson.
+func (c *nameCache) lookup(ctx context.Context, id primitive.ObjectID, find func() (string, error)) (string, error) {
+	if id.IsZero() {
+		return "", nil
+	}
+	if name, ok := c.names[id]; ok {
+		return name, nil
+	}
+	name, err := find()
+	if apperr.IsNotFound(err) {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	c.names[id] = name
+	return name, nil
+}
+
+func fullName(first, last string) string {
+	return strings.TrimSpace(first + " " + last)
+}
+
+// stamp is when the event was last revised, which DTSTAMP holds in published
+// calendars.
+func stamp(created, updated time.Time) time.Time {
+	if updated.IsZero() {
+		return created
+	}
+	return updated
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// noCalendar reports a caller without a student or instructor record as
+// having no calendar.
+func noCalendar(err error) error {
+	if apperr.IsNotFound(err) {
+		return errNoCalendar
+	}
+	return err
+}
+
+// ownerGone treats the feed of a deleted student or instructor as gone too.
+func ownerGone(err error) error {
+	if apperr.IsNotFound(err) {
+		return apperr.ResourceNotFound("calendar feed")
+	}
+	return err
+}
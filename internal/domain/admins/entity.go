@@ -1,6 +1,7 @@
 package admins
 
 import (
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,3 +18,8 @@ type Admin struct {
 	CreatedAt    time.Time          `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt    time.Time          `bson:"updated_at,omitempty" json:"updated_at"`
 }
+
+// LogValue keeps the password hash and personal details out of logs.
+func (a Admin) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", a.ID.Hex()), slog.String("email", a.Email), slog.String("role", a.Role))
+}
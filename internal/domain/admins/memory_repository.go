@@ -0,0 +1,75 @@
+package admins
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryAdminRepository keeps admins in memory with the same semantics as
+// MongoAdminRepository, including the unique email index. It is meant for
+// tests and local development.
+type MemoryAdminRepository struct {
+	store *memstore.Store[Admin]
+}
+
+func NewMemoryAdminRepository() *MemoryAdminRepository {
+	return &MemoryAdminRepository{
+		store: memstore.New[Admin](func(a, b Admin) bool { return a.Email != "" && a.Email == b.Email }),
+	}
+}
+
+func (r *MemoryAdminRepository) CreateAdmin(ctx context.Context, admin Admin) (primitive.ObjectID, error) {
+	admin.ID = primitive.NewObjectID()
+	admin.CreatedAt = time.Now()
+	admin.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(admin.ID, admin)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("admin")
+	}
+	return admin.ID, nil
+}
+
+func (r *MemoryAdminRepository) GetAdminByID(ctx context.Context, id primitive.ObjectID) (*Admin, error) {
+	admin, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("admin")
+	}
+	return &admin, nil
+}
+
+func (r *MemoryAdminRepository) GetAdminByEmail(ctx context.Context, email string) (*Admin, error) {
+	admin, ok := r.store.FindOne(func(candidate Admin) bool { return candidate.Email == email })
+	if !ok {
+		return nil, apperr.ResourceNotFound("admin")
+	}
+	return &admin, nil
+}
+
+func (r *MemoryAdminRepository) UpdateAdmin(ctx context.Context, admin Admin) error {
+	admin.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(admin.ID, admin)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("admin")
+	}
+	if !ok {
+		return apperr.ResourceConflict("admin")
+	}
+	return nil
+}
+
+func (r *MemoryAdminRepository) DeleteAdmin(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("admin")
+	}
+	return nil
+}
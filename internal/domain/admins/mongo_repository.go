@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,39 +20,53 @@ func NewMongoAdminRepository(db *mongo.Database) *MongoAdminRepository {
 	}
 }
 
-func (r *MongoAdminRepository) CreateAdmin(admin Admin) (primitive.ObjectID, error) {
+func (r *MongoAdminRepository) CreateAdmin(ctx context.Context, admin Admin) (primitive.ObjectID, error) {
 	admin.ID = primitive.NewObjectID()
 	admin.CreatedAt = time.Now()
 	admin.UpdatedAt = time.Now()
-	_, err := r.db.InsertOne(context.Background(), admin)
-	return admin.ID, err
+	if _, err := r.db.InsertOne(ctx, admin); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "admin")
+	}
+	return admin.ID, nil
 }
 
-func (r *MongoAdminRepository) GetAdminByID(id primitive.ObjectID) (*Admin, error) {
+func (r *MongoAdminRepository) GetAdminByID(ctx context.Context, id primitive.ObjectID) (*Admin, error) {
 	var admin Admin
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&admin)
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&admin)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "admin")
 	}
 	return &admin, nil
 }
 
-func (r *MongoAdminRepository) GetAdminByEmail(email string) (*Admin, error) {
+func (r *MongoAdminRepository) GetAdminByEmail(ctx context.Context, email string) (*Admin, error) {
 	var admin Admin
-	err := r.db.FindOne(context.Background(), bson.M{"email": email}).Decode(&admin)
+	err := r.db.FindOne(ctx, bson.M{"email": email}).Decode(&admin)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "admin")
 	}
 	return &admin, nil
 }
 
-func (r *MongoAdminRepository) UpdateAdmin(admin Admin) error {
+func (r *MongoAdminRepository) UpdateAdmin(ctx context.Context, admin Admin) error {
 	admin.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": admin.ID}, bson.M{"$set": admin})
-	return err
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": admin.ID}, bson.M{"$set": admin})
+	if err != nil {
+		return apperr.FromMongo(err, "admin")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "admin")
+	}
+	return nil
 }
 
-func (r *MongoAdminRepository) DeleteAdmin(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoAdminRepository) DeleteAdmin(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "admin")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "admin")
+	}
+	return nil
 }
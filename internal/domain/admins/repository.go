@@ -1,11 +1,15 @@
 package admins
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type AdminRepository interface {
-	CreateAdmin(admin Admin) (primitive.ObjectID, error)
-	GetAdminByID(id primitive.ObjectID) (*Admin, error)
-	GetAdminByEmail(email string) (*Admin, error)
-	UpdateAdmin(admin Admin) error
-	DeleteAdmin(id primitive.ObjectID) error
+	CreateAdmin(ctx context.Context, admin Admin) (primitive.ObjectID, error)
+	GetAdminByID(ctx context.Context, id primitive.ObjectID) (*Admin, error)
+	GetAdminByEmail(ctx context.Context, email string) (*Admin, error)
+	UpdateAdmin(ctx context.Context, admin Admin) error
+	DeleteAdmin(ctx context.Context, id primitive.ObjectID) error
 }
@@ -0,0 +1,99 @@
+package admins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryAdminRepository(t *testing.T) {
+	testAdminRepository(t, func(t *testing.T) AdminRepository {
+		return NewMemoryAdminRepository()
+	})
+}
+
+func TestMongoAdminRepository(t *testing.T) {
+	testAdminRepository(t, func(t *testing.T) AdminRepository {
+		return NewMongoAdminRepository(mongotest.Database(t))
+	})
+}
+
+// testAdminRepository is the contract every AdminRepository must satisfy.
+func testAdminRepository(t *testing.T, newRepo func(t *testing.T) AdminRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateAdmin(ctx, Admin{FirstName: "Nora", LastName: "Ortega", Email: "nora@example.com"})
+		if err != nil {
+			t.Fatalf("CreateAdmin failed: %v", err)
+		}
+
+		admin, err := repo.GetAdminByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetAdminByID failed: %v", err)
+		}
+		if admin.ID != id || admin.FirstName != "Nora" || admin.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected admin %+v", admin)
+		}
+
+		byEmail, err := repo.GetAdminByEmail(ctx, "nora@example.com")
+		if err != nil || byEmail.ID != id {
+			t.Fatalf("GetAdminByEmail returned %+v, %v", byEmail, err)
+		}
+		if _, err := repo.GetAdminByEmail(ctx, "nobody@example.com"); !apperr.IsNotFound(err) {
+			t.Fatalf("GetAdminByEmail: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("unique email", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.CreateAdmin(ctx, Admin{FirstName: "Nora", LastName: "Ortega", Email: "nora@example.com"}); err != nil {
+			t.Fatalf("CreateAdmin failed: %v", err)
+		}
+		_, err := repo.CreateAdmin(ctx, Admin{FirstName: "Nora", LastName: "Ortega", Email: "nora@example.com"})
+		if apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetAdminByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetAdminByID: expected not found but got %v", err)
+		}
+		if err := repo.UpdateAdmin(ctx, Admin{ID: missing, FirstName: "Nora"}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateAdmin: expected not found but got %v", err)
+		}
+		if err := repo.DeleteAdmin(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteAdmin: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("update keeps omitted fields", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateAdmin(ctx, Admin{FirstName: "Nora", LastName: "Ortega", Email: "nora@example.com"})
+		if err := repo.UpdateAdmin(ctx, Admin{ID: id, FirstName: "Noemi"}); err != nil {
+			t.Fatalf("UpdateAdmin failed: %v", err)
+		}
+		admin, _ := repo.GetAdminByID(ctx, id)
+		if admin.FirstName != "Noemi" || admin.LastName != "Ortega" {
+			t.Fatalf("Unexpected admin after update %+v", admin)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateAdmin(ctx, Admin{FirstName: "Nora", LastName: "Ortega", Email: "nora@example.com"})
+		if err := repo.DeleteAdmin(ctx, id); err != nil {
+			t.Fatalf("DeleteAdmin failed: %v", err)
+		}
+		if _, err := repo.GetAdminByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted admin to be gone but got %v", err)
+		}
+	})
+}
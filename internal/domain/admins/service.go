@@ -1,211 +1,139 @@
 package admins
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"net/http"
-	"net/url"
-	"strings"
+	"context"
+	"log/slog"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
-	"github.com/lucasgarciaf/df-backend-go/config"
-	"github.com/lucasgarciaf/df-backend-go/internal/utils"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var tracer = tracing.Tracer("internal/domain/admins")
+
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrEmailExists        = errors.New("email already exists")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "invalid email or password")
+	ErrEmailExists        = apperr.Conflict("email_exists", "email already exists")
 )
 
-type AuthClaims struct {
-	AdminID primitive.ObjectID `json:"admin_id"`
-	Role    string             `json:"role"`
-	jwt.RegisteredClaims
-}
-
 type AdminService struct {
-	repo AdminRepository
+	repo   AdminRepository
+	idp    identity.Provider
+	audit  audit.Recorder
+	logger *slog.Logger
 }
 
-func NewAdminService(repo AdminRepository) *AdminService {
-	return &AdminService{repo: repo}
+func NewAdminService(repo AdminRepository, idp identity.Provider, auditor audit.Recorder, logger *slog.Logger) *AdminService {
+	return &AdminService{repo: repo, idp: idp, audit: auditor, logger: logger}
 }
 
-func (s *AdminService) CreateAdmin(admin Admin, password string) (primitive.ObjectID, error) {
-	// Create user in Keycloak
-	err := s.createUserInKeycloak(admin, password)
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-
-	// Create user in MongoDB
-	existingAdmin, _ := s.repo.GetAdminByEmail(admin.Email)
+func (s *AdminService) CreateAdmin(ctx context.Context, admin Admin, password string) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.CreateAdmin")
+	defer tracing.End(span, &err)
+	existingAdmin, err := s.repo.GetAdminByEmail(ctx, admin.Email)
 	if existingAdmin != nil {
 		return primitive.NilObjectID, ErrEmailExists
 	}
+	if err != nil && !apperr.IsNotFound(err) {
+		return primitive.NilObjectID, err
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := identity.HashPassword(ctx, password)
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
-	admin.PasswordHash = string(hashedPassword)
-	admin.Role = "admin" // Ensure Role is set
+	admin.PasswordHash = hashedPassword
+	admin.Role = identity.RoleAdmin // Ensure Role is set
 	admin.CreatedAt = time.Now()
 	admin.UpdatedAt = time.Now()
 
-	return s.repo.CreateAdmin(admin)
-}
-
-func (s *AdminService) Authenticate(email, password string) (string, error) {
-	admin, err := s.repo.GetAdminByEmail(email)
-	if err != nil {
-		return "", ErrInvalidCredentials
+	id, err := s.repo.CreateAdmin(ctx, admin)
+	if apperr.KindOf(err) == apperr.KindConflict {
+		return primitive.NilObjectID, ErrEmailExists
 	}
-
-	err = bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password))
 	if err != nil {
-		return "", ErrInvalidCredentials
+		return primitive.NilObjectID, err
 	}
 
-	token, err := s.createToken(admin.ID, admin.Role)
+	// Create user in the identity provider, rolling back the stored admin if that fails
+	err = s.idp.CreateUser(ctx, identity.User{
+		Username:  admin.Username,
+		FirstName: admin.FirstName,
+		LastName:  admin.LastName,
+		Email:     admin.Email,
+		Password:  password,
+		Role:      identity.RoleAdmin,
+	})
 	if err != nil {
-		return "", err
+		s.logger.WarnContext(ctx, "identity provider rejected new admin, rolling back", slog.String("admin_id", id.Hex()), slog.Any("error", err))
+		if rollbackErr := s.repo.DeleteAdmin(ctx, id); rollbackErr != nil {
+			s.logger.ErrorContext(ctx, "failed to roll back admin", slog.String("admin_id", id.Hex()), slog.Any("error", rollbackErr))
+		}
+		if apperr.KindOf(err) == apperr.KindConflict {
+			return primitive.NilObjectID, ErrEmailExists
+		}
+		return primitive.NilObjectID, err
 	}
-
-	return token, nil
+	s.audit.Record(ctx, audit.ActionCreate, "admin", id, nil, admin)
+	return id, nil
 }
 
-func (s *AdminService) createToken(adminID primitive.ObjectID, role string) (string, error) {
-	claims := &AuthClaims{
-		AdminID: adminID,
-		Role:    role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.TokenExpiry)),
-		},
+func (s *AdminService) Authenticate(ctx context.Context, email, password string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.Authenticate")
+	defer tracing.End(span, &err)
+	token, err := s.idp.Login(ctx, email, password)
+	if apperr.KindOf(err) == apperr.KindUnauthorized {
+		return "", ErrInvalidCredentials
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(config.JWTSecretKey)
+	return token, err
 }
 
-func (s *AdminService) GetAdminByID(id primitive.ObjectID) (*Admin, error) {
-	return s.repo.GetAdminByID(id)
+func (s *AdminService) GetAdminByID(ctx context.Context, id primitive.ObjectID) (_ *Admin, err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetAdminByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetAdminByID(ctx, id)
 }
 
-func (s *AdminService) GetAdminByEmail(email string) (*Admin, error) {
-	return s.repo.GetAdminByEmail(email)
+func (s *AdminService) GetAdminByEmail(ctx context.Context, email string) (_ *Admin, err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetAdminByEmail")
+	defer tracing.End(span, &err)
+	return s.repo.GetAdminByEmail(ctx, email)
 }
 
-func (s *AdminService) UpdateAdmin(admin Admin) error {
+func (s *AdminService) UpdateAdmin(ctx context.Context, admin Admin) (err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.UpdateAdmin")
+	defer tracing.End(span, &err)
 	admin.UpdatedAt = time.Now()
-	return s.repo.UpdateAdmin(admin)
-}
-
-func (s *AdminService) DeleteAdmin(id primitive.ObjectID) error {
-	return s.repo.DeleteAdmin(id)
-}
-
-func (s *AdminService) Logout(refreshToken string) error {
-	logoutURL := fmt.Sprintf("%sprotocol/openid-connect/logout", config.KeycloakURL)
-
-	data := map[string]string{
-		"client_id":     config.KeycloakClientID,
-		"client_secret": config.KeycloakClientSecret,
-		"refresh_token": refreshToken,
-	}
-
-	formData := url.Values{}
-	for key, value := range data {
-		formData.Set(key, value)
-	}
-
-	req, err := http.NewRequest("POST", logoutURL, strings.NewReader(formData.Encode()))
+	before, err := s.repo.GetAdminByID(ctx, admin.ID)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+	if err := s.repo.UpdateAdmin(ctx, admin); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to logout from Keycloak: %s", resp.Status)
-	}
-
+	s.audit.Record(ctx, audit.ActionUpdate, "admin", admin.ID, before, admin)
 	return nil
 }
 
-func (s *AdminService) createUserInKeycloak(admin Admin, password string) error {
-	keycloakUser := struct {
-		Username    string `json:"username"`
-		FirstName   string `json:"firstName"`
-		LastName    string `json:"lastName"`
-		Email       string `json:"email"`
-		Enabled     bool   `json:"enabled"`
-		Credentials []struct {
-			Type      string `json:"type"`
-			Value     string `json:"value"`
-			Temporary bool   `json:"temporary"`
-		} `json:"credentials"`
-		RealmRoles []string `json:"realmRoles"`
-	}{
-		Username:  admin.Username,
-		FirstName: admin.FirstName,
-		LastName:  admin.LastName,
-		Email:     admin.Email,
-		Enabled:   true,
-		Credentials: []struct {
-			Type      string `json:"type"`
-			Value     string `json:"value"`
-			Temporary bool   `json:"temporary"`
-		}{
-			{
-				Type:      "password",
-				Value:     password,
-				Temporary: false,
-			},
-		},
-		RealmRoles: []string{"admin"}, // Assign "admin" role
-	}
-
-	userJSON, err := json.Marshal(keycloakUser)
-	if err != nil {
-		return err
-	}
-
-	url := fmt.Sprintf("%sadmin/realms/%s/users", config.KeycloakURL, config.KeycloakRealm)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(userJSON))
+func (s *AdminService) DeleteAdmin(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.DeleteAdmin")
+	defer tracing.End(span, &err)
+	before, err := s.repo.GetAdminByID(ctx, id)
 	if err != nil {
 		return err
 	}
-
-	token, err := utils.GetClientToken()
-	if err != nil {
+	if err := s.repo.DeleteAdmin(ctx, id); err != nil {
 		return err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token) // Use a valid client access token
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create user in Keycloak: %s", resp.Status)
-	}
-
+	s.audit.Record(ctx, audit.ActionDelete, "admin", id, before, nil)
 	return nil
 }
+
+func (s *AdminService) Logout(ctx context.Context, refreshToken string) (err error) {
+	ctx, span := tracer.Start(ctx, "AdminService.Logout")
+	defer tracing.End(span, &err)
+	return s.idp.Logout(ctx, refreshToken)
+}
@@ -11,6 +11,23 @@ type Availability struct {
 	InstructorID primitive.ObjectID `bson:"instructor_id,omitempty"`
 	StartTime    time.Time          `bson:"start_time,omitempty"`
 	EndTime      time.Time          `bson:"end_time,omitempty"`
-	CreatedAt    time.Time          `bson:"created_at,omitempty"`
-	UpdatedAt    time.Time          `bson:"updated_at,omitempty"`
+	// Sequence counts the updates to the window, so calendar clients can
+	// tell which version is newer
+	Sequence int `bson:"sequence,omitempty"`
+	// Import is set on windows that came from an imported calendar
+	Import    *Import   `bson:"import,omitempty"`
+	CreatedAt time.Time `bson:"created_at,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty"`
+}
+
+// Import ties a window to the calendar event it was imported from, so that
+// importing the same calendar again updates it instead of adding a copy.
+type Import struct {
+	// Source is the URL the calendar was fetched from, or "file" for uploads.
+	// Calendar URLs are often secret links, so it is not shown to clients
+	Source string `bson:"source" json:"-"`
+	UID    string `bson:"uid"`
+	// Key is unique per instructor and source: the UID, followed by "@" and
+	// the original start of the occurrence for recurring events
+	Key string `bson:"key"`
 }
@@ -0,0 +1,96 @@
+package availability
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryAvailabilityRepository keeps availability windows in memory with the same semantics as
+// MongoAvailabilityRepository. It is meant for tests and local development.
+type MemoryAvailabilityRepository struct {
+	store *memstore.Store[Availability]
+	// mu makes incrementing the sequence atomic, like the MongoDB update
+	mu sync.Mutex
+}
+
+func NewMemoryAvailabilityRepository() *MemoryAvailabilityRepository {
+	return &MemoryAvailabilityRepository{
+		store: memstore.New[Availability](func(a, b Availability) bool {
+			return a.Import != nil && b.Import != nil && a.InstructorID == b.InstructorID &&
+				a.Import.Source == b.Import.Source && a.Import.Key == b.Import.Key
+		}),
+	}
+}
+
+func (r *MemoryAvailabilityRepository) CreateAvailability(ctx context.Context, availability Availability) (primitive.ObjectID, error) {
+	availability.ID = primitive.NewObjectID()
+	availability.Sequence = 0
+	availability.CreatedAt = time.Now()
+	availability.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(availability.ID, availability)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("availability")
+	}
+	return availability.ID, nil
+}
+
+func (r *MemoryAvailabilityRepository) GetAvailabilityByID(ctx context.Context, id primitive.ObjectID) (*Availability, error) {
+	availability, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("availability")
+	}
+	return &availability, nil
+}
+
+func (r *MemoryAvailabilityRepository) ListAvailability(ctx context.Context, filter Filter) ([]Availability, error) {
+	windows := r.store.Find(func(availability Availability) bool {
+		switch {
+		case !filter.InstructorID.IsZero() && availability.InstructorID != filter.InstructorID,
+			!filter.From.IsZero() && !availability.EndTime.After(filter.From),
+			!filter.To.IsZero() && !availability.StartTime.Before(filter.To),
+			filter.ImportSource != "" && (availability.Import == nil || availability.Import.Source != filter.ImportSource):
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].StartTime.Before(windows[j].StartTime) })
+	return windows, nil
+}
+
+func (r *MemoryAvailabilityRepository) UpdateAvailability(ctx context.Context, availability Availability) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, found := r.store.Get(availability.ID)
+	if !found {
+		return apperr.ResourceNotFound("availability")
+	}
+	availability.Sequence = current.Sequence + 1
+	availability.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(availability.ID, availability)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("availability")
+	}
+	if !ok {
+		return apperr.ResourceConflict("availability")
+	}
+	return nil
+}
+
+func (r *MemoryAvailabilityRepository) DeleteAvailability(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("availability")
+	}
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoAvailabilityRepository struct {
@@ -19,30 +21,74 @@ func NewMongoAvailabilityRepository(db *mongo.Database) *MongoAvailabilityReposi
 	}
 }
 
-func (r *MongoAvailabilityRepository) CreateAvailability(availability Availability) (primitive.ObjectID, error) {
+func (r *MongoAvailabilityRepository) CreateAvailability(ctx context.Context, availability Availability) (primitive.ObjectID, error) {
 	availability.ID = primitive.NewObjectID()
+	availability.Sequence = 0
 	availability.CreatedAt = time.Now()
 	availability.UpdatedAt = time.Now()
-	_, err := r.db.InsertOne(context.Background(), availability)
-	return availability.ID, err
+	if _, err := r.db.InsertOne(ctx, availability); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "availability")
+	}
+	return availability.ID, nil
 }
 
-func (r *MongoAvailabilityRepository) GetAvailabilityByID(id primitive.ObjectID) (*Availability, error) {
+func (r *MongoAvailabilityRepository) GetAvailabilityByID(ctx context.Context, id primitive.ObjectID) (*Availability, error) {
 	var availability Availability
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&availability)
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&availability)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "availability")
 	}
 	return &availability, nil
 }
 
-func (r *MongoAvailabilityRepository) UpdateAvailability(availability Availability) error {
+func (r *MongoAvailabilityRepository) ListAvailability(ctx context.Context, filter Filter) ([]Availability, error) {
+	query := bson.M{}
+	if !filter.InstructorID.IsZero() {
+		query["instructor_id"] = filter.InstructorID
+	}
+	if !filter.From.IsZero() {
+		query["end_time"] = bson.M{"$gt": filter.From}
+	}
+	if !filter.To.IsZero() {
+		query["start_time"] = bson.M{"$lt": filter.To}
+	}
+	if filter.ImportSource != "" {
+		query["import.source"] = filter.ImportSource
+	}
+
+	cursor, err := r.db.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}}))
+	if err != nil {
+		return nil, apperr.FromMongo(err, "availability")
+	}
+	defer cursor.Close(ctx)
+
+	var windows []Availability
+	if err := cursor.All(ctx, &windows); err != nil {
+		return nil, apperr.FromMongo(err, "availability")
+	}
+	return windows, nil
+}
+
+func (r *MongoAvailabilityRepository) UpdateAvailability(ctx context.Context, availability Availability) error {
 	availability.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": availability.ID}, bson.M{"$set": availability})
-	return err
+	availability.Sequence = 0
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": availability.ID}, bson.M{"$set": availability, "$inc": bson.M{"sequence": 1}})
+	if err != nil {
+		return apperr.FromMongo(err, "availability")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "availability")
+	}
+	return nil
 }
 
-func (r *MongoAvailabilityRepository) DeleteAvailability(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoAvailabilityRepository) DeleteAvailability(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "availability")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "availability")
+	}
+	return nil
 }
@@ -1,10 +1,27 @@
 package availability
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter narrows down ListAvailability. Zero-valued fields are ignored.
+type Filter struct {
+	InstructorID primitive.ObjectID
+	// From and To select the windows that overlap them
+	From time.Time
+	To   time.Time
+	// ImportSource selects the windows imported from a calendar source
+	ImportSource string
+}
 
 type AvailabilityRepository interface {
-	CreateAvailability(availability Availability) (primitive.ObjectID, error)
-	GetAvailabilityByID(id primitive.ObjectID) (*Availability, error)
-	UpdateAvailability(availability Availability) error
-	DeleteAvailability(id primitive.ObjectID) error
+	CreateAvailability(ctx context.Context, availability Availability) (primitive.ObjectID, error)
+	GetAvailabilityByID(ctx context.Context, id primitive.ObjectID) (*Availability, error)
+	// ListAvailability returns the matching windows sorted by start time.
+	ListAvailability(ctx context.Context, filter Filter) ([]Availability, error)
+	UpdateAvailability(ctx context.Context, availability Availability) error
+	DeleteAvailability(ctx context.Context, id primitive.ObjectID) error
 }
@@ -0,0 +1,167 @@
+package availability
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryAvailabilityRepository(t *testing.T) {
+	testAvailabilityRepository(t, func(t *testing.T) AvailabilityRepository {
+		return NewMemoryAvailabilityRepository()
+	})
+}
+
+func TestMongoAvailabilityRepository(t *testing.T) {
+	testAvailabilityRepository(t, func(t *testing.T) AvailabilityRepository {
+		return NewMongoAvailabilityRepository(mongotest.Database(t))
+	})
+}
+
+// testAvailabilityRepository is the contract every AvailabilityRepository must satisfy.
+func testAvailabilityRepository(t *testing.T, newRepo func(t *testing.T) AvailabilityRepository) {
+	ctx := context.Background()
+	start := time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)
+	sample := Availability{InstructorID: primitive.NewObjectID(), StartTime: start, EndTime: start.Add(3 * time.Hour)}
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateAvailability(ctx, sample)
+		if err != nil {
+			t.Fatalf("CreateAvailability failed: %v", err)
+		}
+
+		availability, err := repo.GetAvailabilityByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetAvailabilityByID failed: %v", err)
+		}
+		if availability.ID != id || availability.InstructorID != sample.InstructorID ||
+			!availability.StartTime.Equal(sample.StartTime) || availability.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected availability %+v", availability)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetAvailabilityByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetAvailabilityByID: expected not found but got %v", err)
+		}
+		if err := repo.UpdateAvailability(ctx, Availability{ID: missing, StartTime: start}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateAvailability: expected not found but got %v", err)
+		}
+		if err := repo.DeleteAvailability(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteAvailability: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("update keeps omitted fields", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateAvailability(ctx, sample)
+		later := start.Add(time.Hour)
+		if err := repo.UpdateAvailability(ctx, Availability{ID: id, StartTime: later}); err != nil {
+			t.Fatalf("UpdateAvailability failed: %v", err)
+		}
+		availability, _ := repo.GetAvailabilityByID(ctx, id)
+		if !availability.StartTime.Equal(later) || !availability.EndTime.Equal(sample.EndTime) ||
+			availability.InstructorID != sample.InstructorID || availability.Sequence != 1 {
+			t.Fatalf("Unexpected availability after update %+v", availability)
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		repo := newRepo(t)
+		afternoon := Availability{InstructorID: sample.InstructorID, StartTime: start.Add(5 * time.Hour), EndTime: start.Add(8 * time.Hour)}
+		other := Availability{InstructorID: primitive.NewObjectID(), StartTime: start, EndTime: start.Add(time.Hour)}
+		ids := map[string]primitive.ObjectID{}
+		for name, availability := range map[string]Availability{"afternoon": afternoon, "morning": sample, "other": other} {
+			id, err := repo.CreateAvailability(ctx, availability)
+			if err != nil {
+				t.Fatalf("CreateAvailability failed: %v", err)
+			}
+			ids[name] = id
+		}
+
+		tests := []struct {
+			name   string
+			filter Filter
+			want   []string
+		}{
+			{"instructor sorted by start", Filter{InstructorID: sample.InstructorID}, []string{"morning", "afternoon"}},
+			{"overlapping the range", Filter{From: start.Add(2 * time.Hour), To: start.Add(6 * time.Hour)}, []string{"morning", "afternoon"}},
+			{"ending at the start of the range", Filter{From: start.Add(3 * time.Hour), To: start.Add(4 * time.Hour)}, nil},
+			{"starting at the end of the range", Filter{InstructorID: other.InstructorID, To: start}, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				windows, err := repo.ListAvailability(ctx, tt.filter)
+				if err != nil {
+					t.Fatalf("ListAvailability failed: %v", err)
+				}
+				var got []string
+				for _, window := range windows {
+					for name, id := range ids {
+						if window.ID == id {
+							got = append(got, name)
+						}
+					}
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Fatalf("Expected %v but got %v", tt.want, got)
+				}
+			})
+		}
+	})
+
+	t.Run("import keys are unique per instructor and source", func(t *testing.T) {
+		repo := newRepo(t)
+		imported := sample
+		imported.Import = &Import{Source: "file", UID: "event-1", Key: "event-1"}
+		if _, err := repo.CreateAvailability(ctx, imported); err != nil {
+			t.Fatalf("CreateAvailability failed: %v", err)
+		}
+		if _, err := repo.CreateAvailability(ctx, imported); apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+		other := imported
+		other.InstructorID = primitive.NewObjectID()
+		if _, err := repo.CreateAvailability(ctx, other); err != nil {
+			t.Fatalf("Expected another instructor to import the same event but got %v", err)
+		}
+		fromURL := imported
+		fromURL.Import = &Import{Source: "https://calendar.example.com/a.ics", UID: "event-1", Key: "event-1"}
+		if _, err := repo.CreateAvailability(ctx, fromURL); err != nil {
+			t.Fatalf("Expected another source to have the same event but got %v", err)
+		}
+		if _, err := repo.CreateAvailability(ctx, sample); err != nil {
+			t.Fatalf("CreateAvailability failed: %v", err)
+		}
+		if _, err := repo.CreateAvailability(ctx, sample); err != nil {
+			t.Fatalf("Expected windows that were not imported not to conflict but got %v", err)
+		}
+
+		windows, err := repo.ListAvailability(ctx, Filter{InstructorID: sample.InstructorID, ImportSource: "file"})
+		if err != nil {
+			t.Fatalf("ListAvailability failed: %v", err)
+		}
+		if len(windows) != 1 || windows[0].Import == nil || *windows[0].Import != *imported.Import {
+			t.Fatalf("Expected just the imported window but got %+v", windows)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateAvailability(ctx, sample)
+		if err := repo.DeleteAvailability(ctx, id); err != nil {
+			t.Fatalf("DeleteAvailability failed: %v", err)
+		}
+		if _, err := repo.GetAvailabilityByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted availability to be gone but got %v", err)
+		}
+	})
+}
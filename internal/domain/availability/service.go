@@ -1,27 +1,102 @@
 package availability
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tracer = tracing.Tracer("internal/domain/availability")
 
 type AvailabilityService struct {
-	repo AvailabilityRepository
+	repo   AvailabilityRepository
+	audit  audit.Recorder
+	events events.Emitter
+}
+
+func NewAvailabilityService(repo AvailabilityRepository, auditor audit.Recorder, emitter events.Emitter) *AvailabilityService {
+	return &AvailabilityService{repo: repo, audit: auditor, events: emitter}
+}
+
+func (s *AvailabilityService) CreateAvailability(ctx context.Context, availability Availability) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "AvailabilityService.CreateAvailability")
+	defer tracing.End(span, &err)
+	var id primitive.ObjectID
+	err = s.events.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.repo.CreateAvailability(ctx, availability)
+		if err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionCreate, "availability", id, nil, availability)
+		availability.ID = id
+		return s.events.Emit(ctx, changed(availability, events.ChangeCreated))
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return id, nil
 }
 
-func NewAvailabilityService(repo AvailabilityRepository) *AvailabilityService {
-	return &AvailabilityService{repo: repo}
+func (s *AvailabilityService) GetAvailabilityByID(ctx context.Context, id primitive.ObjectID) (_ *Availability, err error) {
+	ctx, span := tracer.Start(ctx, "AvailabilityService.GetAvailabilityByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetAvailabilityByID(ctx, id)
 }
 
-func (s *AvailabilityService) CreateAvailability(availability Availability) (primitive.ObjectID, error) {
-	return s.repo.CreateAvailability(availability)
+func (s *AvailabilityService) ListAvailability(ctx context.Context, filter Filter) (_ []Availability, err error) {
+	ctx, span := tracer.Start(ctx, "AvailabilityService.ListAvailability")
+	defer tracing.End(span, &err)
+	return s.repo.ListAvailability(ctx, filter)
 }
 
-func (s *AvailabilityService) GetAvailabilityByID(id primitive.ObjectID) (*Availability, error) {
-	return s.repo.GetAvailabilityByID(id)
+func (s *AvailabilityService) UpdateAvailability(ctx context.Context, availability Availability) (err error) {
+	ctx, span := tracer.Start(ctx, "AvailabilityService.UpdateAvailability")
+	defer tracing.End(span, &err)
+	return s.events.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetAvailabilityByID(ctx, availability.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.UpdateAvailability(ctx, availability); err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionUpdate, "availability", availability.ID, before, availability)
+		// The update only carries the fields that change
+		after, err := s.repo.GetAvailabilityByID(ctx, availability.ID)
+		if err != nil {
+			return err
+		}
+		return s.events.Emit(ctx, changed(*after, events.ChangeUpdated))
+	})
 }
 
-func (s *AvailabilityService) UpdateAvailability(availability Availability) error {
-	return s.repo.UpdateAvailability(availability)
+func (s *AvailabilityService) DeleteAvailability(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "AvailabilityService.DeleteAvailability")
+	defer tracing.End(span, &err)
+	return s.events.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetAvailabilityByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.DeleteAvailability(ctx, id); err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionDelete, "availability", id, before, nil)
+		return s.events.Emit(ctx, changed(*before, events.ChangeDeleted))
+	})
 }
 
-func (s *AvailabilityService) DeleteAvailability(id primitive.ObjectID) error {
-	return s.repo.DeleteAvailability(id)
+func changed(availability Availability, change events.Change) events.AvailabilityChanged {
+	return events.AvailabilityChanged{
+		AvailabilityID: availability.ID,
+		InstructorID:   availability.InstructorID,
+		StartTime:      availability.StartTime,
+		EndTime:        availability.EndTime,
+		Change:         change,
+		Deleted:        change == events.ChangeDeleted,
+	}
 }
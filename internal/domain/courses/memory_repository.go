@@ -0,0 +1,66 @@
+package courses
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryCourseRepository keeps courses in memory with the same semantics as
+// MongoCourseRepository. It is meant for tests and local development.
+type MemoryCourseRepository struct {
+	store *memstore.Store[Course]
+}
+
+func NewMemoryCourseRepository() *MemoryCourseRepository {
+	return &MemoryCourseRepository{
+		store: memstore.New[Course](nil),
+	}
+}
+
+func (r *MemoryCourseRepository) CreateCourse(ctx context.Context, course Course) (primitive.ObjectID, error) {
+	course.ID = primitive.NewObjectID()
+	course.CreatedAt = time.Now()
+	course.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(course.ID, course)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("course")
+	}
+	return course.ID, nil
+}
+
+func (r *MemoryCourseRepository) GetCourseByID(ctx context.Context, id primitive.ObjectID) (*Course, error) {
+	course, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("course")
+	}
+	return &course, nil
+}
+
+func (r *MemoryCourseRepository) UpdateCourse(ctx context.Context, course Course) error {
+	course.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(course.ID, course)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("course")
+	}
+	if !ok {
+		return apperr.ResourceConflict("course")
+	}
+	return nil
+}
+
+func (r *MemoryCourseRepository) DeleteCourse(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("course")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,30 +20,44 @@ func NewMongoCourseRepository(db *mongo.Database) *MongoCourseRepository {
 	}
 }
 
-func (r *MongoCourseRepository) CreateCourse(course Course) (primitive.ObjectID, error) {
+func (r *MongoCourseRepository) CreateCourse(ctx context.Context, course Course) (primitive.ObjectID, error) {
 	course.ID = primitive.NewObjectID()
 	course.CreatedAt = time.Now()
 	course.UpdatedAt = time.Now()
-	_, err := r.db.InsertOne(context.Background(), course)
-	return course.ID, err
+	if _, err := r.db.InsertOne(ctx, course); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "course")
+	}
+	return course.ID, nil
 }
 
-func (r *MongoCourseRepository) GetCourseByID(id primitive.ObjectID) (*Course, error) {
+func (r *MongoCourseRepository) GetCourseByID(ctx context.Context, id primitive.ObjectID) (*Course, error) {
 	var course Course
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&course)
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&course)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "course")
 	}
 	return &course, nil
 }
 
-func (r *MongoCourseRepository) UpdateCourse(course Course) error {
+func (r *MongoCourseRepository) UpdateCourse(ctx context.Context, course Course) error {
 	course.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": course.ID}, bson.M{"$set": course})
-	return err
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": course.ID}, bson.M{"$set": course})
+	if err != nil {
+		return apperr.FromMongo(err, "course")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "course")
+	}
+	return nil
 }
 
-func (r *MongoCourseRepository) DeleteCourse(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoCourseRepository) DeleteCourse(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "course")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "course")
+	}
+	return nil
 }
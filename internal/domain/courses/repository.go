@@ -1,10 +1,14 @@
 package courses
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type CourseRepository interface {
-	CreateCourse(course Course) (primitive.ObjectID, error)
-	GetCourseByID(id primitive.ObjectID) (*Course, error)
-	UpdateCourse(course Course) error
-	DeleteCourse(id primitive.ObjectID) error
+	CreateCourse(ctx context.Context, course Course) (primitive.ObjectID, error)
+	GetCourseByID(ctx context.Context, id primitive.ObjectID) (*Course, error)
+	UpdateCourse(ctx context.Context, course Course) error
+	DeleteCourse(ctx context.Context, id primitive.ObjectID) error
 }
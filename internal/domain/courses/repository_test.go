@@ -0,0 +1,80 @@
+package courses
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryCourseRepository(t *testing.T) {
+	testCourseRepository(t, func(t *testing.T) CourseRepository {
+		return NewMemoryCourseRepository()
+	})
+}
+
+func TestMongoCourseRepository(t *testing.T) {
+	testCourseRepository(t, func(t *testing.T) CourseRepository {
+		return NewMongoCourseRepository(mongotest.Database(t))
+	})
+}
+
+// testCourseRepository is the contract every CourseRepository must satisfy.
+func testCourseRepository(t *testing.T, newRepo func(t *testing.T) CourseRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateCourse(ctx, Course{Title: "Beginner Driving", Description: "Basics", Duration: 20})
+		if err != nil {
+			t.Fatalf("CreateCourse failed: %v", err)
+		}
+
+		course, err := repo.GetCourseByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetCourseByID failed: %v", err)
+		}
+		if course.ID != id || course.Title != "Beginner Driving" || course.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected course %+v", course)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetCourseByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetCourseByID: expected not found but got %v", err)
+		}
+		if err := repo.UpdateCourse(ctx, Course{ID: missing, Title: "Beginner Driving"}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateCourse: expected not found but got %v", err)
+		}
+		if err := repo.DeleteCourse(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteCourse: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("update keeps omitted fields", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateCourse(ctx, Course{Title: "Beginner Driving", Description: "Basics", Duration: 20})
+		if err := repo.UpdateCourse(ctx, Course{ID: id, Title: "Advanced Driving"}); err != nil {
+			t.Fatalf("UpdateCourse failed: %v", err)
+		}
+		course, _ := repo.GetCourseByID(ctx, id)
+		if course.Title != "Advanced Driving" || course.Duration != 20 {
+			t.Fatalf("Unexpected course after update %+v", course)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateCourse(ctx, Course{Title: "Beginner Driving", Description: "Basics", Duration: 20})
+		if err := repo.DeleteCourse(ctx, id); err != nil {
+			t.Fatalf("DeleteCourse failed: %v", err)
+		}
+		if _, err := repo.GetCourseByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted course to be gone but got %v", err)
+		}
+	})
+}
@@ -1,35 +1,80 @@
 package courses
 
 import (
+	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var tracer = tracing.Tracer("internal/domain/courses")
+
+// DeletionGuard is consulted before a course is deleted so that lessons are
+// not left pointing at it.
+type DeletionGuard interface {
+	BeforeDeleteCourse(ctx context.Context, id primitive.ObjectID) error
+}
+
 type CourseService struct {
-	repo CourseRepository
+	repo  CourseRepository
+	guard DeletionGuard
+	audit audit.Recorder
 }
 
-func NewCourseService(repo CourseRepository) *CourseService {
-	return &CourseService{repo: repo}
+func NewCourseService(repo CourseRepository, guard DeletionGuard, auditor audit.Recorder) *CourseService {
+	return &CourseService{repo: repo, guard: guard, audit: auditor}
 }
 
-func (s *CourseService) CreateCourse(course Course) (primitive.ObjectID, error) {
+func (s *CourseService) CreateCourse(ctx context.Context, course Course) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "CourseService.CreateCourse")
+	defer tracing.End(span, &err)
 	course.ID = primitive.NewObjectID()
 	course.CreatedAt = time.Now()
 	course.UpdatedAt = time.Now()
-	return s.repo.CreateCourse(course)
+	id, err := s.repo.CreateCourse(ctx, course)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	s.audit.Record(ctx, audit.ActionCreate, "course", id, nil, course)
+	return id, nil
 }
 
-func (s *CourseService) GetCourseByID(id primitive.ObjectID) (*Course, error) {
-	return s.repo.GetCourseByID(id)
+func (s *CourseService) GetCourseByID(ctx context.Context, id primitive.ObjectID) (_ *Course, err error) {
+	ctx, span := tracer.Start(ctx, "CourseService.GetCourseByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetCourseByID(ctx, id)
 }
 
-func (s *CourseService) UpdateCourse(course Course) error {
+func (s *CourseService) UpdateCourse(ctx context.Context, course Course) (err error) {
+	ctx, span := tracer.Start(ctx, "CourseService.UpdateCourse")
+	defer tracing.End(span, &err)
 	course.UpdatedAt = time.Now()
-	return s.repo.UpdateCourse(course)
+	before, err := s.repo.GetCourseByID(ctx, course.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateCourse(ctx, course); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionUpdate, "course", course.ID, before, course)
+	return nil
 }
 
-func (s *CourseService) DeleteCourse(id primitive.ObjectID) error {
-	return s.repo.DeleteCourse(id)
+func (s *CourseService) DeleteCourse(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "CourseService.DeleteCourse")
+	defer tracing.End(span, &err)
+	if err := s.guard.BeforeDeleteCourse(ctx, id); err != nil {
+		return err
+	}
+	before, err := s.repo.GetCourseByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteCourse(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "course", id, before, nil)
+	return nil
 }
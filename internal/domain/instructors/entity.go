@@ -1,6 +1,7 @@
 package instructors
 
 import (
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,8 +13,24 @@ type Instructor struct {
 	FirstName    string             `json:"firstName"`
 	LastName     string             `json:"lastName"`
 	Email        string             `json:"email"`
+	Phone        string             `bson:"phone,omitempty" json:"phone,omitempty"`
 	PasswordHash string             `json:"-"`
 	Role         string             `json:"role"`
 	CreatedAt    time.Time          `json:"createdAt"`
 	UpdatedAt    time.Time          `json:"updatedAt"`
+	// NotificationPreferences is nil until the instructor picks their channels
+	NotificationPreferences *NotificationPreferences `bson:"notification_preferences,omitempty" json:"notificationPreferences,omitempty"`
+}
+
+// NotificationPreferences are the channels an instructor is notified through.
+// Without any, instructors get email and the in-app inbox.
+type NotificationPreferences struct {
+	Email bool `bson:"email" json:"email"`
+	SMS   bool `bson:"sms" json:"sms"`
+	InApp bool `bson:"in_app" json:"inApp"`
+}
+
+// LogValue keeps the password hash and personal details out of logs.
+func (i Instructor) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", i.ID.Hex()), slog.String("email", i.Email), slog.String("role", i.Role))
 }
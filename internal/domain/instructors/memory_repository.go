@@ -0,0 +1,75 @@
+package instructors
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryInstructorRepository keeps instructors in memory with the same semantics as
+// MongoInstructorRepository, including the unique email index. It is meant for
+// tests and local development.
+type MemoryInstructorRepository struct {
+	store *memstore.Store[Instructor]
+}
+
+func NewMemoryInstructorRepository() *MemoryInstructorRepository {
+	return &MemoryInstructorRepository{
+		store: memstore.New[Instructor](func(a, b Instructor) bool { return a.Email != "" && a.Email == b.Email }),
+	}
+}
+
+func (r *MemoryInstructorRepository) CreateInstructor(ctx context.Context, instructor Instructor) (primitive.ObjectID, error) {
+	instructor.ID = primitive.NewObjectID()
+	instructor.CreatedAt = time.Now()
+	instructor.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(instructor.ID, instructor)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("instructor")
+	}
+	return instructor.ID, nil
+}
+
+func (r *MemoryInstructorRepository) GetInstructorByID(ctx context.Context, id primitive.ObjectID) (*Instructor, error) {
+	instructor, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("instructor")
+	}
+	return &instructor, nil
+}
+
+func (r *MemoryInstructorRepository) GetInstructorByEmail(ctx context.Context, email string) (*Instructor, error) {
+	instructor, ok := r.store.FindOne(func(candidate Instructor) bool { return candidate.Email == email })
+	if !ok {
+		return nil, apperr.ResourceNotFound("instructor")
+	}
+	return &instructor, nil
+}
+
+func (r *MemoryInstructorRepository) UpdateInstructor(ctx context.Context, instructor Instructor) error {
+	instructor.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(instructor.ID, instructor)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("instructor")
+	}
+	if !ok {
+		return apperr.ResourceConflict("instructor")
+	}
+	return nil
+}
+
+func (r *MemoryInstructorRepository) DeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("instructor")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -19,39 +20,53 @@ func NewMongoInstructorRepository(db *mongo.Database) *MongoInstructorRepository
 	}
 }
 
-func (r *MongoInstructorRepository) CreateInstructor(instructor Instructor) (primitive.ObjectID, error) {
+func (r *MongoInstructorRepository) CreateInstructor(ctx context.Context, instructor Instructor) (primitive.ObjectID, error) {
 	instructor.ID = primitive.NewObjectID()
 	instructor.CreatedAt = time.Now()
 	instructor.UpdatedAt = time.Now()
-	_, err := r.db.InsertOne(context.Background(), instructor)
-	return instructor.ID, err
+	if _, err := r.db.InsertOne(ctx, instructor); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "instructor")
+	}
+	return instructor.ID, nil
 }
 
-func (r *MongoInstructorRepository) GetInstructorByID(id primitive.ObjectID) (*Instructor, error) {
+func (r *MongoInstructorRepository) GetInstructorByID(ctx context.Context, id primitive.ObjectID) (*Instructor, error) {
 	var instructor Instructor
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&instructor)
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&instructor)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "instructor")
 	}
 	return &instructor, nil
 }
 
-func (r *MongoInstructorRepository) GetInstructorByEmail(email string) (*Instructor, error) {
+func (r *MongoInstructorRepository) GetInstructorByEmail(ctx context.Context, email string) (*Instructor, error) {
 	var instructor Instructor
-	err := r.db.FindOne(context.Background(), bson.M{"email": email}).Decode(&instructor)
+	err := r.db.FindOne(ctx, bson.M{"email": email}).Decode(&instructor)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "instructor")
 	}
 	return &instructor, nil
 }
 
-func (r *MongoInstructorRepository) UpdateInstructor(instructor Instructor) error {
+func (r *MongoInstructorRepository) UpdateInstructor(ctx context.Context, instructor Instructor) error {
 	instructor.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": instructor.ID}, bson.M{"$set": instructor})
-	return err
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": instructor.ID}, bson.M{"$set": instructor})
+	if err != nil {
+		return apperr.FromMongo(err, "instructor")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "instructor")
+	}
+	return nil
 }
 
-func (r *MongoInstructorRepository) DeleteInstructor(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoInstructorRepository) DeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "instructor")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "instructor")
+	}
+	return nil
 }
@@ -1,13 +1,14 @@
 package instructors
 
 import (
+	"context"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type InstructorRepository interface {
-	CreateInstructor(instructor Instructor) (primitive.ObjectID, error)
-	GetInstructorByEmail(email string) (*Instructor, error)
-	GetInstructorByID(id primitive.ObjectID) (*Instructor, error)
-	UpdateInstructor(instructor Instructor) error
-	DeleteInstructor(id primitive.ObjectID) error
+	CreateInstructor(ctx context.Context, instructor Instructor) (primitive.ObjectID, error)
+	GetInstructorByEmail(ctx context.Context, email string) (*Instructor, error)
+	GetInstructorByID(ctx context.Context, id primitive.ObjectID) (*Instructor, error)
+	UpdateInstructor(ctx context.Context, instructor Instructor) error
+	DeleteInstructor(ctx context.Context, id primitive.ObjectID) error
 }
@@ -0,0 +1,101 @@
+package instructors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryInstructorRepository(t *testing.T) {
+	testInstructorRepository(t, func(t *testing.T) InstructorRepository {
+		return NewMemoryInstructorRepository()
+	})
+}
+
+func TestMongoInstructorRepository(t *testing.T) {
+	testInstructorRepository(t, func(t *testing.T) InstructorRepository {
+		return NewMongoInstructorRepository(mongotest.Database(t))
+	})
+}
+
+// testInstructorRepository is the contract every InstructorRepository must satisfy.
+func testInstructorRepository(t *testing.T, newRepo func(t *testing.T) InstructorRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateInstructor(ctx, Instructor{FirstName: "Hugo", LastName: "Castro", Email: "hugo@example.com"})
+		if err != nil {
+			t.Fatalf("CreateInstructor failed: %v", err)
+		}
+
+		instructor, err := repo.GetInstructorByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetInstructorByID failed: %v", err)
+		}
+		if instructor.ID != id || instructor.FirstName != "Hugo" || instructor.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected instructor %+v", instructor)
+		}
+
+		byEmail, err := repo.GetInstructorByEmail(ctx, "hugo@example.com")
+		if err != nil || byEmail.ID != id {
+			t.Fatalf("GetInstructorByEmail returned %+v, %v", byEmail, err)
+		}
+		if _, err := repo.GetInstructorByEmail(ctx, "nobody@example.com"); !apperr.IsNotFound(err) {
+			t.Fatalf("GetInstructorByEmail: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("unique email", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.CreateInstructor(ctx, Instructor{FirstName: "Hugo", LastName: "Castro", Email: "hugo@example.com"}); err != nil {
+			t.Fatalf("CreateInstructor failed: %v", err)
+		}
+		_, err := repo.CreateInstructor(ctx, Instructor{FirstName: "Hugo", LastName: "Castro", Email: "hugo@example.com"})
+		if apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetInstructorByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetInstructorByID: expected not found but got %v", err)
+		}
+		if err := repo.UpdateInstructor(ctx, Instructor{ID: missing, FirstName: "Hugo"}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateInstructor: expected not found but got %v", err)
+		}
+		if err := repo.DeleteInstructor(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteInstructor: expected not found but got %v", err)
+		}
+	})
+
+	// Instructor fields are not tagged omitempty, so an update replaces the
+	// whole document rather than merging into it.
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateInstructor(ctx, Instructor{FirstName: "Hugo", LastName: "Castro", Email: "hugo@example.com"})
+		if err := repo.UpdateInstructor(ctx, Instructor{ID: id, FirstName: "Victor"}); err != nil {
+			t.Fatalf("UpdateInstructor failed: %v", err)
+		}
+		instructor, _ := repo.GetInstructorByID(ctx, id)
+		if instructor.FirstName != "Victor" || instructor.LastName != "" {
+			t.Fatalf("Unexpected instructor after update %+v", instructor)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateInstructor(ctx, Instructor{FirstName: "Hugo", LastName: "Castro", Email: "hugo@example.com"})
+		if err := repo.DeleteInstructor(ctx, id); err != nil {
+			t.Fatalf("DeleteInstructor failed: %v", err)
+		}
+		if _, err := repo.GetInstructorByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted instructor to be gone but got %v", err)
+		}
+	})
+}
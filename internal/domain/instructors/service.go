@@ -1,207 +1,152 @@
 package instructors
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"net/http"
-	"net/url"
-	"strings"
+	"context"
+	"log/slog"
 	"time"
 
-	"github.com/golang-jwt/jwt/v4"
-	"github.com/lucasgarciaf/df-backend-go/config"
-	"github.com/lucasgarciaf/df-backend-go/internal/utils"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var tracer = tracing.Tracer("internal/domain/instructors")
+
 var (
-	ErrInvalidCredentials = errors.New("invalid email or password")
-	ErrEmailExists        = errors.New("email already exists")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "invalid email or password")
+	ErrEmailExists        = apperr.Conflict("email_exists", "email already exists")
 )
 
-type AuthClaims struct {
-	InstructorID primitive.ObjectID `json:"instructor_id"`
-	Role         string             `json:"role"`
-	jwt.RegisteredClaims
+// DeletionGuard is consulted around the deletion of an instructor so that
+// lessons are not left pointing at it: before, to refuse the deletion, and
+// after, to deal with the lessons once the instructor is gone.
+type DeletionGuard interface {
+	BeforeDeleteInstructor(ctx context.Context, id primitive.ObjectID) error
+	AfterDeleteInstructor(ctx context.Context, id primitive.ObjectID) error
 }
 
 type InstructorService struct {
-	repo InstructorRepository
+	repo   InstructorRepository
+	guard  DeletionGuard
+	idp    identity.Provider
+	audit  audit.Recorder
+	events events.Emitter
+	logger *slog.Logger
 }
 
-func NewInstructorService(repo InstructorRepository) *InstructorService {
-	return &InstructorService{repo: repo}
+func NewInstructorService(repo InstructorRepository, guard DeletionGuard, idp identity.Provider, auditor audit.Recorder, emitter events.Emitter, logger *slog.Logger) *InstructorService {
+	return &InstructorService{repo: repo, guard: guard, idp: idp, audit: auditor, events: emitter, logger: logger}
 }
 
-func (s *InstructorService) CreateInstructor(instructor Instructor, password string) (primitive.ObjectID, error) {
-	// Create user in Keycloak
-	err := s.createUserInKeycloak(instructor, password)
-	if err != nil {
-		return primitive.NilObjectID, err
-	}
-
-	// Create user in MongoDB
-	existingInstructor, _ := s.repo.GetInstructorByEmail(instructor.Email)
+func (s *InstructorService) CreateInstructor(ctx context.Context, instructor Instructor, password string) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "InstructorService.CreateInstructor")
+	defer tracing.End(span, &err)
+	existingInstructor, err := s.repo.GetInstructorByEmail(ctx, instructor.Email)
 	if existingInstructor != nil {
 		return primitive.NilObjectID, ErrEmailExists
 	}
+	if err != nil && !apperr.IsNotFound(err) {
+		return primitive.NilObjectID, err
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := identity.HashPassword(ctx, password)
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
-	instructor.PasswordHash = string(hashedPassword)
-	instructor.Role = "instructor" // Ensure Role is set
+	instructor.PasswordHash = hashedPassword
+	instructor.Role = identity.RoleInstructor // Ensure Role is set
 	instructor.CreatedAt = time.Now()
 	instructor.UpdatedAt = time.Now()
 
-	return s.repo.CreateInstructor(instructor)
-}
-
-func (s *InstructorService) Authenticate(email, password string) (string, error) {
-	instructor, err := s.repo.GetInstructorByEmail(email)
-	if err != nil {
-		return "", ErrInvalidCredentials
+	id, err := s.repo.CreateInstructor(ctx, instructor)
+	if apperr.KindOf(err) == apperr.KindConflict {
+		return primitive.NilObjectID, ErrEmailExists
 	}
-
-	err = bcrypt.CompareHashAndPassword([]byte(instructor.PasswordHash), []byte(password))
 	if err != nil {
-		return "", ErrInvalidCredentials
+		return primitive.NilObjectID, err
 	}
 
-	token, err := s.createToken(instructor.ID, instructor.Role)
+	// Create user in the identity provider, rolling back the stored instructor if that fails
+	err = s.idp.CreateUser(ctx, identity.User{
+		Username:  instructor.Username,
+		FirstName: instructor.FirstName,
+		LastName:  instructor.LastName,
+		Email:     instructor.Email,
+		Password:  password,
+		Role:      identity.RoleInstructor,
+	})
 	if err != nil {
-		return "", err
+		s.logger.WarnContext(ctx, "identity provider rejected new instructor, rolling back", slog.String("instructor_id", id.Hex()), slog.Any("error", err))
+		if rollbackErr := s.repo.DeleteInstructor(ctx, id); rollbackErr != nil {
+			s.logger.ErrorContext(ctx, "failed to roll back instructor", slog.String("instructor_id", id.Hex()), slog.Any("error", rollbackErr))
+		}
+		if apperr.KindOf(err) == apperr.KindConflict {
+			return primitive.NilObjectID, ErrEmailExists
+		}
+		return primitive.NilObjectID, err
 	}
-
-	return token, nil
+	s.audit.Record(ctx, audit.ActionCreate, "instructor", id, nil, instructor)
+	return id, nil
 }
 
-func (s *InstructorService) createToken(instructorID primitive.ObjectID, role string) (string, error) {
-	claims := &AuthClaims{
-		InstructorID: instructorID,
-		Role:         role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.TokenExpiry)),
-		},
+func (s *InstructorService) Authenticate(ctx context.Context, email, password string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "InstructorService.Authenticate")
+	defer tracing.End(span, &err)
+	token, err := s.idp.Login(ctx, email, password)
+	if apperr.KindOf(err) == apperr.KindUnauthorized {
+		return "", ErrInvalidCredentials
 	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(config.JWTSecretKey)
+	return token, err
 }
 
-func (s *InstructorService) GetInstructorByID(id primitive.ObjectID) (*Instructor, error) {
-	return s.repo.GetInstructorByID(id)
+func (s *InstructorService) GetInstructorByID(ctx context.Context, id primitive.ObjectID) (_ *Instructor, err error) {
+	ctx, span := tracer.Start(ctx, "InstructorService.GetInstructorByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetInstructorByID(ctx, id)
 }
 
-func (s *InstructorService) UpdateInstructor(instructor Instructor) error {
+func (s *InstructorService) UpdateInstructor(ctx context.Context, instructor Instructor) (err error) {
+	ctx, span := tracer.Start(ctx, "InstructorService.UpdateInstructor")
+	defer tracing.End(span, &err)
 	instructor.UpdatedAt = time.Now()
-	return s.repo.UpdateInstructor(instructor)
-}
-
-func (s *InstructorService) DeleteInstructor(id primitive.ObjectID) error {
-	return s.repo.DeleteInstructor(id)
-}
-
-func (s *InstructorService) Logout(refreshToken string) error {
-	logoutURL := fmt.Sprintf("%sprotocol/openid-connect/logout", config.KeycloakURL)
-
-	data := map[string]string{
-		"client_id":     config.KeycloakClientID,
-		"client_secret": config.KeycloakClientSecret,
-		"refresh_token": refreshToken,
-	}
-
-	formData := url.Values{}
-	for key, value := range data {
-		formData.Set(key, value)
-	}
-
-	req, err := http.NewRequest("POST", logoutURL, strings.NewReader(formData.Encode()))
+	before, err := s.repo.GetInstructorByID(ctx, instructor.ID)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+	if err := s.repo.UpdateInstructor(ctx, instructor); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to logout from Keycloak: %s", resp.Status)
-	}
-
+	s.audit.Record(ctx, audit.ActionUpdate, "instructor", instructor.ID, before, instructor)
 	return nil
 }
 
-func (s *InstructorService) createUserInKeycloak(instructor Instructor, password string) error {
-	keycloakUser := struct {
-		Username    string `json:"username"`
-		FirstName   string `json:"firstName"`
-		LastName    string `json:"lastName"`
-		Email       string `json:"email"`
-		Enabled     bool   `json:"enabled"`
-		Credentials []struct {
-			Type      string `json:"type"`
-			Value     string `json:"value"`
-			Temporary bool   `json:"temporary"`
-		} `json:"credentials"`
-		RealmRoles []string `json:"realmRoles"`
-	}{
-		Username:  instructor.Username,
-		FirstName: instructor.FirstName,
-		LastName:  instructor.LastName,
-		Email:     instructor.Email,
-		Enabled:   true,
-		Credentials: []struct {
-			Type      string `json:"type"`
-			Value     string `json:"value"`
-			Temporary bool   `json:"temporary"`
-		}{
-			{
-				Type:      "password",
-				Value:     password,
-				Temporary: false,
-			},
-		},
-		RealmRoles: []string{"instructor"}, // Assign "instructor" role
-	}
-
-	userJSON, err := json.Marshal(keycloakUser)
-	if err != nil {
-		return err
-	}
-
-	url := fmt.Sprintf("%sadmin/realms/%s/users", config.KeycloakURL, config.KeycloakRealm)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(userJSON))
-	if err != nil {
-		return err
-	}
-
-	token, err := utils.GetClientToken()
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token) // Use a valid client access token
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("failed to create user in Keycloak: %s", resp.Status)
-	}
+func (s *InstructorService) DeleteInstructor(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "InstructorService.DeleteInstructor")
+	defer tracing.End(span, &err)
+	// Lessons are only cancelled once the instructor is deleted. Without a
+	// replica set there is no transaction, and if cancelling fails part way
+	// the remaining lessons stay scheduled under the deleted instructor
+	return s.events.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.guard.BeforeDeleteInstructor(ctx, id); err != nil {
+			return err
+		}
+		before, err := s.repo.GetInstructorByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.DeleteInstructor(ctx, id); err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionDelete, "instructor", id, before, nil)
+		return s.guard.AfterDeleteInstructor(ctx, id)
+	})
+}
 
-	return nil
+func (s *InstructorService) Logout(ctx context.Context, refreshToken string) (err error) {
+	ctx, span := tracer.Start(ctx, "InstructorService.Logout")
+	defer tracing.End(span, &err)
+	return s.idp.Logout(ctx, refreshToken)
 }
@@ -0,0 +1,196 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Policy decides what happens to upcoming lessons when their instructor is deleted.
+type Policy string
+
+const (
+	// PolicyBlock refuses to delete an instructor that still has upcoming lessons.
+	PolicyBlock Policy = "block"
+	// PolicyCancel cancels the instructor's upcoming lessons once they are deleted.
+	PolicyCancel Policy = "cancel"
+)
+
+// ParsePolicy parses the value of the instructor delete policy setting.
+func ParsePolicy(s string) (Policy, error) {
+	switch Policy(s) {
+	case PolicyBlock, PolicyCancel:
+		return Policy(s), nil
+	}
+	return "", fmt.Errorf("unknown instructor delete policy %q", s)
+}
+
+// Reference points at a document that caused an integrity violation.
+type Reference struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// Checker validates references between lessons and the documents they point at.
+type Checker struct {
+	lessons     lessons.LessonRepository
+	courses     courses.CourseRepository
+	instructors instructors.InstructorRepository
+	students    students.StudentRepository
+	vehicles    vehicles.VehicleRepository
+	policy      Policy
+	audit       audit.Recorder
+	events      events.Emitter
+}
+
+func NewChecker(
+	lessonRepo lessons.LessonRepository,
+	courseRepo courses.CourseRepository,
+	instructorRepo instructors.InstructorRepository,
+	studentRepo students.StudentRepository,
+	vehicleRepo vehicles.VehicleRepository,
+	policy Policy,
+	auditor audit.Recorder,
+	emitter events.Emitter,
+) *Checker {
+	return &Checker{
+		lessons:     lessonRepo,
+		courses:     courseRepo,
+		instructors: instructorRepo,
+		students:    studentRepo,
+		vehicles:    vehicleRepo,
+		policy:      policy,
+		audit:       auditor,
+		events:      emitter,
+	}
+}
+
+// ValidateLesson makes sure the course and instructor of a lesson exist, as well
+// as the student and vehicle when they are set.
+func (c *Checker) ValidateLesson(ctx context.Context, lesson lessons.Lesson) error {
+	var missing []Reference
+
+	if ok, err := exists(c.courses.GetCourseByID(ctx, lesson.CourseID)); err != nil {
+		return err
+	} else if !ok {
+		missing = append(missing, Reference{Kind: "course", ID: lesson.CourseID.Hex()})
+	}
+
+	if ok, err := exists(c.instructors.GetInstructorByID(ctx, lesson.InstructorID)); err != nil {
+		return err
+	} else if !ok {
+		missing = append(missing, Reference{Kind: "instructor", ID: lesson.InstructorID.Hex()})
+	}
+
+	if !lesson.StudentID.IsZero() {
+		if ok, err := exists(c.students.GetStudentByID(ctx, lesson.StudentID)); err != nil {
+			return err
+		} else if !ok {
+			missing = append(missing, Reference{Kind: "student", ID: lesson.StudentID.Hex()})
+		}
+	}
+
+	if !lesson.VehicleID.IsZero() {
+		if ok, err := exists(c.vehicles.GetVehicleByID(ctx, lesson.VehicleID)); err != nil {
+			return err
+		} else if !ok {
+			missing = append(missing, Reference{Kind: "vehicle", ID: lesson.VehicleID.Hex()})
+		}
+	}
+
+	if len(missing) > 0 {
+		return apperr.Validation("unknown_references", "lesson references unknown documents").
+			WithDetail("references", missing)
+	}
+	return nil
+}
+
+// BeforeDeleteCourse blocks deleting a course that still has lessons.
+func (c *Checker) BeforeDeleteCourse(ctx context.Context, id primitive.ObjectID) error {
+	return c.blockIfReferenced(ctx, "course", lessons.Filter{CourseID: id})
+}
+
+// BeforeDeleteStudent blocks deleting a student with upcoming lessons.
+func (c *Checker) BeforeDeleteStudent(ctx context.Context, id primitive.ObjectID) error {
+	return c.blockIfReferenced(ctx, "student", lessons.Filter{StudentID: id, From: time.Now()})
+}
+
+// BeforeDeleteVehicle blocks deleting a vehicle with upcoming lessons.
+func (c *Checker) BeforeDeleteVehicle(ctx context.Context, id primitive.ObjectID) error {
+	return c.blockIfReferenced(ctx, "vehicle", lessons.Filter{VehicleID: id, From: time.Now()})
+}
+
+// BeforeDeleteInstructor blocks deleting an instructor with upcoming lessons,
+// unless the policy is to cancel them.
+func (c *Checker) BeforeDeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
+	if c.policy == PolicyCancel {
+		return nil
+	}
+	return c.blockIfReferenced(ctx, "instructor", lessons.Filter{InstructorID: id, From: time.Now()})
+}
+
+// AfterDeleteInstructor cancels the upcoming lessons of a deleted instructor
+// when the policy says so. It runs after the deletion, so that a failed
+// deletion leaves the lessons alone even without a transaction.
+func (c *Checker) AfterDeleteInstructor(ctx context.Context, id primitive.ObjectID) error {
+	if c.policy != PolicyCancel {
+		return nil
+	}
+	upcoming, err := c.lessons.ListLessons(ctx, lessons.Filter{InstructorID: id, From: time.Now()})
+	if err != nil {
+		return err
+	}
+	for _, before := range upcoming {
+		if before.Status == lessons.StatusCancelled {
+			continue
+		}
+		lesson := before
+		lesson.Status = lessons.StatusCancelled
+		if err := c.lessons.UpdateLesson(ctx, lesson); err != nil {
+			return err
+		}
+		c.audit.Record(ctx, audit.ActionCancel, "lesson", lesson.ID, before, lesson)
+		if err := c.events.Emit(ctx, lessons.Changed(lesson, events.ChangeUpdated, &before),
+			events.LessonCancelled{Lesson: lessons.EventDetails(lesson)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Checker) blockIfReferenced(ctx context.Context, kind string, filter lessons.Filter) error {
+	referencing, err := c.lessons.ListLessons(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if len(referencing) == 0 {
+		return nil
+	}
+
+	refs := make([]Reference, len(referencing))
+	for i, lesson := range referencing {
+		refs[i] = Reference{Kind: "lesson", ID: lesson.ID.Hex()}
+	}
+	return apperr.Validation("dangling_references", kind+" is still referenced by lessons").
+		WithDetail("references", refs)
+}
+
+func exists[T any](doc *T, err error) (bool, error) {
+	if apperr.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return doc != nil, nil
+}
@@ -6,13 +6,24 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	StatusScheduled = "scheduled"
+	StatusCancelled = "cancelled"
+)
+
 type Lesson struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty"`
 	CourseID     primitive.ObjectID `bson:"course_id,omitempty"`
 	InstructorID primitive.ObjectID `bson:"instructor_id,omitempty"`
+	StudentID    primitive.ObjectID `bson:"student_id,omitempty"`
+	VehicleID    primitive.ObjectID `bson:"vehicle_id,omitempty"`
 	Title        string             `bson:"title,omitempty"`
 	Description  string             `bson:"description,omitempty"`
 	Schedule     time.Time          `bson:"schedule,omitempty"`
-	CreatedAt    time.Time          `bson:"created_at,omitempty"`
-	UpdatedAt    time.Time          `bson:"updated_at,omitempty"`
+	Status       string             `bson:"status,omitempty"`
+	// Sequence counts the updates to the lesson, so calendar clients can tell
+	// which version is newer
+	Sequence  int       `bson:"sequence,omitempty"`
+	CreatedAt time.Time `bson:"created_at,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty"`
 }
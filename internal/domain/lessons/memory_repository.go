@@ -0,0 +1,96 @@
+package lessons
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryLessonRepository keeps lessons in memory with the same semantics as
+// MongoLessonRepository. It is meant for tests and local development.
+type MemoryLessonRepository struct {
+	store *memstore.Store[Lesson]
+	// mu makes incrementing the sequence atomic, like the MongoDB update
+	mu sync.Mutex
+}
+
+func NewMemoryLessonRepository() *MemoryLessonRepository {
+	return &MemoryLessonRepository{
+		store: memstore.New[Lesson](nil),
+	}
+}
+
+func (r *MemoryLessonRepository) CreateLesson(ctx context.Context, lesson Lesson) (primitive.ObjectID, error) {
+	lesson.ID = primitive.NewObjectID()
+	lesson.Sequence = 0
+	lesson.CreatedAt = time.Now()
+	lesson.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(lesson.ID, lesson)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("lesson")
+	}
+	return lesson.ID, nil
+}
+
+func (r *MemoryLessonRepository) GetLessonByID(ctx context.Context, id primitive.ObjectID) (*Lesson, error) {
+	lesson, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("lesson")
+	}
+	return &lesson, nil
+}
+
+func (r *MemoryLessonRepository) ListLessons(ctx context.Context, filter Filter) ([]Lesson, error) {
+	lessons := r.store.Find(func(lesson Lesson) bool {
+		switch {
+		case !filter.CourseID.IsZero() && lesson.CourseID != filter.CourseID,
+			!filter.InstructorID.IsZero() && lesson.InstructorID != filter.InstructorID,
+			!filter.StudentID.IsZero() && lesson.StudentID != filter.StudentID,
+			!filter.VehicleID.IsZero() && lesson.VehicleID != filter.VehicleID,
+			!filter.From.IsZero() && lesson.Schedule.Before(filter.From),
+			!filter.To.IsZero() && !lesson.Schedule.Before(filter.To),
+			!filter.IncludeCancelled && lesson.Status == StatusCancelled:
+			return false
+		}
+		return true
+	})
+	sort.SliceStable(lessons, func(i, j int) bool { return lessons[i].Schedule.Before(lessons[j].Schedule) })
+	return lessons, nil
+}
+
+func (r *MemoryLessonRepository) UpdateLesson(ctx context.Context, lesson Lesson) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current, found := r.store.Get(lesson.ID)
+	if !found {
+		return apperr.ResourceNotFound("lesson")
+	}
+	lesson.Sequence = current.Sequence + 1
+	lesson.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(lesson.ID, lesson)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("lesson")
+	}
+	if !ok {
+		return apperr.ResourceConflict("lesson")
+	}
+	return nil
+}
+
+func (r *MemoryLessonRepository) DeleteLesson(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("lesson")
+	}
+	return nil
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoLessonRepository struct {
@@ -19,30 +21,87 @@ func NewMongoLessonRepository(db *mongo.Database) *MongoLessonRepository {
 	}
 }
 
-func (r *MongoLessonRepository) CreateLesson(lesson Lesson) (primitive.ObjectID, error) {
+func (r *MongoLessonRepository) CreateLesson(ctx context.Context, lesson Lesson) (primitive.ObjectID, error) {
 	lesson.ID = primitive.NewObjectID()
+	lesson.Sequence = 0
 	lesson.CreatedAt = time.Now()
 	lesson.UpdatedAt = time.Now()
-	_, err := r.db.InsertOne(context.Background(), lesson)
-	return lesson.ID, err
+	if _, err := r.db.InsertOne(ctx, lesson); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "lesson")
+	}
+	return lesson.ID, nil
 }
 
-func (r *MongoLessonRepository) GetLessonByID(id primitive.ObjectID) (*Lesson, error) {
+func (r *MongoLessonRepository) GetLessonByID(ctx context.Context, id primitive.ObjectID) (*Lesson, error) {
 	var lesson Lesson
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&lesson)
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&lesson)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "lesson")
 	}
 	return &lesson, nil
 }
 
-func (r *MongoLessonRepository) UpdateLesson(lesson Lesson) error {
+func (r *MongoLessonRepository) ListLessons(ctx context.Context, filter Filter) ([]Lesson, error) {
+	query := bson.M{}
+	if !filter.CourseID.IsZero() {
+		query["course_id"] = filter.CourseID
+	}
+	if !filter.InstructorID.IsZero() {
+		query["instructor_id"] = filter.InstructorID
+	}
+	if !filter.StudentID.IsZero() {
+		query["student_id"] = filter.StudentID
+	}
+	if !filter.VehicleID.IsZero() {
+		query["vehicle_id"] = filter.VehicleID
+	}
+	schedule := bson.M{}
+	if !filter.From.IsZero() {
+		schedule["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		schedule["$lt"] = filter.To
+	}
+	if len(schedule) > 0 {
+		query["schedule"] = schedule
+	}
+	if !filter.IncludeCancelled {
+		query["status"] = bson.M{"$ne": StatusCancelled}
+	}
+
+	cursor, err := r.db.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "schedule", Value: 1}}))
+	if err != nil {
+		return nil, apperr.FromMongo(err, "lesson")
+	}
+	defer cursor.Close(ctx)
+
+	var lessons []Lesson
+	if err := cursor.All(ctx, &lessons); err != nil {
+		return nil, apperr.FromMongo(err, "lesson")
+	}
+	return lessons, nil
+}
+
+func (r *MongoLessonRepository) UpdateLesson(ctx context.Context, lesson Lesson) error {
 	lesson.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": lesson.ID}, bson.M{"$set": lesson})
-	return err
+	lesson.Sequence = 0
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": lesson.ID}, bson.M{"$set": lesson, "$inc": bson.M{"sequence": 1}})
+	if err != nil {
+		return apperr.FromMongo(err, "lesson")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "lesson")
+	}
+	return nil
 }
 
-func (r *MongoLessonRepository) DeleteLesson(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoLessonRepository) DeleteLesson(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "lesson")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "lesson")
+	}
+	return nil
 }
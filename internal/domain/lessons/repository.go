@@ -1,10 +1,27 @@
 package lessons
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter narrows down ListLessons. Zero-valued fields are ignored.
+type Filter struct {
+	CourseID         primitive.ObjectID
+	InstructorID     primitive.ObjectID
+	StudentID        primitive.ObjectID
+	VehicleID        primitive.ObjectID
+	From             time.Time
+	To               time.Time
+	IncludeCancelled bool
+}
 
 type LessonRepository interface {
-	CreateLesson(lesson Lesson) (primitive.ObjectID, error)
-	GetLessonByID(id primitive.ObjectID) (*Lesson, error)
-	UpdateLesson(lesson Lesson) error
-	DeleteLesson(id primitive.ObjectID) error
+	CreateLesson(ctx context.Context, lesson Lesson) (primitive.ObjectID, error)
+	GetLessonByID(ctx context.Context, id primitive.ObjectID) (*Lesson, error)
+	ListLessons(ctx context.Context, filter Filter) ([]Lesson, error)
+	UpdateLesson(ctx context.Context, lesson Lesson) error
+	DeleteLesson(ctx context.Context, id primitive.ObjectID) error
 }
@@ -0,0 +1,145 @@
+package lessons
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryLessonRepository(t *testing.T) {
+	testLessonRepository(t, func(t *testing.T) LessonRepository {
+		return NewMemoryLessonRepository()
+	})
+}
+
+func TestMongoLessonRepository(t *testing.T) {
+	testLessonRepository(t, func(t *testing.T) LessonRepository {
+		return NewMongoLessonRepository(mongotest.Database(t))
+	})
+}
+
+// testLessonRepository is the contract every LessonRepository must satisfy.
+func testLessonRepository(t *testing.T, newRepo func(t *testing.T) LessonRepository) {
+	ctx := context.Background()
+	monday := time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)
+	newLesson := func(schedule time.Time) Lesson {
+		return Lesson{
+			CourseID:     primitive.NewObjectID(),
+			InstructorID: primitive.NewObjectID(),
+			StudentID:    primitive.NewObjectID(),
+			VehicleID:    primitive.NewObjectID(),
+			Title:        "Parallel parking",
+			Schedule:     schedule,
+			Status:       StatusScheduled,
+		}
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		sample := newLesson(monday)
+		id, err := repo.CreateLesson(ctx, sample)
+		if err != nil {
+			t.Fatalf("CreateLesson failed: %v", err)
+		}
+
+		lesson, err := repo.GetLessonByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetLessonByID failed: %v", err)
+		}
+		if lesson.ID != id || lesson.CourseID != sample.CourseID || !lesson.Schedule.Equal(monday) || lesson.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected lesson %+v", lesson)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetLessonByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetLessonByID: expected not found but got %v", err)
+		}
+		if err := repo.UpdateLesson(ctx, Lesson{ID: missing, Title: "Roundabouts"}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateLesson: expected not found but got %v", err)
+		}
+		if err := repo.DeleteLesson(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteLesson: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("update keeps omitted fields", func(t *testing.T) {
+		repo := newRepo(t)
+		sample := newLesson(monday)
+		id, _ := repo.CreateLesson(ctx, sample)
+		if err := repo.UpdateLesson(ctx, Lesson{ID: id, Title: "Roundabouts"}); err != nil {
+			t.Fatalf("UpdateLesson failed: %v", err)
+		}
+		lesson, _ := repo.GetLessonByID(ctx, id)
+		if lesson.Title != "Roundabouts" || lesson.InstructorID != sample.InstructorID || !lesson.Schedule.Equal(monday) || lesson.Sequence != 1 {
+			t.Fatalf("Unexpected lesson after update %+v", lesson)
+		}
+	})
+
+	t.Run("list filters", func(t *testing.T) {
+		repo := newRepo(t)
+		first := newLesson(monday.Add(2 * time.Hour))
+		second := newLesson(monday)
+		second.InstructorID = first.InstructorID
+		other := newLesson(monday.AddDate(0, 0, 1))
+		cancelled := newLesson(monday.Add(time.Hour))
+		cancelled.InstructorID = first.InstructorID
+		cancelled.Status = StatusCancelled
+
+		ids := map[string]primitive.ObjectID{}
+		for name, lesson := range map[string]Lesson{"first": first, "second": second, "other": other, "cancelled": cancelled} {
+			id, err := repo.CreateLesson(ctx, lesson)
+			if err != nil {
+				t.Fatalf("CreateLesson failed: %v", err)
+			}
+			ids[name] = id
+		}
+
+		tests := []struct {
+			name   string
+			filter Filter
+			want   []string
+		}{
+			{"all sorted by schedule", Filter{}, []string{"second", "first", "other"}},
+			{"instructor", Filter{InstructorID: first.InstructorID}, []string{"second", "first"}},
+			{"student", Filter{StudentID: other.StudentID}, []string{"other"}},
+			{"vehicle", Filter{VehicleID: second.VehicleID}, []string{"second"}},
+			{"course", Filter{CourseID: first.CourseID}, []string{"first"}},
+			{"time range", Filter{From: monday.Add(time.Hour), To: monday.AddDate(0, 0, 1)}, []string{"first"}},
+			{"include cancelled", Filter{InstructorID: first.InstructorID, IncludeCancelled: true}, []string{"second", "cancelled", "first"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				lessons, err := repo.ListLessons(ctx, tt.filter)
+				if err != nil {
+					t.Fatalf("ListLessons failed: %v", err)
+				}
+				if len(lessons) != len(tt.want) {
+					t.Fatalf("Expected %d lessons but got %d", len(tt.want), len(lessons))
+				}
+				for i, name := range tt.want {
+					if lessons[i].ID != ids[name] {
+						t.Errorf("Lesson %d: expected %s", i, name)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateLesson(ctx, newLesson(monday))
+		if err := repo.DeleteLesson(ctx, id); err != nil {
+			t.Fatalf("DeleteLesson failed: %v", err)
+		}
+		if _, err := repo.GetLessonByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted lesson to be gone but got %v", err)
+		}
+	})
+}
@@ -1,27 +1,191 @@
 package lessons
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tracer = tracing.Tracer("internal/domain/lessons")
+
+// ReferenceValidator checks that the documents a lesson points at exist.
+type ReferenceValidator interface {
+	ValidateLesson(ctx context.Context, lesson Lesson) error
+}
 
 type LessonService struct {
-	repo LessonRepository
+	repo      LessonRepository
+	validator ReferenceValidator
+	audit     audit.Recorder
+	events    events.Emitter
+}
+
+func NewLessonService(repo LessonRepository, validator ReferenceValidator, auditor audit.Recorder, emitter events.Emitter) *LessonService {
+	return &LessonService{repo: repo, validator: validator, audit: auditor, events: emitter}
+}
+
+func (s *LessonService) CreateLesson(ctx context.Context, lesson Lesson) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "LessonService.CreateLesson")
+	defer tracing.End(span, &err)
+	if err := s.validator.ValidateLesson(ctx, lesson); err != nil {
+		return primitive.NilObjectID, err
+	}
+	if lesson.Status == "" {
+		lesson.Status = StatusScheduled
+	}
+	var id primitive.ObjectID
+	err = s.events.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = s.repo.CreateLesson(ctx, lesson)
+		if err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionCreate, "lesson", id, nil, lesson)
+		lesson.ID = id
+		if err := s.events.Emit(ctx, Changed(lesson, events.ChangeCreated, nil)); err != nil {
+			return err
+		}
+		if lesson.StudentID.IsZero() || lesson.Status != StatusScheduled {
+			return nil
+		}
+		return s.events.Emit(ctx, events.LessonBooked{Lesson: EventDetails(lesson)})
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return id, nil
+}
+
+func (s *LessonService) GetLessonByID(ctx context.Context, id primitive.ObjectID) (_ *Lesson, err error) {
+	ctx, span := tracer.Start(ctx, "LessonService.GetLessonByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetLessonByID(ctx, id)
+}
+
+func (s *LessonService) ListLessons(ctx context.Context, filter Filter) (_ []Lesson, err error) {
+	ctx, span := tracer.Start(ctx, "LessonService.ListLessons")
+	defer tracing.End(span, &err)
+	return s.repo.ListLessons(ctx, filter)
+}
+
+func (s *LessonService) UpdateLesson(ctx context.Context, lesson Lesson) (err error) {
+	ctx, span := tracer.Start(ctx, "LessonService.UpdateLesson")
+	defer tracing.End(span, &err)
+	return s.events.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetLessonByID(ctx, lesson.ID)
+		if err != nil {
+			return err
+		}
+		// The update may leave out the references it keeps
+		if err := s.validator.ValidateLesson(ctx, merge(*before, lesson)); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateLesson(ctx, lesson); err != nil {
+			return err
+		}
+		action := UpdateAction(*before, lesson)
+		s.audit.Record(ctx, action, "lesson", lesson.ID, before, lesson)
+
+		// The update only carries the fields that change
+		after, err := s.repo.GetLessonByID(ctx, lesson.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.events.Emit(ctx, Changed(*after, events.ChangeUpdated, before)); err != nil {
+			return err
+		}
+		booked := !lesson.StudentID.IsZero() && lesson.StudentID != before.StudentID
+		rescheduled := !lesson.Schedule.IsZero() && !lesson.Schedule.Equal(before.Schedule)
+		switch {
+		case action != audit.ActionCancel && !booked && !rescheduled:
+			return nil
+		case action == audit.ActionCancel:
+			return s.events.Emit(ctx, events.LessonCancelled{Lesson: EventDetails(*after)})
+		case after.Status != StatusScheduled || after.StudentID.IsZero():
+			return nil
+		case booked:
+			return s.events.Emit(ctx, events.LessonBooked{Lesson: EventDetails(*after)})
+		}
+		return s.events.Emit(ctx, events.LessonRescheduled{Lesson: EventDetails(*after), PreviousSchedule: before.Schedule})
+	})
 }
 
-func NewLessonService(repo LessonRepository) *LessonService {
-	return &LessonService{repo: repo}
+func (s *LessonService) DeleteLesson(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "LessonService.DeleteLesson")
+	defer tracing.End(span, &err)
+	return s.events.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetLessonByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.DeleteLesson(ctx, id); err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionDelete, "lesson", id, before, nil)
+		if err := s.events.Emit(ctx, Changed(*before, events.ChangeDeleted, nil)); err != nil {
+			return err
+		}
+		// For the booked student, deleting a lesson is as good as cancelling it
+		if before.StudentID.IsZero() || before.Status != StatusScheduled {
+			return nil
+		}
+		return s.events.Emit(ctx, events.LessonCancelled{Lesson: EventDetails(*before)})
+	})
 }
 
-func (s *LessonService) CreateLesson(lesson Lesson) (primitive.ObjectID, error) {
-	return s.repo.CreateLesson(lesson)
+// merge is the lesson an update turns before into: the update's set fields
+// replace before's, as the repository stores them.
+func merge(before, update Lesson) Lesson {
+	merged := before
+	if !update.CourseID.IsZero() {
+		merged.CourseID = update.CourseID
+	}
+	if !update.InstructorID.IsZero() {
+		merged.InstructorID = update.InstructorID
+	}
+	if !update.StudentID.IsZero() {
+		merged.StudentID = update.StudentID
+	}
+	if !update.VehicleID.IsZero() {
+		merged.VehicleID = update.VehicleID
+	}
+	if !update.Schedule.IsZero() {
+		merged.Schedule = update.Schedule
+	}
+	return merged
 }
 
-func (s *LessonService) GetLessonByID(id primitive.ObjectID) (*Lesson, error) {
-	return s.repo.GetLessonByID(id)
+// UpdateAction tells cancelling a lesson apart from other updates in the audit trail.
+func UpdateAction(before, update Lesson) audit.Action {
+	if update.Status == StatusCancelled && before.Status != StatusCancelled {
+		return audit.ActionCancel
+	}
+	return audit.ActionUpdate
 }
 
-func (s *LessonService) UpdateLesson(lesson Lesson) error {
-	return s.repo.UpdateLesson(lesson)
+// Changed is the LessonChanged event for a change to lesson. previous is the
+// lesson before an update, and nil otherwise.
+func Changed(lesson Lesson, change events.Change, previous *Lesson) events.LessonChanged {
+	event := events.LessonChanged{Lesson: EventDetails(lesson), Status: lesson.Status, Change: change}
+	if previous != nil {
+		details := EventDetails(*previous)
+		event.Previous = &details
+	}
+	return event
 }
 
-func (s *LessonService) DeleteLesson(id primitive.ObjectID) error {
-	return s.repo.DeleteLesson(id)
+// EventDetails describes a lesson in the events about it.
+func EventDetails(lesson Lesson) events.Lesson {
+	return events.Lesson{
+		LessonID:     lesson.ID,
+		CourseID:     lesson.CourseID,
+		InstructorID: lesson.InstructorID,
+		StudentID:    lesson.StudentID,
+		VehicleID:    lesson.VehicleID,
+		Title:        lesson.Title,
+		Schedule:     lesson.Schedule,
+	}
 }
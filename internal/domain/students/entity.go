@@ -1,6 +1,7 @@
 package students
 
 import (
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,8 +14,24 @@ type Student struct {
 	Username     string             `bson:"username,omitempty" json:"username"`
 	Email        string             `bson:"email,omitempty" json:"email"`
 	Age          int                `bson:"age,omitempty" json:"age"`
-	PasswordHash string             `bson:"password_hash,omitempty" json:"password_hash"`
+	Phone        string             `bson:"phone,omitempty" json:"phone,omitempty"`
+	PasswordHash string             `bson:"password_hash,omitempty" json:"-"`
 	Role         string             `bson:"role,omitempty" json:"role"`
 	CreatedAt    time.Time          `bson:"created_at,omitempty" json:"created_at"`
 	UpdatedAt    time.Time          `bson:"updated_at,omitempty" json:"updated_at"`
+	// NotificationPreferences is nil until the student picks their channels
+	NotificationPreferences *NotificationPreferences `bson:"notification_preferences,omitempty" json:"notification_preferences,omitempty"`
+}
+
+// NotificationPreferences are the channels a student is notified through.
+// Without any, students get email and the in-app inbox.
+type NotificationPreferences struct {
+	Email bool `bson:"email" json:"email"`
+	SMS   bool `bson:"sms" json:"sms"`
+	InApp bool `bson:"in_app" json:"in_app"`
+}
+
+// LogValue keeps the password hash and personal details out of logs.
+func (s Student) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("id", s.ID.Hex()), slog.String("email", s.Email), slog.String("role", s.Role))
 }
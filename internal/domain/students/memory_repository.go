@@ -0,0 +1,79 @@
+package students
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStudentRepository keeps students in memory with the same semantics as
+// MongoStudentRepository, including the unique email index. It is meant for
+// tests and local development.
+type MemoryStudentRepository struct {
+	store *memstore.Store[Student]
+}
+
+func NewMemoryStudentRepository() *MemoryStudentRepository {
+	return &MemoryStudentRepository{
+		store: memstore.New[Student](func(a, b Student) bool { return a.Email != "" && a.Email == b.Email }),
+	}
+}
+
+func (r *MemoryStudentRepository) CreateStudent(ctx context.Context, student Student) (primitive.ObjectID, error) {
+	student.ID = primitive.NewObjectID()
+	student.CreatedAt = time.Now()
+	student.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(student.ID, student)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("student")
+	}
+	return student.ID, nil
+}
+
+func (r *MemoryStudentRepository) GetStudentByID(ctx context.Context, id primitive.ObjectID) (*Student, error) {
+	student, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("student")
+	}
+	return &student, nil
+}
+
+func (r *MemoryStudentRepository) GetStudentByEmail(ctx context.Context, email string) (*Student, error) {
+	student, ok := r.store.FindOne(func(candidate Student) bool { return candidate.Email == email })
+	if !ok {
+		return nil, apperr.ResourceNotFound("student")
+	}
+	return &student, nil
+}
+
+func (r *MemoryStudentRepository) GetAllStudents(ctx context.Context) ([]Student, error) {
+	return r.store.Find(nil), nil
+}
+
+func (r *MemoryStudentRepository) UpdateStudent(ctx context.Context, student Student) error {
+	student.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(student.ID, student)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("student")
+	}
+	if !ok {
+		return apperr.ResourceConflict("student")
+	}
+	return nil
+}
+
+func (r *MemoryStudentRepository) DeleteStudent(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("student")
+	}
+	return nil
+}
@@ -2,88 +2,99 @@ package students
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type MongoStudentRepository struct {
-	db *mongo.Collection
+	db     *mongo.Collection
+	logger *slog.Logger
 }
 
-func NewMongoStudentRepository(db *mongo.Database) *MongoStudentRepository {
+func NewMongoStudentRepository(db *mongo.Database, logger *slog.Logger) *MongoStudentRepository {
 	return &MongoStudentRepository{
-		db: db.Collection("students"),
+		db:     db.Collection("students"),
+		logger: logger,
 	}
 }
 
-func (r *MongoStudentRepository) CreateStudent(student Student) (primitive.ObjectID, error) {
+func (r *MongoStudentRepository) CreateStudent(ctx context.Context, student Student) (primitive.ObjectID, error) {
 	student.ID = primitive.NewObjectID()
 	student.CreatedAt = time.Now()
 	student.UpdatedAt = time.Now()
-	result, err := r.db.InsertOne(context.Background(), student)
-	if err != nil {
-		log.Printf("Failed to insert student: %v", err)
-		return primitive.NilObjectID, err
+	if _, err := r.db.InsertOne(ctx, student); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "student")
 	}
-	log.Printf("Student inserted successfully: %v", student)
-	log.Printf("InsertOne result: %v", result)
-	return student.ID, err
+	r.logger.DebugContext(ctx, "student inserted", slog.Any("student", student))
+	return student.ID, nil
 }
 
-func (r *MongoStudentRepository) GetStudentByID(id primitive.ObjectID) (*Student, error) {
+func (r *MongoStudentRepository) GetStudentByID(ctx context.Context, id primitive.ObjectID) (*Student, error) {
 	var student Student
-	log.Println("Looking for student ID: ", id)
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&student)
+	r.logger.DebugContext(ctx, "looking up student", slog.String("student_id", id.Hex()))
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&student)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "student")
 	}
 	return &student, nil
 }
 
-func (r *MongoStudentRepository) GetAllStudents() ([]Student, error) {
+func (r *MongoStudentRepository) GetAllStudents(ctx context.Context) ([]Student, error) {
 	var students []Student
-	cursor, err := r.db.Find(context.Background(), bson.M{})
+	cursor, err := r.db.Find(ctx, bson.M{})
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "student")
 	}
-	defer cursor.Close(context.Background())
+	defer cursor.Close(ctx)
 
-	for cursor.Next(context.Background()) {
+	for cursor.Next(ctx) {
 		var student Student
 		if err := cursor.Decode(&student); err != nil {
-			log.Printf("Failed to decode student: %v", err)
-			return nil, err
+			return nil, apperr.FromMongo(err, "student")
 		}
 		students = append(students, student)
 	}
 
 	if err := cursor.Err(); err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "student")
 	}
 
 	return students, nil
 }
 
-func (r *MongoStudentRepository) GetStudentByEmail(email string) (*Student, error) {
+func (r *MongoStudentRepository) GetStudentByEmail(ctx context.Context, email string) (*Student, error) {
 	var student Student
-	err := r.db.FindOne(context.Background(), bson.M{"email": email}).Decode(&student)
+	err := r.db.FindOne(ctx, bson.M{"email": email}).Decode(&student)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "student")
 	}
 	return &student, nil
 }
 
-func (r *MongoStudentRepository) UpdateStudent(student Student) error {
+func (r *MongoStudentRepository) UpdateStudent(ctx context.Context, student Student) error {
 	student.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": student.ID}, bson.M{"$set": student})
-	return err
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": student.ID}, bson.M{"$set": student})
+	if err != nil {
+		return apperr.FromMongo(err, "student")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "student")
+	}
+	return nil
 }
 
-func (r *MongoStudentRepository) DeleteStudent(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoStudentRepository) DeleteStudent(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "student")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "student")
+	}
+	return nil
 }
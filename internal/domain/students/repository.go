@@ -1,12 +1,16 @@
 package students
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type StudentRepository interface {
-	CreateStudent(student Student) (primitive.ObjectID, error)
-	GetStudentByID(id primitive.ObjectID) (*Student, error)
-	GetStudentByEmail(email string) (*Student, error)
-	UpdateStudent(student Student) error
-	DeleteStudent(id primitive.ObjectID) error
-	GetAllStudents() ([]Student, error)
+	CreateStudent(ctx context.Context, student Student) (primitive.ObjectID, error)
+	GetStudentByID(ctx context.Context, id primitive.ObjectID) (*Student, error)
+	GetStudentByEmail(ctx context.Context, email string) (*Student, error)
+	UpdateStudent(ctx context.Context, student Student) error
+	DeleteStudent(ctx context.Context, id primitive.ObjectID) error
+	GetAllStudents(ctx context.Context) ([]Student, error)
 }
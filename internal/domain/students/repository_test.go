@@ -0,0 +1,105 @@
+package students
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryStudentRepository(t *testing.T) {
+	testStudentRepository(t, func(t *testing.T) StudentRepository {
+		return NewMemoryStudentRepository()
+	})
+}
+
+func TestMongoStudentRepository(t *testing.T) {
+	testStudentRepository(t, func(t *testing.T) StudentRepository {
+		return NewMongoStudentRepository(mongotest.Database(t), logging.Discard())
+	})
+}
+
+// testStudentRepository is the contract every StudentRepository must satisfy.
+func testStudentRepository(t *testing.T, newRepo func(t *testing.T) StudentRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateStudent(ctx, Student{FirstName: "Ana", Email: "ana@example.com", Age: 20})
+		if err != nil {
+			t.Fatalf("CreateStudent failed: %v", err)
+		}
+
+		byID, err := repo.GetStudentByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetStudentByID failed: %v", err)
+		}
+		if byID.ID != id || byID.FirstName != "Ana" || byID.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected student %+v", byID)
+		}
+
+		byEmail, err := repo.GetStudentByEmail(ctx, "ana@example.com")
+		if err != nil || byEmail.ID != id {
+			t.Fatalf("GetStudentByEmail returned %+v, %v", byEmail, err)
+		}
+
+		all, err := repo.GetAllStudents(ctx)
+		if err != nil || len(all) != 1 {
+			t.Fatalf("GetAllStudents returned %d students, %v", len(all), err)
+		}
+	})
+
+	t.Run("unique email", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.CreateStudent(ctx, Student{Email: "dup@example.com"}); err != nil {
+			t.Fatalf("CreateStudent failed: %v", err)
+		}
+		_, err := repo.CreateStudent(ctx, Student{Email: "dup@example.com"})
+		if apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetStudentByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetStudentByID: expected not found but got %v", err)
+		}
+		if _, err := repo.GetStudentByEmail(ctx, "nobody@example.com"); !apperr.IsNotFound(err) {
+			t.Fatalf("GetStudentByEmail: expected not found but got %v", err)
+		}
+		if err := repo.UpdateStudent(ctx, Student{ID: missing, FirstName: "x"}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateStudent: expected not found but got %v", err)
+		}
+		if err := repo.DeleteStudent(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteStudent: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("update keeps omitted fields", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateStudent(ctx, Student{FirstName: "Ana", LastName: "Lopez", Email: "lopez@example.com"})
+		if err := repo.UpdateStudent(ctx, Student{ID: id, FirstName: "Anna"}); err != nil {
+			t.Fatalf("UpdateStudent failed: %v", err)
+		}
+		student, _ := repo.GetStudentByID(ctx, id)
+		if student.FirstName != "Anna" || student.LastName != "Lopez" {
+			t.Fatalf("Unexpected student after update %+v", student)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateStudent(ctx, Student{Email: "gone@example.com"})
+		if err := repo.DeleteStudent(ctx, id); err != nil {
+			t.Fatalf("DeleteStudent failed: %v", err)
+		}
+		if _, err := repo.GetStudentByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted student to be gone but got %v", err)
+		}
+	})
+}
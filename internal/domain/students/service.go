@@ -1,282 +1,191 @@
 package students
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"log"
-	"net/http"
-	"strings"
+	"context"
+	"log/slog"
 	"time"
 
-	"github.com/Nerzal/gocloak"
-	"github.com/golang-jwt/jwt/v4"
-	"github.com/lucasgarciaf/df-backend-go/config"
-	"github.com/lucasgarciaf/df-backend-go/internal/utils"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var tracer = tracing.Tracer("internal/domain/students")
+
 var (
-	ErrInvalidCredentials = errors.New("error in sercvice.go = invalid email or password")
-	ErrEmailExists        = errors.New("error in sercvice.go = email already exists")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "invalid email or password")
+	ErrEmailExists        = apperr.Conflict("email_exists", "email already exists")
 )
 
-type AuthClaims struct {
-	StudentID primitive.ObjectID `json:"student_id"`
-	Role      string             `json:"role"`
-	jwt.RegisteredClaims
+// DeletionGuard is consulted before a student is deleted so that lessons are
+// not left pointing at it.
+type DeletionGuard interface {
+	BeforeDeleteStudent(ctx context.Context, id primitive.ObjectID) error
 }
 
 type StudentService struct {
-	repo StudentRepository
+	repo   StudentRepository
+	guard  DeletionGuard
+	idp    identity.Provider
+	audit  audit.Recorder
+	events events.Emitter
+	logger *slog.Logger
 }
 
-func NewStudentService(repo StudentRepository) *StudentService {
-	return &StudentService{repo: repo}
+func NewStudentService(repo StudentRepository, guard DeletionGuard, idp identity.Provider, auditor audit.Recorder, emitter events.Emitter, logger *slog.Logger) *StudentService {
+	return &StudentService{repo: repo, guard: guard, idp: idp, audit: auditor, events: emitter, logger: logger}
 }
 
-func (s *StudentService) CreateStudent(student Student, password string) (primitive.ObjectID, error) {
+func (s *StudentService) CreateStudent(ctx context.Context, student Student, password string) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "StudentService.CreateStudent")
+	defer tracing.End(span, &err)
 	// Check if the email already exists
-	existingStudent, _ := s.repo.GetStudentByEmail(student.Email)
+	existingStudent, err := s.repo.GetStudentByEmail(ctx, student.Email)
 	if existingStudent != nil {
-		log.Printf("Email already exists: %s", student.Email)
 		return primitive.NilObjectID, ErrEmailExists
 	}
+	if err != nil && !apperr.IsNotFound(err) {
+		return primitive.NilObjectID, err
+	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := identity.HashPassword(ctx, password)
 	if err != nil {
-		log.Printf("Error hashing password: %v", err)
 		return primitive.NilObjectID, err
 	}
-	student.PasswordHash = string(hashedPassword)
+	student.PasswordHash = hashedPassword
 	student.Role = "student"
 	student.CreatedAt = time.Now()
 	student.UpdatedAt = time.Now()
 
-	// Create the student in MongoDB
-	studentID, err := s.repo.CreateStudent(student)
+	var studentID primitive.ObjectID
+	err = s.events.Transaction(ctx, func(ctx context.Context) error {
+		// Create the student in MongoDB
+		var err error
+		studentID, err = s.repo.CreateStudent(ctx, student)
+		if apperr.KindOf(err) == apperr.KindConflict {
+			return ErrEmailExists
+		}
+		if err != nil {
+			return err
+		}
+
+		// Create the student in the identity provider
+		err = s.idp.CreateUser(ctx, identity.User{
+			Username:  student.Username,
+			FirstName: student.FirstName,
+			LastName:  student.LastName,
+			Email:     student.Email,
+			Password:  password,
+			Role:      identity.RoleStudent,
+		})
+		if err != nil {
+			// Rollback MongoDB creation in case of identity provider error
+			s.logger.WarnContext(ctx, "identity provider rejected new student, rolling back", slog.String("student_id", studentID.Hex()), slog.Any("error", err))
+			s.rollback(ctx, studentID, "")
+			if apperr.KindOf(err) == apperr.KindConflict {
+				return ErrEmailExists
+			}
+			return err
+		}
+
+		err = s.events.Emit(ctx, events.StudentRegistered{
+			StudentID: studentID,
+			Email:     student.Email,
+			FirstName: student.FirstName,
+			LastName:  student.LastName,
+		})
+		if err != nil {
+			// The identity provider is not part of the transaction, so the
+			// user is removed from it by hand, letting the student sign up again
+			s.logger.WarnContext(ctx, "failed to emit student registration, rolling back", slog.String("student_id", studentID.Hex()), slog.Any("error", err))
+			s.rollback(ctx, studentID, student.Email)
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionCreate, "student", studentID, nil, student)
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error creating student in MongoDB: %v", err)
-		return primitive.NilObjectID, err
-	}
-
-	// Create the student in Keycloak
-	err = s.createUserInKeycloak(student, password)
-	if err != nil {
-		// Rollback MongoDB creation in case of Keycloak error
-		s.repo.DeleteStudent(studentID)
-		log.Printf("Error creating student in Keycloak: %v", err)
 		return primitive.NilObjectID, err
 	}
 
+	s.logger.InfoContext(ctx, "student created", slog.String("student_id", studentID.Hex()))
 	return studentID, nil
 }
 
-func (s *StudentService) Authenticate(email, password string) (string, error) {
-	client := gocloak.NewClient(config.KeycloakURL)
-	token, err := client.Login(config.KeycloakClientID, config.KeycloakClientSecret, config.KeycloakRealm, email, password)
-	if err != nil {
-		log.Printf("Login to Keycloak failed: %v", err)
-		return "", ErrInvalidCredentials
+// Authenticate logs the student in against the identity provider and returns the access token.
+// rollback undoes a registration that failed part way. The student is deleted
+// in case there is no transaction to roll back, and so is their identity
+// provider user if email is set.
+func (s *StudentService) rollback(ctx context.Context, id primitive.ObjectID, email string) {
+	if err := s.repo.DeleteStudent(ctx, id); err != nil {
+		s.logger.ErrorContext(ctx, "failed to roll back student", slog.String("student_id", id.Hex()), slog.Any("error", err))
+	}
+	if email == "" {
+		return
+	}
+	if err := s.idp.DeleteUser(ctx, email); err != nil {
+		s.logger.ErrorContext(ctx, "failed to roll back identity provider user", slog.String("student_id", id.Hex()), slog.Any("error", err))
 	}
-
-	return token.AccessToken, nil
 }
 
-func (s *StudentService) AuthenticateWithKeycloak(email, password string) (string, error) {
-	// Login to Keycloak
-	token, err := utils.GetKeycloakToken(email, password)
+func (s *StudentService) Authenticate(ctx context.Context, email, password string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "StudentService.Authenticate")
+	defer tracing.End(span, &err)
+	token, err := s.idp.Login(ctx, email, password)
+	if apperr.KindOf(err) == apperr.KindUnauthorized {
+		return "", ErrInvalidCredentials
+	}
 	if err != nil {
-		log.Printf("Login to Keycloak failed: %v", err)
 		return "", err
 	}
 
 	return token, nil
 }
 
-func (s *StudentService) GetStudentByID(id primitive.ObjectID) (*Student, error) {
-	return s.repo.GetStudentByID(id)
+func (s *StudentService) GetStudentByID(ctx context.Context, id primitive.ObjectID) (_ *Student, err error) {
+	ctx, span := tracer.Start(ctx, "StudentService.GetStudentByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetStudentByID(ctx, id)
 }
 
-func (s *StudentService) GetAllStudents() ([]Student, error) {
-	return s.repo.GetAllStudents()
+func (s *StudentService) GetAllStudents(ctx context.Context) (_ []Student, err error) {
+	ctx, span := tracer.Start(ctx, "StudentService.GetAllStudents")
+	defer tracing.End(span, &err)
+	return s.repo.GetAllStudents(ctx)
 }
 
-func (s *StudentService) UpdateStudent(student Student) error {
+func (s *StudentService) UpdateStudent(ctx context.Context, student Student) (err error) {
+	ctx, span := tracer.Start(ctx, "StudentService.UpdateStudent")
+	defer tracing.End(span, &err)
 	student.UpdatedAt = time.Now()
-	return s.repo.UpdateStudent(student)
-}
-
-func (s *StudentService) DeleteStudent(id primitive.ObjectID) error {
-	return s.repo.DeleteStudent(id)
-}
-
-func (s *StudentService) createUserInKeycloak(student Student, password string) error {
-	// Create user in Keycloak
-	keycloakUser := struct {
-		Username    string `json:"username"`
-		FirstName   string `json:"firstName"`
-		LastName    string `json:"lastName"`
-		Email       string `json:"email"`
-		Enabled     bool   `json:"enabled"`
-		Credentials []struct {
-			Type      string `json:"type"`
-			Value     string `json:"value"`
-			Temporary bool   `json:"temporary"`
-		} `json:"credentials"`
-		RealmRoles []string `json:"realmRoles"`
-	}{
-		Username:  student.Username,
-		FirstName: student.FirstName,
-		LastName:  student.LastName,
-		Email:     student.Email,
-		Enabled:   true,
-		Credentials: []struct {
-			Type      string `json:"type"`
-			Value     string `json:"value"`
-			Temporary bool   `json:"temporary"`
-		}{
-			{
-				Type:      "password",
-				Value:     password,
-				Temporary: false,
-			},
-		},
-		RealmRoles: []string{"student"},
-	}
-
-	userJSON, err := json.Marshal(keycloakUser)
-	if err != nil {
-		log.Printf("Failed to marshal user JSON: %v", err)
-		return err
-	}
-
-	url := fmt.Sprintf("%s/admin/realms/%s/users", config.KeycloakURL, config.KeycloakRealm)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(userJSON))
-	if err != nil {
-		log.Printf("Failed to create request: %v", err)
-		return err
-	}
-
-	token, err := utils.GetClientToken()
+	before, err := s.repo.GetStudentByID(ctx, student.ID)
 	if err != nil {
-		log.Printf("Failed to get client token: %v", err)
 		return err
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Failed to do request: %v", err)
+	if err := s.repo.UpdateStudent(ctx, student); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated {
-		log.Printf("Failed to create user in Keycloak: %s", resp.Status)
-		return fmt.Errorf("failed to create user in Keycloak: %s", resp.Status)
-	}
-
-	// Keycloak does not return a body on successful user creation, so we use the Location header to get the user ID.
-	location := resp.Header.Get("Location")
-	if location == "" {
-		log.Printf("Failed to get user location header from Keycloak response")
-		return fmt.Errorf("failed to get user location header from Keycloak response")
-	}
-
-	// Extract user ID from the Location header
-	segments := strings.Split(location, "/")
-	userID := segments[len(segments)-1]
-	log.Printf("User ID from Keycloak: %s", userID)
-
-	// Assign the "student" role to the user
-	if err := s.assignRoleToUser(userID, "student"); err != nil {
-		log.Printf("Failed to assign role to user: %v", err)
-		return fmt.Errorf("failed to assign role to user: %w", err)
-	}
-
+	s.audit.Record(ctx, audit.ActionUpdate, "student", student.ID, before, student)
 	return nil
 }
 
-func (s *StudentService) assignRoleToUser(userID, roleName string) error {
-	url := fmt.Sprintf("%s/admin/realms/%s/roles/%s", config.KeycloakURL, config.KeycloakRealm, roleName)
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("Failed to create request for role ID: %v", err)
+func (s *StudentService) DeleteStudent(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "StudentService.DeleteStudent")
+	defer tracing.End(span, &err)
+	if err := s.guard.BeforeDeleteStudent(ctx, id); err != nil {
 		return err
 	}
-
-	token, err := utils.GetClientToken()
+	before, err := s.repo.GetStudentByID(ctx, id)
 	if err != nil {
-		log.Printf("Failed to get client token: %v", err)
 		return err
 	}
-
-	req.Header.Set("Authorization", "Bearer "+token)
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("Failed to execute request for role ID: %v", err)
+	if err := s.repo.DeleteStudent(ctx, id); err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Failed to get role ID from Keycloak: %s", resp.Status)
-		return fmt.Errorf("failed to get role ID from Keycloak: %s", resp.Status)
-	}
-
-	var role struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&role); err != nil {
-		log.Printf("Failed to decode role response: %v", err)
-		return fmt.Errorf("failed to decode role response: %w", err)
-	}
-	log.Printf("Role ID for %s: %s", roleName, role.ID)
-
-	assignRoleURL := fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm", config.KeycloakURL, config.KeycloakRealm, userID)
-	roleMapping := []struct {
-		ID   string `json:"id"`
-		Name string `json:"name"`
-	}{
-		{ID: role.ID, Name: role.Name},
-	}
-
-	roleMappingJSON, err := json.Marshal(roleMapping)
-	if err != nil {
-		log.Printf("Failed to marshal role mapping JSON: %v", err)
-		return err
-	}
-
-	req, err = http.NewRequest("POST", assignRoleURL, bytes.NewBuffer(roleMappingJSON))
-	if err != nil {
-		log.Printf("Failed to create request for assigning role: %v", err)
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	resp, err = client.Do(req)
-	if err != nil {
-		log.Printf("Failed to execute request for assigning role: %v", err)
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent {
-		log.Printf("Failed to assign role to user in Keycloak: %s", resp.Status)
-		return fmt.Errorf("failed to assign role to user in Keycloak: %s", resp.Status)
-	}
-
-	log.Printf("Successfully assigned role %s to user %s", roleName, userID)
+	s.audit.Record(ctx, audit.ActionDelete, "student", id, before, nil)
 	return nil
 }
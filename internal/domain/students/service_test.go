@@ -0,0 +1,46 @@
+package students
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+// failingEmitter stores nothing, like an outbox that is down.
+type failingEmitter struct{}
+
+func (failingEmitter) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (failingEmitter) Emit(ctx context.Context, payloads ...events.Payload) error {
+	return errors.New("outbox unavailable")
+}
+
+func TestCreateStudentRollsBackWhenEmitFails(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryStudentRepository()
+	idp := identity.NewLocal([]byte("test-secret"), time.Hour)
+	student := Student{Username: "jdoe", Email: "john@example.com"}
+
+	failing := NewStudentService(repo, nil, idp, audit.Discard, failingEmitter{}, logging.Discard())
+	if _, err := failing.CreateStudent(ctx, student, "secret"); err == nil {
+		t.Fatal("Expected CreateStudent to fail")
+	}
+	if _, err := repo.GetStudentByEmail(ctx, student.Email); !apperr.IsNotFound(err) {
+		t.Fatalf("Expected the student to be rolled back but got %v", err)
+	}
+
+	// Neither the repository nor the identity provider keeps the email
+	service := NewStudentService(repo, nil, idp, audit.Discard, events.NewMemoryOutbox(), logging.Discard())
+	if _, err := service.CreateStudent(ctx, student, "secret"); err != nil {
+		t.Fatalf("Expected the student to be able to sign up again but got %v", err)
+	}
+}
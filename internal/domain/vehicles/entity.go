@@ -6,12 +6,22 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// A vehicle is in service unless it has been taken out, for repairs say.
+const (
+	StatusInService    = "in_service"
+	StatusOutOfService = "out_of_service"
+)
+
 type Vehicle struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty"`
 	Make         string             `bson:"make,omitempty"`
 	Model        string             `bson:"model,omitempty"`
 	Year         int                `bson:"year,omitempty"`
 	LicensePlate string             `bson:"license_plate,omitempty"`
-	CreatedAt    time.Time          `bson:"created_at,omitempty"`
-	UpdatedAt    time.Time          `bson:"updated_at,omitempty"`
+	// Type is what kind of vehicle it is, such as car, automatic or
+	// motorcycle, in lower case. Scheduling suggestions can ask for a type.
+	Type      string    `bson:"type,omitempty"`
+	Status    string    `bson:"status,omitempty"`
+	CreatedAt time.Time `bson:"created_at,omitempty"`
+	UpdatedAt time.Time `bson:"updated_at,omitempty"`
 }
@@ -0,0 +1,75 @@
+package vehicles
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryVehicleRepository keeps vehicles in memory with the same semantics as
+// MongoVehicleRepository, including the unique license plate index. It is meant for
+// tests and local development.
+type MemoryVehicleRepository struct {
+	store *memstore.Store[Vehicle]
+}
+
+func NewMemoryVehicleRepository() *MemoryVehicleRepository {
+	return &MemoryVehicleRepository{
+		store: memstore.New[Vehicle](func(a, b Vehicle) bool {
+			return a.LicensePlate != "" && a.LicensePlate == b.LicensePlate
+		}),
+	}
+}
+
+func (r *MemoryVehicleRepository) CreateVehicle(ctx context.Context, vehicle Vehicle) (primitive.ObjectID, error) {
+	vehicle.ID = primitive.NewObjectID()
+	vehicle.CreatedAt = time.Now()
+	vehicle.UpdatedAt = time.Now()
+	ok, err := r.store.Insert(vehicle.ID, vehicle)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("vehicle")
+	}
+	return vehicle.ID, nil
+}
+
+func (r *MemoryVehicleRepository) GetVehicleByID(ctx context.Context, id primitive.ObjectID) (*Vehicle, error) {
+	vehicle, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("vehicle")
+	}
+	return &vehicle, nil
+}
+
+func (r *MemoryVehicleRepository) ListVehicles(ctx context.Context, filter Filter) ([]Vehicle, error) {
+	return r.store.Find(func(vehicle Vehicle) bool {
+		return (filter.Type == "" || vehicle.Type == filter.Type) && (filter.Status == "" || vehicle.Status == filter.Status)
+	}), nil
+}
+
+func (r *MemoryVehicleRepository) UpdateVehicle(ctx context.Context, vehicle Vehicle) error {
+	vehicle.UpdatedAt = time.Now()
+	found, ok, err := r.store.Set(vehicle.ID, vehicle)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("vehicle")
+	}
+	if !ok {
+		return apperr.ResourceConflict("vehicle")
+	}
+	return nil
+}
+
+func (r *MemoryVehicleRepository) DeleteVehicle(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("vehicle")
+	}
+	return nil
+}
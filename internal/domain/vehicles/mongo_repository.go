@@ -4,9 +4,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoVehicleRepository struct {
@@ -19,30 +21,65 @@ func NewMongoVehicleRepository(db *mongo.Database) *MongoVehicleRepository {
 	}
 }
 
-func (r *MongoVehicleRepository) CreateVehicle(vehicle Vehicle) (primitive.ObjectID, error) {
+func (r *MongoVehicleRepository) CreateVehicle(ctx context.Context, vehicle Vehicle) (primitive.ObjectID, error) {
 	vehicle.ID = primitive.NewObjectID()
 	vehicle.CreatedAt = time.Now()
 	vehicle.UpdatedAt = time.Now()
-	_, err := r.db.InsertOne(context.Background(), vehicle)
-	return vehicle.ID, err
+	if _, err := r.db.InsertOne(ctx, vehicle); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "vehicle")
+	}
+	return vehicle.ID, nil
 }
 
-func (r *MongoVehicleRepository) GetVehicleByID(id primitive.ObjectID) (*Vehicle, error) {
+func (r *MongoVehicleRepository) GetVehicleByID(ctx context.Context, id primitive.ObjectID) (*Vehicle, error) {
 	var vehicle Vehicle
-	err := r.db.FindOne(context.Background(), bson.M{"_id": id}).Decode(&vehicle)
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&vehicle)
 	if err != nil {
-		return nil, err
+		return nil, apperr.FromMongo(err, "vehicle")
 	}
 	return &vehicle, nil
 }
 
-func (r *MongoVehicleRepository) UpdateVehicle(vehicle Vehicle) error {
+func (r *MongoVehicleRepository) ListVehicles(ctx context.Context, filter Filter) ([]Vehicle, error) {
+	query := bson.M{}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	cursor, err := r.db.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, apperr.FromMongo(err, "vehicle")
+	}
+	defer cursor.Close(ctx)
+
+	var vehicles []Vehicle
+	if err := cursor.All(ctx, &vehicles); err != nil {
+		return nil, apperr.FromMongo(err, "vehicle")
+	}
+	return vehicles, nil
+}
+
+func (r *MongoVehicleRepository) UpdateVehicle(ctx context.Context, vehicle Vehicle) error {
 	vehicle.UpdatedAt = time.Now()
-	_, err := r.db.UpdateOne(context.Background(), bson.M{"_id": vehicle.ID}, bson.M{"$set": vehicle})
-	return err
+	result, err := r.db.UpdateOne(ctx, bson.M{"_id": vehicle.ID}, bson.M{"$set": vehicle})
+	if err != nil {
+		return apperr.FromMongo(err, "vehicle")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "vehicle")
+	}
+	return nil
 }
 
-func (r *MongoVehicleRepository) DeleteVehicle(id primitive.ObjectID) error {
-	_, err := r.db.DeleteOne(context.Background(), bson.M{"_id": id})
-	return err
+func (r *MongoVehicleRepository) DeleteVehicle(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "vehicle")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "vehicle")
+	}
+	return nil
 }
@@ -1,10 +1,22 @@
 package vehicles
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Filter narrows down ListVehicles. Zero-valued fields are ignored.
+type Filter struct {
+	Type   string
+	Status string
+}
 
 type VehicleRepository interface {
-	CreateVehicle(vehicle Vehicle) (primitive.ObjectID, error)
-	GetVehicleByID(id primitive.ObjectID) (*Vehicle, error)
-	UpdateVehicle(vehicle Vehicle) error
-	DeleteVehicle(id primitive.ObjectID) error
+	CreateVehicle(ctx context.Context, vehicle Vehicle) (primitive.ObjectID, error)
+	GetVehicleByID(ctx context.Context, id primitive.ObjectID) (*Vehicle, error)
+	// ListVehicles returns the matching vehicles oldest first.
+	ListVehicles(ctx context.Context, filter Filter) ([]Vehicle, error)
+	UpdateVehicle(ctx context.Context, vehicle Vehicle) error
+	DeleteVehicle(ctx context.Context, id primitive.ObjectID) error
 }
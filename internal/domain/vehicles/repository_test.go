@@ -0,0 +1,109 @@
+package vehicles
+
+import (
+	"context"
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryVehicleRepository(t *testing.T) {
+	testVehicleRepository(t, func(t *testing.T) VehicleRepository {
+		return NewMemoryVehicleRepository()
+	})
+}
+
+func TestMongoVehicleRepository(t *testing.T) {
+	testVehicleRepository(t, func(t *testing.T) VehicleRepository {
+		return NewMongoVehicleRepository(mongotest.Database(t))
+	})
+}
+
+// testVehicleRepository is the contract every VehicleRepository must satisfy.
+func testVehicleRepository(t *testing.T, newRepo func(t *testing.T) VehicleRepository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		id, err := repo.CreateVehicle(ctx, Vehicle{Make: "Toyota", Model: "Yaris", LicensePlate: "1234BCD"})
+		if err != nil {
+			t.Fatalf("CreateVehicle failed: %v", err)
+		}
+
+		vehicle, err := repo.GetVehicleByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetVehicleByID failed: %v", err)
+		}
+		if vehicle.ID != id || vehicle.Model != "Yaris" || vehicle.CreatedAt.IsZero() {
+			t.Fatalf("Unexpected vehicle %+v", vehicle)
+		}
+	})
+
+	t.Run("unique license plate", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.CreateVehicle(ctx, Vehicle{Make: "Toyota", Model: "Yaris", LicensePlate: "1234BCD"}); err != nil {
+			t.Fatalf("CreateVehicle failed: %v", err)
+		}
+		_, err := repo.CreateVehicle(ctx, Vehicle{Make: "Toyota", Model: "Yaris", LicensePlate: "1234BCD"})
+		if apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		repo := newRepo(t)
+		missing := primitive.NewObjectID()
+		if _, err := repo.GetVehicleByID(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("GetVehicleByID: expected not found but got %v", err)
+		}
+		if err := repo.UpdateVehicle(ctx, Vehicle{ID: missing, Model: "Yaris"}); !apperr.IsNotFound(err) {
+			t.Fatalf("UpdateVehicle: expected not found but got %v", err)
+		}
+		if err := repo.DeleteVehicle(ctx, missing); !apperr.IsNotFound(err) {
+			t.Fatalf("DeleteVehicle: expected not found but got %v", err)
+		}
+	})
+
+	t.Run("update keeps omitted fields", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateVehicle(ctx, Vehicle{Make: "Toyota", Model: "Yaris", LicensePlate: "1234BCD"})
+		if err := repo.UpdateVehicle(ctx, Vehicle{ID: id, Model: "Corolla"}); err != nil {
+			t.Fatalf("UpdateVehicle failed: %v", err)
+		}
+		vehicle, _ := repo.GetVehicleByID(ctx, id)
+		if vehicle.Model != "Corolla" || vehicle.LicensePlate != "1234BCD" {
+			t.Fatalf("Unexpected vehicle after update %+v", vehicle)
+		}
+	})
+
+	t.Run("list by type and status", func(t *testing.T) {
+		repo := newRepo(t)
+		car, _ := repo.CreateVehicle(ctx, Vehicle{Make: "Toyota", Model: "Yaris", LicensePlate: "1234BCD", Type: "car", Status: StatusInService})
+		repo.CreateVehicle(ctx, Vehicle{Make: "Seat", Model: "Ibiza", LicensePlate: "5678FGH", Type: "car", Status: StatusOutOfService})
+		repo.CreateVehicle(ctx, Vehicle{Make: "Honda", Model: "CBF", LicensePlate: "9012JKL", Type: "motorcycle", Status: StatusInService})
+
+		found, err := repo.ListVehicles(ctx, Filter{Type: "car", Status: StatusInService})
+		if err != nil {
+			t.Fatalf("ListVehicles failed: %v", err)
+		}
+		if len(found) != 1 || found[0].ID != car {
+			t.Fatalf("Expected just the car in service but got %+v", found)
+		}
+		if all, _ := repo.ListVehicles(ctx, Filter{}); len(all) != 3 {
+			t.Fatalf("Expected all 3 vehicles but got %d", len(all))
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		repo := newRepo(t)
+		id, _ := repo.CreateVehicle(ctx, Vehicle{Make: "Toyota", Model: "Yaris", LicensePlate: "1234BCD"})
+		if err := repo.DeleteVehicle(ctx, id); err != nil {
+			t.Fatalf("DeleteVehicle failed: %v", err)
+		}
+		if _, err := repo.GetVehicleByID(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected deleted vehicle to be gone but got %v", err)
+		}
+	})
+}
@@ -1,27 +1,111 @@
 package vehicles
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"context"
+	"strings"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tracer = tracing.Tracer("internal/domain/vehicles")
+
+var ErrInvalidStatus = apperr.Invalid("invalid_status", `status must be "in_service" or "out_of_service"`)
+
+// DeletionGuard is consulted before a vehicle is deleted so that lessons are
+// not left pointing at it.
+type DeletionGuard interface {
+	BeforeDeleteVehicle(ctx context.Context, id primitive.ObjectID) error
+}
 
 type VehicleService struct {
-	repo VehicleRepository
+	repo   VehicleRepository
+	guard  DeletionGuard
+	audit  audit.Recorder
+	events events.Emitter
+}
+
+func NewVehicleService(repo VehicleRepository, guard DeletionGuard, auditor audit.Recorder, emitter events.Emitter) *VehicleService {
+	return &VehicleService{repo: repo, guard: guard, audit: auditor, events: emitter}
+}
+
+func (s *VehicleService) CreateVehicle(ctx context.Context, vehicle Vehicle) (_ primitive.ObjectID, err error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.CreateVehicle")
+	defer tracing.End(span, &err)
+	if vehicle.Status == "" {
+		vehicle.Status = StatusInService
+	}
+	vehicle.Type = NormalizeType(vehicle.Type)
+	if !validStatus(vehicle.Status) {
+		return primitive.NilObjectID, ErrInvalidStatus
+	}
+	id, err := s.repo.CreateVehicle(ctx, vehicle)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	s.audit.Record(ctx, audit.ActionCreate, "vehicle", id, nil, vehicle)
+	return id, nil
 }
 
-func NewVehicleService(repo VehicleRepository) *VehicleService {
-	return &VehicleService{repo: repo}
+func (s *VehicleService) GetVehicleByID(ctx context.Context, id primitive.ObjectID) (_ *Vehicle, err error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.GetVehicleByID")
+	defer tracing.End(span, &err)
+	return s.repo.GetVehicleByID(ctx, id)
 }
 
-func (s *VehicleService) CreateVehicle(vehicle Vehicle) (primitive.ObjectID, error) {
-	return s.repo.CreateVehicle(vehicle)
+func (s *VehicleService) UpdateVehicle(ctx context.Context, vehicle Vehicle) (err error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.UpdateVehicle")
+	defer tracing.End(span, &err)
+	if vehicle.Status != "" && !validStatus(vehicle.Status) {
+		return ErrInvalidStatus
+	}
+	vehicle.Type = NormalizeType(vehicle.Type)
+	return s.events.Transaction(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetVehicleByID(ctx, vehicle.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.UpdateVehicle(ctx, vehicle); err != nil {
+			return err
+		}
+		s.audit.Record(ctx, audit.ActionUpdate, "vehicle", vehicle.ID, before, vehicle)
+		if vehicle.Status != StatusOutOfService || before.Status == StatusOutOfService {
+			return nil
+		}
+		plate := before.LicensePlate
+		if vehicle.LicensePlate != "" {
+			plate = vehicle.LicensePlate
+		}
+		return s.events.Emit(ctx, events.VehicleOutOfService{VehicleID: vehicle.ID, LicensePlate: plate})
+	})
 }
 
-func (s *VehicleService) GetVehicleByID(id primitive.ObjectID) (*Vehicle, error) {
-	return s.repo.GetVehicleByID(id)
+func (s *VehicleService) DeleteVehicle(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "VehicleService.DeleteVehicle")
+	defer tracing.End(span, &err)
+	if err := s.guard.BeforeDeleteVehicle(ctx, id); err != nil {
+		return err
+	}
+	before, err := s.repo.GetVehicleByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteVehicle(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "vehicle", id, before, nil)
+	return nil
 }
 
-func (s *VehicleService) UpdateVehicle(vehicle Vehicle) error {
-	return s.repo.UpdateVehicle(vehicle)
+// NormalizeType is how vehicle types are stored and looked up, so that
+// "Car" and "car " are the same type.
+func NormalizeType(vehicleType string) string {
+	return strings.ToLower(strings.TrimSpace(vehicleType))
 }
 
-func (s *VehicleService) DeleteVehicle(id primitive.ObjectID) error {
-	return s.repo.DeleteVehicle(id)
+func validStatus(status string) bool {
+	return status == StatusInService || status == StatusOutOfService
 }
@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("internal/events")
+
+const (
+	// lease is how long a claimed event is hidden from other instances. It must
+	// be longer than delivering a batch takes.
+	lease = time.Minute
+	// maxRetryDelay caps the exponential backoff between deliveries.
+	maxRetryDelay = 15 * time.Minute
+)
+
+// Handler reacts to an event in-process.
+type Handler func(ctx context.Context, event Event) error
+
+// Publisher forwards events to an external broker.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Dispatcher delivers the events in an outbox to the handlers subscribed to
+// their type and to every publisher. Delivery is at least once: an event is
+// delivered again, to all of them, if any fails, so they must be idempotent.
+type Dispatcher struct {
+	outbox Outbox
+	cfg    config.OutboxConfig
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu         sync.RWMutex
+	handlers   map[Type][]Handler
+	publishers []Publisher
+}
+
+func NewDispatcher(outbox Outbox, cfg config.OutboxConfig, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		outbox:   outbox,
+		cfg:      cfg,
+		logger:   logger,
+		now:      time.Now,
+		handlers: make(map[Type][]Handler),
+	}
+}
+
+func (d *Dispatcher) Subscribe(typ Type, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[typ] = append(d.handlers[typ], handler)
+}
+
+func (d *Dispatcher) AddPublisher(publisher Publisher) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.publishers = append(d.publishers, publisher)
+}
+
+// Run delivers pending events every PollInterval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "failed to dispatch events", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending delivers the events that are due, a batch at a time, and
+// returns how many were delivered.
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		batch, err := d.outbox.Claim(ctx, d.now(), lease, d.cfg.BatchSize)
+		if err != nil {
+			return delivered, err
+		}
+		for _, event := range batch {
+			if d.dispatch(ctx, event) {
+				delivered++
+			}
+		}
+		if len(batch) < d.cfg.BatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, event Event) bool {
+	ctx, span := tracer.Start(ctx, "Dispatcher.dispatch", trace.WithAttributes(
+		attribute.String("event.type", string(event.Type)), attribute.String("event.id", event.ID.Hex())))
+	err := d.deliver(ctx, event)
+	defer tracing.End(span, &err)
+
+	if err == nil {
+		if markErr := d.outbox.MarkDispatched(ctx, event.ID, d.now()); markErr != nil {
+			d.logger.ErrorContext(ctx, "failed to mark event dispatched", slog.String("event_id", event.ID.Hex()), slog.Any("error", markErr))
+		}
+		return true
+	}
+
+	attempts := event.Attempts + 1
+	var retryAt time.Time
+	if attempts < d.cfg.MaxAttempts {
+		retryAt = d.now().Add(retryDelay(d.cfg.PollInterval, attempts))
+	}
+	attrs := []any{slog.String("event_id", event.ID.Hex()), slog.String("event_type", string(event.Type)), slog.Int("attempts", attempts), slog.Any("error", err)}
+	if retryAt.IsZero() {
+		d.logger.ErrorContext(ctx, "giving up on event", attrs...)
+	} else {
+		d.logger.WarnContext(ctx, "failed to deliver event, will retry", append(attrs, slog.Time("retry_at", retryAt))...)
+	}
+	if markErr := d.outbox.MarkFailed(ctx, event.ID, err.Error(), retryAt); markErr != nil {
+		d.logger.ErrorContext(ctx, "failed to mark event failed", slog.String("event_id", event.ID.Hex()), slog.Any("error", markErr))
+	}
+	return false
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, event Event) error {
+	d.mu.RLock()
+	handlers := d.handlers[event.Type]
+	publishers := d.publishers
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, handler := range handlers {
+		errs = append(errs, handler(ctx, event))
+	}
+	for _, publisher := range publishers {
+		errs = append(errs, publisher.Publish(ctx, event))
+	}
+	return errors.Join(errs...)
+}
+
+// retryDelay doubles base with each failed attempt, up to maxRetryDelay.
+func retryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type publisherFunc func(ctx context.Context, event Event) error
+
+func (f publisherFunc) Publish(ctx context.Context, event Event) error { return f(ctx, event) }
+
+func newTestDispatcher(outbox Outbox, now *time.Time) *Dispatcher {
+	d := NewDispatcher(outbox, config.OutboxConfig{PollInterval: time.Second, BatchSize: 2, MaxAttempts: 3}, logging.Discard())
+	d.now = func() time.Time { return *now }
+	return d
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	now := time.Now().Add(time.Second)
+	d := newTestDispatcher(outbox, &now)
+
+	var booked []LessonBooked
+	d.Subscribe(TypeLessonBooked, func(ctx context.Context, event Event) error {
+		var payload LessonBooked
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		booked = append(booked, payload)
+		return nil
+	})
+	var published []Type
+	d.AddPublisher(publisherFunc(func(ctx context.Context, event Event) error {
+		published = append(published, event.Type)
+		return nil
+	}))
+
+	lessonID := primitive.NewObjectID()
+	outbox.Emit(ctx, LessonBooked{Lesson{LessonID: lessonID}}, StudentRegistered{}, LessonCancelled{Lesson{LessonID: lessonID}})
+
+	// The batch size is 2, so this takes two batches
+	delivered, err := d.DispatchPending(ctx)
+	if err != nil || delivered != 3 {
+		t.Fatalf("Expected 3 events delivered but got %d (%v)", delivered, err)
+	}
+	if len(booked) != 1 || booked[0].LessonID != lessonID {
+		t.Fatalf("Expected the handler to get only the booking but got %+v", booked)
+	}
+	if len(published) != 3 {
+		t.Fatalf("Expected the publisher to get every event but got %v", published)
+	}
+	if delivered, _ := d.DispatchPending(ctx); delivered != 0 {
+		t.Fatalf("Expected nothing left to deliver but got %d", delivered)
+	}
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	now := time.Now().Add(time.Second)
+	d := newTestDispatcher(outbox, &now)
+
+	calls := 0
+	d.Subscribe(TypeStudentRegistered, func(ctx context.Context, event Event) error {
+		calls++
+		return errors.New("mail server down")
+	})
+	outbox.Emit(ctx, StudentRegistered{})
+
+	if delivered, _ := d.DispatchPending(ctx); delivered != 0 || calls != 1 {
+		t.Fatalf("Expected the first attempt to fail but got %d calls, %d delivered", calls, delivered)
+	}
+	// Retried after 1s, then 2s, and given up on at the third attempt
+	for i, wait := range []time.Duration{time.Second, 2 * time.Second} {
+		now = now.Add(wait - time.Millisecond)
+		if d.DispatchPending(ctx); calls != i+1 {
+			t.Fatalf("Expected no attempt before the backoff elapsed, got %d calls", calls)
+		}
+		now = now.Add(time.Millisecond)
+		if delivered, _ := d.DispatchPending(ctx); delivered != 0 || calls != i+2 {
+			t.Fatalf("Expected attempt %d to fail but got %d calls, %d delivered", i+2, calls, delivered)
+		}
+	}
+	now = now.Add(time.Hour)
+	if d.DispatchPending(ctx); calls != 3 {
+		t.Fatalf("Expected the event to be given up on after 3 attempts but got %d calls", calls)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 20: maxRetryDelay} {
+		if got := retryDelay(time.Second, attempts); got != want {
+			t.Errorf("retryDelay(1s, %d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
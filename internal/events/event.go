@@ -0,0 +1,184 @@
+// Package events carries domain events from the services that cause them to
+// whoever is interested. Services emit events into an outbox in the same
+// transaction as the change itself, and a Dispatcher later delivers them to
+// in-process handlers and external publishers.
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Type string
+
+const (
+	TypeStudentRegistered   Type = "student.registered"
+	TypeLessonBooked        Type = "lesson.booked"
+	TypeLessonCancelled     Type = "lesson.cancelled"
+	TypeLessonRescheduled   Type = "lesson.rescheduled"
+	TypeLessonChanged       Type = "lesson.changed"
+	TypeAvailabilityChanged Type = "availability.changed"
+	TypeVehicleOutOfService Type = "vehicle.out_of_service"
+)
+
+// Types lists every event type.
+var Types = []Type{TypeStudentRegistered, TypeLessonBooked, TypeLessonCancelled, TypeLessonRescheduled, TypeLessonChanged,
+	TypeAvailabilityChanged, TypeVehicleOutOfService}
+
+// Change is what happened to the document a LessonChanged or
+// AvailabilityChanged event is about.
+type Change string
+
+const (
+	ChangeCreated Change = "created"
+	ChangeUpdated Change = "updated"
+	ChangeDeleted Change = "deleted"
+)
+
+// Event is an emitted Payload as it is stored in the outbox.
+type Event struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type Type               `bson:"type" json:"type"`
+	// AggregateID is the document the event is about
+	AggregateID primitive.ObjectID `bson:"aggregate_id" json:"aggregate_id"`
+	OccurredAt  time.Time          `bson:"occurred_at" json:"occurred_at"`
+	RequestID   string             `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	// Payload is the BSON encoding of the Payload; see Decode
+	Payload bson.Raw `bson:"payload" json:"-"`
+	// Attempts counts the failed deliveries so far
+	Attempts int `bson:"attempts" json:"-"`
+}
+
+// Decode unmarshals the payload into v, which should be the Payload type
+// matching the event's Type.
+func (e Event) Decode(v any) error {
+	return bson.Unmarshal(e.Payload, v)
+}
+
+// DecodePayload unmarshals the payload into the Payload type matching the
+// event's Type.
+func (e Event) DecodePayload() (Payload, error) {
+	switch e.Type {
+	case TypeStudentRegistered:
+		return decode[StudentRegistered](e.Payload)
+	case TypeLessonBooked:
+		return decode[LessonBooked](e.Payload)
+	case TypeLessonCancelled:
+		return decode[LessonCancelled](e.Payload)
+	case TypeLessonRescheduled:
+		return decode[LessonRescheduled](e.Payload)
+	case TypeLessonChanged:
+		return decode[LessonChanged](e.Payload)
+	case TypeAvailabilityChanged:
+		return decode[AvailabilityChanged](e.Payload)
+	case TypeVehicleOutOfService:
+		return decode[VehicleOutOfService](e.Payload)
+	}
+	return nil, fmt.Errorf("unknown event type %q", e.Type)
+}
+
+func decode[P Payload](raw bson.Raw) (Payload, error) {
+	var payload P
+	if err := bson.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Payload is the content of a domain event.
+type Payload interface {
+	EventType() Type
+	AggregateID() primitive.ObjectID
+}
+
+// StudentRegistered is emitted once a student has signed up.
+type StudentRegistered struct {
+	StudentID primitive.ObjectID `bson:"student_id" json:"student_id"`
+	Email     string             `bson:"email" json:"email"`
+	FirstName string             `bson:"first_name,omitempty" json:"first_name,omitempty"`
+	LastName  string             `bson:"last_name,omitempty" json:"last_name,omitempty"`
+}
+
+func (StudentRegistered) EventType() Type                   { return TypeStudentRegistered }
+func (p StudentRegistered) AggregateID() primitive.ObjectID { return p.StudentID }
+
+// Lesson describes the lesson a LessonBooked or LessonCancelled event is about.
+type Lesson struct {
+	LessonID     primitive.ObjectID `bson:"lesson_id" json:"lesson_id"`
+	CourseID     primitive.ObjectID `bson:"course_id" json:"course_id"`
+	InstructorID primitive.ObjectID `bson:"instructor_id" json:"instructor_id"`
+	StudentID    primitive.ObjectID `bson:"student_id,omitempty" json:"student_id,omitempty"`
+	VehicleID    primitive.ObjectID `bson:"vehicle_id,omitempty" json:"vehicle_id,omitempty"`
+	Title        string             `bson:"title,omitempty" json:"title,omitempty"`
+	Schedule     time.Time          `bson:"schedule" json:"schedule"`
+}
+
+// LessonBooked is emitted when a student is booked on a lesson, either as it
+// is created or later.
+type LessonBooked struct {
+	Lesson `bson:",inline"`
+}
+
+func (LessonBooked) EventType() Type                   { return TypeLessonBooked }
+func (p LessonBooked) AggregateID() primitive.ObjectID { return p.LessonID }
+
+// LessonCancelled is emitted when a lesson is cancelled, including when its
+// instructor is deleted, and when a booked lesson is deleted.
+type LessonCancelled struct {
+	Lesson `bson:",inline"`
+}
+
+func (LessonCancelled) EventType() Type                   { return TypeLessonCancelled }
+func (p LessonCancelled) AggregateID() primitive.ObjectID { return p.LessonID }
+
+// LessonRescheduled is emitted when a booked lesson is moved to another time.
+// Schedule is the new time.
+type LessonRescheduled struct {
+	Lesson           `bson:",inline"`
+	PreviousSchedule time.Time `bson:"previous_schedule" json:"previous_schedule"`
+}
+
+func (LessonRescheduled) EventType() Type                   { return TypeLessonRescheduled }
+func (p LessonRescheduled) AggregateID() primitive.ObjectID { return p.LessonID }
+
+// LessonChanged is emitted whenever a lesson is created, updated or deleted,
+// alongside the more specific lesson events. Lesson is the lesson after the
+// change, or before it for a deleted lesson; Previous is the lesson before an
+// update, so that whoever it was taken from can be told.
+type LessonChanged struct {
+	Lesson   `bson:",inline"`
+	Status   string  `bson:"status,omitempty" json:"status,omitempty"`
+	Change   Change  `bson:"change" json:"change"`
+	Previous *Lesson `bson:"previous,omitempty" json:"previous,omitempty"`
+}
+
+func (LessonChanged) EventType() Type                   { return TypeLessonChanged }
+func (p LessonChanged) AggregateID() primitive.ObjectID { return p.LessonID }
+
+// AvailabilityChanged is emitted when an instructor's availability slot is
+// created, updated or deleted. The times are those after the change, or
+// before it for a deleted slot.
+type AvailabilityChanged struct {
+	AvailabilityID primitive.ObjectID `bson:"availability_id" json:"availability_id"`
+	InstructorID   primitive.ObjectID `bson:"instructor_id" json:"instructor_id"`
+	StartTime      time.Time          `bson:"start_time" json:"start_time"`
+	EndTime        time.Time          `bson:"end_time" json:"end_time"`
+	Change         Change             `bson:"change" json:"change"`
+	// Deleted is Change == ChangeDeleted, kept for existing consumers
+	Deleted bool `bson:"deleted,omitempty" json:"deleted,omitempty"`
+}
+
+func (AvailabilityChanged) EventType() Type                   { return TypeAvailabilityChanged }
+func (p AvailabilityChanged) AggregateID() primitive.ObjectID { return p.AvailabilityID }
+
+// VehicleOutOfService is emitted when a vehicle is taken out of service.
+type VehicleOutOfService struct {
+	VehicleID    primitive.ObjectID `bson:"vehicle_id" json:"vehicle_id"`
+	LicensePlate string             `bson:"license_plate,omitempty" json:"license_plate,omitempty"`
+}
+
+func (VehicleOutOfService) EventType() Type                   { return TypeVehicleOutOfService }
+func (p VehicleOutOfService) AggregateID() primitive.ObjectID { return p.VehicleID }
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// keepDispatched is how many dispatched events a MemoryOutbox holds on to,
+// standing in for the week MongoOutbox keeps them.
+const keepDispatched = 1000
+
+// MemoryOutbox keeps events in memory with the same semantics as MongoOutbox,
+// except that Transaction cannot roll anything back and only the last
+// keepDispatched dispatched events are kept. It is meant for tests and local
+// development.
+type MemoryOutbox struct {
+	mu             sync.Mutex
+	records        []*record
+	dispatched     int
+	keepDispatched int
+}
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{keepDispatched: keepDispatched}
+}
+
+func (o *MemoryOutbox) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (o *MemoryOutbox) Emit(ctx context.Context, payloads ...Payload) error {
+	events, err := newEvents(ctx, time.Now(), payloads)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, event := range events {
+		o.records = append(o.records, &record{Event: event, Status: statusPending, NextAttemptAt: event.OccurredAt})
+	}
+	return nil
+}
+
+func (o *MemoryOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var events []Event
+	for _, r := range o.records {
+		if len(events) == limit {
+			break
+		}
+		if r.Status != statusPending || r.NextAttemptAt.After(now) || r.LockedUntil.After(now) {
+			continue
+		}
+		r.LockedUntil = now.Add(lease)
+		events = append(events, r.Event)
+	}
+	return events, nil
+}
+
+func (o *MemoryOutbox) MarkDispatched(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return o.update(id, func(r *record) {
+		if r.Status == statusDispatched {
+			return
+		}
+		r.Status = statusDispatched
+		r.DispatchedAt = at
+		o.dispatched++
+		if o.dispatched > o.keepDispatched {
+			o.dropOldestDispatched()
+		}
+	})
+}
+
+// dropOldestDispatched forgets the dispatched event that was emitted first.
+func (o *MemoryOutbox) dropOldestDispatched() {
+	for i, r := range o.records {
+		if r.Status == statusDispatched {
+			o.records = append(o.records[:i], o.records[i+1:]...)
+			o.dispatched--
+			return
+		}
+	}
+}
+
+func (o *MemoryOutbox) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string, retryAt time.Time) error {
+	return o.update(id, func(r *record) {
+		r.Attempts++
+		r.LastError = reason
+		r.LockedUntil = time.Time{}
+		if retryAt.IsZero() {
+			r.Status = statusFailed
+		} else {
+			r.NextAttemptAt = retryAt
+		}
+	})
+}
+
+// Events returns the events emitted so far, oldest first, whatever their
+// delivery state, leaving out the dispatched ones that have been dropped.
+func (o *MemoryOutbox) Events() []Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	events := make([]Event, len(o.records))
+	for i, r := range o.records {
+		events[i] = r.Event
+	}
+	return events
+}
+
+func (o *MemoryOutbox) update(id primitive.ObjectID, apply func(r *record)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, r := range o.records {
+		if r.ID == id {
+			apply(r)
+			return nil
+		}
+	}
+	return apperr.ResourceNotFound("event")
+}
@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// record is an Event with its delivery state, as stored in the outbox.
+type record struct {
+	Event         `bson:",inline"`
+	Status        string    `bson:"status"`
+	NextAttemptAt time.Time `bson:"next_attempt_at"`
+	LockedUntil   time.Time `bson:"locked_until"`
+	DispatchedAt  time.Time `bson:"dispatched_at,omitempty"`
+	LastError     string    `bson:"last_error,omitempty"`
+}
+
+// MongoOutbox stores events in the outbox collection. Transactions need a
+// replica set or a sharded cluster; on a standalone server Transaction runs
+// without one and logs a warning.
+type MongoOutbox struct {
+	db     *mongo.Database
+	coll   *mongo.Collection
+	logger *slog.Logger
+
+	mu           sync.Mutex
+	checked      bool
+	transactions bool
+}
+
+func NewMongoOutbox(db *mongo.Database, logger *slog.Logger) *MongoOutbox {
+	return &MongoOutbox{
+		db:     db,
+		coll:   db.Collection("outbox"),
+		logger: logger,
+	}
+}
+
+func (o *MongoOutbox) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil || !o.supportsTransactions(ctx) {
+		return fn(ctx)
+	}
+	session, err := o.db.Client().StartSession()
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	defer session.EndSession(context.WithoutCancel(ctx))
+
+	// Not session.WithTransaction: it retries fn, which may have side effects
+	// outside Mongo such as creating a Keycloak user
+	return mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+		if err := sc.StartTransaction(); err != nil {
+			return apperr.Internal(err)
+		}
+		if err := fn(sc); err != nil {
+			if abortErr := sc.AbortTransaction(context.WithoutCancel(sc)); abortErr != nil {
+				o.logger.ErrorContext(sc, "failed to abort transaction", slog.Any("error", abortErr))
+			}
+			return err
+		}
+		if err := sc.CommitTransaction(sc); err != nil {
+			return apperr.FromMongo(err, "event")
+		}
+		return nil
+	})
+}
+
+// supportsTransactions asks the server once whether it is part of a replica
+// set or a sharded cluster.
+func (o *MongoOutbox) supportsTransactions(ctx context.Context) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.checked {
+		return o.transactions
+	}
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := o.db.RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		// Asked again next time; whatever fn does is likely to fail as well
+		return false
+	}
+	o.checked = true
+	o.transactions = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !o.transactions {
+		o.logger.WarnContext(ctx, "MongoDB does not support transactions; events are stored separately from the changes that cause them")
+	}
+	return o.transactions
+}
+
+func (o *MongoOutbox) Emit(ctx context.Context, payloads ...Payload) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	events, err := newEvents(ctx, time.Now(), payloads)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	docs := make([]any, len(events))
+	for i, event := range events {
+		docs[i] = record{Event: event, Status: statusPending, NextAttemptAt: event.OccurredAt}
+	}
+	if _, err := o.coll.InsertMany(ctx, docs); err != nil {
+		return apperr.FromMongo(err, "event")
+	}
+	return nil
+}
+
+func (o *MongoOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Event, error) {
+	filter := bson.M{
+		"status":          statusPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"locked_until":    bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}}).SetReturnDocument(options.After)
+
+	// One at a time, so that each event is claimed by a single instance
+	var events []Event
+	for len(events) < limit {
+		var claimed record
+		err := o.coll.FindOneAndUpdate(ctx, filter, update, opts).Decode(&claimed)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return events, apperr.FromMongo(err, "event")
+		}
+		events = append(events, claimed.Event)
+	}
+	return events, nil
+}
+
+func (o *MongoOutbox) MarkDispatched(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{"status": statusDispatched, "dispatched_at": at}}
+	return o.update(ctx, id, update)
+}
+
+func (o *MongoOutbox) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string, retryAt time.Time) error {
+	set := bson.M{"last_error": reason, "locked_until": time.Time{}}
+	if retryAt.IsZero() {
+		set["status"] = statusFailed
+	} else {
+		set["next_attempt_at"] = retryAt
+	}
+	return o.update(ctx, id, bson.M{"$set": set, "$inc": bson.M{"attempts": 1}})
+}
+
+func (o *MongoOutbox) update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := o.coll.UpdateByID(ctx, id, update)
+	if err != nil {
+		return apperr.FromMongo(err, "event")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.ResourceNotFound("event")
+	}
+	return nil
+}
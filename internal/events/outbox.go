@@ -0,0 +1,70 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Emitter is what the domain services emit their events through.
+type Emitter interface {
+	// Transaction runs fn so that the writes made with the context it is
+	// given, including emitted events, are committed together or not at all.
+	// A Transaction inside another one joins it.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// Emit stores the events in the outbox. Called outside a Transaction, the
+	// events are stored whether or not the change that caused them is.
+	Emit(ctx context.Context, payloads ...Payload) error
+}
+
+// Outbox holds emitted events until the Dispatcher has delivered them.
+type Outbox interface {
+	Emitter
+	// Claim returns up to limit events that are due for delivery at now, oldest
+	// first, and hides them from other claims for lease.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Event, error)
+	MarkDispatched(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// MarkFailed records a failed delivery. The event is claimed again from
+	// retryAt, or never if retryAt is zero.
+	MarkFailed(ctx context.Context, id primitive.ObjectID, reason string, retryAt time.Time) error
+}
+
+// Delivery states of the events in an outbox.
+const (
+	statusPending    = "pending"
+	statusDispatched = "dispatched"
+	statusFailed     = "failed"
+)
+
+func newEvents(ctx context.Context, now time.Time, payloads []Payload) ([]Event, error) {
+	events := make([]Event, len(payloads))
+	for i, payload := range payloads {
+		raw, err := bson.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = Event{
+			ID:          primitive.NewObjectID(),
+			Type:        payload.EventType(),
+			AggregateID: payload.AggregateID(),
+			OccurredAt:  now,
+			RequestID:   logging.RequestID(ctx),
+			Payload:     raw,
+		}
+	}
+	return events, nil
+}
+
+type discard struct{}
+
+func (discard) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (discard) Emit(context.Context, ...Payload) error { return nil }
+
+// Discard is an Emitter that drops every event, for tests.
+var Discard Emitter = discard{}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryOutbox(t *testing.T) {
+	testOutbox(t, func(t *testing.T) Outbox {
+		return NewMemoryOutbox()
+	})
+}
+
+func TestMemoryOutboxDropsOldDispatchedEvents(t *testing.T) {
+	ctx := context.Background()
+	outbox := NewMemoryOutbox()
+	outbox.keepDispatched = 2
+	for range 4 {
+		outbox.Emit(ctx, StudentRegistered{StudentID: primitive.NewObjectID()})
+	}
+	emitted := outbox.Events()
+	for _, event := range emitted[:3] {
+		if err := outbox.MarkDispatched(ctx, event.ID, time.Now()); err != nil {
+			t.Fatalf("MarkDispatched failed: %v", err)
+		}
+	}
+	// The oldest dispatched event is gone; the pending one stays
+	if events := outbox.Events(); len(events) != 3 || events[0].ID != emitted[1].ID || events[2].ID != emitted[3].ID {
+		t.Fatalf("Expected the two newest dispatched events and the pending one but got %+v", events)
+	}
+}
+
+func TestMongoOutbox(t *testing.T) {
+	testOutbox(t, func(t *testing.T) Outbox {
+		return NewMongoOutbox(mongotest.Database(t), logging.Discard())
+	})
+}
+
+// testOutbox is the contract every Outbox must satisfy.
+func testOutbox(t *testing.T, newOutbox func(t *testing.T) Outbox) {
+	ctx := logging.WithRequestID(context.Background(), "req-1")
+	studentID := primitive.NewObjectID()
+	registered := StudentRegistered{StudentID: studentID, Email: "john@example.com"}
+	// Mongo stores times with millisecond precision
+	later := func(d time.Duration) time.Time { return time.Now().Add(d).Truncate(time.Millisecond) }
+
+	t.Run("emit and claim", func(t *testing.T) {
+		outbox := newOutbox(t)
+		vehicle := VehicleOutOfService{VehicleID: primitive.NewObjectID()}
+		if err := outbox.Emit(ctx, registered, vehicle); err != nil {
+			t.Fatalf("Emit failed: %v", err)
+		}
+
+		claimed, err := outbox.Claim(ctx, later(time.Second), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("Claim failed: %v", err)
+		}
+		if len(claimed) != 2 || claimed[0].Type != TypeStudentRegistered || claimed[1].Type != TypeVehicleOutOfService {
+			t.Fatalf("Expected both events oldest first but got %+v", claimed)
+		}
+		event := claimed[0]
+		if event.ID.IsZero() || event.AggregateID != studentID || event.RequestID != "req-1" || event.OccurredAt.IsZero() {
+			t.Fatalf("Unexpected event %+v", event)
+		}
+		var payload StudentRegistered
+		if err := event.Decode(&payload); err != nil || payload != registered {
+			t.Fatalf("Expected payload %+v but got %+v (%v)", registered, payload, err)
+		}
+		if decoded, err := claimed[1].DecodePayload(); err != nil || decoded != vehicle {
+			t.Fatalf("Expected payload %+v but got %+v (%v)", vehicle, decoded, err)
+		}
+	})
+
+	t.Run("claims are leased", func(t *testing.T) {
+		outbox := newOutbox(t)
+		outbox.Emit(ctx, registered)
+		now := later(time.Second)
+		if claimed, _ := outbox.Claim(ctx, now, time.Minute, 10); len(claimed) != 1 {
+			t.Fatalf("Expected one event but got %+v", claimed)
+		}
+		if claimed, _ := outbox.Claim(ctx, now.Add(time.Second), time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected the leased event to be hidden but got %+v", claimed)
+		}
+		if claimed, _ := outbox.Claim(ctx, now.Add(2*time.Minute), time.Minute, 10); len(claimed) != 1 {
+			t.Fatalf("Expected the event back once the lease expired but got %+v", claimed)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		outbox := newOutbox(t)
+		outbox.Emit(ctx, registered, registered, registered)
+		if claimed, _ := outbox.Claim(ctx, later(time.Second), time.Minute, 2); len(claimed) != 2 {
+			t.Fatalf("Expected two events but got %+v", claimed)
+		}
+	})
+
+	t.Run("dispatched events are not claimed again", func(t *testing.T) {
+		outbox := newOutbox(t)
+		outbox.Emit(ctx, registered)
+		claimed, _ := outbox.Claim(ctx, later(time.Second), time.Minute, 10)
+		if err := outbox.MarkDispatched(ctx, claimed[0].ID, time.Now()); err != nil {
+			t.Fatalf("MarkDispatched failed: %v", err)
+		}
+		if claimed, _ := outbox.Claim(ctx, later(time.Hour), time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected no events but got %+v", claimed)
+		}
+	})
+
+	t.Run("failed events are retried", func(t *testing.T) {
+		outbox := newOutbox(t)
+		outbox.Emit(ctx, registered)
+		claimed, _ := outbox.Claim(ctx, later(time.Second), time.Minute, 10)
+		retryAt := later(10 * time.Second)
+		if err := outbox.MarkFailed(ctx, claimed[0].ID, "boom", retryAt); err != nil {
+			t.Fatalf("MarkFailed failed: %v", err)
+		}
+		if claimed, _ := outbox.Claim(ctx, retryAt.Add(-time.Second), time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected no events before the retry but got %+v", claimed)
+		}
+		claimed, _ = outbox.Claim(ctx, retryAt, time.Minute, 10)
+		if len(claimed) != 1 || claimed[0].Attempts != 1 {
+			t.Fatalf("Expected the event back with one attempt but got %+v", claimed)
+		}
+
+		if err := outbox.MarkFailed(ctx, claimed[0].ID, "boom", time.Time{}); err != nil {
+			t.Fatalf("MarkFailed failed: %v", err)
+		}
+		if claimed, _ := outbox.Claim(ctx, later(time.Hour), time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected the event to be given up on but got %+v", claimed)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		outbox := newOutbox(t)
+		if err := outbox.MarkDispatched(ctx, primitive.NewObjectID(), time.Now()); err == nil {
+			t.Fatal("Expected an error for an unknown event")
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		outbox := newOutbox(t)
+		err := outbox.Transaction(ctx, func(ctx context.Context) error {
+			return outbox.Transaction(ctx, func(ctx context.Context) error {
+				return outbox.Emit(ctx, registered)
+			})
+		})
+		if err != nil {
+			t.Fatalf("Transaction failed: %v", err)
+		}
+		if claimed, _ := outbox.Claim(ctx, later(time.Second), time.Minute, 10); len(claimed) != 1 {
+			t.Fatalf("Expected the event to be committed but got %+v", claimed)
+		}
+	})
+}
+
+func TestMongoOutboxRollback(t *testing.T) {
+	outbox := NewMongoOutbox(mongotest.Database(t), logging.Discard())
+	ctx := context.Background()
+	if !outbox.supportsTransactions(ctx) {
+		t.Skip("MongoDB is not running as a replica set")
+	}
+
+	failure := errors.New("boom")
+	err := outbox.Transaction(ctx, func(ctx context.Context) error {
+		if err := outbox.Emit(ctx, StudentRegistered{StudentID: primitive.NewObjectID()}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the error from fn but got %v", err)
+	}
+	if claimed, _ := outbox.Claim(ctx, time.Now().Add(time.Second), time.Minute, 10); len(claimed) != 0 {
+		t.Fatalf("Expected the event to be rolled back but got %+v", claimed)
+	}
+}
@@ -0,0 +1,144 @@
+// Package health runs the dependency checks behind the readiness probe.
+// Results are cached for a short while so that frequent probes from an
+// orchestrator do not turn into a steady load on MongoDB and Keycloak.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker reports whether a dependency is usable. Check must respect ctx,
+// which carries the per-check timeout.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// CheckFunc adapts a function to a Checker.
+func CheckFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkFunc{name: name, fn: fn}
+}
+
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  float64   `json:"duration_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type registered struct {
+	checker Checker
+	timeout time.Duration
+
+	// mu serialises runs of the check so that concurrent probes share one
+	// result instead of each calling the dependency.
+	mu   sync.Mutex
+	last *Result
+}
+
+type Registry struct {
+	ttl    time.Duration
+	logger *slog.Logger
+	checks []*registered
+	now    func() time.Time
+}
+
+// NewRegistry returns a registry whose results are reused for ttl.
+func NewRegistry(ttl time.Duration, logger *slog.Logger) *Registry {
+	return &Registry{ttl: ttl, logger: logger, now: time.Now}
+}
+
+// Register adds a check that is abandoned, and counted as failed, after timeout.
+func (r *Registry) Register(c Checker, timeout time.Duration) {
+	r.checks = append(r.checks, &registered{checker: c, timeout: timeout})
+}
+
+// Run runs every check concurrently, reusing results younger than the TTL.
+func (r *Registry) Run(ctx context.Context) Report {
+	results := make([]Result, len(r.checks))
+	var wg sync.WaitGroup
+	for i, check := range r.checks {
+		wg.Add(1)
+		go func(i int, check *registered) {
+			defer wg.Done()
+			results[i] = r.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: results}
+	for _, result := range results {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, check *registered) Result {
+	check.mu.Lock()
+	defer check.mu.Unlock()
+
+	if check.last != nil && r.now().Sub(check.last.CheckedAt) < r.ttl {
+		cached := *check.last
+		cached.Cached = true
+		return cached
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, check.timeout)
+	defer cancel()
+	start := r.now()
+
+	// A check that ignores its context must still not hold up the probe
+	done := make(chan error, 1)
+	go func() { done <- check.checker.Check(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", check.timeout)
+	}
+
+	result := Result{Name: check.checker.Name(), Status: StatusOK, CheckedAt: start, Duration: float64(r.now().Sub(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	if check.last == nil || check.last.Status != result.Status {
+		r.logTransition(ctx, result)
+	}
+	check.last = &result
+	return result
+}
+
+func (r *Registry) logTransition(ctx context.Context, result Result) {
+	if result.Status == StatusOK {
+		r.logger.InfoContext(ctx, "health check passing", slog.String("check", result.Name))
+		return
+	}
+	r.logger.WarnContext(ctx, "health check failing", slog.String("check", result.Name), slog.String("error", result.Error))
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+func TestRunCachesResults(t *testing.T) {
+	var calls atomic.Int32
+	now := time.Now()
+	registry := NewRegistry(5*time.Second, logging.Discard())
+	registry.now = func() time.Time { return now }
+	registry.Register(CheckFunc("db", func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}), time.Second)
+
+	if report := registry.Run(context.Background()); report.Status != StatusOK || report.Checks[0].Cached {
+		t.Fatalf("Expected a fresh passing report but got %+v", report)
+	}
+	if report := registry.Run(context.Background()); !report.Checks[0].Cached {
+		t.Fatalf("Expected the second report to be cached but got %+v", report)
+	}
+	now = now.Add(6 * time.Second)
+	registry.Run(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("Expected the check to run twice but it ran %d times", got)
+	}
+}
+
+func TestRunReportsFailuresAndTimeouts(t *testing.T) {
+	registry := NewRegistry(0, logging.Discard())
+	registry.Register(CheckFunc("ok", func(ctx context.Context) error { return nil }), time.Second)
+	registry.Register(CheckFunc("broken", func(ctx context.Context) error { return errors.New("connection refused") }), time.Second)
+	registry.Register(CheckFunc("hung", func(ctx context.Context) error {
+		time.Sleep(time.Second) // ignores ctx on purpose
+		return nil
+	}), 20*time.Millisecond)
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Expected the hung check to be abandoned but the run took %s", elapsed)
+	}
+	if report.Status != StatusFail {
+		t.Fatalf("Expected the report to fail but got %+v", report)
+	}
+	want := map[string]string{"ok": "", "broken": "connection refused", "hung": "timed out after 20ms"}
+	for _, result := range report.Checks {
+		if result.Error != want[result.Name] {
+			t.Errorf("Check %s: expected error %q but got %q", result.Name, want[result.Name], result.Error)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package identity abstracts the service that owns user credentials and issues
+// access tokens. Production uses Keycloak; the local provider keeps users in
+// memory and signs its own tokens so the API can run without Keycloak.
+package identity
+
+import (
+	"context"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+)
+
+const (
+	RoleAdmin      = "admin"
+	RoleInstructor = "instructor"
+	RoleStudent    = "student"
+)
+
+var (
+	ErrUserExists         = apperr.Conflict("email_exists", "email already exists")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "invalid email or password")
+	ErrInvalidToken       = apperr.Unauthorized("invalid_token", "Invalid or expired token")
+)
+
+// User is an account to be created in the identity provider.
+type User struct {
+	Username  string
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+	Role      string
+}
+
+// Principal is the authenticated caller behind an access token.
+type Principal struct {
+	Subject string
+	Email   string
+	Role    string
+}
+
+type Provider interface {
+	// CreateUser registers the user with the given realm role. It returns
+	// ErrUserExists if the email is already taken.
+	CreateUser(ctx context.Context, user User) error
+	// Login exchanges credentials for an access token. It returns
+	// ErrInvalidCredentials if they are rejected.
+	Login(ctx context.Context, email, password string) (string, error)
+	// Introspect validates an access token. It returns ErrInvalidToken if the
+	// token is expired, revoked or not issued by this provider.
+	Introspect(ctx context.Context, token string) (*Principal, error)
+	Logout(ctx context.Context, refreshToken string) error
+	// DeleteUser deletes the user with the given email, if there is one.
+	DeleteUser(ctx context.Context, email string) error
+	// DeleteUsers deletes every user whose username, email, first or last
+	// name contains search and returns how many were removed.
+	DeleteUsers(ctx context.Context, search string) (int, error)
+}
+
+func unavailable(message string, err error) *apperr.Error {
+	return apperr.Unavailable("identity_unavailable", message, err)
+}
+
+// primaryRole picks the most privileged application role out of roles.
+func primaryRole(roles []string) string {
+	for _, candidate := range []string{RoleAdmin, RoleInstructor, RoleStudent} {
+		for _, role := range roles {
+			if role == candidate {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
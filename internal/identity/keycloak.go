@@ -0,0 +1,367 @@
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/metrics"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type Keycloak struct {
+	cfg     config.KeycloakConfig
+	baseURL string
+	client  *gocloak.GoCloak
+	http    *http.Client
+	logger  *slog.Logger
+	metrics *metrics.Metrics
+}
+
+// NewKeycloak returns a provider backed by Keycloak. cfg.Timeout bounds every
+// call, including the ones made with a caller context that has no deadline.
+func NewKeycloak(cfg config.KeycloakConfig, logger *slog.Logger, m *metrics.Metrics) *Keycloak {
+	// Both clients inject the W3C trace context and get a span per request
+	transport := otelhttp.NewTransport(http.DefaultTransport)
+	client := gocloak.NewClient(cfg.URL)
+	client.RestyClient().SetTransport(transport)
+	return &Keycloak{
+		cfg:     cfg,
+		baseURL: strings.TrimSuffix(cfg.URL, "/"),
+		client:  client,
+		http:    &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		logger:  logger,
+		metrics: m,
+	}
+}
+
+// Ping checks that the service account can log in to the realm.
+func (k *Keycloak) Ping(ctx context.Context) error {
+	_, err := k.clientToken(ctx)
+	return err
+}
+
+// Ready checks that the realm's OpenID configuration is served. Unlike Ping it
+// needs no credentials, so it is cheap enough for a readiness probe.
+func (k *Keycloak) Ready(ctx context.Context) (err error) {
+	ctx, end := k.instrument(ctx, "well_known")
+	defer func() { end(err) }()
+
+	url := fmt.Sprintf("%s/realms/%s/.well-known/openid-configuration", k.baseURL, k.cfg.Realm)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return unavailable("the identity provider is unavailable", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return unavailable("the identity provider is unavailable", fmt.Errorf("well-known endpoint returned %s", resp.Status))
+	}
+	return nil
+}
+
+func (k *Keycloak) CreateUser(ctx context.Context, user User) (err error) {
+	ctx, end := k.instrument(ctx, "create_user")
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+	return k.createUserInKeycloak(ctx, user)
+}
+
+func (k *Keycloak) Login(ctx context.Context, email, password string) (_ string, err error) {
+	ctx, end := k.instrument(ctx, "login")
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	token, err := k.client.Login(ctx, k.cfg.ClientID, k.cfg.ClientSecret, k.cfg.Realm, email, password)
+	if err != nil {
+		return "", keycloakError(fmt.Errorf("login to Keycloak failed: %w", err))
+	}
+	return token.AccessToken, nil
+}
+
+func (k *Keycloak) Introspect(ctx context.Context, token string) (_ *Principal, err error) {
+	ctx, end := k.instrument(ctx, "introspect")
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	result, err := k.client.RetrospectToken(ctx, token, k.cfg.ClientID, k.cfg.ClientSecret, k.cfg.Realm)
+	if err != nil {
+		return nil, unavailable("the identity provider is unavailable", err)
+	}
+	if result.Active == nil || !*result.Active {
+		return nil, ErrInvalidToken
+	}
+
+	// Keycloak has just vouched for the token, so its claims can be read
+	// without verifying the signature again.
+	var claims struct {
+		Email       string `json:"email"`
+		RealmAccess struct {
+			Roles []string `json:"roles"`
+		} `json:"realm_access"`
+		jwt.RegisteredClaims
+	}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{Subject: claims.Subject, Email: claims.Email, Role: primaryRole(claims.RealmAccess.Roles)}, nil
+}
+
+func (k *Keycloak) Logout(ctx context.Context, refreshToken string) (err error) {
+	ctx, end := k.instrument(ctx, "logout")
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	if err := k.client.Logout(ctx, k.cfg.ClientID, k.cfg.ClientSecret, k.cfg.Realm, refreshToken); err != nil {
+		return unavailable("failed to logout from the identity provider", err)
+	}
+	return nil
+}
+
+func (k *Keycloak) DeleteUser(ctx context.Context, email string) (err error) {
+	ctx, end := k.instrument(ctx, "delete_user")
+	defer func() { end(err) }()
+
+	token, err := k.clientToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	exact := true
+	users, err := k.client.GetUsers(ctx, token, k.cfg.Realm, gocloak.GetUsersParams{Email: &email, Exact: &exact})
+	if err != nil {
+		return unavailable("the identity provider is unavailable", fmt.Errorf("failed to find Keycloak user: %w", err))
+	}
+	for _, user := range users {
+		if user.ID == nil {
+			continue
+		}
+		if err := k.client.DeleteUser(ctx, token, k.cfg.Realm, *user.ID); err != nil {
+			return unavailable("the identity provider is unavailable", fmt.Errorf("failed to delete Keycloak user %s: %w", *user.ID, err))
+		}
+	}
+	return nil
+}
+
+func (k *Keycloak) DeleteUsers(ctx context.Context, search string) (_ int, err error) {
+	ctx, end := k.instrument(ctx, "delete_users")
+	defer func() { end(err) }()
+
+	token, err := k.clientToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	max := 1000
+	users, err := k.client.GetUsers(ctx, token, k.cfg.Realm, gocloak.GetUsersParams{Search: &search, Max: &max})
+	if err != nil {
+		return 0, unavailable("the identity provider is unavailable", fmt.Errorf("failed to list Keycloak users: %w", err))
+	}
+
+	deleted := 0
+	for _, user := range users {
+		if user.ID == nil {
+			continue
+		}
+		if err := k.client.DeleteUser(ctx, token, k.cfg.Realm, *user.ID); err != nil {
+			return deleted, unavailable("the identity provider is unavailable", fmt.Errorf("failed to delete Keycloak user %s: %w", *user.ID, err))
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (k *Keycloak) clientToken(ctx context.Context) (_ string, err error) {
+	ctx, end := k.instrument(ctx, "client_login")
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, k.cfg.Timeout)
+	defer cancel()
+
+	token, err := k.client.LoginClient(ctx, k.cfg.ClientID, k.cfg.ClientSecret, k.cfg.Realm)
+	if err != nil {
+		// A rejected service account is a server misconfiguration, never the
+		// caller's fault, so it is not reported as invalid credentials.
+		return "", unavailable("the identity provider is unavailable", fmt.Errorf("client login to Keycloak failed: %w", err))
+	}
+	return token.AccessToken, nil
+}
+
+func (k *Keycloak) createUserInKeycloak(ctx context.Context, user User) error {
+	type credential struct {
+		Type      string `json:"type"`
+		Value     string `json:"value"`
+		Temporary bool   `json:"temporary"`
+	}
+	keycloakUser := struct {
+		Username    string       `json:"username"`
+		FirstName   string       `json:"firstName"`
+		LastName    string       `json:"lastName"`
+		Email       string       `json:"email"`
+		Enabled     bool         `json:"enabled"`
+		Credentials []credential `json:"credentials"`
+	}{
+		Username:    user.Username,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Email:       user.Email,
+		Enabled:     true,
+		Credentials: []credential{{Type: "password", Value: user.Password}},
+	}
+
+	userJSON, err := json.Marshal(keycloakUser)
+	if err != nil {
+		return err
+	}
+
+	token, err := k.clientToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/admin/realms/%s/users", k.baseURL, k.cfg.Realm)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(userJSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return unavailable("the identity provider is unavailable", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return ErrUserExists
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return unavailable("failed to create user in the identity provider",
+			fmt.Errorf("failed to create user in Keycloak: %s", resp.Status))
+	}
+
+	// Keycloak does not return a body on successful user creation, so we use the Location header to get the user ID.
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return unavailable("failed to create user in the identity provider",
+			fmt.Errorf("failed to get user location header from Keycloak response"))
+	}
+	segments := strings.Split(location, "/")
+	userID := segments[len(segments)-1]
+
+	// Realm roles in the create payload are ignored by Keycloak, so the role
+	// has to be mapped in a separate call.
+	if err := k.assignRoleToUser(ctx, token, userID, user.Role); err != nil {
+		return unavailable("failed to assign role in the identity provider",
+			fmt.Errorf("failed to assign role to user: %w", err))
+	}
+	return nil
+}
+
+func (k *Keycloak) assignRoleToUser(ctx context.Context, token, userID, roleName string) (err error) {
+	ctx, end := k.instrument(ctx, "assign_role")
+	defer func() { end(err) }()
+
+	url := fmt.Sprintf("%s/admin/realms/%s/roles/%s", k.baseURL, k.cfg.Realm, roleName)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := k.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get role ID from Keycloak: %s", resp.Status)
+	}
+
+	var role struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&role); err != nil {
+		return fmt.Errorf("failed to decode role response: %w", err)
+	}
+
+	roleMappingJSON, err := json.Marshal([]any{role})
+	if err != nil {
+		return err
+	}
+
+	assignRoleURL := fmt.Sprintf("%s/admin/realms/%s/users/%s/role-mappings/realm", k.baseURL, k.cfg.Realm, userID)
+	req, err = http.NewRequestWithContext(ctx, "POST", assignRoleURL, bytes.NewBuffer(roleMappingJSON))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err = k.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("failed to assign role to user in Keycloak: %s", resp.Status)
+	}
+
+	k.logger.DebugContext(ctx, "assigned Keycloak role", slog.String("role", roleName), slog.String("keycloak_user_id", userID))
+	return nil
+}
+
+// instrument starts a span for a Keycloak operation. The returned function
+// ends it and records the call in the metrics; callers defer it with the
+// error they are about to return.
+func (k *Keycloak) instrument(ctx context.Context, operation string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "keycloak."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, func(err error) {
+		k.metrics.ObserveKeycloak(operation, start, err)
+		tracing.End(span, &err)
+	}
+}
+
+// keycloakError maps a failed user login onto the shared error model. A 401
+// from the token endpoint means the credentials were rejected; anything else
+// means Keycloak could not serve the request.
+func keycloakError(err error) error {
+	var apiErr *gocloak.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized {
+		return ErrInvalidCredentials.Wrap(err)
+	}
+	return unavailable("the identity provider is unavailable", err)
+}
@@ -0,0 +1,132 @@
+package identity
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const localIssuer = "drivefluency-local"
+
+// Local is a self-contained identity provider for development and tests. Users
+// live in memory and tokens are HS256 JWTs signed with a shared secret, so
+// every token is invalidated when the process restarts unless the same secret
+// is reused.
+type Local struct {
+	secret []byte
+	expiry time.Duration
+
+	mu    sync.RWMutex
+	users map[string]localUser // keyed by lower-cased email
+}
+
+type localUser struct {
+	id           string
+	user         User
+	passwordHash []byte
+}
+
+type localClaims struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+func NewLocal(secret []byte, expiry time.Duration) *Local {
+	return &Local{secret: secret, expiry: expiry, users: map[string]localUser{}}
+}
+
+func (l *Local) CreateUser(ctx context.Context, user User) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	user.Password = ""
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := strings.ToLower(user.Email)
+	if _, exists := l.users[key]; exists {
+		return ErrUserExists
+	}
+	l.users[key] = localUser{id: primitive.NewObjectID().Hex(), user: user, passwordHash: hash}
+	return nil
+}
+
+func (l *Local) Login(ctx context.Context, email, password string) (string, error) {
+	l.mu.RLock()
+	account, ok := l.users[strings.ToLower(email)]
+	l.mu.RUnlock()
+	if !ok || bcrypt.CompareHashAndPassword(account.passwordHash, []byte(password)) != nil {
+		return "", ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	claims := localClaims{
+		Email: account.user.Email,
+		Role:  account.user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    localIssuer,
+			Subject:   account.id,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(l.expiry)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(l.secret)
+}
+
+func (l *Local) Introspect(ctx context.Context, token string) (*Principal, error) {
+	var claims localClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return l.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || claims.Issuer != localIssuer {
+		return nil, ErrInvalidToken
+	}
+
+	// Like Keycloak, tokens of deleted users stop being accepted.
+	l.mu.RLock()
+	account, ok := l.users[strings.ToLower(claims.Email)]
+	l.mu.RUnlock()
+	if !ok || account.id != claims.Subject {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{Subject: claims.Subject, Email: claims.Email, Role: claims.Role}, nil
+}
+
+// Logout is a no-op: the local provider issues no refresh tokens and access
+// tokens simply expire.
+func (l *Local) Logout(ctx context.Context, refreshToken string) error {
+	return nil
+}
+
+func (l *Local) DeleteUser(ctx context.Context, email string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.users, strings.ToLower(email))
+	return nil
+}
+
+func (l *Local) DeleteUsers(ctx context.Context, search string) (int, error) {
+	search = strings.ToLower(search)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deleted := 0
+	for key, account := range l.users {
+		u := account.user
+		for _, field := range []string{u.Username, u.Email, u.FirstName, u.LastName} {
+			if strings.Contains(strings.ToLower(field), search) {
+				delete(l.users, key)
+				deleted++
+				break
+			}
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,62 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLocalProvider(t *testing.T) {
+	ctx := context.Background()
+	idp := NewLocal([]byte("test-secret"), time.Hour)
+	user := User{Username: "jdoe", Email: "John@Example.com", Password: "secret", Role: RoleInstructor}
+
+	if err := idp.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if err := idp.CreateUser(ctx, user); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("Expected ErrUserExists but got %v", err)
+	}
+
+	if _, err := idp.Login(ctx, "john@example.com", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials but got %v", err)
+	}
+	token, err := idp.Login(ctx, "john@example.com", "secret")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	principal, err := idp.Introspect(ctx, token)
+	if err != nil {
+		t.Fatalf("Introspect failed: %v", err)
+	}
+	if principal.Role != RoleInstructor || principal.Email != user.Email || principal.Subject == "" {
+		t.Fatalf("Unexpected principal %+v", principal)
+	}
+
+	other := NewLocal([]byte("another-secret"), time.Hour)
+	if _, err := other.Introspect(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Expected a token signed with another secret to be rejected but got %v", err)
+	}
+
+	if err := idp.DeleteUser(ctx, "someone@example.com"); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if n, err := idp.DeleteUsers(ctx, "example.com"); err != nil || n != 1 {
+		t.Fatalf("DeleteUsers returned %d, %v", n, err)
+	}
+	if _, err := idp.Introspect(ctx, token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Expected the token of a deleted user to be rejected but got %v", err)
+	}
+
+	if err := idp.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if err := idp.DeleteUser(ctx, "JOHN@example.com"); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if err := idp.CreateUser(ctx, user); err != nil {
+		t.Fatalf("Expected the email to be free again but got %v", err)
+	}
+}
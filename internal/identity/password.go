@@ -0,0 +1,24 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var tracer = tracing.Tracer("internal/identity")
+
+// HashPassword hashes password with bcrypt. It gets a span of its own because
+// at the default cost it is one of the slowest steps of a registration.
+func HashPassword(ctx context.Context, password string) (_ string, err error) {
+	_, span := tracer.Start(ctx, "bcrypt.GenerateFromPassword")
+	defer tracing.End(span, &err)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", apperr.Internal(err)
+	}
+	return string(hash), nil
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces sensitive values in logs and the audit trail.
+const Redacted = "[REDACTED]"
+
+// sensitiveSuffixes are matched against attribute keys lower-cased and with
+// "_" and "-" removed, so "password", "password_hash", "clientSecret" and
+// "refresh-token" are all caught while "token_expiry" is not.
+var sensitiveSuffixes = []string{"password", "hash", "secret", "token", "authorization", "cookie", "apikey"}
+
+var (
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bcryptPattern = regexp.MustCompile(`\$2[abxy]?\$\d{2}\$[./A-Za-z0-9]{53}`)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]+`)
+)
+
+// redact is the ReplaceAttr hook of every handler built by New. Values under
+// sensitive keys are dropped outright; any other string, including the
+// message and error texts, is scrubbed of anything that looks like a token or
+// a password hash.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if SensitiveKey(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Scrub(err.Error()))
+		}
+	}
+	return a
+}
+
+// SensitiveKey reports whether values stored under key must never be written out.
+func SensitiveKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scrub replaces JWTs, bearer credentials and bcrypt hashes in s.
+func Scrub(s string) string {
+	s = bearerPattern.ReplaceAllString(s, "${1}"+Redacted)
+	s = jwtPattern.ReplaceAllString(s, Redacted)
+	return bcryptPattern.ReplaceAllString(s, Redacted)
+}
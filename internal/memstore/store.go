@@ -0,0 +1,168 @@
+// Package memstore is a small thread-safe document store used by the
+// in-memory repositories. Documents are round-tripped through BSON so that
+// they behave like the MongoDB ones: times are truncated to milliseconds and
+// updates follow $set semantics, where fields tagged omitempty that are left
+// at their zero value do not overwrite the stored value.
+package memstore
+
+import (
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Conflicts reports whether two documents violate a unique constraint.
+type Conflicts[T any] func(a, b T) bool
+
+type Store[T any] struct {
+	mu        sync.RWMutex
+	docs      map[primitive.ObjectID]T
+	conflicts Conflicts[T]
+}
+
+// New returns an empty store. conflicts may be nil when there is no unique constraint.
+func New[T any](conflicts Conflicts[T]) *Store[T] {
+	return &Store[T]{docs: map[primitive.ObjectID]T{}, conflicts: conflicts}
+}
+
+// Insert stores doc under id. It returns false if doc conflicts with a stored document.
+func (s *Store[T]) Insert(id primitive.ObjectID, doc T) (bool, error) {
+	stored, err := roundTrip(doc)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conflictsWith(primitive.NilObjectID, stored) {
+		return false, nil
+	}
+	s.docs[id] = stored
+	return true, nil
+}
+
+func (s *Store[T]) Get(id primitive.ObjectID) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	doc, ok := s.docs[id]
+	return doc, ok
+}
+
+// Find returns the documents matching match in insertion order. ObjectIDs
+// start with a timestamp and a counter, so sorting by ID gives that order.
+func (s *Store[T]) Find(match func(T) bool) []T {
+	s.mu.RLock()
+	ids := make([]primitive.ObjectID, 0, len(s.docs))
+	for id, doc := range s.docs {
+		if match == nil || match(doc) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].Hex() < ids[j].Hex() })
+	docs := make([]T, len(ids))
+	for i, id := range ids {
+		docs[i] = s.docs[id]
+	}
+	s.mu.RUnlock()
+
+	if len(docs) == 0 {
+		return nil
+	}
+	return docs
+}
+
+// FindOne returns the first document matching match in insertion order.
+func (s *Store[T]) FindOne(match func(T) bool) (T, bool) {
+	docs := s.Find(match)
+	if len(docs) == 0 {
+		var zero T
+		return zero, false
+	}
+	return docs[0], true
+}
+
+// Set merges update into the document stored under id using $set semantics.
+// found is false if there is no such document; ok is false if the merged
+// document would conflict with another stored document.
+func (s *Store[T]) Set(id primitive.ObjectID, update T) (found, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, found := s.docs[id]
+	if !found {
+		return false, false, nil
+	}
+	merged, err := merge(current, update)
+	if err != nil {
+		return true, false, err
+	}
+	if s.conflictsWith(id, merged) {
+		return true, false, nil
+	}
+	s.docs[id] = merged
+	return true, true, nil
+}
+
+// Delete removes the document stored under id and reports whether it existed.
+func (s *Store[T]) Delete(id primitive.ObjectID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.docs[id]; !ok {
+		return false
+	}
+	delete(s.docs, id)
+	return true
+}
+
+func (s *Store[T]) conflictsWith(id primitive.ObjectID, doc T) bool {
+	if s.conflicts == nil {
+		return false
+	}
+	for otherID, other := range s.docs {
+		if otherID != id && s.conflicts(doc, other) {
+			return true
+		}
+	}
+	return false
+}
+
+func roundTrip[T any](doc T) (T, error) {
+	var out T
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return out, err
+	}
+	err = bson.Unmarshal(data, &out)
+	return out, err
+}
+
+func merge[T any](current, update T) (T, error) {
+	var out T
+	var currentDoc, updateDoc bson.M
+	if err := unmarshalInto(current, &currentDoc); err != nil {
+		return out, err
+	}
+	if err := unmarshalInto(update, &updateDoc); err != nil {
+		return out, err
+	}
+	for key, value := range updateDoc {
+		currentDoc[key] = value
+	}
+
+	data, err := bson.Marshal(currentDoc)
+	if err != nil {
+		return out, err
+	}
+	err = bson.Unmarshal(data, &out)
+	return out, err
+}
+
+func unmarshalInto(doc any, out *bson.M) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, out)
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// activeWindow is how far back a lesson keeps its student counted as active.
+const activeWindow = 30 * 24 * time.Hour
+
+// Business computes gauges from the lesson store each time it is scraped, so
+// they are always current and cost nothing between scrapes.
+type Business struct {
+	lessons lessons.LessonRepository
+	timeout time.Duration
+	now     func() time.Time
+
+	bookedToday    *prometheus.Desc
+	activeStudents *prometheus.Desc
+}
+
+func NewBusiness(repo lessons.LessonRepository, timeout time.Duration) *Business {
+	return &Business{
+		lessons: repo,
+		timeout: timeout,
+		now:     time.Now,
+		bookedToday: prometheus.NewDesc(namespace+"_lessons_booked_today",
+			"Lessons that are not cancelled and are scheduled for the current UTC day.", nil, nil),
+		activeStudents: prometheus.NewDesc(namespace+"_active_students",
+			"Students with a lesson that is not cancelled in the last 30 days or later.", nil, nil),
+	}
+}
+
+func (b *Business) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.bookedToday
+	ch <- b.activeStudents
+}
+
+func (b *Business) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	now := b.now().UTC()
+	recent, err := b.lessons.ListLessons(ctx, lessons.Filter{From: now.Add(-activeWindow)})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(b.bookedToday, err)
+		ch <- prometheus.NewInvalidMetric(b.activeStudents, err)
+		return
+	}
+
+	dayStart := now.Truncate(24 * time.Hour)
+	dayEnd := dayStart.Add(24 * time.Hour)
+	bookedToday := 0
+	students := map[string]bool{}
+	for _, lesson := range recent {
+		if !lesson.Schedule.Before(dayStart) && lesson.Schedule.Before(dayEnd) {
+			bookedToday++
+		}
+		students[lesson.StudentID.Hex()] = true
+	}
+	ch <- prometheus.MustNewConstMetric(b.bookedToday, prometheus.GaugeValue, float64(bookedToday))
+	ch <- prometheus.MustNewConstMetric(b.activeStudents, prometheus.GaugeValue, float64(len(students)))
+}
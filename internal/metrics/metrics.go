@@ -0,0 +1,119 @@
+// Package metrics exposes Prometheus metrics for HTTP traffic, repository
+// calls, the identity provider and a few business figures. Every collector
+// lives in the registry owned by a Metrics value rather than the global one,
+// so several instances (one per test harness, say) can coexist.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "drivefluency"
+
+// Outcomes recorded for repository and identity provider calls. A rejected
+// call failed for a reason the caller caused (not found, bad credentials);
+// only errors point at a problem on our side.
+const (
+	OutcomeOK       = "ok"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	repoDuration     *prometheus.HistogramVec
+	keycloakRequests *prometheus.CounterVec
+	keycloakDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests handled, by route template and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to handle HTTP requests, by route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_duration_seconds",
+			Help:      "Time taken by repository methods, by repository, method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "method", "outcome"}),
+		keycloakRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "keycloak_requests_total",
+			Help:      "Calls made to Keycloak, by operation and outcome.",
+		}, []string{"operation", "outcome"}),
+		keycloakDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "keycloak_request_duration_seconds",
+			Help:      "Time taken by calls to Keycloak, by operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration, m.repoDuration, m.keycloakRequests, m.keycloakDuration,
+	)
+	return m
+}
+
+// Register adds further collectors, such as the business gauges, to the registry.
+func (m *Metrics) Register(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Handler serves the registry in the Prometheus exposition format. A
+// collector that fails, such as a business gauge whose query timed out, is
+// reported in the response without hiding the remaining metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// ObserveHTTP records a handled request. route must be the route template
+// (such as /api/lessons/:id), never the raw path, to keep cardinality bounded.
+func (m *Metrics) ObserveHTTP(method, route string, status int, elapsed time.Duration) {
+	m.httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// ObserveRepository records a repository method call that started at start.
+func (m *Metrics) ObserveRepository(repository, method string, start time.Time, err error) {
+	m.repoDuration.WithLabelValues(repository, method, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveKeycloak records a call to Keycloak that started at start.
+func (m *Metrics) ObserveKeycloak(operation string, start time.Time, err error) {
+	m.keycloakRequests.WithLabelValues(operation, Outcome(err)).Inc()
+	m.keycloakDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// Outcome classifies err for the outcome label.
+func Outcome(err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case apperr.KindOf(err).Status() < http.StatusInternalServerError:
+		return OutcomeRejected
+	default:
+		return OutcomeError
+	}
+}
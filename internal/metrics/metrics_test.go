@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestOutcome(t *testing.T) {
+	cases := map[error]string{
+		nil:                                     OutcomeOK,
+		apperr.ResourceNotFound("lesson"):       OutcomeRejected,
+		apperr.Unauthorized("bad", "bad"):       OutcomeRejected,
+		apperr.Unavailable("down", "down", nil): OutcomeError,
+		errors.New("boom"):                      OutcomeError,
+	}
+	for err, want := range cases {
+		if got := Outcome(err); got != want {
+			t.Errorf("Outcome(%v) = %s, expected %s", err, got, want)
+		}
+	}
+}
+
+func TestBusinessGauges(t *testing.T) {
+	now := time.Date(2030, 3, 4, 15, 0, 0, 0, time.UTC)
+	repo := lessons.NewMemoryLessonRepository()
+	alice, bob, carol := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	for _, lesson := range []lessons.Lesson{
+		{StudentID: alice, Schedule: now.Add(-6 * time.Hour), Status: lessons.StatusScheduled},
+		{StudentID: alice, Schedule: now.Add(2 * time.Hour), Status: lessons.StatusScheduled},
+		{StudentID: bob, Schedule: now.Add(48 * time.Hour), Status: lessons.StatusScheduled},
+		{StudentID: carol, Schedule: now.Add(time.Hour), Status: lessons.StatusCancelled},
+		{StudentID: carol, Schedule: now.Add(-60 * 24 * time.Hour), Status: lessons.StatusScheduled},
+	} {
+		if _, err := repo.CreateLesson(context.Background(), lesson); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	business := NewBusiness(repo, time.Second)
+	business.now = func() time.Time { return now }
+
+	expected := `
+# HELP drivefluency_active_students Students with a lesson that is not cancelled in the last 30 days or later.
+# TYPE drivefluency_active_students gauge
+drivefluency_active_students 2
+# HELP drivefluency_lessons_booked_today Lessons that are not cancelled and are scheduled for the current UTC day.
+# TYPE drivefluency_lessons_booked_today gauge
+drivefluency_lessons_booked_today 2
+`
+	if err := testutil.CollectAndCompare(business, strings.NewReader(expected)); err != nil {
+		t.Fatal(err)
+	}
+}
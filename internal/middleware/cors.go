@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+)
+
+var errPreflightRejected = apperr.Forbidden("cors_rejected", "cross-origin request not allowed")
+
+// originPattern matches an allowed origin. A wildcard pattern such as
+// https://*.example.com is stored as prefix "https://" and suffix
+// ".example.com", and matches any subdomain but not example.com itself.
+type originPattern struct {
+	prefix, suffix string
+	wildcard       bool
+}
+
+func (p originPattern) matches(origin string) bool {
+	if !p.wildcard {
+		return origin == p.prefix
+	}
+	if len(origin) <= len(p.prefix)+len(p.suffix) || !strings.HasPrefix(origin, p.prefix) || !strings.HasSuffix(origin, p.suffix) {
+		return false
+	}
+	// Only host name characters may stand in for the wildcard, so that
+	// https://evil.com/.example.com or a different port cannot sneak through
+	sub := origin[len(p.prefix) : len(origin)-len(p.suffix)]
+	return strings.IndexFunc(sub, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.')
+	}) < 0
+}
+
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          []originPattern
+	methods          []string
+	anyHeader        bool
+	headers          []string
+	allowMethods     string
+	allowHeaders     string
+	exposeHeaders    string
+	maxAge           string
+	allowCredentials bool
+}
+
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		allowHeaders:     strings.Join(cfg.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(cfg.ExposedHeaders, ", "),
+		allowCredentials: cfg.AllowCredentials,
+	}
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge.Seconds()))
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			p.anyOrigin = true
+		} else if prefix, suffix, ok := strings.Cut(origin, "://*."); ok {
+			p.origins = append(p.origins, originPattern{prefix: prefix + "://", suffix: "." + suffix, wildcard: true})
+		} else {
+			p.origins = append(p.origins, originPattern{prefix: origin})
+		}
+	}
+	for _, method := range cfg.AllowedMethods {
+		p.methods = append(p.methods, strings.ToUpper(method))
+	}
+	p.allowMethods = strings.Join(p.methods, ", ")
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			p.anyHeader = true
+		}
+		p.headers = append(p.headers, strings.ToLower(header))
+	}
+	return p
+}
+
+func (p *corsPolicy) allowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	return slices.ContainsFunc(p.origins, func(pattern originPattern) bool { return pattern.matches(origin) })
+}
+
+// allowsHeaders checks an Access-Control-Request-Headers value.
+func (p *corsPolicy) allowsHeaders(requested string) bool {
+	if p.anyHeader {
+		return true
+	}
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !slices.Contains(p.headers, header) {
+			return false
+		}
+	}
+	return true
+}
+
+// CORS applies the configured cross-origin policy. Preflight requests are
+// answered here with 204, or 403 when the origin, method or headers are not
+// allowed. Other requests go through either way, but only allowed origins get
+// the headers that let a browser read the response.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	p := newCORSPolicy(cfg)
+	// The response only depends on the origin when it is not the same for all of them
+	varyByOrigin := !p.anyOrigin || p.allowCredentials
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Caches must not hand a response made for one origin to another,
+		// nor one made without an Origin header to a cross-origin caller
+		if varyByOrigin {
+			header.Add("Vary", "Origin")
+		}
+		if preflight {
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !p.allowsOrigin(origin) {
+			if preflight {
+				c.Error(errPreflightRejected)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if p.anyOrigin && !p.allowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.allowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if p.exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", p.exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		requestedHeaders := c.GetHeader("Access-Control-Request-Headers")
+		if !slices.Contains(p.methods, c.GetHeader("Access-Control-Request-Method")) || !p.allowsHeaders(requestedHeaders) {
+			header.Del("Access-Control-Allow-Origin")
+			header.Del("Access-Control-Allow-Credentials")
+			c.Error(errPreflightRejected)
+			c.Abort()
+			return
+		}
+		header.Set("Access-Control-Allow-Methods", p.allowMethods)
+		if p.anyHeader {
+			// Credentialed requests do not honour a literal "*", so echo the request
+			if requestedHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", requestedHeaders)
+			}
+		} else if p.allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", p.allowHeaders)
+		}
+		if p.maxAge != "" {
+			header.Set("Access-Control-Max-Age", p.maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+func corsRouter(cfg config.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(logging.Discard()), CORS(cfg))
+	r.GET("/courses", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func corsRequest(r http.Handler, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/courses", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+var allowList = config.CORSConfig{
+	AllowedOrigins: []string{"https://app.example.com", "https://*.drivefluency.com"},
+	AllowedMethods: []string{"GET", "POST", "PATCH"},
+	AllowedHeaders: []string{"Authorization", "Content-Type"},
+	ExposedHeaders: []string{"X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := corsRouter(allowList)
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers string
+		allowed bool
+	}{
+		{"exact origin", "https://app.example.com", "PATCH", "authorization, content-type", true},
+		{"wildcard subdomain", "https://school.drivefluency.com", "POST", "", true},
+		{"nested subdomain", "https://eu.school.drivefluency.com", "GET", "Content-Type", true},
+		{"wildcard does not match the apex", "https://drivefluency.com", "GET", "", false},
+		{"wildcard does not match another port", "https://school.drivefluency.com:8443", "GET", "", false},
+		{"suffix trick", "https://evil.com/.drivefluency.com", "GET", "", false},
+		{"scheme must match", "http://app.example.com", "GET", "", false},
+		{"unknown origin", "https://evil.example.org", "GET", "", false},
+		{"method not allowed", "https://app.example.com", "DELETE", "", false},
+		{"header not allowed", "https://app.example.com", "GET", "X-Custom", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := corsRequest(r, http.MethodOptions, tt.origin, map[string]string{
+				"Access-Control-Request-Method":  tt.method,
+				"Access-Control-Request-Headers": tt.headers,
+			})
+			if !tt.allowed {
+				if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+					t.Fatalf("Expected a 403 without CORS headers but got %d %v", w.Code, w.Header())
+				}
+				return
+			}
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204 but got %d", w.Code)
+			}
+			want := map[string]string{
+				"Access-Control-Allow-Origin":  tt.origin,
+				"Access-Control-Allow-Methods": "GET, POST, PATCH",
+				"Access-Control-Allow-Headers": "Authorization, Content-Type",
+				"Access-Control-Max-Age":       "600",
+			}
+			for key, value := range want {
+				if got := w.Header().Get(key); got != value {
+					t.Errorf("Expected %s: %s but got %q", key, value, got)
+				}
+			}
+			if got := w.Header().Values("Vary"); len(got) != 3 || got[0] != "Origin" {
+				t.Errorf("Expected Vary on the origin and the request method and headers but got %v", got)
+			}
+			if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+				t.Error("Expected no credentials header")
+			}
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	r := corsRouter(allowList)
+
+	w := corsRequest(r, http.MethodGet, "https://app.example.com", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+		t.Fatalf("Expected the origin to be allowed but got %d %v", w.Code, w.Header())
+	}
+
+	// Unknown origins are still served, but the browser is not allowed to read the response
+	w = corsRequest(r, http.MethodGet, "https://evil.example.org", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("Expected no CORS headers but got %d %v", w.Code, w.Header())
+	}
+
+	// Same-origin responses can be cached, so they must vary on Origin too
+	w = corsRequest(r, http.MethodGet, "", nil)
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("Expected Vary: Origin but got %q", got)
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := allowList
+	cfg.AllowedOrigins = []string{"*"}
+	w := corsRequest(corsRouter(cfg), http.MethodGet, "https://anything.example", nil)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Expected Access-Control-Allow-Origin: * but got %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "" {
+		t.Fatalf("Expected no Vary header when every origin gets the same answer but got %q", got)
+	}
+
+	cfg.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.AllowCredentials = true
+	w = corsRequest(corsRouter(cfg), http.MethodGet, "https://app.example.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("Expected the origin to be echoed with credentials but got %v", w.Header())
+	}
+}
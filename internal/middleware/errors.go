@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+)
+
+const problemTypePrefix = "urn:drivefluency:problem:"
+
+// ErrorHandler renders the last error attached with c.Error as an RFC 7807
+// problem document. Internal and upstream failures are logged and their cause
+// is never sent to the client.
+func ErrorHandler(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := apperr.From(c.Errors.Last().Err)
+		status := err.Kind.Status()
+		if status >= http.StatusInternalServerError {
+			logger.ErrorContext(c.Request.Context(), "request failed",
+				slog.String("method", c.Request.Method), slog.String("path", loggedPath(c)), slog.Any("error", err))
+		}
+
+		problem := gin.H{}
+		for key, value := range err.Details {
+			problem[key] = value
+		}
+		problem["type"] = problemTypePrefix + err.Code
+		problem["title"] = http.StatusText(status)
+		problem["status"] = status
+		problem["detail"] = err.Message
+		problem["code"] = err.Code
+		problem["instance"] = c.Request.URL.Path
+
+		c.Header("Content-Type", "application/problem+json")
+		c.JSON(status, problem)
+	}
+}
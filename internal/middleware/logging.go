@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Incoming IDs end up in logs and response headers, so anything unusual is
+// replaced rather than trusted.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID propagates the caller's X-Request-ID, or assigns a new one, and
+// stores it in the request context and the response headers.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// ClientIP adds the client's IP to the request context so that audit entries
+// recorded while handling the request carry it. It relies on the trusted
+// proxies configured on the engine, like c.ClientIP.
+func ClientIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(audit.WithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+// SecretPath marks a route whose path carries a credential, such as a
+// calendar feed token, so that logs show the route instead of the path.
+func SecretPath() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(secretPathKey, true)
+		c.Next()
+	}
+}
+
+const secretPathKey = "secret_path"
+
+// loggedPath is the request's path, unless SecretPath keeps it out of logs.
+func loggedPath(c *gin.Context) string {
+	if c.GetBool(secretPathKey) {
+		return c.FullPath()
+	}
+	return c.Request.URL.Path
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs one record per request once it has been handled. Query
+// strings are left out because they may carry credentials.
+func AccessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", loggedPath(c)),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Recovery turns a panic into an internal error for ErrorHandler to render,
+// logging the stack trace. It must be registered after ErrorHandler.
+func Recovery(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				logger.ErrorContext(c.Request.Context(), "panic while handling request",
+					slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+				c.Error(apperr.Internal(fmt.Errorf("panic: %v", recovered)))
+				c.Abort()
+			}
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/ratelimit"
+)
+
+// RateLimit takes a token from the client IP's bucket for every request and
+// answers 429 once it is empty. The RateLimit-* headers are sent either way.
+// When the store fails the request goes through, so that a broken limiter
+// cannot take the endpoints down with it.
+func RateLimit(limiter *ratelimit.Limiter, logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision, err := limiter.Allow(c.Request.Context(), c.ClientIP())
+		decision.WriteHeaders(c.Writer.Header())
+		if err != nil {
+			if apperr.KindOf(err) == apperr.KindTooManyRequests {
+				logger.InfoContext(c.Request.Context(), "request rate limited", slog.String("ip", c.ClientIP()), slog.String("path", c.Request.URL.Path))
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			logger.WarnContext(c.Request.Context(), "failed to rate limit request", slog.Any("error", err))
+		}
+		c.Next()
+	}
+}
@@ -1,10 +1,10 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
 )
 
 type Role string
@@ -15,6 +15,8 @@ const (
 	Student    Role = "student"
 )
 
+var errForbidden = apperr.Forbidden("forbidden", "you are not allowed to access this resource")
+
 var rolePermissions = map[Role][]string{
 	Admin:      {"*"},
 	Instructor: {"/courses", "/lessons", "/availability"},
@@ -39,13 +41,15 @@ func RBACMiddleware(roles ...Role) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, ok := c.Get("role")
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Error(errForbidden)
+			c.Abort()
 			return
 		}
 
 		role, ok := userRole.(Role)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			c.Error(errForbidden)
+			c.Abort()
 			return
 		}
 
@@ -56,6 +60,7 @@ func RBACMiddleware(roles ...Role) gin.HandlerFunc {
 			}
 		}
 
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		c.Error(errForbidden)
+		c.Abort()
 	}
 }
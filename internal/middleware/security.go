@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+)
+
+var errBodyTooLarge = apperr.TooLarge("body_too_large", "request body too large")
+
+const httpsKey = "https"
+
+// SecurityHeaders sets the headers that keep browsers from sniffing, framing
+// or leaking the API's responses. Strict-Transport-Security is only sent on
+// HTTPS, whether terminated here or by one of the trusted proxies in front;
+// X-Forwarded-Proto from anyone else is ignored. IsHTTPS tells handlers the
+// same.
+func SecurityHeaders(cfg config.ServerConfig) gin.HandlerFunc {
+	var hsts string
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+	}
+	proxies := parsePrefixes(cfg.TrustedProxies)
+	return func(c *gin.Context) {
+		https := c.Request.TLS != nil || (c.GetHeader("X-Forwarded-Proto") == "https" && trusted(proxies, c.RemoteIP()))
+		c.Set(httpsKey, https)
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "no-referrer")
+		// The API only serves data, never pages, scripts or frames
+		header.Set("Content-Security-Policy", "default-src 'none'; frame-ancestors 'none'")
+		if hsts != "" && https {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// IsHTTPS reports whether the client reached the API over HTTPS, as decided
+// by SecurityHeaders.
+func IsHTTPS(c *gin.Context) bool {
+	if https, ok := c.Get(httpsKey); ok {
+		return https.(bool)
+	}
+	return c.Request.TLS != nil
+}
+
+// parsePrefixes reads IPs and CIDR ranges, which the configuration has
+// already checked.
+func parsePrefixes(values []string) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		if prefix, err := netip.ParsePrefix(value); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+		} else if addr, err := netip.ParseAddr(value); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	return prefixes
+}
+
+func trusted(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBody reports whether a handler read past the limit, since handlers
+// turn decoding errors into their own invalid_request problems.
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// BodyLimit refuses request bodies larger than limit bytes with 413. Bodies
+// declaring their length are refused before the handler runs; others are cut
+// off once they go over.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			c.Error(errBodyTooLarge)
+			c.Abort()
+			return
+		}
+		if c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, limit)}
+		c.Request.Body = body
+		c.Next()
+
+		if body.exceeded && !c.Writer.Written() {
+			c.Error(errBodyTooLarge)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SecurityHeaders(config.ServerConfig{HSTSMaxAge: 24 * time.Hour, TrustedProxies: []string{"10.0.0.0/8"}}))
+	r.GET("/courses", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/courses", nil))
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("X-Frame-Options") != "DENY" {
+		t.Fatalf("Expected the security headers but got %v", w.Header())
+	}
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Fatalf("Expected no HSTS over plain HTTP but got %q", got)
+	}
+
+	forwarded := func(remoteAddr string) string {
+		req := httptest.NewRequest(http.MethodGet, "/courses", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Header().Get("Strict-Transport-Security")
+	}
+	if got := forwarded("10.1.2.3:4567"); got != "max-age=86400; includeSubDomains" {
+		t.Fatalf("Expected HSTS behind a trusted TLS proxy but got %q", got)
+	}
+	// Clients cannot claim HTTPS themselves
+	if got := forwarded("203.0.113.7:4567"); got != "" {
+		t.Fatalf("Expected X-Forwarded-Proto from an untrusted client to be ignored but got %q", got)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler(logging.Discard()), BodyLimit(16))
+	r.POST("/courses", func(c *gin.Context) {
+		// Like the handlers, turn a failed read into a problem of its own
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Error(apperr.Invalid("invalid_request", err.Error()))
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	send := func(body string, chunked bool) int {
+		req := httptest.NewRequest(http.MethodPost, "/courses", strings.NewReader(body))
+		if chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := send(`{"name":"B"}`, false); code != http.StatusCreated {
+		t.Fatalf("Expected a small body to be accepted but got %d", code)
+	}
+	if code := send(strings.Repeat("x", 17), false); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected a declared large body to be refused but got %d", code)
+	}
+	if code := send(strings.Repeat("x", 17), true); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected a streamed large body to be refused but got %d", code)
+	}
+}
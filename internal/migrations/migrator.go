@@ -0,0 +1,121 @@
+// Package migrations applies versioned, idempotent changes to the MongoDB
+// schema (indexes, validators) and records which versions have been applied.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const collectionName = "schema_migrations"
+
+// Migration is a single schema change. Up must be safe to run more than once.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// Record is stored in the schema_migrations collection for every applied migration.
+type Record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+func NewMigrator(db *mongo.Database, migrations []Migration, logger *slog.Logger) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted, logger: logger}
+}
+
+// Applied returns the records of all applied migrations, oldest first.
+func (m *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	cursor, err := m.db.Collection(collectionName).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Version returns the highest applied migration version, or 0 if none ran yet.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	records, err := m.Applied(ctx)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	return records[len(records)-1].Version, nil
+}
+
+// Latest returns the highest version known to this binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Ready fails while migrations known to this binary are still pending. A
+// schema newer than the binary is fine; it happens during a rolling deploy.
+func (m *Migrator) Ready(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if latest := m.Latest(); version < latest {
+		return fmt.Errorf("schema is at version %d, expected %d", version, latest)
+	}
+	return nil
+}
+
+// Up applies every migration that has not been recorded yet, in version order,
+// and returns the versions it applied.
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	records, err := m.Applied(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	done := make(map[int]bool, len(records))
+	for _, record := range records {
+		done[record.Version] = true
+	}
+
+	var applied []int
+	for _, migration := range m.migrations {
+		if done[migration.Version] {
+			continue
+		}
+
+		m.logger.InfoContext(ctx, "applying migration", slog.Int("version", migration.Version), slog.String("description", migration.Description))
+		if err := migration.Up(ctx, m.db); err != nil {
+			return applied, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Description, err)
+		}
+
+		record := Record{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now()}
+		_, err := m.db.Collection(collectionName).InsertOne(ctx, record)
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return applied, fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+		}
+		applied = append(applied, migration.Version)
+	}
+	return applied, nil
+}
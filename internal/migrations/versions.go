@@ -0,0 +1,237 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// All lists every migration in the order they were introduced. Never change or
+// renumber a migration once it has shipped; add a new one instead.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "unique indexes on user emails",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, collection := range []string{"students", "instructors", "admins"} {
+				if err := createIndexes(ctx, db, collection, mongo.IndexModel{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: options.Index().SetName("email_unique").SetUnique(true),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "unique index on vehicle license plates",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "vehicles", mongo.IndexModel{
+				Keys: bson.D{{Key: "license_plate", Value: 1}},
+				Options: options.Index().SetName("license_plate_unique").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"license_plate": bson.M{"$type": "string"}}),
+			})
+		},
+	},
+	{
+		Version:     3,
+		Description: "lesson and availability lookup indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db, "lessons",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "instructor_id", Value: 1}, {Key: "schedule", Value: 1}},
+					Options: options.Index().SetName("instructor_schedule"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "student_id", Value: 1}, {Key: "schedule", Value: 1}},
+					Options: options.Index().SetName("student_schedule"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "vehicle_id", Value: 1}, {Key: "schedule", Value: 1}},
+					Options: options.Index().SetName("vehicle_schedule"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "course_id", Value: 1}},
+					Options: options.Index().SetName("course"),
+				},
+			)
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db, "availability", mongo.IndexModel{
+				Keys:    bson.D{{Key: "instructor_id", Value: 1}, {Key: "start_time", Value: 1}},
+				Options: options.Index().SetName("instructor_start_time"),
+			})
+		},
+	},
+	{
+		Version:     4,
+		Description: "JSON schema validators",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			validators := map[string]bson.M{
+				"students": schema([]string{"email"}, bson.M{
+					"email": bson.M{"bsonType": "string"},
+					"age":   bson.M{"bsonType": []string{"int", "long"}, "minimum": 0},
+				}),
+				"lessons": schema([]string{"course_id", "instructor_id", "schedule"}, bson.M{
+					"course_id":     bson.M{"bsonType": "objectId"},
+					"instructor_id": bson.M{"bsonType": "objectId"},
+					"student_id":    bson.M{"bsonType": "objectId"},
+					"vehicle_id":    bson.M{"bsonType": "objectId"},
+					"schedule":      bson.M{"bsonType": "date"},
+					"status":        bson.M{"enum": []string{"scheduled", "cancelled"}},
+				}),
+				"availability": schema([]string{"instructor_id", "start_time", "end_time"}, bson.M{
+					"instructor_id": bson.M{"bsonType": "objectId"},
+					"start_time":    bson.M{"bsonType": "date"},
+					"end_time":      bson.M{"bsonType": "date"},
+				}),
+				"vehicles": schema([]string{"license_plate"}, bson.M{
+					"license_plate": bson.M{"bsonType": "string"},
+					"year":          bson.M{"bsonType": []string{"int", "long"}},
+				}),
+			}
+			for collection, validator := range validators {
+				if err := setValidator(ctx, db, collection, validator); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     5,
+		Description: "audit log lookup indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "audit_log",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "time", Value: -1}},
+					Options: options.Index().SetName("time"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "actor.id", Value: 1}, {Key: "time", Value: -1}},
+					Options: options.Index().SetName("actor_time"),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "resource", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "time", Value: -1}},
+					Options: options.Index().SetName("resource_time"),
+				},
+			)
+		},
+	},
+	{
+		Version:     6,
+		Description: "outbox indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "outbox",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+					Options: options.Index().SetName("status_next_attempt"),
+				},
+				// Delivered events are kept for a week, for troubleshooting
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "dispatched_at", Value: 1}},
+					Options: options.Index().SetName("dispatched_ttl").SetExpireAfterSeconds(7 * 24 * 60 * 60),
+				},
+			)
+		},
+	},
+	{
+		Version:     7,
+		Description: "webhook delivery indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "webhook_deliveries",
+				// An event is queued once per subscription however often it is published
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "subscription_id", Value: 1}, {Key: "event_id", Value: 1}},
+					Options: options.Index().SetName("subscription_event").SetUnique(true),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+					Options: options.Index().SetName("status_next_attempt"),
+				},
+			)
+		},
+	},
+	{
+		Version:     8,
+		Description: "notification indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "notifications",
+				// Nothing is sent twice; see the notifications package
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "key", Value: 1}},
+					Options: options.Index().SetName("key").SetUnique(true),
+				},
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "recipient.email", Value: 1}, {Key: "recipient.role", Value: 1}, {Key: "_id", Value: -1}},
+					Options: options.Index().SetName("recipient_inbox"),
+				},
+			)
+		},
+	},
+	{
+		Version:     9,
+		Description: "calendar feed token index",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return createIndexes(ctx, db, "calendar_feeds", mongo.IndexModel{
+				Keys:    bson.D{{Key: "token_hash", Value: 1}},
+				Options: options.Index().SetName("token_hash").SetUnique(true),
+			})
+		},
+	},
+	{
+		Version:     10,
+		Description: "calendar import indexes",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			err := createIndexes(ctx, db, "availability", mongo.IndexModel{
+				Keys: bson.D{{Key: "instructor_id", Value: 1}, {Key: "import.source", Value: 1}, {Key: "import.key", Value: 1}},
+				Options: options.Index().SetName("instructor_import").SetUnique(true).
+					SetPartialFilterExpression(bson.M{"import.key": bson.M{"$type": "string"}}),
+			})
+			if err != nil {
+				return err
+			}
+			return createIndexes(ctx, db, "calendar_syncs", mongo.IndexModel{
+				Keys:    bson.D{{Key: "instructor_id", Value: 1}, {Key: "url", Value: 1}},
+				Options: options.Index().SetName("instructor_url").SetUnique(true),
+			})
+		},
+	},
+}
+
+func createIndexes(ctx context.Context, db *mongo.Database, collection string, models ...mongo.IndexModel) error {
+	_, err := db.Collection(collection).Indexes().CreateMany(ctx, models)
+	return err
+}
+
+func schema(required []string, properties bson.M) bson.M {
+	return bson.M{"$jsonSchema": bson.M{
+		"bsonType":   "object",
+		"required":   required,
+		"properties": properties,
+	}}
+}
+
+// setValidator attaches a validator to a collection, creating the collection
+// first if needed. Validation is "moderate" so documents written before the
+// validator existed can still be updated.
+func setValidator(ctx context.Context, db *mongo.Database, collection string, validator bson.M) error {
+	names, err := db.ListCollectionNames(ctx, bson.M{"name": collection})
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		opts := options.CreateCollection().SetValidator(validator).SetValidationLevel("moderate")
+		return db.CreateCollection(ctx, collection, opts)
+	}
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: "moderate"},
+	}).Err()
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+func TestMigrationVersionsAreUniqueAndAscending(t *testing.T) {
+	for i, migration := range All {
+		if migration.Version != i+1 {
+			t.Fatalf("Expected migration %d to have version %d but got %d", i, i+1, migration.Version)
+		}
+		if migration.Description == "" || migration.Up == nil {
+			t.Fatalf("Migration %d is missing a description or an Up function", migration.Version)
+		}
+	}
+}
+
+func TestNewMigratorSortsByVersion(t *testing.T) {
+	migrator := NewMigrator(nil, []Migration{{Version: 3}, {Version: 1}, {Version: 2}}, logging.Discard())
+
+	if migrator.Latest() != 3 {
+		t.Fatalf("Expected latest version 3 but got %d", migrator.Latest())
+	}
+	for i, migration := range migrator.migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("Expected migrations to be sorted, got %v at position %d", migration.Version, i)
+		}
+	}
+}
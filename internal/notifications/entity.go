@@ -0,0 +1,42 @@
+package notifications
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Kind string
+
+const (
+	KindLessonBooked      Kind = "lesson_booked"
+	KindLessonRescheduled Kind = "lesson_rescheduled"
+	KindLessonCancelled   Kind = "lesson_cancelled"
+	KindLessonReminder    Kind = "lesson_reminder"
+)
+
+// Notification is one message to one recipient, whatever the channels it was
+// sent through. Those with InApp set make up the recipient's inbox.
+type Notification struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	// Key names what the notification is about, for whom; it is unique so
+	// that nothing is sent twice
+	Key       string             `bson:"key" json:"-"`
+	Recipient Recipient          `bson:"recipient" json:"-"`
+	Kind      Kind               `bson:"kind" json:"kind"`
+	Subject   string             `bson:"subject" json:"subject"`
+	Body      string             `bson:"body" json:"body"`
+	LessonID  primitive.ObjectID `bson:"lesson_id,omitempty" json:"lesson_id,omitempty"`
+	InApp     bool               `bson:"in_app" json:"-"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	// ReadAt is nil until the recipient marks the notification read
+	ReadAt *time.Time `bson:"read_at,omitempty" json:"read_at"`
+}
+
+// Recipient is the student or instructor a notification is for. The email and
+// role are what an authenticated caller is known by.
+type Recipient struct {
+	ID    primitive.ObjectID `bson:"id"`
+	Role  string             `bson:"role"`
+	Email string             `bson:"email"`
+}
@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// owner is whose inbox a notification is in.
+type owner struct {
+	email, role string
+}
+
+// Inbox is the in-app side of notifications: what people have been sent and
+// read. Watch lets streams hear about changes as this process makes them;
+// changes made by other instances are only seen by looking again.
+type Inbox struct {
+	repo InboxRepository
+	now  func() time.Time
+
+	mu       sync.Mutex
+	watchers map[owner]map[chan struct{}]struct{}
+	closed   bool
+}
+
+func NewInbox(repo InboxRepository) *Inbox {
+	return &Inbox{repo: repo, now: time.Now, watchers: map[owner]map[chan struct{}]struct{}{}}
+}
+
+// Add records a notification, telling the recipient's watchers if it is in-app.
+func (i *Inbox) Add(ctx context.Context, notification Notification) (primitive.ObjectID, error) {
+	id, err := i.repo.AddNotification(ctx, notification)
+	if err == nil && notification.InApp {
+		i.changed(notification.Recipient.Email, notification.Recipient.Role)
+	}
+	return id, err
+}
+
+// SentKeys returns which of keys a notification has been sent under.
+func (i *Inbox) SentKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	return i.repo.SentKeys(ctx, keys)
+}
+
+func (i *Inbox) List(ctx context.Context, query Query) (_ []Notification, err error) {
+	ctx, span := tracer.Start(ctx, "Inbox.List")
+	defer tracing.End(span, &err)
+	return i.repo.ListNotifications(ctx, query)
+}
+
+func (i *Inbox) CountUnread(ctx context.Context, email, role string) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "Inbox.CountUnread")
+	defer tracing.End(span, &err)
+	return i.repo.CountUnread(ctx, email, role)
+}
+
+func (i *Inbox) MarkRead(ctx context.Context, email, role string, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "Inbox.MarkRead")
+	defer tracing.End(span, &err)
+	if err := i.repo.MarkRead(ctx, email, role, id, i.now()); err != nil {
+		return err
+	}
+	i.changed(email, role)
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the recipient read and
+// returns how many there were.
+func (i *Inbox) MarkAllRead(ctx context.Context, email, role string) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "Inbox.MarkAllRead")
+	defer tracing.End(span, &err)
+	n, err := i.repo.MarkAllRead(ctx, email, role, i.now())
+	if err != nil {
+		return 0, err
+	}
+	if n > 0 {
+		i.changed(email, role)
+	}
+	return n, nil
+}
+
+// Watch returns a channel that receives a value whenever the inbox of the
+// recipient with the given email and role changes, and a function to stop
+// watching. Changes that happen while the last one is unread are merged into
+// it. The channel is closed by Close.
+func (i *Inbox) Watch(email, role string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	key := owner{email, role}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if i.watchers[key] == nil {
+		i.watchers[key] = map[chan struct{}]struct{}{}
+	}
+	i.watchers[key][ch] = struct{}{}
+
+	return ch, func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+		if _, ok := i.watchers[key][ch]; !ok {
+			return
+		}
+		delete(i.watchers[key], ch)
+		if len(i.watchers[key]) == 0 {
+			delete(i.watchers, key)
+		}
+		close(ch)
+	}
+}
+
+// Close closes every watcher's channel, ending their streams, so that the
+// server can shut down.
+func (i *Inbox) Close() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.closed = true
+	for key, chans := range i.watchers {
+		for ch := range chans {
+			close(ch)
+		}
+		delete(i.watchers, key)
+	}
+}
+
+func (i *Inbox) changed(email, role string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for ch := range i.watchers[owner{email, role}] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
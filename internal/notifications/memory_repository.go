@@ -0,0 +1,112 @@
+package notifications
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryInboxRepository keeps notifications in memory with the same semantics
+// as MongoInboxRepository, including the unique key index. It is meant for
+// tests and local development.
+type MemoryInboxRepository struct {
+	store *memstore.Store[Notification]
+	// mu makes marking notifications read atomic, like the MongoDB updates
+	mu sync.Mutex
+}
+
+func NewMemoryInboxRepository() *MemoryInboxRepository {
+	return &MemoryInboxRepository{
+		store: memstore.New[Notification](func(a, b Notification) bool {
+			return a.Key == b.Key
+		}),
+	}
+}
+
+func (r *MemoryInboxRepository) AddNotification(ctx context.Context, notification Notification) (primitive.ObjectID, error) {
+	notification.ID = primitive.NewObjectID()
+	notification.CreatedAt = time.Now()
+	ok, err := r.store.Insert(notification.ID, notification)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("notification")
+	}
+	return notification.ID, nil
+}
+
+func (r *MemoryInboxRepository) SentKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	sent := map[string]bool{}
+	for _, notification := range r.store.Find(func(notification Notification) bool { return slices.Contains(keys, notification.Key) }) {
+		sent[notification.Key] = true
+	}
+	return sent, nil
+}
+
+func (r *MemoryInboxRepository) ListNotifications(ctx context.Context, query Query) ([]Notification, error) {
+	notifications := r.store.Find(func(notification Notification) bool {
+		return inInbox(notification, query.Email, query.Role) &&
+			(!query.UnreadOnly || notification.ReadAt == nil) &&
+			(query.Before.IsZero() || notification.ID.Hex() < query.Before.Hex()) &&
+			(query.After.IsZero() || notification.ID.Hex() > query.After.Hex())
+	})
+	for i, j := 0, len(notifications)-1; i < j; i, j = i+1, j-1 {
+		notifications[i], notifications[j] = notifications[j], notifications[i]
+	}
+	if len(notifications) > limit(query.Limit) {
+		notifications = notifications[:limit(query.Limit)]
+	}
+	return notifications, nil
+}
+
+func (r *MemoryInboxRepository) CountUnread(ctx context.Context, email, role string) (int, error) {
+	unread := r.store.Find(func(notification Notification) bool {
+		return inInbox(notification, email, role) && notification.ReadAt == nil
+	})
+	return len(unread), nil
+}
+
+func (r *MemoryInboxRepository) MarkRead(ctx context.Context, email, role string, id primitive.ObjectID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	notification, ok := r.store.Get(id)
+	if !ok || !inInbox(notification, email, role) {
+		return apperr.ResourceNotFound("notification")
+	}
+	if notification.ReadAt != nil {
+		return nil
+	}
+	return r.markRead(notification, at)
+}
+
+func (r *MemoryInboxRepository) MarkAllRead(ctx context.Context, email, role string, at time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	unread := r.store.Find(func(notification Notification) bool {
+		return inInbox(notification, email, role) && notification.ReadAt == nil
+	})
+	for _, notification := range unread {
+		if err := r.markRead(notification, at); err != nil {
+			return 0, err
+		}
+	}
+	return len(unread), nil
+}
+
+func (r *MemoryInboxRepository) markRead(notification Notification, at time.Time) error {
+	notification.ReadAt = &at
+	if _, _, err := r.store.Set(notification.ID, notification); err != nil {
+		return apperr.Internal(err)
+	}
+	return nil
+}
+
+func inInbox(notification Notification, email, role string) bool {
+	return notification.InApp && notification.Recipient.Email == email && notification.Recipient.Role == role
+}
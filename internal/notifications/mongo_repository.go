@@ -0,0 +1,122 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoInboxRepository struct {
+	db *mongo.Collection
+}
+
+func NewMongoInboxRepository(db *mongo.Database) *MongoInboxRepository {
+	return &MongoInboxRepository{
+		db: db.Collection("notifications"),
+	}
+}
+
+func (r *MongoInboxRepository) AddNotification(ctx context.Context, notification Notification) (primitive.ObjectID, error) {
+	notification.ID = primitive.NewObjectID()
+	notification.CreatedAt = time.Now()
+	if _, err := r.db.InsertOne(ctx, notification); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "notification")
+	}
+	return notification.ID, nil
+}
+
+func (r *MongoInboxRepository) SentKeys(ctx context.Context, keys []string) (map[string]bool, error) {
+	sent := map[string]bool{}
+	if len(keys) == 0 {
+		return sent, nil
+	}
+	opts := options.Find().SetProjection(bson.M{"key": 1})
+	cursor, err := r.db.Find(ctx, bson.M{"key": bson.M{"$in": keys}}, opts)
+	if err != nil {
+		return nil, apperr.FromMongo(err, "notification")
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, apperr.FromMongo(err, "notification")
+	}
+	for _, notification := range notifications {
+		sent[notification.Key] = true
+	}
+	return sent, nil
+}
+
+func (r *MongoInboxRepository) ListNotifications(ctx context.Context, query Query) ([]Notification, error) {
+	filter := inbox(query.Email, query.Role)
+	if query.UnreadOnly {
+		filter["read_at"] = nil
+	}
+	id := bson.M{}
+	if !query.Before.IsZero() {
+		id["$lt"] = query.Before
+	}
+	if !query.After.IsZero() {
+		id["$gt"] = query.After
+	}
+	if len(id) > 0 {
+		filter["_id"] = id
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit(query.Limit)))
+	cursor, err := r.db.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, apperr.FromMongo(err, "notification")
+	}
+	defer cursor.Close(ctx)
+
+	var notifications []Notification
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, apperr.FromMongo(err, "notification")
+	}
+	return notifications, nil
+}
+
+func (r *MongoInboxRepository) CountUnread(ctx context.Context, email, role string) (int, error) {
+	filter := inbox(email, role)
+	filter["read_at"] = nil
+	count, err := r.db.CountDocuments(ctx, filter)
+	if err != nil {
+		return 0, apperr.FromMongo(err, "notification")
+	}
+	return int(count), nil
+}
+
+func (r *MongoInboxRepository) MarkRead(ctx context.Context, email, role string, id primitive.ObjectID, at time.Time) error {
+	filter := inbox(email, role)
+	filter["_id"] = id
+	// Keeps the time it was first read
+	update := bson.A{bson.M{"$set": bson.M{"read_at": bson.M{"$ifNull": bson.A{"$read_at", at}}}}}
+	result, err := r.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return apperr.FromMongo(err, "notification")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.ResourceNotFound("notification")
+	}
+	return nil
+}
+
+func (r *MongoInboxRepository) MarkAllRead(ctx context.Context, email, role string, at time.Time) (int, error) {
+	filter := inbox(email, role)
+	filter["read_at"] = nil
+	result, err := r.db.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read_at": at}})
+	if err != nil {
+		return 0, apperr.FromMongo(err, "notification")
+	}
+	return int(result.ModifiedCount), nil
+}
+
+// inbox matches the in-app notifications of one recipient.
+func inbox(email, role string) bson.M {
+	return bson.M{"recipient.email": email, "recipient.role": role, "in_app": true}
+}
@@ -0,0 +1,347 @@
+// Package notifications tells students and instructors about their lessons:
+// when one is booked, moved or cancelled, and ahead of it as a reminder. Each
+// notification goes to the channels the recipient has chosen among email,
+// SMS and the in-app inbox.
+//
+// Every notification is recorded in the inbox under a unique key before it is
+// sent, and one whose key is already there is skipped. Events are delivered
+// at least once and several instances may look for the same reminders, so
+// that is what keeps anyone from being told twice. Email and SMS failures are logged
+// rather than retried for the same reason.
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("internal/notifications")
+
+// defaultTitle stands in for lessons without a title.
+const defaultTitle = "Your driving lesson"
+
+type Notifier struct {
+	inbox       *Inbox
+	lessons     lessons.LessonRepository
+	students    students.StudentRepository
+	instructors instructors.InstructorRepository
+	mailer      Mailer
+	sms         SMSProvider
+	reminders   []time.Duration
+	interval    time.Duration
+	location    *time.Location
+	logger      *slog.Logger
+	now         func() time.Time
+}
+
+func NewNotifier(inbox *Inbox, lessonRepo lessons.LessonRepository, studentRepo students.StudentRepository, instructorRepo instructors.InstructorRepository,
+	mailer Mailer, sms SMSProvider, cfg config.NotificationConfig, logger *slog.Logger) (*Notifier, error) {
+	location, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	// Longest first, which is the order the reminder windows are worked out in
+	reminders := slices.Clone(cfg.Reminders)
+	slices.SortFunc(reminders, func(a, b time.Duration) int { return int(b - a) })
+	reminders = slices.Compact(reminders)
+	return &Notifier{
+		inbox:       inbox,
+		lessons:     lessonRepo,
+		students:    studentRepo,
+		instructors: instructorRepo,
+		mailer:      mailer,
+		sms:         sms,
+		reminders:   reminders,
+		interval:    cfg.ReminderInterval,
+		location:    location,
+		logger:      logger,
+		now:         time.Now,
+	}, nil
+}
+
+// Register subscribes the notifier to the lesson events it tells people about.
+func (n *Notifier) Register(dispatcher *events.Dispatcher) {
+	for _, typ := range []events.Type{events.TypeLessonBooked, events.TypeLessonRescheduled, events.TypeLessonCancelled} {
+		dispatcher.Subscribe(typ, n.Handle)
+	}
+}
+
+// Handle notifies the student and instructor of the lesson an event is about.
+func (n *Notifier) Handle(ctx context.Context, event events.Event) (err error) {
+	ctx, span := tracer.Start(ctx, "Notifier.Handle", trace.WithAttributes(
+		attribute.String("event.type", string(event.Type)), attribute.String("event.id", event.ID.Hex())))
+	defer tracing.End(span, &err)
+
+	payload, err := event.DecodePayload()
+	if err != nil {
+		return err
+	}
+	var (
+		kind     Kind
+		lesson   events.Lesson
+		previous time.Time
+	)
+	switch p := payload.(type) {
+	case events.LessonBooked:
+		kind, lesson = KindLessonBooked, p.Lesson
+	case events.LessonRescheduled:
+		kind, lesson, previous = KindLessonRescheduled, p.Lesson, p.PreviousSchedule
+	case events.LessonCancelled:
+		kind, lesson = KindLessonCancelled, p.Lesson
+	default:
+		return nil
+	}
+	// The event ID is the same every time the event is delivered
+	return n.notifyLesson(ctx, kind, "event:"+event.ID.Hex(), lesson, previous)
+}
+
+// RunReminders sends the reminders that are due every ReminderInterval until
+// ctx is cancelled.
+func (n *Notifier) RunReminders(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := n.SendReminders(ctx); err != nil && ctx.Err() == nil {
+			n.logger.ErrorContext(ctx, "failed to send lesson reminders", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendReminders notifies the people on every booked lesson that has come
+// within one of the reminder offsets and who have not been reminded of it yet,
+// and returns how many lessons that was. Someone who has turned off every
+// channel is never recorded as reminded, so their lessons are looked at again
+// each time, to no effect.
+// A lesson gets the reminder for the shortest offset it is within, so one
+// booked two hours ahead gets the two-hour reminder but not the 24-hour one.
+func (n *Notifier) SendReminders(ctx context.Context) (sent int, err error) {
+	ctx, span := tracer.Start(ctx, "Notifier.SendReminders")
+	defer tracing.End(span, &err)
+
+	now := n.now()
+	for i, offset := range n.reminders {
+		from := now
+		if i+1 < len(n.reminders) {
+			from = now.Add(n.reminders[i+1])
+		}
+		due, err := n.lessons.ListLessons(ctx, lessons.Filter{From: from, To: now.Add(offset)})
+		if err != nil {
+			return sent, err
+		}
+		due = slices.DeleteFunc(due, func(lesson lessons.Lesson) bool {
+			return lesson.StudentID.IsZero() || lesson.Status != lessons.StatusScheduled
+		})
+		// The schedule is part of the key so a lesson that is moved is
+		// reminded of again
+		key := func(lesson lessons.Lesson) string {
+			return fmt.Sprintf("reminder:%s:%s:%d", lesson.ID.Hex(), offset, lesson.Schedule.Unix())
+		}
+		// A lesson stays due until it starts, so the ones everyone has been
+		// reminded of are left out here; the unique keys only catch races
+		var keys []string
+		for _, lesson := range due {
+			keys = append(keys, recipientKeys(key(lesson), lesson)...)
+		}
+		reminded, err := n.inbox.SentKeys(ctx, keys)
+		if err != nil {
+			return sent, err
+		}
+		for _, lesson := range due {
+			if !slices.ContainsFunc(recipientKeys(key(lesson), lesson), func(key string) bool { return !reminded[key] }) {
+				continue
+			}
+			details := events.Lesson{
+				LessonID:     lesson.ID,
+				CourseID:     lesson.CourseID,
+				InstructorID: lesson.InstructorID,
+				StudentID:    lesson.StudentID,
+				VehicleID:    lesson.VehicleID,
+				Title:        lesson.Title,
+				Schedule:     lesson.Schedule,
+			}
+			if err := n.notifyLesson(ctx, KindLessonReminder, key(lesson), details, time.Time{}); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// contact is a recipient along with how to reach them.
+type contact struct {
+	Recipient
+	Name  string
+	Phone string
+	// The channels they have chosen
+	Email, SMS, InApp bool
+}
+
+// notifyLesson notifies the student and the instructor of lesson. key
+// identifies what they are being told about; the recipient is added to it.
+func (n *Notifier) notifyLesson(ctx context.Context, kind Kind, key string, lesson events.Lesson, previous time.Time) error {
+	instructor, err := n.instructor(ctx, lesson)
+	if err != nil {
+		return err
+	}
+	student, err := n.student(ctx, lesson)
+	if err != nil {
+		return err
+	}
+
+	data := templateData{
+		Title:        lesson.Title,
+		Time:         n.format(lesson.Schedule),
+		PreviousTime: n.format(previous),
+	}
+	if data.Title == "" {
+		data.Title = defaultTitle
+	}
+	for _, pair := range [][2]*contact{{student, instructor}, {instructor, student}} {
+		to, with := pair[0], pair[1]
+		if to == nil {
+			continue
+		}
+		data.Name, data.With = to.Name, ""
+		if with != nil {
+			data.With = with.Name
+		}
+		if err := n.notify(ctx, kind, key, lesson, to, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify sends one notification to one recipient, unless one with the same
+// key was already sent.
+func (n *Notifier) notify(ctx context.Context, kind Kind, key string, lesson events.Lesson, to *contact, data templateData) error {
+	if !to.Email && !to.SMS && !to.InApp {
+		return nil
+	}
+	text, err := render(kind, data)
+	if err != nil {
+		return err
+	}
+	_, err = n.inbox.Add(ctx, Notification{
+		Key:       recipientKey(key, to.Role, to.ID),
+		Recipient: to.Recipient,
+		Kind:      kind,
+		Subject:   text.Subject,
+		Body:      text.Body,
+		LessonID:  lesson.LessonID,
+		InApp:     to.InApp,
+	})
+	if apperr.KindOf(err) == apperr.KindConflict {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	attrs := []any{slog.String("kind", string(kind)), slog.String("lesson_id", lesson.LessonID.Hex()), slog.String("recipient_id", to.ID.Hex())}
+	if to.Email && to.Recipient.Email != "" {
+		if err := n.mailer.SendEmail(ctx, to.Recipient.Email, text.Subject, text.Body); err != nil {
+			n.logger.ErrorContext(ctx, "failed to email notification", append(attrs, slog.Any("error", err))...)
+		}
+	}
+	if to.SMS && to.Phone != "" {
+		if err := n.sms.SendSMS(ctx, to.Phone, text.SMS); err != nil {
+			n.logger.ErrorContext(ctx, "failed to text notification", append(attrs, slog.Any("error", err))...)
+		}
+	}
+	return nil
+}
+
+// recipientKey is the key of what key names for one recipient.
+func recipientKey(key, role string, id primitive.ObjectID) string {
+	return fmt.Sprintf("%s:%s:%s", key, role, id.Hex())
+}
+
+// recipientKeys are the keys of what key names for the lesson's student and
+// instructor.
+func recipientKeys(key string, lesson lessons.Lesson) []string {
+	keys := []string{recipientKey(key, identity.RoleStudent, lesson.StudentID)}
+	if !lesson.InstructorID.IsZero() {
+		keys = append(keys, recipientKey(key, identity.RoleInstructor, lesson.InstructorID))
+	}
+	return keys
+}
+
+// student returns the lesson's student, or nil if it has none or they no
+// longer exist.
+func (n *Notifier) student(ctx context.Context, lesson events.Lesson) (*contact, error) {
+	if lesson.StudentID.IsZero() {
+		return nil, nil
+	}
+	student, err := n.students.GetStudentByID(ctx, lesson.StudentID)
+	if apperr.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &contact{
+		Recipient: Recipient{ID: student.ID, Role: identity.RoleStudent, Email: student.Email},
+		Name:      student.FirstName,
+		Phone:     student.Phone,
+		Email:     true,
+		InApp:     true,
+	}
+	if p := student.NotificationPreferences; p != nil {
+		c.Email, c.SMS, c.InApp = p.Email, p.SMS, p.InApp
+	}
+	return c, nil
+}
+
+// instructor returns the lesson's instructor, or nil if they no longer exist.
+func (n *Notifier) instructor(ctx context.Context, lesson events.Lesson) (*contact, error) {
+	if lesson.InstructorID.IsZero() {
+		return nil, nil
+	}
+	instructor, err := n.instructors.GetInstructorByID(ctx, lesson.InstructorID)
+	if apperr.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &contact{
+		Recipient: Recipient{ID: instructor.ID, Role: identity.RoleInstructor, Email: instructor.Email},
+		Name:      instructor.FirstName,
+		Phone:     instructor.Phone,
+		Email:     true,
+		InApp:     true,
+	}
+	if p := instructor.NotificationPreferences; p != nil {
+		c.Email, c.SMS, c.InApp = p.Email, p.SMS, p.InApp
+	}
+	return c, nil
+}
+
+func (n *Notifier) format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(n.location).Format(timeLayout)
+}
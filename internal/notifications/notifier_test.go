@@ -0,0 +1,261 @@
+package notifications
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/fakesms"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/smtpsink"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fixture struct {
+	notifier   *Notifier
+	inbox      *MemoryInboxRepository
+	lessons    *lessons.MemoryLessonRepository
+	smtp       *smtpsink.Server
+	sms        *fakesms.Provider
+	student    students.Student
+	instructor instructors.Instructor
+	now        time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &fixture{
+		inbox:   NewMemoryInboxRepository(),
+		lessons: lessons.NewMemoryLessonRepository(),
+		smtp:    smtpsink.New(t),
+		sms:     &fakesms.Provider{},
+		now:     time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC),
+	}
+	studentRepo := students.NewMemoryStudentRepository()
+	instructorRepo := instructors.NewMemoryInstructorRepository()
+
+	f.student = students.Student{FirstName: "Ana", Email: "ana@example.com", Phone: "+34600000001",
+		NotificationPreferences: &students.NotificationPreferences{Email: true, SMS: true, InApp: true}}
+	id, err := studentRepo.CreateStudent(ctx, f.student)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.student.ID = id
+	f.instructor = instructors.Instructor{FirstName: "Marta", Email: "marta@example.com", Phone: "+34600000002"}
+	id, err = instructorRepo.CreateInstructor(ctx, f.instructor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.instructor.ID = id
+
+	mailer := NewMailer(config.SMTPConfig{Host: f.smtp.Host(), Port: f.smtp.Port(), From: "DriveFluency <no-reply@example.com>"}, logging.Discard())
+	cfg := config.NotificationConfig{Reminders: []time.Duration{2 * time.Hour, 24 * time.Hour}, ReminderInterval: time.Minute, TimeZone: "Europe/Madrid"}
+	f.notifier, err = NewNotifier(NewInbox(f.inbox), f.lessons, studentRepo, instructorRepo, mailer, f.sms, cfg, logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.notifier.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *fixture) event(t *testing.T, payload events.Payload) events.Event {
+	t.Helper()
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.Event{ID: primitive.NewObjectID(), Type: payload.EventType(), AggregateID: payload.AggregateID(), OccurredAt: f.now, Payload: raw}
+}
+
+func (f *fixture) inboxOf(t *testing.T, email, role string) []Notification {
+	t.Helper()
+	got, err := f.inbox.ListNotifications(context.Background(), Query{Email: email, Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestNotifierHandlesLessonEvents(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	lesson := events.Lesson{
+		LessonID: primitive.NewObjectID(), InstructorID: f.instructor.ID, StudentID: f.student.ID,
+		Title: "Roundabouts", Schedule: time.Date(2030, 3, 10, 10, 0, 0, 0, time.UTC),
+	}
+
+	booked := f.event(t, events.LessonBooked{Lesson: lesson})
+	if err := f.notifier.Handle(ctx, booked); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	// The dispatcher may deliver an event again
+	if err := f.notifier.Handle(ctx, booked); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+
+	mails := f.smtp.Messages()
+	if len(mails) != 2 {
+		t.Fatalf("expected an email to the student and one to the instructor, got %+v", mails)
+	}
+	for _, mail := range mails {
+		if !strings.Contains(mail.Subject, "Roundabouts") || !strings.Contains(mail.Body, "Sun 10 Mar 2030 at 11:00 CET") {
+			t.Errorf("expected the lesson in the configured time zone, got %+v", mail)
+		}
+	}
+	if mails[0].To[0] != f.student.Email || !strings.Contains(mails[0].Body, "Hi Ana") || !strings.Contains(mails[0].Body, "with Marta") {
+		t.Errorf("expected the student's email to name their instructor, got %+v", mails[0])
+	}
+	// Only the student has asked for text messages
+	if texts := f.sms.Messages(); len(texts) != 1 || texts[0].To != f.student.Phone {
+		t.Errorf("expected one text message to the student, got %+v", texts)
+	}
+	if got := f.inboxOf(t, f.student.Email, "student"); len(got) != 1 || got[0].Kind != KindLessonBooked || got[0].LessonID != lesson.LessonID {
+		t.Errorf("expected the booking in the student's inbox, got %+v", got)
+	}
+	if got := f.inboxOf(t, f.instructor.Email, "instructor"); len(got) != 1 {
+		t.Errorf("expected the booking in the instructor's inbox by default, got %+v", got)
+	}
+
+	previous := lesson.Schedule
+	lesson.Schedule = lesson.Schedule.Add(24 * time.Hour)
+	if err := f.notifier.Handle(ctx, f.event(t, events.LessonRescheduled{Lesson: lesson, PreviousSchedule: previous})); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if err := f.notifier.Handle(ctx, f.event(t, events.LessonCancelled{Lesson: lesson})); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	got := f.inboxOf(t, f.student.Email, "student")
+	if len(got) != 3 || got[0].Kind != KindLessonCancelled || got[1].Kind != KindLessonRescheduled {
+		t.Fatalf("expected the reschedule and cancellation in the inbox, got %+v", got)
+	}
+	if !strings.Contains(got[1].Body, "from Sun 10 Mar 2030 at 11:00 CET to Mon 11 Mar 2030 at 11:00 CET") {
+		t.Errorf("expected the old and new times, got %q", got[1].Body)
+	}
+}
+
+func TestNotifierPreferences(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	studentRepo := students.NewMemoryStudentRepository()
+	id, err := studentRepo.CreateStudent(ctx, students.Student{FirstName: "Leo", Email: "leo@example.com", Phone: "+34600000003",
+		NotificationPreferences: &students.NotificationPreferences{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.notifier.students = studentRepo
+
+	lesson := events.Lesson{LessonID: primitive.NewObjectID(), InstructorID: f.instructor.ID, StudentID: id, Schedule: f.now.Add(48 * time.Hour)}
+	if err := f.notifier.Handle(ctx, f.event(t, events.LessonBooked{Lesson: lesson})); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	mails := f.smtp.Messages()
+	if len(mails) != 1 || mails[0].To[0] != f.instructor.Email {
+		t.Errorf("expected only the instructor to be emailed, got %+v", mails)
+	}
+	if !strings.Contains(mails[0].Subject, defaultTitle) || !strings.Contains(mails[0].Body, "with Leo") {
+		t.Errorf("expected the default title and the student's name, got %+v", mails[0])
+	}
+	if texts := f.sms.Messages(); len(texts) != 0 {
+		t.Errorf("expected no text messages, got %+v", texts)
+	}
+	if got := f.inboxOf(t, "leo@example.com", "student"); len(got) != 0 {
+		t.Errorf("expected nothing in the inbox of a student who opted out, got %+v", got)
+	}
+}
+
+func TestSendReminders(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	create := func(schedule time.Time, student primitive.ObjectID, status string) primitive.ObjectID {
+		id, err := f.lessons.CreateLesson(ctx, lessons.Lesson{
+			InstructorID: f.instructor.ID, StudentID: student, Title: "Parking", Schedule: schedule, Status: status,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	tomorrow := create(f.now.Add(20*time.Hour), f.student.ID, lessons.StatusScheduled)
+	soon := create(f.now.Add(90*time.Minute), f.student.ID, lessons.StatusScheduled)
+	create(f.now.Add(3*time.Hour), primitive.NilObjectID, lessons.StatusScheduled)
+	create(f.now.Add(time.Hour), f.student.ID, lessons.StatusCancelled)
+	create(f.now.Add(30*time.Hour), f.student.ID, lessons.StatusScheduled)
+
+	sent, err := f.notifier.SendReminders(ctx)
+	if err != nil {
+		t.Fatalf("SendReminders failed: %v", err)
+	}
+	if sent != 2 {
+		t.Fatalf("expected reminders for the two booked lessons within 24 hours, got %d", sent)
+	}
+	if sent, err := f.notifier.SendReminders(ctx); err != nil || sent != 0 {
+		t.Fatalf("expected the lessons already reminded of to be left out, got %d, %v", sent, err)
+	}
+	got := f.inboxOf(t, f.student.Email, "student")
+	if len(got) != 2 {
+		t.Fatalf("expected each reminder once, got %+v", got)
+	}
+	reminded := map[primitive.ObjectID]bool{}
+	for _, n := range got {
+		reminded[n.LessonID] = n.Kind == KindLessonReminder
+	}
+	if !reminded[tomorrow] || !reminded[soon] {
+		t.Errorf("expected reminders for %s and %s, got %+v", tomorrow.Hex(), soon.Hex(), got)
+	}
+	if mails := f.smtp.Messages(); len(mails) != 4 {
+		t.Errorf("expected the student and instructor to be emailed twice each, got %d emails", len(mails))
+	}
+
+	// Tomorrow's lesson is now within two hours and the last one within 24
+	f.now = f.now.Add(19 * time.Hour)
+	if sent, err := f.notifier.SendReminders(ctx); err != nil || sent != 2 {
+		t.Fatalf("expected two more reminders, got %d, %v", sent, err)
+	}
+	if got := f.inboxOf(t, f.student.Email, "student"); len(got) != 4 || got[0].LessonID == got[1].LessonID {
+		t.Errorf("expected a reminder for each lesson, got %+v", got)
+	}
+}
+
+func TestTwilioSMS(t *testing.T) {
+	var form url.Values
+	var user, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" {
+			http.NotFound(w, r)
+			return
+		}
+		user, password, _ = r.BasicAuth()
+		body, _ := io.ReadAll(r.Body)
+		form, _ = url.ParseQuery(string(body))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	sms := NewTwilioSMS(config.TwilioConfig{APIURL: server.URL, AccountSID: "AC123", AuthToken: "token", From: "MG456"})
+	if err := sms.SendSMS(context.Background(), "+34600000001", "Reminder"); err != nil {
+		t.Fatalf("SendSMS failed: %v", err)
+	}
+	if user != "AC123" || password != "token" {
+		t.Errorf("expected basic auth with the account SID and token, got %q:%q", user, password)
+	}
+	if form.Get("To") != "+34600000001" || form.Get("Body") != "Reminder" || form.Get("MessagingServiceSid") != "MG456" || form.Has("From") {
+		t.Errorf("unexpected form %v", form)
+	}
+
+	sms = NewTwilioSMS(config.TwilioConfig{APIURL: server.URL, AccountSID: "AC999", AuthToken: "token", From: "+15550000000"})
+	if err := sms.SendSMS(context.Background(), "+34600000001", "Reminder"); err == nil {
+		t.Error("expected an error when Twilio does not accept the message")
+	}
+}
@@ -0,0 +1,55 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultLimit and MaxLimit bound how many notifications ListNotifications returns.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+// Query selects in-app notifications of one recipient for ListNotifications.
+// Zero-valued optional fields are ignored.
+type Query struct {
+	Email string
+	Role  string
+	// UnreadOnly leaves out the notifications that have been read
+	UnreadOnly bool
+	// Before and After keep only the notifications older, or newer, than
+	// the one with that ID
+	Before primitive.ObjectID
+	After  primitive.ObjectID
+	// Limit of zero means DefaultLimit
+	Limit int
+}
+
+type InboxRepository interface {
+	// AddNotification fails with a conflict if a notification with the same
+	// Key exists.
+	AddNotification(ctx context.Context, notification Notification) (primitive.ObjectID, error)
+	// SentKeys returns which of keys a notification has been added under.
+	SentKeys(ctx context.Context, keys []string) (map[string]bool, error)
+	// ListNotifications returns the in-app notifications matching query,
+	// newest first.
+	ListNotifications(ctx context.Context, query Query) ([]Notification, error)
+	CountUnread(ctx context.Context, email, role string) (int, error)
+	// MarkRead marks an in-app notification of the recipient with the given
+	// email and role read at at, unless it already was. Anyone else's
+	// notification is not found.
+	MarkRead(ctx context.Context, email, role string, id primitive.ObjectID, at time.Time) error
+	// MarkAllRead marks every unread in-app notification of the recipient
+	// read at at and returns how many there were.
+	MarkAllRead(ctx context.Context, email, role string, at time.Time) (int, error)
+}
+
+func limit(n int) int {
+	if n <= 0 {
+		return DefaultLimit
+	}
+	return min(n, MaxLimit)
+}
@@ -0,0 +1,161 @@
+package notifications
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryInboxRepository(t *testing.T) {
+	testInboxRepository(t, func(t *testing.T) InboxRepository {
+		return NewMemoryInboxRepository()
+	})
+}
+
+func TestMongoInboxRepository(t *testing.T) {
+	testInboxRepository(t, func(t *testing.T) InboxRepository {
+		return NewMongoInboxRepository(mongotest.Database(t))
+	})
+}
+
+// testInboxRepository is the contract every InboxRepository must satisfy.
+func testInboxRepository(t *testing.T, newRepo func(t *testing.T) InboxRepository) {
+	ctx := context.Background()
+	student := Recipient{ID: primitive.NewObjectID(), Role: "student", Email: "ana@example.com"}
+	instructor := Recipient{ID: primitive.NewObjectID(), Role: "instructor", Email: "ana@example.com"}
+
+	t.Run("keys are unique", func(t *testing.T) {
+		repo := newRepo(t)
+		notification := Notification{Key: "event:1:student", Recipient: student, Kind: KindLessonBooked, InApp: true}
+		if _, err := repo.AddNotification(ctx, notification); err != nil {
+			t.Fatalf("AddNotification failed: %v", err)
+		}
+		if _, err := repo.AddNotification(ctx, notification); apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("expected a conflict adding the same key twice, got %v", err)
+		}
+		sent, err := repo.SentKeys(ctx, []string{"event:1:student", "event:1:instructor"})
+		if err != nil || !maps.Equal(sent, map[string]bool{"event:1:student": true}) {
+			t.Fatalf("expected only the added key to be sent, got %v, %v", sent, err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, n := range []Notification{
+			{Key: "1", Recipient: student, Subject: "first", InApp: true},
+			{Key: "2", Recipient: student, Subject: "email only"},
+			{Key: "3", Recipient: instructor, Subject: "for the instructor", InApp: true},
+			{Key: "4", Recipient: student, Subject: "second", InApp: true},
+		} {
+			if _, err := repo.AddNotification(ctx, n); err != nil {
+				t.Fatalf("AddNotification failed: %v", err)
+			}
+		}
+
+		got, err := repo.ListNotifications(ctx, Query{Email: student.Email, Role: student.Role})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if len(got) != 2 || got[0].Subject != "second" || got[1].Subject != "first" {
+			t.Fatalf("expected the student's in-app notifications newest first, got %+v", got)
+		}
+		if got[0].Recipient != student || got[0].CreatedAt.IsZero() {
+			t.Errorf("expected the recipient and creation time to be stored, got %+v", got[0])
+		}
+
+		got, err = repo.ListNotifications(ctx, Query{Email: student.Email, Role: student.Role, Limit: 1})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if len(got) != 1 || got[0].Subject != "second" {
+			t.Fatalf("expected the limit to keep the newest, got %+v", got)
+		}
+		second := got[0].ID
+
+		got, err = repo.ListNotifications(ctx, Query{Email: student.Email, Role: student.Role, Before: second})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if len(got) != 1 || got[0].Subject != "first" {
+			t.Fatalf("expected the notifications before %s, got %+v", second.Hex(), got)
+		}
+		got, err = repo.ListNotifications(ctx, Query{Email: student.Email, Role: student.Role, After: got[0].ID})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != second {
+			t.Fatalf("expected the notifications after the first, got %+v", got)
+		}
+	})
+
+	t.Run("read state", func(t *testing.T) {
+		repo := newRepo(t)
+		var ids []primitive.ObjectID
+		for _, key := range []string{"1", "2", "3"} {
+			id, err := repo.AddNotification(ctx, Notification{Key: key, Recipient: student, InApp: true})
+			if err != nil {
+				t.Fatalf("AddNotification failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		if _, err := repo.AddNotification(ctx, Notification{Key: "4", Recipient: instructor, InApp: true}); err != nil {
+			t.Fatalf("AddNotification failed: %v", err)
+		}
+		countUnread := func(r Recipient) int {
+			t.Helper()
+			n, err := repo.CountUnread(ctx, r.Email, r.Role)
+			if err != nil {
+				t.Fatalf("CountUnread failed: %v", err)
+			}
+			return n
+		}
+		if n := countUnread(student); n != 3 {
+			t.Fatalf("expected 3 unread, got %d", n)
+		}
+
+		readAt := time.Now().Truncate(time.Millisecond)
+		if err := repo.MarkRead(ctx, student.Email, student.Role, ids[0], readAt); err != nil {
+			t.Fatalf("MarkRead failed: %v", err)
+		}
+		// Marking it again keeps the time it was first read
+		if err := repo.MarkRead(ctx, student.Email, student.Role, ids[0], readAt.Add(time.Hour)); err != nil {
+			t.Fatalf("MarkRead failed: %v", err)
+		}
+		if err := repo.MarkRead(ctx, instructor.Email, instructor.Role, ids[1], readAt); !apperr.IsNotFound(err) {
+			t.Fatalf("expected someone else's notification not to be found, got %v", err)
+		}
+		if n := countUnread(student); n != 2 {
+			t.Fatalf("expected 2 unread, got %d", n)
+		}
+		got, err := repo.ListNotifications(ctx, Query{Email: student.Email, Role: student.Role})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if got[2].ReadAt == nil || !got[2].ReadAt.Equal(readAt) || got[1].ReadAt != nil {
+			t.Fatalf("expected only the first notification to be read at %v, got %+v", readAt, got)
+		}
+		got, err = repo.ListNotifications(ctx, Query{Email: student.Email, Role: student.Role, UnreadOnly: true})
+		if err != nil {
+			t.Fatalf("ListNotifications failed: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("expected the 2 unread notifications, got %+v", got)
+		}
+
+		marked, err := repo.MarkAllRead(ctx, student.Email, student.Role, readAt)
+		if err != nil {
+			t.Fatalf("MarkAllRead failed: %v", err)
+		}
+		if marked != 2 || countUnread(student) != 0 {
+			t.Fatalf("expected 2 notifications to be marked read, got %d", marked)
+		}
+		if n := countUnread(instructor); n != 1 {
+			t.Fatalf("expected the instructor's notification to stay unread, got %d unread", n)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+)
+
+// SMSProvider sends text messages. New providers only need to implement it
+// and be added to NewSMSProvider.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// NewSMSProvider returns the provider named by cfg.SMSProvider.
+func NewSMSProvider(cfg config.NotificationConfig, logger *slog.Logger) (SMSProvider, error) {
+	switch cfg.SMSProvider {
+	case "log":
+		return LogSMS{logger: logger}, nil
+	case "twilio":
+		return NewTwilioSMS(cfg.Twilio), nil
+	}
+	return nil, fmt.Errorf("unknown SMS provider %q", cfg.SMSProvider)
+}
+
+// LogSMS writes text messages to the log instead of sending them, for development.
+type LogSMS struct {
+	logger *slog.Logger
+}
+
+func (p LogSMS) SendSMS(ctx context.Context, to, body string) error {
+	p.logger.InfoContext(ctx, "text message not sent, SMS_PROVIDER=log", slog.String("to", to), slog.String("body", body))
+	return nil
+}
+
+// TwilioSMS sends text messages with Twilio's Messages API.
+type TwilioSMS struct {
+	cfg    config.TwilioConfig
+	client *http.Client
+}
+
+func NewTwilioSMS(cfg config.TwilioConfig) *TwilioSMS {
+	return &TwilioSMS{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *TwilioSMS) SendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	// Messaging service IDs start with MG; anything else is a phone number
+	if strings.HasPrefix(p.cfg.From, "MG") {
+		form.Set("MessagingServiceSid", p.cfg.From)
+	} else {
+		form.Set("From", p.cfg.From)
+	}
+	endpoint := p.cfg.APIURL + "/2010-04-01/Accounts/" + url.PathEscape(p.cfg.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.cfg.AccountSID, p.cfg.AuthToken)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("twilio responded with status %d: %s", resp.StatusCode, detail)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// message templates, three per Kind: the email subject, the email and in-app
+// body, and the shorter text message.
+var templates = template.Must(template.New("notifications").Parse(`
+{{define "lesson_booked.subject"}}Lesson booked: {{.Title}} on {{.Time}}{{end}}
+{{define "lesson_booked.body"}}Hi {{.Name}},
+
+{{.Title}} with {{.With}} is booked for {{.Time}}.
+
+See you there!{{end}}
+{{define "lesson_booked.sms"}}Booked: {{.Title}} with {{.With}}, {{.Time}}.{{end}}
+
+{{define "lesson_rescheduled.subject"}}Lesson moved: {{.Title}} is now on {{.Time}}{{end}}
+{{define "lesson_rescheduled.body"}}Hi {{.Name}},
+
+{{.Title}} with {{.With}} has moved from {{.PreviousTime}} to {{.Time}}.{{end}}
+{{define "lesson_rescheduled.sms"}}Moved: {{.Title}} with {{.With}} is now {{.Time}} (was {{.PreviousTime}}).{{end}}
+
+{{define "lesson_cancelled.subject"}}Lesson cancelled: {{.Title}} on {{.Time}}{{end}}
+{{define "lesson_cancelled.body"}}Hi {{.Name}},
+
+{{.Title}}{{with .With}} with {{.}}{{end}} on {{.Time}} has been cancelled.{{end}}
+{{define "lesson_cancelled.sms"}}Cancelled: {{.Title}} on {{.Time}}.{{end}}
+
+{{define "lesson_reminder.subject"}}Reminder: {{.Title}} on {{.Time}}{{end}}
+{{define "lesson_reminder.body"}}Hi {{.Name}},
+
+A reminder that {{.Title}} with {{.With}} is on {{.Time}}.{{end}}
+{{define "lesson_reminder.sms"}}Reminder: {{.Title}} with {{.With}}, {{.Time}}.{{end}}
+`))
+
+// timeLayout is how lesson times appear in messages.
+const timeLayout = "Mon 2 Jan 2006 at 15:04 MST"
+
+// templateData is what the templates can refer to.
+type templateData struct {
+	// Name is the recipient's
+	Name string
+	// With is the other person on the lesson: the instructor for a student
+	// and the student for an instructor
+	With         string
+	Title        string
+	Time         string
+	PreviousTime string
+}
+
+// rendered is a notification's text for every channel.
+type rendered struct {
+	Subject string
+	Body    string
+	SMS     string
+}
+
+func render(kind Kind, data templateData) (rendered, error) {
+	var r rendered
+	for _, part := range []struct {
+		name string
+		dst  *string
+	}{{"subject", &r.Subject}, {"body", &r.Body}, {"sms", &r.SMS}} {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, fmt.Sprintf("%s.%s", kind, part.name), data); err != nil {
+			return rendered{}, err
+		}
+		*part.dst = strings.TrimSpace(buf.String())
+	}
+	return r, nil
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+)
+
+// Logins protects the login endpoints of one account at a time: it limits how
+// often an account may try to log in and locks it out after repeated failures,
+// whichever client IPs the attempts come from. Store failures are logged and
+// never block a login.
+type Logins struct {
+	store   Store
+	limiter *Limiter
+	policy  LockoutPolicy
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+func NewLogins(store Store, perAccount Limit, policy LockoutPolicy, logger *slog.Logger) *Logins {
+	return &Logins{
+		store:   store,
+		limiter: NewLimiter(store, "account", perAccount),
+		policy:  policy,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+func lockoutKey(account string) string {
+	return "lockout:" + account
+}
+
+// normalize makes differently typed versions of one email share their state.
+func normalize(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+// Allow reports whether a login for account may go ahead. It returns
+// ErrLockedOut while the account is locked and ErrRateLimited when the
+// account's bucket is empty.
+func (l *Logins) Allow(ctx context.Context, account string) (Decision, error) {
+	account = normalize(account)
+	if !l.policy.disabled() {
+		until, err := l.store.LockedUntil(ctx, lockoutKey(account))
+		if err != nil {
+			l.logger.WarnContext(ctx, "failed to read login lockout", slog.Any("error", err))
+		} else if wait := until.Sub(l.now()); wait > 0 {
+			l.logger.InfoContext(ctx, "login refused for locked account", slog.String("email", account))
+			decision := Decision{Limit: l.limiter.limit, RetryAfter: wait}
+			return decision, ErrLockedOut.WithDetail("retry_after", int(math.Ceil(wait.Seconds())))
+		}
+	}
+	decision, err := l.limiter.Allow(ctx, account)
+	if err != nil && apperr.KindOf(err) != apperr.KindTooManyRequests {
+		l.logger.WarnContext(ctx, "failed to rate limit login", slog.Any("error", err))
+		return decision, nil
+	}
+	return decision, err
+}
+
+// Record updates the lockout state of account with the result of a login:
+// rejected credentials count as a failure and a success clears the failures.
+// Other errors, such as the identity provider being down, are not the
+// caller's fault and change nothing.
+func (l *Logins) Record(ctx context.Context, account string, loginErr error) {
+	if l.policy.disabled() {
+		return
+	}
+	account = normalize(account)
+	key := lockoutKey(account)
+	switch {
+	case loginErr == nil:
+		if err := l.store.Reset(ctx, key); err != nil {
+			l.logger.WarnContext(ctx, "failed to reset login failures", slog.Any("error", err))
+		}
+	case apperr.KindOf(loginErr) == apperr.KindUnauthorized:
+		now := l.now()
+		until, err := l.store.Fail(ctx, key, l.policy, now)
+		if err != nil {
+			l.logger.WarnContext(ctx, "failed to record login failure", slog.Any("error", err))
+		} else if until.After(now) {
+			l.logger.WarnContext(ctx, "account locked after failed logins", slog.String("email", account), slog.Time("locked_until", until))
+		}
+	}
+}
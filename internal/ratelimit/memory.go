@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore drops state that no longer matters.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// idle is when the bucket will be full again and can be forgotten.
+	idle time.Time
+}
+
+type lockout struct {
+	failures    int
+	lockedUntil time.Time
+	// expires is when the failures are forgotten.
+	expires time.Time
+}
+
+// MemoryStore keeps rate limiting state in process. Each API instance has its
+// own, so limits are per instance when several run behind a load balancer.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lockouts  map[string]*lockout
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		lockouts: make(map[string]*lockout),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	rate := limit.rate()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = min(float64(limit.Burst), b.tokens+elapsed*rate)
+		b.updated = now
+	}
+
+	decision := Decision{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = durationOf((1 - b.tokens) / rate)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = durationOf((float64(limit.Burst) - b.tokens) / rate)
+	b.idle = now.Add(decision.Reset)
+	return decision, nil
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, policy LockoutPolicy, now time.Time) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	l, ok := s.lockouts[key]
+	if !ok || !now.Before(l.expires) {
+		l = &lockout{}
+		s.lockouts[key] = l
+	}
+	l.failures++
+	if lock := policy.LockFor(l.failures); lock > 0 {
+		l.lockedUntil = now.Add(lock)
+	}
+	l.expires = now.Add(max(policy.Max, policy.Base))
+	if l.lockedUntil.After(l.expires) {
+		l.expires = l.lockedUntil
+	}
+	return l.lockedUntil, nil
+}
+
+func (s *MemoryStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.lockouts[key]; ok {
+		return l.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lockouts, key)
+	return nil
+}
+
+// sweep drops full buckets and forgotten failures, so that the maps do not
+// grow with every client that ever made a request. The caller holds s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.idle) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, l := range s.lockouts {
+		if !now.Before(l.expires) {
+			delete(s.lockouts, key)
+		}
+	}
+}
+
+func durationOf(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
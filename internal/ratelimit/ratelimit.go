@@ -0,0 +1,136 @@
+// Package ratelimit throttles requests with token buckets and locks accounts
+// out after repeated failed logins. All state lives in a Store, so several API
+// instances can share it; MemoryStore keeps it inside one process.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+)
+
+var (
+	ErrRateLimited = apperr.TooManyRequests("rate_limited", "too many requests, try again later")
+	ErrLockedOut   = apperr.TooManyRequests("account_locked", "too many failed logins, try again later")
+)
+
+// Limit is a token bucket: Burst requests may arrive at once, and an empty
+// bucket refills completely over Per. A zero Burst disables the limit.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+func (l Limit) disabled() bool {
+	return l.Burst <= 0 || l.Per <= 0
+}
+
+// rate is the number of tokens added per second.
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Per.Seconds()
+}
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until a token is available; zero when allowed.
+	RetryAfter time.Duration
+}
+
+// WriteHeaders sets the RateLimit-* headers from the IETF draft, plus
+// Retry-After when the request was refused.
+func (d Decision) WriteHeaders(h http.Header) {
+	if d.Limit.disabled() {
+		return
+	}
+	h.Set("RateLimit-Policy", strconv.Itoa(d.Limit.Burst)+";w="+seconds(d.Limit.Per))
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", seconds(d.Reset))
+	if !d.Allowed {
+		h.Set("Retry-After", seconds(d.RetryAfter))
+	}
+}
+
+// seconds rounds d up to whole seconds, so clients never retry too early.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// LockoutPolicy decides how long an account is locked after failed logins.
+// The first Threshold failures are free; each failure after that locks the
+// account for Base, doubling with every further failure up to Max. Failures
+// are forgotten once Max has passed since the last one.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+func (p LockoutPolicy) disabled() bool {
+	return p.Threshold <= 0 || p.Base <= 0
+}
+
+// LockFor returns how long the account is locked after its nth consecutive failure.
+func (p LockoutPolicy) LockFor(failures int) time.Duration {
+	if p.disabled() || failures < p.Threshold {
+		return 0
+	}
+	lock := p.Base
+	for i := p.Threshold; i < failures && lock < p.Max; i++ {
+		lock *= 2
+	}
+	return min(lock, max(p.Max, p.Base))
+}
+
+// Store holds bucket and lockout state. Implementations must be safe for
+// concurrent use and apply each call atomically.
+type Store interface {
+	// Take removes a token from the bucket at key, refilling it first.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+	// Fail records a failed login for key and returns when its lock ends,
+	// which is not after now if the account is not locked.
+	Fail(ctx context.Context, key string, policy LockoutPolicy, now time.Time) (time.Time, error)
+	// LockedUntil returns when the lock on key ends.
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset forgets the failed logins recorded for key.
+	Reset(ctx context.Context, key string) error
+}
+
+// Limiter applies one Limit to many keys, for example one bucket per client IP.
+type Limiter struct {
+	store  Store
+	prefix string
+	limit  Limit
+	now    func() time.Time
+}
+
+// NewLimiter returns a limiter whose buckets are stored under prefix, so that
+// limiters sharing a store do not share buckets.
+func NewLimiter(store Store, prefix string, limit Limit) *Limiter {
+	return &Limiter{store: store, prefix: prefix, limit: limit, now: time.Now}
+}
+
+// Allow takes a token for key. The error is ErrRateLimited when the bucket is
+// empty, or the store's error, in which case the request should be let through.
+func (l *Limiter) Allow(ctx context.Context, key string) (Decision, error) {
+	if l.limit.disabled() {
+		return Decision{Allowed: true, Limit: l.limit}, nil
+	}
+	decision, err := l.store.Take(ctx, l.prefix+":"+key, l.limit, l.now())
+	if err != nil {
+		return Decision{Allowed: true}, err
+	}
+	if !decision.Allowed {
+		return decision, ErrRateLimited.WithDetail("retry_after", int(math.Ceil(decision.RetryAfter.Seconds())))
+	}
+	return decision, nil
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func TestLimiterRefillsOverTime(t *testing.T) {
+	ctx := context.Background()
+	c := &clock{now: time.Unix(1_700_000_000, 0)}
+	limiter := NewLimiter(NewMemoryStore(), "ip", Limit{Burst: 2, Per: time.Minute})
+	limiter.now = c.Now
+
+	for i := 0; i < 2; i++ {
+		if _, err := limiter.Allow(ctx, "192.0.2.1"); err != nil {
+			t.Fatalf("Request %d: expected to be allowed but got %v", i+1, err)
+		}
+	}
+	decision, err := limiter.Allow(ctx, "192.0.2.1")
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("Expected ErrRateLimited but got %v", err)
+	}
+	if decision.RetryAfter != 30*time.Second {
+		t.Fatalf("Expected to retry after 30s but got %s", decision.RetryAfter)
+	}
+
+	header := http.Header{}
+	decision.WriteHeaders(header)
+	want := map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "30", "RateLimit-Policy": "2;w=60"}
+	for key, value := range want {
+		if got := header.Get(key); got != value {
+			t.Errorf("Expected %s: %s but got %q", key, value, got)
+		}
+	}
+
+	if _, err := limiter.Allow(ctx, "192.0.2.2"); err != nil {
+		t.Fatalf("Expected another IP to have its own bucket but got %v", err)
+	}
+	c.now = c.now.Add(30 * time.Second)
+	if _, err := limiter.Allow(ctx, "192.0.2.1"); err != nil {
+		t.Fatalf("Expected a token after refilling but got %v", err)
+	}
+}
+
+func TestLockForDoublesUpToMax(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 3, Base: time.Minute, Max: 5 * time.Minute}
+	want := []time.Duration{0, 0, time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, lock := range want {
+		if got := policy.LockFor(i + 1); got != lock {
+			t.Errorf("Failure %d: expected a %s lock but got %s", i+1, lock, got)
+		}
+	}
+}
+
+func TestLoginsLockOutAfterRepeatedFailures(t *testing.T) {
+	ctx := context.Background()
+	c := &clock{now: time.Unix(1_700_000_000, 0)}
+	logins := NewLogins(NewMemoryStore(), Limit{}, LockoutPolicy{Threshold: 2, Base: time.Minute, Max: time.Hour}, logging.Discard())
+	logins.now = c.Now
+
+	logins.Record(ctx, "John@Example.com", identity.ErrInvalidCredentials)
+	if _, err := logins.Allow(ctx, "john@example.com"); err != nil {
+		t.Fatalf("Expected a single failure to be tolerated but got %v", err)
+	}
+	// An outage is not the caller's fault
+	logins.Record(ctx, "john@example.com", errors.New("identity provider unavailable"))
+	logins.Record(ctx, " john@example.com", identity.ErrInvalidCredentials)
+
+	decision, err := logins.Allow(ctx, "john@example.com")
+	if !errors.Is(err, ErrLockedOut) {
+		t.Fatalf("Expected ErrLockedOut but got %v", err)
+	}
+	if decision.RetryAfter != time.Minute {
+		t.Fatalf("Expected to retry after a minute but got %s", decision.RetryAfter)
+	}
+
+	c.now = c.now.Add(time.Minute)
+	if _, err := logins.Allow(ctx, "john@example.com"); err != nil {
+		t.Fatalf("Expected the lock to expire but got %v", err)
+	}
+	logins.Record(ctx, "john@example.com", identity.ErrInvalidCredentials)
+	if decision, _ := logins.Allow(ctx, "john@example.com"); decision.RetryAfter != 2*time.Minute {
+		t.Fatalf("Expected the next lock to last two minutes but got %s", decision.RetryAfter)
+	}
+
+	c.now = c.now.Add(2 * time.Minute)
+	logins.Record(ctx, "john@example.com", nil)
+	logins.Record(ctx, "john@example.com", identity.ErrInvalidCredentials)
+	if _, err := logins.Allow(ctx, "john@example.com"); err != nil {
+		t.Fatalf("Expected a successful login to clear the failures but got %v", err)
+	}
+}
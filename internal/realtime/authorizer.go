@@ -0,0 +1,67 @@
+package realtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+)
+
+var errForbiddenTopic = apperr.Forbidden("forbidden_topic", "you are not allowed to watch this schedule")
+
+// Authorizer decides who may watch which schedule: admins any, instructors
+// their own and those of the vehicles they have upcoming lessons in, and
+// students their own.
+type Authorizer struct {
+	students    students.StudentRepository
+	instructors instructors.InstructorRepository
+	lessons     lessons.LessonRepository
+	now         func() time.Time
+}
+
+func NewAuthorizer(studentRepo students.StudentRepository, instructorRepo instructors.InstructorRepository, lessonRepo lessons.LessonRepository) *Authorizer {
+	return &Authorizer{students: studentRepo, instructors: instructorRepo, lessons: lessonRepo, now: time.Now}
+}
+
+func (a *Authorizer) Authorize(ctx context.Context, principal *identity.Principal, topic Topic) error {
+	switch principal.Role {
+	case identity.RoleAdmin:
+		return nil
+	case identity.RoleInstructor:
+		if topic.Kind != KindInstructor && topic.Kind != KindVehicle {
+			return errForbiddenTopic
+		}
+		instructor, err := a.instructors.GetInstructorByEmail(ctx, principal.Email)
+		if err != nil || topic.Kind == KindInstructor {
+			return own(instructor != nil && instructor.ID == topic.ID, err)
+		}
+		upcoming, err := a.lessons.ListLessons(ctx, lessons.Filter{InstructorID: instructor.ID, VehicleID: topic.ID, From: a.now()})
+		return own(len(upcoming) > 0, err)
+	case identity.RoleStudent:
+		if topic.Kind != KindStudent {
+			return errForbiddenTopic
+		}
+		student, err := a.students.GetStudentByEmail(ctx, principal.Email)
+		return own(student != nil && student.ID == topic.ID, err)
+	}
+	return errForbiddenTopic
+}
+
+// own turns the lookup of the caller's own record into the authorization
+// decision. A caller without one owns nothing.
+func own(matches bool, err error) error {
+	if apperr.IsNotFound(err) {
+		return errForbiddenTopic
+	}
+	if err != nil {
+		return err
+	}
+	if !matches {
+		return errForbiddenTopic
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Package realtime pushes changes to instructors', vehicles' and students'
+// schedules to the clients watching them. The Publisher turns the lesson and
+// availability events the Dispatcher hands it into Messages on a Broker,
+// which delivers each to the subscribers of its topic.
+//
+// Hub is an in-process Broker, so a client only hears of the changes whose
+// events were dispatched by the instance it is connected to. Running several
+// instances needs a Broker backed by something they share, such as Redis or
+// NATS.
+package realtime
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker delivers messages to the subscribers of their topic.
+type Broker interface {
+	Publish(ctx context.Context, topic Topic, msg Message) error
+	// Subscribe calls deliver with every message published to topic until
+	// unsubscribe is called. deliver must not block.
+	Subscribe(topic Topic, deliver func(Message)) (unsubscribe func())
+	// Done is closed when the broker shuts down, which should end every
+	// subscriber's connection.
+	Done() <-chan struct{}
+}
+
+type subscriber struct {
+	deliver func(Message)
+}
+
+// Hub is the in-process Broker.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[Topic]map[*subscriber]struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[Topic]map[*subscriber]struct{}{}, done: make(chan struct{})}
+}
+
+func (h *Hub) Publish(ctx context.Context, topic Topic, msg Message) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subscribers[topic] {
+		s.deliver(msg)
+	}
+	return nil
+}
+
+func (h *Hub) Subscribe(topic Topic, deliver func(Message)) func() {
+	s := &subscriber{deliver: deliver}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[topic] == nil {
+		h.subscribers[topic] = map[*subscriber]struct{}{}
+	}
+	h.subscribers[topic][s] = struct{}{}
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[topic], s)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+}
+
+func (h *Hub) Done() <-chan struct{} {
+	return h.done
+}
+
+// Close closes Done, so that the server can shut down.
+func (h *Hub) Close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
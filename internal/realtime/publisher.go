@@ -0,0 +1,62 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+)
+
+// Publisher is the events.Publisher that puts schedule changes on a Broker.
+type Publisher struct {
+	broker Broker
+}
+
+func NewPublisher(broker Broker) *Publisher {
+	return &Publisher{broker: broker}
+}
+
+func (p *Publisher) Publish(ctx context.Context, event events.Event) error {
+	switch event.Type {
+	case events.TypeLessonChanged:
+		var payload events.LessonChanged
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		topics := lessonTopics(payload.Lesson)
+		// Whoever the lesson was moved away from is told too
+		if payload.Previous != nil {
+			topics = append(topics, lessonTopics(*payload.Previous)...)
+		}
+		return p.publish(ctx, event, "lesson."+string(payload.Change), payload, topics)
+	case events.TypeAvailabilityChanged:
+		var payload events.AvailabilityChanged
+		if err := event.Decode(&payload); err != nil {
+			return err
+		}
+		return p.publish(ctx, event, "availability."+string(payload.Change), payload, []Topic{{KindInstructor, payload.InstructorID}})
+	}
+	return nil
+}
+
+func (p *Publisher) publish(ctx context.Context, event events.Event, typ string, payload events.Payload, topics []Topic) error {
+	var errs []error
+	seen := map[Topic]bool{}
+	for _, topic := range topics {
+		if topic.ID.IsZero() || seen[topic] {
+			continue
+		}
+		seen[topic] = true
+		msg := Message{Type: typ, Topic: topic.String(), EventID: event.ID.Hex(), OccurredAt: event.OccurredAt, Data: payload}
+		errs = append(errs, p.broker.Publish(ctx, topic, msg))
+	}
+	return errors.Join(errs...)
+}
+
+func lessonTopics(lesson events.Lesson) []Topic {
+	return []Topic{
+		{KindInstructor, lesson.InstructorID},
+		{KindVehicle, lesson.VehicleID},
+		{KindStudent, lesson.StudentID},
+	}
+}
@@ -0,0 +1,156 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func event(t *testing.T, payload events.Payload) events.Event {
+	t.Helper()
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.Event{ID: primitive.NewObjectID(), Type: payload.EventType(), AggregateID: payload.AggregateID(), OccurredAt: time.Now(), Payload: raw}
+}
+
+// watch subscribes to topic and returns the messages it has received so far.
+func watch(hub *Hub, topic Topic) func() []Message {
+	var received []Message
+	hub.Subscribe(topic, func(msg Message) { received = append(received, msg) })
+	return func() []Message { return received }
+}
+
+func TestPublisherReachesEveryoneOnTheLesson(t *testing.T) {
+	hub := NewHub()
+	publisher := NewPublisher(hub)
+	oldInstructor, newInstructor := Topic{KindInstructor, primitive.NewObjectID()}, Topic{KindInstructor, primitive.NewObjectID()}
+	student, vehicle := Topic{KindStudent, primitive.NewObjectID()}, Topic{KindVehicle, primitive.NewObjectID()}
+	toOld, toNew, toStudent, toVehicle := watch(hub, oldInstructor), watch(hub, newInstructor), watch(hub, student), watch(hub, vehicle)
+
+	lesson := events.Lesson{LessonID: primitive.NewObjectID(), InstructorID: oldInstructor.ID, StudentID: student.ID, VehicleID: vehicle.ID}
+	moved := lesson
+	moved.InstructorID = newInstructor.ID
+	change := event(t, events.LessonChanged{Lesson: moved, Change: events.ChangeUpdated, Previous: &lesson})
+	if err := publisher.Publish(context.Background(), change); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, received := range map[string][]Message{"old instructor": toOld(), "new instructor": toNew(), "student": toStudent(), "vehicle": toVehicle()} {
+		// The student and vehicle are on both versions of the lesson, but hear of it once
+		if len(received) != 1 {
+			t.Fatalf("Expected the %s to get one message but got %+v", name, received)
+		}
+		if msg := received[0]; msg.Type != "lesson.updated" || msg.EventID != change.ID.Hex() {
+			t.Fatalf("Unexpected message to the %s: %+v", name, msg)
+		}
+	}
+	if toOld()[0].Topic != oldInstructor.String() {
+		t.Fatalf("Expected the message to name the old instructor's topic but got %q", toOld()[0].Topic)
+	}
+
+	// Other events do not concern schedules
+	if err := publisher.Publish(context.Background(), event(t, events.LessonBooked{Lesson: lesson})); err != nil {
+		t.Fatal(err)
+	}
+	deleted := event(t, events.AvailabilityChanged{AvailabilityID: primitive.NewObjectID(), InstructorID: newInstructor.ID, Change: events.ChangeDeleted})
+	if err := publisher.Publish(context.Background(), deleted); err != nil {
+		t.Fatal(err)
+	}
+	if received := toNew(); len(received) != 2 || received[1].Type != "availability.deleted" {
+		t.Fatalf("Expected the availability change to reach the instructor but got %+v", received)
+	}
+	if len(toOld()) != 1 {
+		t.Fatalf("Expected the other instructor to hear nothing more but got %+v", toOld())
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+	topic := Topic{KindStudent, primitive.NewObjectID()}
+	var received int
+	unsubscribe := hub.Subscribe(topic, func(Message) { received++ })
+	hub.Publish(context.Background(), topic, Message{})
+	unsubscribe()
+	hub.Publish(context.Background(), topic, Message{})
+	if received != 1 {
+		t.Fatalf("Expected one message before unsubscribing but got %d", received)
+	}
+
+	hub.Close()
+	hub.Close()
+	select {
+	case <-hub.Done():
+	default:
+		t.Fatal("Expected Done to be closed")
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	studentRepo, instructorRepo := students.NewMemoryStudentRepository(), instructors.NewMemoryInstructorRepository()
+	studentID, err := studentRepo.CreateStudent(ctx, students.Student{Email: "student@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instructorID, err := instructorRepo.CreateInstructor(ctx, instructors.Instructor{Email: "instructor@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lessonRepo := lessons.NewMemoryLessonRepository()
+	car, van := primitive.NewObjectID(), primitive.NewObjectID()
+	for _, lesson := range []lessons.Lesson{
+		{InstructorID: instructorID, VehicleID: car, Schedule: time.Now().Add(time.Hour), Status: lessons.StatusScheduled},
+		// Past lessons give no claim on the vehicle
+		{InstructorID: instructorID, VehicleID: van, Schedule: time.Now().Add(-time.Hour), Status: lessons.StatusScheduled},
+	} {
+		if _, err := lessonRepo.CreateLesson(ctx, lesson); err != nil {
+			t.Fatal(err)
+		}
+	}
+	authorizer := NewAuthorizer(studentRepo, instructorRepo, lessonRepo)
+
+	admin := &identity.Principal{Email: "admin@example.com", Role: identity.RoleAdmin}
+	asStudent := &identity.Principal{Email: "student@example.com", Role: identity.RoleStudent}
+	asInstructor := &identity.Principal{Email: "instructor@example.com", Role: identity.RoleInstructor}
+	stranger := &identity.Principal{Email: "stranger@example.com", Role: identity.RoleStudent}
+	other := primitive.NewObjectID()
+
+	tests := []struct {
+		principal *identity.Principal
+		topic     Topic
+		allowed   bool
+	}{
+		{admin, Topic{KindStudent, other}, true},
+		{asStudent, Topic{KindStudent, studentID}, true},
+		{asStudent, Topic{KindStudent, other}, false},
+		{asStudent, Topic{KindInstructor, instructorID}, false},
+		{asInstructor, Topic{KindInstructor, instructorID}, true},
+		{asInstructor, Topic{KindInstructor, other}, false},
+		{asInstructor, Topic{KindVehicle, car}, true},
+		{asInstructor, Topic{KindVehicle, van}, false},
+		{asInstructor, Topic{KindVehicle, other}, false},
+		{admin, Topic{KindVehicle, other}, true},
+		{asInstructor, Topic{KindStudent, studentID}, false},
+		{stranger, Topic{KindStudent, studentID}, false},
+	}
+	for _, tt := range tests {
+		err := authorizer.Authorize(ctx, tt.principal, tt.topic)
+		if tt.allowed && err != nil {
+			t.Errorf("Expected %s to watch %s but got %v", tt.principal.Email, tt.topic, err)
+		}
+		if !tt.allowed && apperr.KindOf(err) != apperr.KindForbidden {
+			t.Errorf("Expected %s to be forbidden from %s but got %v", tt.principal.Email, tt.topic, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package realtime
+
+import (
+	"strings"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kind is whose schedule a topic is.
+type Kind string
+
+const (
+	KindInstructor Kind = "instructor"
+	KindVehicle    Kind = "vehicle"
+	KindStudent    Kind = "student"
+)
+
+// Topic is one instructor's, vehicle's or student's schedule. It is written
+// "instructor:<id>".
+type Topic struct {
+	Kind Kind
+	ID   primitive.ObjectID
+}
+
+func (t Topic) String() string {
+	return string(t.Kind) + ":" + t.ID.Hex()
+}
+
+var errInvalidTopic = apperr.Invalid("invalid_topic", `topic must be "instructor:<id>", "vehicle:<id>" or "student:<id>"`)
+
+func ParseTopic(s string) (Topic, error) {
+	kind, hex, ok := strings.Cut(s, ":")
+	if !ok {
+		return Topic{}, errInvalidTopic
+	}
+	switch Kind(kind) {
+	case KindInstructor, KindVehicle, KindStudent:
+	default:
+		return Topic{}, errInvalidTopic
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return Topic{}, errInvalidTopic
+	}
+	return Topic{Kind: Kind(kind), ID: id}, nil
+}
+
+// Message is a change to a schedule, as sent to its subscribers. Type is
+// "lesson." or "availability." followed by the events.Change, and Data is
+// the events.LessonChanged or events.AvailabilityChanged payload.
+type Message struct {
+	Type       string         `json:"type"`
+	Topic      string         `json:"topic"`
+	EventID    string         `json:"event_id"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       events.Payload `json:"data"`
+}
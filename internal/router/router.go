@@ -1,82 +1,218 @@
 package router
 
 import (
+	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
 	adminsHandler "github.com/lucasgarciaf/df-backend-go/handlers/admins"
+	auditHandler "github.com/lucasgarciaf/df-backend-go/handlers/audit"
 	availabilityHandler "github.com/lucasgarciaf/df-backend-go/handlers/availability"
+	calendarHandler "github.com/lucasgarciaf/df-backend-go/handlers/calendar"
 	coursesHandler "github.com/lucasgarciaf/df-backend-go/handlers/courses"
+	healthHandler "github.com/lucasgarciaf/df-backend-go/handlers/health"
 	instructorsHandler "github.com/lucasgarciaf/df-backend-go/handlers/instructors"
 	lessonsHandler "github.com/lucasgarciaf/df-backend-go/handlers/lessons"
+	notificationsHandler "github.com/lucasgarciaf/df-backend-go/handlers/notifications"
+	realtimeHandler "github.com/lucasgarciaf/df-backend-go/handlers/realtime"
+	schedulingHandler "github.com/lucasgarciaf/df-backend-go/handlers/scheduling"
 	studentsHandler "github.com/lucasgarciaf/df-backend-go/handlers/students"
 	vehiclesHandler "github.com/lucasgarciaf/df-backend-go/handlers/vehicles"
+	webhooksHandler "github.com/lucasgarciaf/df-backend-go/handlers/webhooks"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/admins"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/integrity"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
 	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/health"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/metrics"
 	"github.com/lucasgarciaf/df-backend-go/internal/middleware"
-	"go.mongodb.org/mongo-driver/mongo"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"github.com/lucasgarciaf/df-backend-go/internal/ratelimit"
+	"github.com/lucasgarciaf/df-backend-go/internal/realtime"
+	"github.com/lucasgarciaf/df-backend-go/internal/scheduling"
+	"github.com/lucasgarciaf/df-backend-go/internal/storage"
+	"github.com/lucasgarciaf/df-backend-go/internal/webhooks"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func SetupRouter(r *gin.Engine, db *mongo.Database) {
-	studentRepo := students.NewMongoStudentRepository(db)
-	studentService := students.NewStudentService(studentRepo)
-	studentHandler := studentsHandler.NewStudentHandler(studentService)
+// Dependencies are the shared services the routes are built from.
+type Dependencies struct {
+	Config   config.Config
+	Repos    storage.Repositories
+	Identity identity.Provider
+	Logger   *slog.Logger
+	Metrics  *metrics.Metrics
+	Health   *health.Registry
+	// RateLimits holds the login and registration throttling state; nil keeps it in memory
+	RateLimits ratelimit.Store
+	// Inbox should be the one the notifier adds to, so that streams hear of
+	// new notifications at once; nil creates one over Repos.Inbox
+	Inbox *notifications.Inbox
+	// Realtime is the broker the dispatcher publishes schedule changes to;
+	// nil creates a Hub nothing publishes to
+	Realtime realtime.Broker
+	// Importer should be the one that runs the calendar syncs; nil creates one
+	Importer *calendar.Importer
+}
+
+func SetupRouter(r *gin.Engine, deps Dependencies) error {
+	cfg, repos, idp, logger := deps.Config, deps.Repos, deps.Identity, deps.Logger
+	// Without trusted proxies X-Forwarded-For is ignored, so clients cannot
+	// pick the IP they are rate limited and logged under
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		return err
+	}
+	// The trace span comes first so that every other middleware runs inside it
+	r.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(req *http.Request) bool {
+		switch req.URL.Path {
+		case "/metrics", "/livez", "/readyz":
+			return false
+		}
+		// Calendar feed paths hold their secret token, which spans would record
+		return !strings.HasPrefix(req.URL.Path, "/ical/")
+	})))
+	r.Use(middleware.RequestID(), middleware.ClientIP(), middleware.AccessLog(logger), middleware.Metrics(deps.Metrics),
+		middleware.ErrorHandler(logger), middleware.Recovery(logger), middleware.CORS(cfg.CORS),
+		middleware.SecurityHeaders(cfg.Server), middleware.BodyLimit(int64(cfg.Server.MaxBodyBytes)))
+
+	studentRepo := repos.Students
+	instructorRepo := repos.Instructors
+	adminRepo := repos.Admins
+	courseRepo := repos.Courses
+	lessonRepo := repos.Lessons
+	availabilityRepo := repos.Availability
+	vehicleRepo := repos.Vehicles
+
+	auditor := audit.NewLog(repos.Audit, logger)
+	deletePolicy, err := integrity.ParsePolicy(cfg.InstructorDeletePolicy)
+	if err != nil {
+		return err
+	}
+	checker := integrity.NewChecker(lessonRepo, courseRepo, instructorRepo, studentRepo, vehicleRepo, deletePolicy, auditor, repos.Outbox)
+
+	rateLimits := deps.RateLimits
+	if rateLimits == nil {
+		rateLimits = ratelimit.NewMemoryStore()
+	}
+	perIP := ratelimit.NewLimiter(rateLimits, "ip", ratelimit.Limit{Burst: cfg.RateLimit.PerIP, Per: cfg.RateLimit.Window})
+	logins := ratelimit.NewLogins(rateLimits,
+		ratelimit.Limit{Burst: cfg.RateLimit.PerAccount, Per: cfg.RateLimit.Window},
+		ratelimit.LockoutPolicy{Threshold: cfg.RateLimit.LockoutThreshold, Base: cfg.RateLimit.LockoutBase, Max: cfg.RateLimit.LockoutMax},
+		logger)
 
-	instructorRepo := instructors.NewMongoInstructorRepository(db)
-	instructorService := instructors.NewInstructorService(instructorRepo)
-	instructorHandler := instructorsHandler.NewInstructorHandler(instructorService)
+	studentService := students.NewStudentService(studentRepo, checker, idp, auditor, repos.Outbox, logger)
+	studentHandler := studentsHandler.NewStudentHandler(studentService, logins, logger)
 
-	adminRepo := admins.NewMongoAdminRepository(db)
-	adminService := admins.NewAdminService(adminRepo)
-	adminHandler := adminsHandler.NewAdminHandler(adminService)
+	instructorService := instructors.NewInstructorService(instructorRepo, checker, idp, auditor, repos.Outbox, logger)
+	instructorHandler := instructorsHandler.NewInstructorHandler(instructorService, logins)
 
-	courseRepo := courses.NewMongoCourseRepository(db)
-	courseService := courses.NewCourseService(courseRepo)
+	adminService := admins.NewAdminService(adminRepo, idp, auditor, logger)
+	adminHandler := adminsHandler.NewAdminHandler(adminService, logins)
+
+	courseService := courses.NewCourseService(courseRepo, checker, auditor)
 	courseHandler := coursesHandler.NewCourseHandler(courseService)
 
-	lessonRepo := lessons.NewMongoLessonRepository(db)
-	lessonService := lessons.NewLessonService(lessonRepo)
+	lessonService := lessons.NewLessonService(lessonRepo, checker, auditor, repos.Outbox)
 	lessonHandler := lessonsHandler.NewLessonHandler(lessonService)
 
-	availabilityRepo := availability.NewMongoAvailabilityRepository(db)
-	availabilityService := availability.NewAvailabilityService(availabilityRepo)
+	availabilityService := availability.NewAvailabilityService(availabilityRepo, auditor, repos.Outbox)
 	availabilityHandler := availabilityHandler.NewAvailabilityHandler(availabilityService)
 
-	vehicleRepo := vehicles.NewMongoVehicleRepository(db)
-	vehicleService := vehicles.NewVehicleService(vehicleRepo)
+	calendarService := calendar.NewService(repos.Feeds, lessonRepo, availabilityRepo, studentRepo, instructorRepo, cfg.LessonDuration, cfg.Calendar)
+	importer := deps.Importer
+	if importer == nil {
+		importer = calendar.NewImporter(availabilityService, instructorRepo, repos.Syncs, cfg.Calendar, logger)
+	}
+	importHandler := calendarHandler.NewImportHandler(importer)
+	calendarHandler := calendarHandler.NewCalendarHandler(calendarService, cfg.Calendar)
+
+	vehicleService := vehicles.NewVehicleService(vehicleRepo, checker, auditor, repos.Outbox)
 	vehicleHandler := vehiclesHandler.NewVehicleHandler(vehicleService)
 
-	// Set trusted proxies
-	// r.SetTrustedProxies([]string{"<your-proxy-ip-address>"})
+	schedulingService := scheduling.NewService(availabilityRepo, lessonRepo, vehicleRepo, studentRepo, courseRepo, instructorRepo, cfg.LessonDuration, cfg.Scheduling)
+	schedulingHandler := schedulingHandler.NewSchedulingHandler(schedulingService)
+
+	auditHandler := auditHandler.NewAuditHandler(repos.Audit)
+
+	webhookService := webhooks.NewService(repos.Webhooks, repos.Deliveries, auditor)
+	webhookHandler := webhooksHandler.NewWebhookHandler(webhookService)
 
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "healthy",
-		})
-	})
+	inbox := deps.Inbox
+	if inbox == nil {
+		inbox = notifications.NewInbox(repos.Inbox)
+	}
+	notificationHandler := notificationsHandler.NewNotificationHandler(inbox, cfg.Notifications.StreamPoll, logger)
 
-	// Set up CORS middleware
-	r.Use(middleware.CORSMiddleware())
+	broker := deps.Realtime
+	if broker == nil {
+		broker = realtime.NewHub()
+	}
+	authorizer := realtime.NewAuthorizer(studentRepo, instructorRepo, lessonRepo)
+	realtimeHandler := realtimeHandler.NewRealtimeHandler(broker, authorizer, cfg.Realtime, logger)
+
+	r.GET("/metrics", gin.WrapH(deps.Metrics.Handler()))
+
+	healthHandler := healthHandler.NewHealthHandler(deps.Health)
+	r.GET("/livez", healthHandler.Live)
+	r.GET("/readyz", healthHandler.Ready)
+	r.GET("/health", healthHandler.Health)
 
 	// Registration endpoints for different roles
-	r.POST("/register/student", studentHandler.Register)
-	r.POST("/register/instructor", middleware.RBACMiddleware(), instructorHandler.Register)
-	r.POST("/register/admin", middleware.RBACMiddleware(), adminHandler.Register)
+	rateLimit := middleware.RateLimit(perIP, logger)
+	r.POST("/register/student", rateLimit, studentHandler.Register)
+	// Only admins may register instructors and other admins
+	r.POST("/register/instructor", middleware.AuthMiddleware(idp), middleware.RBACMiddleware(middleware.Admin), instructorHandler.Register)
+	r.POST("/register/admin", middleware.AuthMiddleware(idp), middleware.RBACMiddleware(middleware.Admin), adminHandler.Register)
 
 	//login and logout endpoints
-	r.POST("/login/student", studentHandler.Login)
-	r.POST("/login/instructor", instructorHandler.Login)
-	r.POST("/login/admin", adminHandler.Login)
+	r.POST("/login/student", rateLimit, studentHandler.Login)
+	r.POST("/login/instructor", rateLimit, instructorHandler.Login)
+	r.POST("/login/admin", rateLimit, adminHandler.Login)
 
 	// r.POST("/logout", func(c *gin.Context)
 
+	// Browsers cannot set headers on a WebSocket, so the token may come as a
+	// subprotocol instead, which is read before authenticating
+	r.GET("/api/schedule/ws", realtimeHandler.TokenFromProtocol, middleware.AuthMiddleware(idp), realtimeHandler.Serve)
+
+	// Calendar apps cannot authenticate, so the secret in the link stands in
+	r.GET("/ical/:file", middleware.SecretPath(), calendarHandler.Feed)
+
 	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware())
+	api.Use(middleware.AuthMiddleware(idp))
+
+	api.GET("/admin/health", middleware.RBACMiddleware(middleware.Admin), healthHandler.Report)
+	api.GET("/admin/audit", middleware.RBACMiddleware(middleware.Admin), auditHandler.ListEntries)
+
+	adminWebhooks := api.Group("/admin/webhooks", middleware.RBACMiddleware(middleware.Admin))
+	adminWebhooks.POST("", webhookHandler.CreateSubscription)
+	adminWebhooks.GET("", webhookHandler.ListSubscriptions)
+	adminWebhooks.GET("/:id", webhookHandler.GetSubscription)
+	adminWebhooks.PUT("/:id", webhookHandler.UpdateSubscription)
+	adminWebhooks.DELETE("/:id", webhookHandler.DeleteSubscription)
+	adminWebhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+	adminWebhooks.POST("/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayDelivery)
+
+	// The caller's own notifications
+	me := api.Group("/me/notifications")
+	me.GET("", notificationHandler.ListNotifications)
+	me.GET("/unread-count", notificationHandler.UnreadCount)
+	me.GET("/stream", notificationHandler.Stream)
+	me.POST("/read-all", notificationHandler.MarkAllRead)
+	me.POST("/:id/read", notificationHandler.MarkRead)
+
+	// The caller's own calendar feed link
+	api.POST("/me/calendar-feed", calendarHandler.CreateFeed)
+	api.DELETE("/me/calendar-feed", calendarHandler.DeleteFeed)
 
 	api.GET("/students/:id", studentHandler.GetStudentByID)
 	api.GET("/students", studentHandler.GetAllStudents)
@@ -87,6 +223,9 @@ func SetupRouter(r *gin.Engine, db *mongo.Database) {
 	api.GET("/instructors/:id", instructorHandler.GetInstructorByID)
 	api.PUT("/instructors/:id", instructorHandler.UpdateInstructor)
 	api.DELETE("/instructors/:id", instructorHandler.DeleteInstructor)
+	api.POST("/instructors/:id/availability/import", importHandler.Import)
+	api.GET("/instructors/:id/availability/imports", importHandler.ListSyncs)
+	api.DELETE("/instructors/:id/availability/imports/:sync_id", importHandler.DeleteSync)
 
 	api.POST("/admins", adminHandler.CreateAdmin)
 	api.GET("/admins/:id", adminHandler.GetAdminByID)
@@ -103,14 +242,19 @@ func SetupRouter(r *gin.Engine, db *mongo.Database) {
 	api.GET("/lessons/:id", lessonHandler.GetLessonByID)
 	api.PUT("/lessons/:id", lessonHandler.UpdateLesson)
 	api.DELETE("/lessons/:id", lessonHandler.DeleteLesson)
+	api.GET("/lessons/:id/ics", calendarHandler.LessonICS)
 
 	api.POST("/availability", availabilityHandler.CreateAvailability)
 	api.GET("/availability/:id", availabilityHandler.GetAvailabilityByID)
 	api.PUT("/availability/:id", availabilityHandler.UpdateAvailability)
 	api.DELETE("/availability/:id", availabilityHandler.DeleteAvailability)
 
+	// Staff look for lesson times on students' behalf
+	api.POST("/scheduling/suggest", middleware.RBACMiddleware(middleware.Admin), schedulingHandler.Suggest)
+
 	api.POST("/vehicles", vehicleHandler.CreateVehicle)
 	api.GET("/vehicles/:id", vehicleHandler.GetVehicleByID)
 	api.PUT("/vehicles/:id", vehicleHandler.UpdateVehicle)
 	api.DELETE("/vehicles/:id", vehicleHandler.DeleteVehicle)
+	return nil
 }
@@ -0,0 +1,1020 @@
+package router_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"github.com/lucasgarciaf/df-backend-go/internal/realtime"
+	"github.com/lucasgarciaf/df-backend-go/internal/scheduling"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/apitest"
+	"github.com/lucasgarciaf/df-backend-go/internal/webhooks"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type registration struct {
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+var (
+	student    = registration{"jdoe", "John", "Doe", "john@example.com", "student-password"}
+	instructor = registration{"hcastro", "Hugo", "Castro", "hugo@example.com", "instructor-password"}
+)
+
+func createdID(t *testing.T, resp *apitest.Response) string {
+	t.Helper()
+	var body struct {
+		ID string `json:"id"`
+	}
+	resp.Decode(t, &body)
+	return body.ID
+}
+
+func createdHex(t *testing.T, resp *apitest.Response) string {
+	t.Helper()
+	var id string
+	resp.Decode(t, &id)
+	return id
+}
+
+func TestStudentRegistrationAndLogin(t *testing.T) {
+	h := apitest.New(t)
+
+	id := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/student", "", student))
+
+	problem := h.Expect(http.StatusConflict, "POST", "/register/student", "", student).Problem(t)
+	if problem["code"] != "email_exists" {
+		t.Fatalf("Expected code email_exists but got %v", problem["code"])
+	}
+
+	problem = h.Expect(http.StatusUnauthorized, "POST", "/login/student", "",
+		map[string]string{"email": student.Email, "password": "wrong", "role": "student"}).Problem(t)
+	if problem["code"] != "invalid_credentials" {
+		t.Fatalf("Expected code invalid_credentials but got %v", problem["code"])
+	}
+
+	token := h.Login("student", student.Email, student.Password)
+	var got map[string]any
+	h.Expect(http.StatusOK, "GET", "/api/students/"+id, token, nil).Decode(t, &got)
+	if got["email"] != student.Email {
+		t.Fatalf("Expected student %s but got %v", student.Email, got)
+	}
+	if _, ok := got["password_hash"]; ok {
+		t.Fatalf("Expected the password hash to be left out but got %v", got)
+	}
+	if body := h.Expect(http.StatusOK, "GET", "/api/students", h.AdminToken(), nil).Body; bytes.Contains(body, []byte("password")) {
+		t.Fatalf("Expected the password hashes to be left out but got %s", body)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	h := apitest.New(t)
+
+	problem := h.Expect(http.StatusUnauthorized, "GET", "/api/students", "", nil).Problem(t)
+	if problem["code"] != "missing_token" {
+		t.Fatalf("Expected code missing_token but got %v", problem["code"])
+	}
+	problem = h.Expect(http.StatusUnauthorized, "GET", "/api/students", "not-a-token", nil).Problem(t)
+	if problem["code"] != "invalid_token" {
+		t.Fatalf("Expected code invalid_token but got %v", problem["code"])
+	}
+
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	studentToken := h.Login("student", student.Email, student.Password)
+	problem = h.Expect(http.StatusForbidden, "POST", "/register/instructor", studentToken, instructor).Problem(t)
+	if problem["code"] != "forbidden" {
+		t.Fatalf("Expected code forbidden but got %v", problem["code"])
+	}
+
+	adminToken := h.AdminToken()
+	h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor)
+	h.Login("instructor", instructor.Email, instructor.Password)
+}
+
+func TestLessonBooking(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	studentID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/student", "", student))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	vehicleID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/vehicles", adminToken,
+		map[string]any{"Make": "Toyota", "Model": "Yaris", "Year": 2021, "LicensePlate": "1234BCD"}))
+
+	studentToken := h.Login("student", student.Email, student.Password)
+	lesson := map[string]any{
+		"CourseID":     courseID,
+		"InstructorID": instructorID,
+		"StudentID":    studentID,
+		"VehicleID":    vehicleID,
+		"Title":        "Parallel parking",
+		"Schedule":     "2030-03-04T09:00:00Z",
+	}
+	lessonID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", studentToken, lesson))
+
+	var booked map[string]any
+	h.Expect(http.StatusOK, "GET", "/api/lessons/"+lessonID, studentToken, nil).Decode(t, &booked)
+	if booked["Status"] != "scheduled" || booked["InstructorID"] != instructorID {
+		t.Fatalf("Unexpected lesson %v", booked)
+	}
+
+	lesson["CourseID"] = "60c72b2f9b1d8b6a8f8a53e1"
+	problem := h.Expect(http.StatusUnprocessableEntity, "POST", "/api/lessons", studentToken, lesson).Problem(t)
+	if problem["code"] != "unknown_references" {
+		t.Fatalf("Expected code unknown_references but got %v", problem["code"])
+	}
+
+	problem = h.Expect(http.StatusUnprocessableEntity, "DELETE", "/api/courses/"+courseID, adminToken, nil).Problem(t)
+	if problem["code"] != "dangling_references" {
+		t.Fatalf("Expected code dangling_references but got %v", problem["code"])
+	}
+
+	// Updates only carry the fields that change; the references they leave out are kept
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+lessonID, adminToken, map[string]any{"Schedule": "2030-03-04T11:00:00Z"})
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+lessonID, adminToken, map[string]any{"Status": "cancelled"})
+	h.Expect(http.StatusOK, "GET", "/api/lessons/"+lessonID, studentToken, nil).Decode(t, &booked)
+	if booked["Status"] != "cancelled" || booked["CourseID"] != courseID || booked["Schedule"] != "2030-03-04T11:00:00Z" {
+		t.Fatalf("Unexpected lesson after partial updates %v", booked)
+	}
+	problem = h.Expect(http.StatusUnprocessableEntity, "PUT", "/api/lessons/"+lessonID, adminToken,
+		map[string]any{"VehicleID": "60c72b2f9b1d8b6a8f8a53e1"}).Problem(t)
+	if problem["code"] != "unknown_references" {
+		t.Fatalf("Expected code unknown_references but got %v", problem["code"])
+	}
+}
+
+func TestRequestIDAndLogRedaction(t *testing.T) {
+	h := apitest.New(t)
+
+	req := httptest.NewRequest("POST", "/register/student", strings.NewReader(`{"username":"jdoe"}`))
+	req.Header.Set("X-Request-ID", "client-id-1")
+	w := httptest.NewRecorder()
+	h.Handler.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "client-id-1" {
+		t.Fatalf("Expected the caller's request ID to be echoed but got %q", got)
+	}
+	if !strings.Contains(h.Logs.String(), `"request_id":"client-id-1"`) {
+		t.Fatalf("Expected the request ID in the access log:\n%s", h.Logs)
+	}
+
+	resp := h.Do("GET", "/health", "", nil)
+	if id := resp.Header.Get("X-Request-ID"); len(id) != 32 {
+		t.Fatalf("Expected a generated request ID but got %q", id)
+	}
+
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	token := h.Login("student", student.Email, student.Password)
+	logs := h.Logs.String()
+	for _, secret := range []string{student.Password, token, "$2a$"} {
+		if strings.Contains(logs, secret) {
+			t.Fatalf("Expected %q to be kept out of the logs:\n%s", secret, logs)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	h := apitest.New(t)
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	token := h.Login("student", student.Email, student.Password)
+	h.Expect(http.StatusNotFound, "GET", "/api/students/60c72b2f9b1d8b6a8f8a53e1", token, nil)
+
+	body := string(h.Expect(http.StatusOK, "GET", "/metrics", "", nil).Body)
+	for _, want := range []string{
+		`drivefluency_http_requests_total{method="POST",route="/register/student",status="201"} 1`,
+		`drivefluency_http_requests_total{method="GET",route="/api/students/:id",status="404"} 1`,
+		`drivefluency_repository_operation_duration_seconds_count{method="GetStudentByID",outcome="rejected",repository="students"} 1`,
+		`drivefluency_keycloak_requests_total{operation="login",outcome="ok"} 1`,
+		`drivefluency_keycloak_requests_total{operation="introspect",outcome="ok"} 1`,
+		`drivefluency_lessons_booked_today 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in:\n%s", want, body)
+		}
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	h := apitest.New(t)
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	body, _ := json.Marshal(student)
+	req := httptest.NewRequest("POST", "/register/student", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	h.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 but got %d: %s", w.Code, w.Body)
+	}
+
+	names := map[string]bool{}
+	for _, span := range recorder.Ended() {
+		if got := span.SpanContext().TraceID().String(); got != traceID {
+			t.Errorf("Expected span %s to continue trace %s but got %s", span.Name(), traceID, got)
+		}
+		names[span.Name()] = true
+	}
+	for _, want := range []string{
+		"/register/student", "StudentService.CreateStudent", "bcrypt.GenerateFromPassword",
+		"keycloak.create_user", "keycloak.client_login", "keycloak.assign_role", "HTTP POST",
+	} {
+		if !names[want] {
+			t.Errorf("Expected a %q span but got %v", want, names)
+		}
+	}
+}
+
+func TestProbes(t *testing.T) {
+	h := apitest.New(t)
+
+	h.Expect(http.StatusOK, "GET", "/livez", "", nil)
+	h.Expect(http.StatusOK, "GET", "/readyz", "", nil)
+
+	var report struct {
+		Status string `json:"status"`
+		Checks []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		} `json:"checks"`
+	}
+	h.Expect(http.StatusOK, "GET", "/api/admin/health", h.AdminToken(), nil).Decode(t, &report)
+	if report.Status != "ok" || len(report.Checks) != 1 || report.Checks[0].Name != "keycloak" {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+
+	h.Keycloak.SetDown(true)
+	var ready map[string]string
+	h.Expect(http.StatusServiceUnavailable, "GET", "/readyz", "", nil).Decode(t, &ready)
+	if len(ready) != 1 || ready["status"] != "fail" {
+		t.Fatalf("Expected a bare failing status but got %v", ready)
+	}
+	h.Expect(http.StatusServiceUnavailable, "GET", "/health", "", nil)
+	h.Expect(http.StatusOK, "GET", "/livez", "", nil)
+}
+
+func TestLoginLockout(t *testing.T) {
+	h := apitest.NewWithConfig(t, map[string]string{"LOGIN_LOCKOUT_THRESHOLD": "3", "LOGIN_LOCKOUT_BASE": "30s"})
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+
+	wrong := map[string]string{"email": student.Email, "password": "wrong", "role": "student"}
+	for i := 0; i < 3; i++ {
+		h.Expect(http.StatusUnauthorized, "POST", "/login/student", "", wrong)
+	}
+
+	// Even the right password is refused while the account is locked, from any login endpoint
+	right := map[string]string{"email": "JOHN@example.com", "password": student.Password, "role": "student"}
+	resp := h.Expect(http.StatusTooManyRequests, "POST", "/login/student", "", right)
+	if problem := resp.Problem(t); problem["code"] != "account_locked" || problem["retry_after"] != float64(30) {
+		t.Fatalf("Expected an account_locked problem retrying after 30s but got %v", problem)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "30" {
+		t.Fatalf("Expected Retry-After: 30 but got %q", got)
+	}
+	h.Expect(http.StatusTooManyRequests, "POST", "/login/admin", "", right)
+}
+
+func TestRateLimitPerIP(t *testing.T) {
+	h := apitest.NewWithConfig(t, map[string]string{"RATE_LIMIT_PER_IP": "2"})
+
+	resp := h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	if got := resp.Header.Get("RateLimit-Remaining"); got != "1" {
+		t.Fatalf("Expected RateLimit-Remaining: 1 but got %q", got)
+	}
+	h.Expect(http.StatusUnauthorized, "POST", "/login/instructor", "", map[string]string{"email": instructor.Email, "password": "wrong"})
+
+	resp = h.Expect(http.StatusTooManyRequests, "POST", "/login/student", "", map[string]string{"email": student.Email, "password": student.Password, "role": "student"})
+	if problem := resp.Problem(t); problem["code"] != "rate_limited" {
+		t.Fatalf("Expected code rate_limited but got %v", problem["code"])
+	}
+	if got := resp.Header.Get("Retry-After"); got != "30" {
+		t.Fatalf("Expected Retry-After: 30 but got %q", got)
+	}
+	// Only the login and registration endpoints are throttled
+	h.Expect(http.StatusOK, "GET", "/livez", "", nil)
+}
+
+func TestAuditLog(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	lesson := map[string]any{"CourseID": courseID, "InstructorID": instructorID, "Title": "Parallel parking", "Schedule": "2030-03-04T09:00:00Z"}
+	lessonID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken, lesson))
+	lesson["Status"] = "cancelled"
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+lessonID, adminToken, lesson)
+
+	var entries []struct {
+		Action     string            `json:"action"`
+		ResourceID string            `json:"resource_id"`
+		Actor      map[string]string `json:"actor"`
+		RequestID  string            `json:"request_id"`
+		IP         string            `json:"ip"`
+		Changes    []map[string]any  `json:"changes"`
+	}
+	h.Expect(http.StatusOK, "GET", "/api/admin/audit?resource=lesson&resource_id="+lessonID, adminToken, nil).Decode(t, &entries)
+	if len(entries) != 2 || entries[0].Action != "cancel" || entries[1].Action != "create" {
+		t.Fatalf("Expected the lesson to be created then cancelled but got %+v", entries)
+	}
+	cancel := entries[0]
+	if cancel.Actor["email"] != "admin@example.com" || cancel.RequestID == "" || cancel.IP == "" {
+		t.Fatalf("Expected the actor, request ID and IP to be recorded but got %+v", cancel)
+	}
+	if len(cancel.Changes) != 1 || cancel.Changes[0]["field"] != "status" || cancel.Changes[0]["after"] != "cancelled" {
+		t.Fatalf("Expected only the status to change but got %+v", cancel.Changes)
+	}
+
+	// Self-registration is recorded without an actor, and passwords never show
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	var registered []struct {
+		Actor map[string]string `json:"actor"`
+	}
+	h.Expect(http.StatusOK, "GET", "/api/admin/audit?resource=student", adminToken, nil).Decode(t, &registered)
+	if len(registered) != 1 || len(registered[0].Actor) != 0 {
+		t.Fatalf("Expected one anonymous student entry but got %v", registered)
+	}
+	if body := h.Expect(http.StatusOK, "GET", "/api/admin/audit", adminToken, nil).Body; bytes.Contains(body, []byte("$2a$")) {
+		t.Fatalf("Expected password hashes to be redacted but got %s", body)
+	}
+
+	h.Expect(http.StatusOK, "GET", "/api/admin/audit?from=2000-01-01T00:00:00Z&to=2000-01-02T00:00:00Z", adminToken, nil).Decode(t, &registered)
+	if len(registered) != 0 {
+		t.Fatalf("Expected no entries in 2000 but got %v", registered)
+	}
+	h.Expect(http.StatusBadRequest, "GET", "/api/admin/audit?from=yesterday", adminToken, nil)
+	h.Expect(http.StatusBadRequest, "GET", "/api/admin/audit?resource_id=nope", adminToken, nil)
+	studentToken := h.Login("student", student.Email, student.Password)
+	h.Expect(http.StatusForbidden, "GET", "/api/admin/audit", studentToken, nil)
+}
+
+func TestDomainEvents(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+
+	studentID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/student", "", student))
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	vehicleID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/vehicles", adminToken,
+		map[string]any{"Make": "Toyota", "Model": "Yaris", "LicensePlate": "1234BCD"}))
+
+	// Open lessons are not bookings until a student is set
+	lesson := map[string]any{"CourseID": courseID, "InstructorID": instructorID, "Title": "Parallel parking", "Schedule": "2030-03-04T09:00:00Z"}
+	lessonID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken, lesson))
+	lesson["StudentID"] = studentID
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+lessonID, adminToken, lesson)
+	lesson["Schedule"] = "2030-03-04T11:00:00Z"
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+lessonID, adminToken, lesson)
+	lesson["Status"] = "cancelled"
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+lessonID, adminToken, lesson)
+
+	h.Expect(http.StatusCreated, "POST", "/api/availability", adminToken,
+		map[string]any{"InstructorID": instructorID, "StartTime": "2030-03-05T09:00:00Z", "EndTime": "2030-03-05T13:00:00Z"})
+
+	problem := h.Expect(http.StatusBadRequest, "PUT", "/api/vehicles/"+vehicleID, adminToken, map[string]any{"Status": "crashed"}).Problem(t)
+	if problem["code"] != "invalid_status" {
+		t.Fatalf("Expected code invalid_status but got %v", problem["code"])
+	}
+	h.Expect(http.StatusOK, "PUT", "/api/vehicles/"+vehicleID, adminToken, map[string]any{"Status": "out_of_service"})
+	h.Expect(http.StatusOK, "PUT", "/api/vehicles/"+vehicleID, adminToken, map[string]any{"Status": "out_of_service"})
+
+	var types []events.Type
+	for _, event := range h.Outbox.Events() {
+		types = append(types, event.Type)
+	}
+	want := []events.Type{events.TypeStudentRegistered,
+		events.TypeLessonChanged, events.TypeLessonChanged, events.TypeLessonBooked, events.TypeLessonChanged, events.TypeLessonRescheduled,
+		events.TypeLessonChanged, events.TypeLessonCancelled, events.TypeAvailabilityChanged, events.TypeVehicleOutOfService}
+	if !slices.Equal(types, want) {
+		t.Fatalf("Expected events %v but got %v", want, types)
+	}
+
+	var booking events.LessonChanged
+	if err := h.Outbox.Events()[2].Decode(&booking); err != nil {
+		t.Fatal(err)
+	}
+	if booking.Change != events.ChangeUpdated || booking.StudentID.Hex() != studentID || booking.Previous == nil || !booking.Previous.StudentID.IsZero() {
+		t.Fatalf("Unexpected lesson change %+v", booking)
+	}
+	var cancelled events.LessonCancelled
+	if err := h.Outbox.Events()[7].Decode(&cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.LessonID.Hex() != lessonID || cancelled.StudentID.Hex() != studentID || cancelled.Title != "Parallel parking" {
+		t.Fatalf("Unexpected cancellation %+v", cancelled)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+
+	var received [][]byte
+	var signatures []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, body)
+		signatures = append(signatures, r.Header.Get(webhooks.SignatureHeader))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	problem := h.Expect(http.StatusBadRequest, "POST", "/api/admin/webhooks", adminToken,
+		map[string]any{"url": receiver.URL, "events": []string{"lesson.exploded"}}).Problem(t)
+	if problem["code"] != "unknown_event" {
+		t.Fatalf("Expected code unknown_event but got %v", problem["code"])
+	}
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	h.Expect(http.StatusCreated, "POST", "/api/admin/webhooks", adminToken,
+		map[string]any{"url": receiver.URL, "events": []string{"student.registered"}}).Decode(t, &created)
+	if created.ID == "" || !strings.HasPrefix(created.Secret, "whsec_") {
+		t.Fatalf("Expected the new subscription and its secret but got %+v", created)
+	}
+	// The secret is only ever shown on creation
+	if body := h.Expect(http.StatusOK, "GET", "/api/admin/webhooks/"+created.ID, adminToken, nil).Body; bytes.Contains(body, []byte(created.Secret)) {
+		t.Fatalf("Expected the secret to be hidden but got %s", body)
+	}
+
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	studentToken := h.Login("student", student.Email, student.Password)
+	h.Expect(http.StatusForbidden, "GET", "/api/admin/webhooks", studentToken, nil)
+
+	cfg, err := config.Parse(map[string]string{"WEBHOOK_ALLOW_PRIVATE_URLS": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher := events.NewDispatcher(h.Outbox, cfg.Outbox, logging.Discard())
+	deliverer := webhooks.NewDeliverer(h.Repos.Webhooks, h.Repos.Deliveries, cfg.Webhooks, logging.Discard())
+	dispatcher.AddPublisher(deliverer)
+	deliver := func() {
+		t.Helper()
+		if _, err := dispatcher.DispatchPending(context.Background()); err != nil {
+			t.Fatalf("DispatchPending failed: %v", err)
+		}
+		if _, err := deliverer.DeliverPending(context.Background()); err != nil {
+			t.Fatalf("DeliverPending failed: %v", err)
+		}
+	}
+	deliver()
+
+	if len(received) != 1 || !bytes.Contains(received[0], []byte(`"type":"student.registered"`)) {
+		t.Fatalf("Expected the registration to be posted but got %q", received)
+	}
+	if err := webhooks.Verify(created.Secret, signatures[0], received[0], time.Now(), time.Minute); err != nil {
+		t.Fatalf("Expected a valid signature but got %v", err)
+	}
+
+	var deliveries []struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		Attempts []struct {
+			StatusCode int `json:"status_code"`
+		} `json:"attempts"`
+	}
+	h.Expect(http.StatusOK, "GET", "/api/admin/webhooks/"+created.ID+"/deliveries", adminToken, nil).Decode(t, &deliveries)
+	if len(deliveries) != 1 || deliveries[0].Status != "succeeded" || deliveries[0].Attempts[0].StatusCode != http.StatusAccepted {
+		t.Fatalf("Unexpected delivery log %+v", deliveries)
+	}
+
+	h.Expect(http.StatusAccepted, "POST", "/api/admin/webhooks/"+created.ID+"/deliveries/"+deliveries[0].ID+"/replay", adminToken, nil)
+	deliver()
+	if len(received) != 2 || !bytes.Equal(received[0], received[1]) {
+		t.Fatalf("Expected the replay to post the same body but got %q", received)
+	}
+}
+
+func TestNotificationInbox(t *testing.T) {
+	h := apitest.New(t)
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	token := h.Login("student", student.Email, student.Password)
+	adminToken := h.AdminToken()
+
+	add := func(key, subject string, recipient notifications.Recipient) string {
+		t.Helper()
+		id, err := h.Inbox.Add(context.Background(), notifications.Notification{
+			Key: key, Recipient: recipient, Kind: notifications.KindLessonBooked, Subject: subject, InApp: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id.Hex()
+	}
+	me := notifications.Recipient{Email: student.Email, Role: "student"}
+	first := add("1", "first", me)
+	add("2", "second", me)
+	// Same email, different role: someone else's inbox
+	other := add("3", "not mine", notifications.Recipient{Email: student.Email, Role: "admin"})
+
+	var list []struct {
+		ID      string     `json:"id"`
+		Subject string     `json:"subject"`
+		ReadAt  *time.Time `json:"read_at"`
+	}
+	h.Expect(http.StatusOK, "GET", "/api/me/notifications", token, nil).Decode(t, &list)
+	if len(list) != 2 || list[0].Subject != "second" || list[1].ReadAt != nil {
+		t.Fatalf("Expected the student's two unread notifications but got %+v", list)
+	}
+	var unread struct {
+		Unread int `json:"unread"`
+	}
+	h.Expect(http.StatusOK, "GET", "/api/me/notifications/unread-count", token, nil).Decode(t, &unread)
+	if unread.Unread != 2 {
+		t.Fatalf("Expected 2 unread but got %d", unread.Unread)
+	}
+
+	h.Expect(http.StatusNoContent, "POST", "/api/me/notifications/"+first+"/read", token, nil)
+	h.Expect(http.StatusNotFound, "POST", "/api/me/notifications/"+other+"/read", token, nil)
+	h.Expect(http.StatusOK, "GET", "/api/me/notifications?unread=true", token, nil).Decode(t, &list)
+	if len(list) != 1 || list[0].Subject != "second" {
+		t.Fatalf("Expected only the second notification to be unread but got %+v", list)
+	}
+	var marked struct {
+		Marked int `json:"marked"`
+	}
+	h.Expect(http.StatusOK, "POST", "/api/me/notifications/read-all", token, nil).Decode(t, &marked)
+	if marked.Marked != 1 {
+		t.Fatalf("Expected 1 notification to be marked read but got %d", marked.Marked)
+	}
+	h.Expect(http.StatusOK, "GET", "/api/me/notifications/unread-count", adminToken, nil).Decode(t, &unread)
+	if unread.Unread != 0 {
+		t.Fatalf("Expected the admin with another email to have no notifications but got %d", unread.Unread)
+	}
+
+	server := httptest.NewServer(h.Handler)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/api/me/notifications/stream", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream but got %d %q", resp.StatusCode, ct)
+	}
+	events := bufio.NewReader(resp.Body)
+	next := func() string {
+		t.Helper()
+		var event strings.Builder
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read the stream after %q: %v", event.String(), err)
+			}
+			if line == "\n" {
+				return event.String()
+			}
+			event.WriteString(line)
+		}
+	}
+
+	if event := next(); event != "event: unread\ndata: {\"unread\":0}\n" {
+		t.Fatalf("Expected the unread count first but got %q", event)
+	}
+	third := add("4", "third", me)
+	if event := next(); !strings.HasPrefix(event, "id: "+third+"\nevent: notification\n") || !strings.Contains(event, `"subject":"third"`) {
+		t.Fatalf("Expected the new notification but got %q", event)
+	}
+	if event := next(); event != "event: unread\ndata: {\"unread\":1}\n" {
+		t.Fatalf("Expected the new unread count but got %q", event)
+	}
+}
+
+func TestScheduleWebSocket(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+	studentID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/student", "", student))
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	token := h.Login("student", student.Email, student.Password)
+
+	server := httptest.NewServer(h.Handler)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/schedule/ws"
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal("Expected a connection without a token to be refused")
+	}
+	// As a browser would pass it
+	dialer := websocket.Dialer{Subprotocols: []string{"bearer", token}}
+	ws, resp, err := dialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	if protocol := resp.Header.Get("Sec-WebSocket-Protocol"); protocol != "bearer" {
+		t.Fatalf("Expected only the bearer subprotocol to be echoed but got %q", protocol)
+	}
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	type message struct {
+		Type  string `json:"type"`
+		Topic string `json:"topic"`
+		Code  string `json:"code"`
+		Data  struct {
+			LessonID string `json:"lesson_id"`
+		} `json:"data"`
+	}
+	request := func(action, topic string) message {
+		t.Helper()
+		if err := ws.WriteJSON(map[string]string{"action": action, "topic": topic}); err != nil {
+			t.Fatal(err)
+		}
+		var reply message
+		if err := ws.ReadJSON(&reply); err != nil {
+			t.Fatal(err)
+		}
+		return reply
+	}
+	if reply := request("subscribe", "student:"+studentID); reply.Type != "subscribed" || reply.Topic != "student:"+studentID {
+		t.Fatalf("Expected to subscribe to my own schedule but got %+v", reply)
+	}
+	if reply := request("subscribe", "instructor:"+instructorID); reply.Type != "error" || reply.Code != "forbidden_topic" {
+		t.Fatalf("Expected students to be kept from instructors' schedules but got %+v", reply)
+	}
+	if reply := request("subscribe", "lesson:"+studentID); reply.Type != "error" || reply.Code != "invalid_topic" {
+		t.Fatalf("Expected an invalid topic but got %+v", reply)
+	}
+
+	lessonID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken, map[string]any{
+		"CourseID": courseID, "InstructorID": instructorID, "StudentID": studentID, "Title": "Parallel parking", "Schedule": "2030-03-04T09:00:00Z",
+	}))
+	publisher := realtime.NewPublisher(h.Realtime)
+	for _, event := range h.Outbox.Events() {
+		if err := publisher.Publish(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var change message
+	if err := ws.ReadJSON(&change); err != nil {
+		t.Fatal(err)
+	}
+	if change.Type != "lesson.created" || change.Topic != "student:"+studentID || change.Data.LessonID != lessonID {
+		t.Fatalf("Expected the new lesson on my schedule but got %+v", change)
+	}
+
+	if reply := request("unsubscribe", "student:"+studentID); reply.Type != "unsubscribed" {
+		t.Fatalf("Expected to unsubscribe but got %+v", reply)
+	}
+	h.Realtime.Close()
+	if err := ws.ReadJSON(&change); err == nil {
+		t.Fatalf("Expected the connection to end when the server shuts down but got %+v", change)
+	}
+}
+
+func TestCalendarFeeds(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+	studentID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/student", "", student))
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	studentToken := h.Login("student", student.Email, student.Password)
+	instructorToken := h.Login("instructor", instructor.Email, instructor.Password)
+
+	schedule := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	lesson := map[string]any{"CourseID": courseID, "InstructorID": instructorID, "StudentID": studentID, "Title": "Parallel parking", "Schedule": schedule}
+	movedID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken, lesson))
+	lesson["Schedule"] = schedule.Add(2 * time.Hour)
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+movedID, adminToken, lesson)
+	lesson["Title"] = "Roundabouts"
+	cancelledID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken, lesson))
+	lesson["Status"] = "cancelled"
+	h.Expect(http.StatusOK, "PUT", "/api/lessons/"+cancelledID, adminToken, lesson)
+	availabilityID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/availability", adminToken,
+		map[string]any{"InstructorID": instructorID, "StartTime": schedule.Add(24 * time.Hour), "EndTime": schedule.Add(28 * time.Hour)}))
+
+	feedPath := func(token string) string {
+		t.Helper()
+		var feed struct {
+			URL string `json:"url"`
+		}
+		h.Expect(http.StatusCreated, "POST", "/api/me/calendar-feed", token, nil).Decode(t, &feed)
+		path, ok := strings.CutPrefix(feed.URL, "http://example.com/ical/")
+		if !ok || !strings.HasSuffix(path, ".ics") {
+			t.Fatalf("Unexpected feed URL %q", feed.URL)
+		}
+		return "/ical/" + path
+	}
+	event := func(body []byte, uid string) string {
+		t.Helper()
+		_, rest, ok := strings.Cut(string(body), "UID:"+uid+"\r\n")
+		if !ok {
+			t.Fatalf("Expected an event with UID %s in\n%s", uid, body)
+		}
+		event, _, _ := strings.Cut(rest, "END:VEVENT")
+		return event
+	}
+
+	studentFeed := feedPath(studentToken)
+	resp := h.Expect(http.StatusOK, "GET", studentFeed, "", nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/calendar; charset=utf-8" {
+		t.Fatalf("Expected a calendar but got %q", ct)
+	}
+	moved := event(resp.Body, "lesson-"+movedID+"@df-backend-go")
+	for _, want := range []string{"SEQUENCE:1\r\n", "DTSTART:" + schedule.Add(2*time.Hour).Format("20060102T150405Z"), "SUMMARY:Parallel parking with Hugo Castro\r\n", "STATUS:CONFIRMED\r\n"} {
+		if !strings.Contains(moved, want) {
+			t.Fatalf("Expected the moved lesson to have %q but got\n%s", want, moved)
+		}
+	}
+	if cancelled := event(resp.Body, "lesson-"+cancelledID+"@df-backend-go"); !strings.Contains(cancelled, "STATUS:CANCELLED\r\n") || !strings.Contains(cancelled, "SEQUENCE:1\r\n") {
+		t.Fatalf("Expected the cancelled lesson to be marked so but got\n%s", cancelled)
+	}
+	if strings.Contains(string(resp.Body), "availability-") {
+		t.Fatal("Expected students not to see availability")
+	}
+
+	resp = h.Expect(http.StatusOK, "GET", feedPath(instructorToken), "", nil)
+	if moved := event(resp.Body, "lesson-"+movedID+"@df-backend-go"); !strings.Contains(moved, "with John Doe") {
+		t.Fatalf("Expected the instructor to see the student's name but got\n%s", moved)
+	}
+	if window := event(resp.Body, "availability-"+availabilityID+"@df-backend-go"); !strings.Contains(window, "TRANSP:TRANSPARENT\r\n") {
+		t.Fatalf("Expected availability not to make the instructor busy but got\n%s", window)
+	}
+
+	if problem := h.Expect(http.StatusForbidden, "POST", "/api/me/calendar-feed", adminToken, nil).Problem(t); problem["code"] != "no_calendar" {
+		t.Fatalf("Expected code no_calendar but got %v", problem["code"])
+	}
+	// A new link revokes the old one, and so does deleting it
+	rotated := feedPath(studentToken)
+	h.Expect(http.StatusNotFound, "GET", studentFeed, "", nil)
+	h.Expect(http.StatusOK, "GET", rotated, "", nil)
+	h.Expect(http.StatusNoContent, "DELETE", "/api/me/calendar-feed", studentToken, nil)
+	h.Expect(http.StatusNotFound, "GET", rotated, "", nil)
+	h.Expect(http.StatusNotFound, "GET", strings.TrimSuffix(studentFeed, ".ics"), "", nil)
+	token := strings.TrimSuffix(strings.TrimPrefix(studentFeed, "/ical/"), ".ics")
+	if strings.Contains(h.Logs.String(), token) {
+		t.Fatal("Expected feed tokens to be kept out of the logs")
+	}
+
+	resp = h.Expect(http.StatusOK, "GET", "/api/lessons/"+movedID+"/ics", studentToken, nil)
+	if cd := resp.Header.Get("Content-Disposition"); cd != `attachment; filename="lesson-`+movedID+`.ics"` {
+		t.Fatalf("Expected a download but got %q", cd)
+	}
+	if !strings.Contains(string(resp.Body), "UID:lesson-"+movedID+"@df-backend-go\r\n") {
+		t.Fatalf("Expected the lesson in the download but got\n%s", resp.Body)
+	}
+	h.Expect(http.StatusOK, "GET", "/api/lessons/"+movedID+"/ics", instructorToken, nil)
+	h.Expect(http.StatusOK, "GET", "/api/lessons/"+movedID+"/ics", adminToken, nil)
+	// Other people's lessons are not there for anyone else
+	other := registration{"jroe", "Jane", "Roe", "jane@example.com", "other-password"}
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", other)
+	h.Expect(http.StatusNotFound, "GET", "/api/lessons/"+movedID+"/ics", h.Login("student", other.Email, other.Password), nil)
+}
+
+func TestAvailabilityImport(t *testing.T) {
+	h := apitest.NewWithConfig(t, map[string]string{"CALENDAR_ALLOW_PRIVATE_URLS": "true"})
+	adminToken := h.AdminToken()
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	instructorToken := h.Login("instructor", instructor.Email, instructor.Password)
+	h.Expect(http.StatusCreated, "POST", "/register/student", "", student)
+	studentToken := h.Login("student", student.Email, student.Password)
+	importPath := "/api/instructors/" + instructorID + "/availability/import"
+
+	start := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Hour)
+	ics := func(single time.Time, weekly bool) string {
+		format := func(t time.Time) string { return t.Format("20060102T150405Z") }
+		lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//EN",
+			"BEGIN:VEVENT", "UID:single", "SUMMARY:Mornings", "DTSTART:" + format(single), "DTEND:" + format(single.Add(2*time.Hour)), "END:VEVENT",
+			"BEGIN:VEVENT", "UID:holiday", "DTSTART;VALUE=DATE:" + start.Format("20060102"), "END:VEVENT"}
+		if weekly {
+			// Three weeks, less the second
+			lines = append(lines, "BEGIN:VEVENT", "UID:weekly", "DTSTART:"+format(start.Add(24*time.Hour)), "DURATION:PT3H",
+				"RRULE:FREQ=WEEKLY;COUNT=3", "EXDATE:"+format(start.Add(8*24*time.Hour)), "END:VEVENT")
+		}
+		return strings.Join(append(lines, "END:VCALENDAR"), "\r\n") + "\r\n"
+	}
+	send := func(status int, token, query, contentType string, body io.Reader) calendar.ImportResult {
+		t.Helper()
+		req := httptest.NewRequest("POST", importPath+query, body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.Handler.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Fatalf("Expected status %d but got %d: %s", status, w.Code, w.Body)
+		}
+		var result calendar.ImportResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return result
+	}
+	upload := func(calendar string) (string, io.Reader) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", "availability.ics")
+		part.Write([]byte(calendar))
+		form.Close()
+		return form.FormDataContentType(), &body
+	}
+	imported := func() []availability.Availability {
+		t.Helper()
+		windows, err := h.Repos.Availability.ListAvailability(context.Background(), availability.Filter{ImportSource: calendar.SourceFile})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return windows
+	}
+	counts := func(result calendar.ImportResult) [4]int {
+		return [4]int{len(result.Created), len(result.Updated), len(result.Deleted), result.Unchanged}
+	}
+
+	// A dry run previews the import without changing anything
+	result := send(http.StatusOK, instructorToken, "?dry_run=true", "text/calendar", strings.NewReader(ics(start, true)))
+	if !result.DryRun || counts(result) != [4]int{3, 0, 0, 0} || len(imported()) != 0 {
+		t.Fatalf("Unexpected dry run %+v", result)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "holiday") {
+		t.Fatalf("Expected the all-day event to be left out with a warning but got %q", result.Warnings)
+	}
+
+	contentType, body := upload(ics(start, true))
+	result = send(http.StatusOK, instructorToken, "", contentType, body)
+	windows := imported()
+	if counts(result) != [4]int{3, 0, 0, 0} || len(windows) != 3 {
+		t.Fatalf("Expected three windows but got %+v and %+v", result, windows)
+	}
+	if !windows[0].StartTime.Equal(start) || !windows[2].StartTime.Equal(start.Add(15*24*time.Hour)) || !windows[2].EndTime.Equal(start.Add(15*24*time.Hour+3*time.Hour)) {
+		t.Fatalf("Unexpected windows %+v", windows)
+	}
+	manualID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/availability", adminToken,
+		map[string]any{"InstructorID": instructorID, "StartTime": start, "EndTime": start.Add(time.Hour)}))
+
+	// Importing again is idempotent, and then follows the calendar's changes
+	result = send(http.StatusOK, adminToken, "", "text/calendar", strings.NewReader(ics(start, true)))
+	if counts(result) != [4]int{0, 0, 0, 3} || len(imported()) != 3 {
+		t.Fatalf("Expected nothing to change but got %+v", result)
+	}
+	result = send(http.StatusOK, instructorToken, "", "text/calendar", strings.NewReader(ics(start.Add(time.Hour), false)))
+	windows = imported()
+	if counts(result) != [4]int{0, 1, 2, 0} || len(windows) != 1 || windows[0].ID.Hex() != result.Updated[0].ID ||
+		!windows[0].StartTime.Equal(start.Add(time.Hour)) || windows[0].Sequence != 1 {
+		t.Fatalf("Expected the moved event to be updated and the weekly one deleted but got %+v and %+v", result, windows)
+	}
+	h.Expect(http.StatusOK, "GET", "/api/availability/"+manualID, adminToken, nil)
+
+	if problem := h.Expect(http.StatusBadRequest, "POST", importPath, instructorToken, map[string]any{"url": "ftp://example.com/a.ics"}).Problem(t); problem["code"] != "invalid_url" {
+		t.Fatalf("Expected code invalid_url but got %v", problem["code"])
+	}
+	send(http.StatusBadRequest, instructorToken, "", "text/calendar", strings.NewReader("not a calendar"))
+	send(http.StatusForbidden, studentToken, "", "text/calendar", strings.NewReader(ics(start, true)))
+
+	// A synced URL is imported straight away and then on every sync
+	served := ics(start, true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if served == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar")
+		io.WriteString(w, served)
+	}))
+	defer server.Close()
+	var synced struct {
+		calendar.ImportResult
+		Sync calendar.Sync `json:"sync"`
+	}
+	h.Expect(http.StatusOK, "POST", importPath, instructorToken, map[string]any{"url": server.URL, "sync": true}).Decode(t, &synced)
+	if len(synced.Created) != 3 || synced.Sync.URL != server.URL || synced.Sync.ID.IsZero() {
+		t.Fatalf("Unexpected URL import %+v", synced)
+	}
+
+	cfg, err := config.Parse(map[string]string{"CALENDAR_ALLOW_PRIVATE_URLS": "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer := calendar.NewImporter(availability.NewAvailabilityService(h.Repos.Availability, audit.NewLog(h.Repos.Audit, logging.Discard()), h.Outbox),
+		h.Repos.Instructors, h.Repos.Syncs, cfg.Calendar, logging.Discard())
+	served = ics(start, false)
+	if err := importer.SyncAll(context.Background()); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	fromURL, _ := h.Repos.Availability.ListAvailability(context.Background(), availability.Filter{ImportSource: server.URL})
+	if len(fromURL) != 1 || len(imported()) != 1 {
+		t.Fatalf("Expected the sync to delete the weekly windows but got %+v", fromURL)
+	}
+
+	// A failed sync is recorded and leaves the windows alone
+	served = ""
+	if err := importer.SyncAll(context.Background()); err != nil {
+		t.Fatalf("SyncAll failed: %v", err)
+	}
+	var syncs []calendar.Sync
+	h.Expect(http.StatusOK, "GET", "/api/instructors/"+instructorID+"/availability/imports", instructorToken, nil).Decode(t, &syncs)
+	if len(syncs) != 1 || !strings.Contains(syncs[0].LastError, "404 Not Found") {
+		t.Fatalf("Expected the failure to be recorded but got %+v", syncs)
+	}
+	if fromURL, _ := h.Repos.Availability.ListAvailability(context.Background(), availability.Filter{ImportSource: server.URL}); len(fromURL) != 1 {
+		t.Fatalf("Expected a failed sync to keep the windows but got %+v", fromURL)
+	}
+	h.Expect(http.StatusNoContent, "DELETE", "/api/instructors/"+instructorID+"/availability/imports/"+syncs[0].ID.Hex(), instructorToken, nil)
+	h.Expect(http.StatusOK, "GET", "/api/instructors/"+instructorID+"/availability/imports", instructorToken, nil).Decode(t, &syncs)
+	if len(syncs) != 0 {
+		t.Fatalf("Expected the sync to be gone but got %+v", syncs)
+	}
+}
+
+func TestAvailabilityImportRefusesPrivateURLs(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the loopback server not to be fetched")
+	}))
+	defer server.Close()
+
+	problem := h.Expect(http.StatusBadRequest, "POST", "/api/instructors/"+instructorID+"/availability/import", adminToken, map[string]any{"url": server.URL}).Problem(t)
+	if problem["code"] != "calendar_unavailable" {
+		t.Fatalf("Expected code calendar_unavailable but got %v", problem)
+	}
+}
+
+func TestSchedulingSuggest(t *testing.T) {
+	h := apitest.New(t)
+	adminToken := h.AdminToken()
+	studentID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/student", "", student))
+	studentToken := h.Login("student", student.Email, student.Password)
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	vehicleID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/vehicles", adminToken,
+		map[string]any{"Make": "Toyota", "Model": "Yaris", "LicensePlate": "1234BCD", "Type": "Car"}))
+	h.Expect(http.StatusCreated, "POST", "/api/availability", adminToken,
+		map[string]any{"InstructorID": instructorID, "StartTime": "2030-03-04T15:00:00Z", "EndTime": "2030-03-04T17:00:00Z"})
+	h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken,
+		map[string]any{"CourseID": courseID, "InstructorID": instructorID, "VehicleID": vehicleID, "Schedule": "2030-03-04T15:00:00Z"})
+
+	req := map[string]any{
+		"student_id": studentID, "course_id": courseID, "from": "2030-03-04T00:00:00Z", "to": "2030-03-06T00:00:00Z",
+		"times": []map[string]string{{"start": "14:00", "end": "20:00"}}, "vehicle_type": "car", "lessons": 2,
+	}
+	var result scheduling.Suggestions
+	h.Expect(http.StatusOK, "POST", "/api/scheduling/suggest", adminToken, req).Decode(t, &result)
+	if len(result.Suggestions) != 1 || len(result.Plan) != 1 {
+		t.Fatalf("Expected the one free slot but got %+v", result)
+	}
+	slot := result.Suggestions[0]
+	if !slot.Start.Equal(time.Date(2030, 3, 4, 16, 0, 0, 0, time.UTC)) || slot.InstructorID.Hex() != instructorID ||
+		slot.InstructorName != "Hugo Castro" || slot.VehicleID == nil || slot.VehicleID.Hex() != vehicleID {
+		t.Fatalf("Unexpected slot %+v", slot)
+	}
+
+	h.Expect(http.StatusForbidden, "POST", "/api/scheduling/suggest", studentToken, req)
+	req["student_id"] = primitive.NewObjectID().Hex()
+	problem := h.Expect(http.StatusUnprocessableEntity, "POST", "/api/scheduling/suggest", adminToken, req).Problem(t)
+	if problem["code"] != "unknown_references" {
+		t.Fatalf("Expected code unknown_references but got %v", problem["code"])
+	}
+}
+
+func TestDeleteInstructorCancelsLessons(t *testing.T) {
+	h := apitest.NewWithConfig(t, map[string]string{"INSTRUCTOR_DELETE_POLICY": "cancel"})
+	adminToken := h.AdminToken()
+	instructorID := createdID(t, h.Expect(http.StatusCreated, "POST", "/register/instructor", adminToken, instructor))
+	courseID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/courses", adminToken,
+		map[string]any{"Title": "Beginner Driving", "Description": "Basics", "Duration": 20}))
+	lessonID := createdHex(t, h.Expect(http.StatusCreated, "POST", "/api/lessons", adminToken,
+		map[string]any{"CourseID": courseID, "InstructorID": instructorID, "Schedule": "2030-03-04T09:00:00Z"}))
+
+	h.Expect(http.StatusNoContent, "DELETE", "/api/instructors/"+instructorID, adminToken, nil)
+
+	var lesson map[string]any
+	h.Expect(http.StatusOK, "GET", "/api/lessons/"+lessonID, adminToken, nil).Decode(t, &lesson)
+	if lesson["Status"] != "cancelled" {
+		t.Fatalf("Expected the lesson to be cancelled but got %v", lesson)
+	}
+}
@@ -0,0 +1,497 @@
+// Package scheduling suggests when a student's next lessons could take place.
+// It searches instructors' availability for slots that neither they nor the
+// student already have lessons in, finds a free vehicle for each, and ranks
+// them, so that staff no longer compare calendars by hand.
+package scheduling
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/integrity"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tracer = tracing.Tracer("internal/scheduling")
+
+const (
+	defaultLimit = 10
+	maxLimit     = 50
+	maxLessons   = 20
+)
+
+// Reasons explain what raised a slot's score.
+const (
+	ReasonPreferredInstructor = "preferred_instructor"
+	ReasonTaughtBefore        = "taught_student_before"
+	ReasonNextToLesson        = "next_to_another_lesson"
+)
+
+// Scores added for each reason. Earlier slots get up to earlinessScore more,
+// and every lesson the instructor already has that day takes loadPenalty off,
+// which spreads lessons across instructors.
+const (
+	preferredScore    = 50
+	taughtBeforeScore = 20
+	nextToLessonScore = 5
+	earlinessScore    = 10
+	loadPenalty       = 2
+)
+
+// Request describes the lessons a student wants.
+type Request struct {
+	StudentID primitive.ObjectID `json:"student_id"`
+	CourseID  primitive.ObjectID `json:"course_id"`
+	// From and To bound the search; the past is left out
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Times are the parts of the day that suit the student, such as
+	// {"start": "15:00", "end": "20:00"}; none means any time
+	Times []TimeRange `json:"times"`
+	// Weekdays are the days that suit the student, such as "monday"; none
+	// means every day
+	Weekdays []string `json:"weekdays"`
+	// TimeZone is the IANA time zone Times, Weekdays and days are in; UTC by default
+	TimeZone string `json:"time_zone"`
+	// Lessons is how many lessons the plan holds, 1 by default
+	Lessons int `json:"lessons"`
+	// VehicleType asks for a vehicle of that type in every slot
+	VehicleType string `json:"vehicle_type"`
+	// InstructorID is preferred but other instructors are suggested too
+	InstructorID primitive.ObjectID `json:"instructor_id"`
+	// MaxPerDay caps the student's lessons on a day, counting the ones they
+	// already have; 0 means no cap
+	MaxPerDay int `json:"max_per_day"`
+	// Limit is how many suggestions are returned, 10 by default
+	Limit int `json:"limit"`
+}
+
+// TimeRange is a part of the day, from Start to End in 24-hour HH:MM.
+// An End of "24:00" is midnight at the end of the day.
+type TimeRange struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Slot is a suggested lesson time with an instructor and a vehicle.
+type Slot struct {
+	Start          time.Time          `json:"start"`
+	End            time.Time          `json:"end"`
+	InstructorID   primitive.ObjectID `json:"instructor_id"`
+	InstructorName string             `json:"instructor_name"`
+	// VehicleID is left out when no vehicle is free and none was asked for
+	VehicleID *primitive.ObjectID `json:"vehicle_id,omitempty"`
+	Score     int                 `json:"score"`
+	Reasons   []string            `json:"reasons"`
+}
+
+// Suggestions are the best slots, highest score first, and a plan of
+// Request.Lessons slots that can all be booked together, in time order. The
+// plan is shorter when not enough slots fit.
+type Suggestions struct {
+	Suggestions []Slot `json:"suggestions"`
+	Plan        []Slot `json:"plan"`
+}
+
+type Service struct {
+	availability   availability.AvailabilityRepository
+	lessons        lessons.LessonRepository
+	vehicles       vehicles.VehicleRepository
+	students       students.StudentRepository
+	courses        courses.CourseRepository
+	instructors    instructors.InstructorRepository
+	lessonDuration time.Duration
+	slotStep       time.Duration
+	maxRange       time.Duration
+	now            func() time.Time
+}
+
+func NewService(availabilityRepo availability.AvailabilityRepository, lessonRepo lessons.LessonRepository, vehicleRepo vehicles.VehicleRepository,
+	studentRepo students.StudentRepository, courseRepo courses.CourseRepository, instructorRepo instructors.InstructorRepository,
+	lessonDuration time.Duration, cfg config.SchedulingConfig) *Service {
+	return &Service{
+		availability:   availabilityRepo,
+		lessons:        lessonRepo,
+		vehicles:       vehicleRepo,
+		students:       studentRepo,
+		courses:        courseRepo,
+		instructors:    instructorRepo,
+		lessonDuration: lessonDuration,
+		slotStep:       cfg.SlotStep,
+		maxRange:       cfg.MaxRange,
+		now:            time.Now,
+	}
+}
+
+// interval is a span of time from start up to end.
+type interval struct {
+	start, end time.Time
+}
+
+func (i interval) overlaps(other interval) bool {
+	return i.start.Before(other.end) && other.start.Before(i.end)
+}
+
+// slotKey identifies an instructor's slot by its start, in Unix seconds.
+type slotKey struct {
+	instructorID primitive.ObjectID
+	start        int64
+}
+
+// search is a validated request.
+type search struct {
+	Request
+	loc      *time.Location
+	times    [][2]int // minutes since midnight
+	weekdays []time.Weekday
+}
+
+// Suggest finds the slots in which the lessons of req could take place. A
+// slot lies within an instructor's availability and the student's preferred
+// times, and overlaps no lesson of the instructor, the student or its vehicle.
+func (s *Service) Suggest(ctx context.Context, req Request) (_ *Suggestions, err error) {
+	ctx, span := tracer.Start(ctx, "Service.Suggest")
+	defer tracing.End(span, &err)
+	q, err := s.validate(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkReferences(ctx, req); err != nil {
+		return nil, err
+	}
+
+	result := &Suggestions{Suggestions: []Slot{}, Plan: []Slot{}}
+	from, to := q.From, q.To
+	if now := s.now(); from.Before(now) {
+		from = now
+	}
+	if !from.Before(to) {
+		return result, nil
+	}
+
+	windows, err := s.availability.ListAvailability(ctx, availability.Filter{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	// Lessons that started before the search still take up its first slots,
+	// and the student's lessons on the first and last days count towards MaxPerDay
+	booked, err := s.lessons.ListLessons(ctx, lessons.Filter{From: startOfDay(from, q.loc).Add(-s.lessonDuration), To: startOfDay(to, q.loc).AddDate(0, 0, 1)})
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.lessons.ListLessons(ctx, lessons.Filter{StudentID: q.StudentID, CourseID: q.CourseID})
+	if err != nil {
+		return nil, err
+	}
+	fleet, err := s.vehicles.ListVehicles(ctx, vehicles.Filter{Type: q.VehicleType, Status: vehicles.StatusInService})
+	if err != nil {
+		return nil, err
+	}
+
+	busy := map[primitive.ObjectID][]interval{}
+	studentPerDay := map[string]int{}
+	instructorPerDay := map[primitive.ObjectID]map[string]int{}
+	for _, lesson := range booked {
+		taken := interval{lesson.Schedule, lesson.Schedule.Add(s.lessonDuration)}
+		for _, id := range []primitive.ObjectID{lesson.InstructorID, lesson.StudentID, lesson.VehicleID} {
+			if !id.IsZero() {
+				busy[id] = append(busy[id], taken)
+			}
+		}
+		day := dayKey(lesson.Schedule, q.loc)
+		if lesson.StudentID == q.StudentID {
+			studentPerDay[day]++
+		}
+		if instructorPerDay[lesson.InstructorID] == nil {
+			instructorPerDay[lesson.InstructorID] = map[string]int{}
+		}
+		instructorPerDay[lesson.InstructorID][day]++
+	}
+	taughtBefore := map[primitive.ObjectID]bool{}
+	for _, lesson := range history {
+		taughtBefore[lesson.InstructorID] = true
+	}
+
+	preferred := q.preferred(from, to)
+	found := map[primitive.ObjectID]*instructors.Instructor{}
+	seen := map[slotKey]bool{}
+	var candidates []Slot
+	for _, window := range windows {
+		instructor, known := found[window.InstructorID]
+		if !known {
+			instructor, err = s.instructors.GetInstructorByID(ctx, window.InstructorID)
+			if apperr.IsNotFound(err) {
+				// Availability left behind by a deleted instructor
+				instructor, err = nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			found[window.InstructorID] = instructor
+		}
+		if instructor == nil {
+			continue
+		}
+		name := strings.TrimSpace(instructor.FirstName + " " + instructor.LastName)
+
+		for _, p := range preferred {
+			lo, hi := latest(window.StartTime, p.start), earliest(window.EndTime, p.end)
+			for start := s.align(lo, q.loc); !start.Add(s.lessonDuration).After(hi); start = start.Add(s.slotStep) {
+				slot := interval{start, start.Add(s.lessonDuration)}
+				key := slotKey{window.InstructorID, start.Unix()}
+				if seen[key] || overlapsAny(busy[window.InstructorID], slot) || overlapsAny(busy[q.StudentID], slot) {
+					continue
+				}
+				day := dayKey(start, q.loc)
+				if q.MaxPerDay > 0 && studentPerDay[day] >= q.MaxPerDay {
+					continue
+				}
+				vehicleID := freeVehicle(fleet, busy, slot)
+				if vehicleID == nil && q.VehicleType != "" {
+					continue
+				}
+				// Overlapping windows of an instructor offer the same slots
+				seen[key] = true
+
+				candidate := Slot{Start: slot.start, End: slot.end, InstructorID: window.InstructorID, InstructorName: name, VehicleID: vehicleID, Reasons: []string{}}
+				if window.InstructorID == q.InstructorID {
+					candidate.Score += preferredScore
+					candidate.Reasons = append(candidate.Reasons, ReasonPreferredInstructor)
+				}
+				if taughtBefore[window.InstructorID] {
+					candidate.Score += taughtBeforeScore
+					candidate.Reasons = append(candidate.Reasons, ReasonTaughtBefore)
+				}
+				if adjacent(busy[window.InstructorID], slot) {
+					candidate.Score += nextToLessonScore
+					candidate.Reasons = append(candidate.Reasons, ReasonNextToLesson)
+				}
+				candidate.Score += int(earlinessScore * to.Sub(start) / to.Sub(from))
+				candidate.Score -= loadPenalty * instructorPerDay[window.InstructorID][day]
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b Slot) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), a.Start.Compare(b.Start), strings.Compare(a.InstructorID.Hex(), b.InstructorID.Hex()))
+	})
+	result.Suggestions = append(result.Suggestions, candidates[:min(len(candidates), q.Limit)]...)
+	result.Plan = append(result.Plan, plan(candidates, q, studentPerDay)...)
+	return result, nil
+}
+
+// plan picks the best slots that the student can take one after another,
+// keeping to MaxPerDay, and sorts them by time.
+func plan(candidates []Slot, q *search, perDay map[string]int) []Slot {
+	var picked []Slot
+	var taken []interval
+	for _, candidate := range candidates {
+		if len(picked) == q.Lessons {
+			break
+		}
+		slot := interval{candidate.Start, candidate.End}
+		day := dayKey(candidate.Start, q.loc)
+		if overlapsAny(taken, slot) || (q.MaxPerDay > 0 && perDay[day] >= q.MaxPerDay) {
+			continue
+		}
+		picked = append(picked, candidate)
+		taken = append(taken, slot)
+		perDay[day]++
+	}
+	slices.SortFunc(picked, func(a, b Slot) int { return a.Start.Compare(b.Start) })
+	return picked
+}
+
+func (s *Service) validate(req Request) (*search, error) {
+	q := &search{Request: req}
+	switch {
+	case req.StudentID.IsZero() || req.CourseID.IsZero():
+		return nil, apperr.Invalid("invalid_request", "student_id and course_id are required")
+	case req.From.IsZero() || req.To.IsZero() || !req.From.Before(req.To):
+		return nil, apperr.Invalid("invalid_range", "from and to are required and from must be before to")
+	case req.To.Sub(req.From) > s.maxRange:
+		return nil, apperr.Invalid("invalid_range", fmt.Sprintf("from and to must be at most %v apart", s.maxRange))
+	case req.Lessons < 0 || req.Lessons > maxLessons:
+		return nil, apperr.Invalid("invalid_request", fmt.Sprintf("lessons must be between 1 and %d", maxLessons))
+	case req.Limit < 0 || req.Limit > maxLimit:
+		return nil, apperr.Invalid("invalid_request", fmt.Sprintf("limit must be between 1 and %d", maxLimit))
+	case req.MaxPerDay < 0:
+		return nil, apperr.Invalid("invalid_request", "max_per_day must not be negative")
+	}
+	if q.Lessons == 0 {
+		q.Lessons = 1
+	}
+	if q.Limit == 0 {
+		q.Limit = defaultLimit
+	}
+	q.VehicleType = vehicles.NormalizeType(req.VehicleType)
+
+	q.loc = time.UTC
+	if req.TimeZone != "" {
+		loc, err := time.LoadLocation(req.TimeZone)
+		if err != nil {
+			return nil, apperr.Invalid("invalid_time_zone", fmt.Sprintf("time_zone must be an IANA time zone such as Europe/Madrid, got %q", req.TimeZone))
+		}
+		q.loc = loc
+	}
+	for _, t := range req.Times {
+		start, okStart := parseClock(t.Start)
+		end, okEnd := parseClock(t.End)
+		if !okStart || !okEnd || start >= end {
+			return nil, apperr.Invalid("invalid_times", fmt.Sprintf("times must run from an HH:MM start to a later HH:MM end, got %q to %q", t.Start, t.End))
+		}
+		q.times = append(q.times, [2]int{start, end})
+	}
+	for _, name := range req.Weekdays {
+		day, ok := parseWeekday(name)
+		if !ok {
+			return nil, apperr.Invalid("invalid_weekdays", fmt.Sprintf("weekdays must be day names such as monday, got %q", name))
+		}
+		q.weekdays = append(q.weekdays, day)
+	}
+	return q, nil
+}
+
+// checkReferences makes sure the student, the course and the preferred
+// instructor exist.
+func (s *Service) checkReferences(ctx context.Context, req Request) error {
+	var missing []integrity.Reference
+	check := func(kind string, id primitive.ObjectID, err error) error {
+		if apperr.IsNotFound(err) {
+			missing = append(missing, integrity.Reference{Kind: kind, ID: id.Hex()})
+			return nil
+		}
+		return err
+	}
+	_, err := s.students.GetStudentByID(ctx, req.StudentID)
+	if err := check("student", req.StudentID, err); err != nil {
+		return err
+	}
+	_, err = s.courses.GetCourseByID(ctx, req.CourseID)
+	if err := check("course", req.CourseID, err); err != nil {
+		return err
+	}
+	if !req.InstructorID.IsZero() {
+		_, err = s.instructors.GetInstructorByID(ctx, req.InstructorID)
+		if err := check("instructor", req.InstructorID, err); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return apperr.Validation("unknown_references", "the request references unknown documents").
+			WithDetail("references", missing)
+	}
+	return nil
+}
+
+// preferred is the parts of [from, to) on the student's weekdays and times.
+func (q *search) preferred(from, to time.Time) []interval {
+	times := q.times
+	if len(times) == 0 {
+		times = [][2]int{{0, 24 * 60}}
+	}
+	var spans []interval
+	for day := startOfDay(from, q.loc); day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, q.loc) {
+		if len(q.weekdays) > 0 && !slices.Contains(q.weekdays, day.Weekday()) {
+			continue
+		}
+		for _, t := range times {
+			span := interval{
+				latest(from, time.Date(day.Year(), day.Month(), day.Day(), 0, t[0], 0, 0, q.loc)),
+				earliest(to, time.Date(day.Year(), day.Month(), day.Day(), 0, t[1], 0, 0, q.loc)),
+			}
+			if span.start.Before(span.end) {
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans
+}
+
+// align rounds t up to the next start time, which come every slot step from
+// midnight.
+func (s *Service) align(t time.Time, loc *time.Location) time.Time {
+	midnight := startOfDay(t, loc)
+	steps := (t.Sub(midnight) + s.slotStep - 1) / s.slotStep
+	return midnight.Add(steps * s.slotStep)
+}
+
+// freeVehicle is the first vehicle of the fleet without a lesson during slot.
+func freeVehicle(fleet []vehicles.Vehicle, busy map[primitive.ObjectID][]interval, slot interval) *primitive.ObjectID {
+	for _, vehicle := range fleet {
+		if !overlapsAny(busy[vehicle.ID], slot) {
+			id := vehicle.ID
+			return &id
+		}
+	}
+	return nil
+}
+
+func overlapsAny(spans []interval, slot interval) bool {
+	return slices.ContainsFunc(spans, slot.overlaps)
+}
+
+// adjacent reports whether a lesson ends as slot starts or starts as it ends.
+func adjacent(spans []interval, slot interval) bool {
+	return slices.ContainsFunc(spans, func(span interval) bool {
+		return span.end.Equal(slot.start) || span.start.Equal(slot.end)
+	})
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+func dayKey(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(time.DateOnly)
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// parseClock reads HH:MM as minutes since midnight, allowing 24:00.
+func parseClock(s string) (int, bool) {
+	if s == "24:00" {
+		return 24 * 60, true
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, true
+		}
+	}
+	return 0, false
+}
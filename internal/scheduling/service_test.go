@@ -0,0 +1,202 @@
+package scheduling
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 4 March 2030 is a Monday
+func at(day, hour int) time.Time {
+	return time.Date(2030, 3, day, hour, 0, 0, 0, time.UTC)
+}
+
+type fixture struct {
+	service            *Service
+	lessons            lessons.LessonRepository
+	student, course    primitive.ObjectID
+	hugo, ana, car     primitive.ObjectID
+	otherStudent, bike primitive.ObjectID
+}
+
+func newFixture(t *testing.T) *fixture {
+	ctx := context.Background()
+	availabilityRepo := availability.NewMemoryAvailabilityRepository()
+	lessonRepo := lessons.NewMemoryLessonRepository()
+	vehicleRepo := vehicles.NewMemoryVehicleRepository()
+	studentRepo := students.NewMemoryStudentRepository()
+	courseRepo := courses.NewMemoryCourseRepository()
+	instructorRepo := instructors.NewMemoryInstructorRepository()
+
+	f := &fixture{lessons: lessonRepo}
+	must := func(id primitive.ObjectID, err error) primitive.ObjectID {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Setting up failed: %v", err)
+		}
+		return id
+	}
+	f.student = must(studentRepo.CreateStudent(ctx, students.Student{FirstName: "John", LastName: "Doe", Email: "john@example.com"}))
+	f.otherStudent = must(studentRepo.CreateStudent(ctx, students.Student{FirstName: "Jane", LastName: "Roe", Email: "jane@example.com"}))
+	f.course = must(courseRepo.CreateCourse(ctx, courses.Course{Title: "Driving"}))
+	f.hugo = must(instructorRepo.CreateInstructor(ctx, instructors.Instructor{FirstName: "Hugo", LastName: "Castro", Email: "hugo@example.com"}))
+	f.ana = must(instructorRepo.CreateInstructor(ctx, instructors.Instructor{FirstName: "Ana", LastName: "Ruiz", Email: "ana@example.com"}))
+	f.car = must(vehicleRepo.CreateVehicle(ctx, vehicles.Vehicle{LicensePlate: "1234BCD", Type: "car", Status: vehicles.StatusInService}))
+	f.bike = must(vehicleRepo.CreateVehicle(ctx, vehicles.Vehicle{LicensePlate: "5678FGH", Type: "motorcycle", Status: vehicles.StatusInService}))
+
+	for _, window := range []availability.Availability{
+		{InstructorID: f.hugo, StartTime: at(4, 15), EndTime: at(4, 17)},
+		{InstructorID: f.ana, StartTime: at(4, 14), EndTime: at(4, 16)},
+		{InstructorID: f.ana, StartTime: at(5, 15), EndTime: at(5, 16)},
+	} {
+		must(availabilityRepo.CreateAvailability(ctx, window))
+	}
+	// Hugo teaches someone else in the car at 15:00 on Monday
+	must(lessonRepo.CreateLesson(ctx, lessons.Lesson{CourseID: f.course, InstructorID: f.hugo, StudentID: f.otherStudent, VehicleID: f.car, Schedule: at(4, 15), Status: lessons.StatusScheduled}))
+
+	f.service = NewService(availabilityRepo, lessonRepo, vehicleRepo, studentRepo, courseRepo, instructorRepo, time.Hour,
+		config.SchedulingConfig{SlotStep: 30 * time.Minute, MaxRange: 31 * 24 * time.Hour})
+	f.service.now = func() time.Time { return at(4, 0) }
+	return f
+}
+
+func (f *fixture) request() Request {
+	return Request{
+		StudentID:    f.student,
+		CourseID:     f.course,
+		From:         at(4, 0),
+		To:           at(6, 0),
+		Times:        []TimeRange{{Start: "15:00", End: "20:00"}},
+		VehicleType:  " Car",
+		InstructorID: f.hugo,
+		Lessons:      2,
+		MaxPerDay:    1,
+	}
+}
+
+func starts(slots []Slot) []time.Time {
+	var found []time.Time
+	for _, slot := range slots {
+		found = append(found, slot.Start)
+	}
+	return found
+}
+
+func TestSuggest(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ranks free slots and plans the lessons", func(t *testing.T) {
+		f := newFixture(t)
+		result, err := f.service.Suggest(ctx, f.request())
+		if err != nil {
+			t.Fatalf("Suggest failed: %v", err)
+		}
+		// Ana's Monday slot is left out because the only car is taken then
+		if want := []time.Time{at(4, 16), at(5, 15)}; !slices.EqualFunc(starts(result.Suggestions), want, time.Time.Equal) {
+			t.Fatalf("Expected suggestions at %v but got %+v", want, result.Suggestions)
+		}
+		best := result.Suggestions[0]
+		if best.InstructorID != f.hugo || best.InstructorName != "Hugo Castro" || best.VehicleID == nil || *best.VehicleID != f.car {
+			t.Fatalf("Unexpected best slot %+v", best)
+		}
+		if !slices.Equal(best.Reasons, []string{ReasonPreferredInstructor, ReasonNextToLesson}) {
+			t.Fatalf("Unexpected reasons %v", best.Reasons)
+		}
+		// 50 preferred + 5 next to a lesson + 6 for being early - 2 for Hugo's other lesson that day
+		if best.Score != 59 {
+			t.Fatalf("Expected a score of 59 but got %d", best.Score)
+		}
+		if want := []time.Time{at(4, 16), at(5, 15)}; !slices.EqualFunc(starts(result.Plan), want, time.Time.Equal) {
+			t.Fatalf("Expected a plan at %v but got %+v", want, result.Plan)
+		}
+	})
+
+	t.Run("keeps to the lessons a day", func(t *testing.T) {
+		f := newFixture(t)
+		if _, err := f.lessons.CreateLesson(ctx, lessons.Lesson{CourseID: f.course, InstructorID: f.ana, StudentID: f.student, Schedule: at(5, 9), Status: lessons.StatusScheduled}); err != nil {
+			t.Fatalf("CreateLesson failed: %v", err)
+		}
+		result, err := f.service.Suggest(ctx, f.request())
+		if err != nil {
+			t.Fatalf("Suggest failed: %v", err)
+		}
+		if want := []time.Time{at(4, 16)}; !slices.EqualFunc(starts(result.Suggestions), want, time.Time.Equal) {
+			t.Fatalf("Expected suggestions at %v but got %+v", want, result.Suggestions)
+		}
+		if len(result.Plan) != 1 {
+			t.Fatalf("Expected a plan of the one slot left but got %+v", result.Plan)
+		}
+		// Ana has taught the student the course now
+		if slot := result.Suggestions[0]; slices.Contains(slot.Reasons, ReasonTaughtBefore) {
+			t.Fatalf("Expected Hugo's slot not to count Ana's lesson but got %v", slot.Reasons)
+		}
+	})
+
+	t.Run("without a vehicle type slots need no vehicle", func(t *testing.T) {
+		f := newFixture(t)
+		req := f.request()
+		req.VehicleType = ""
+		req.InstructorID = primitive.NilObjectID
+		req.Weekdays = []string{"Monday"}
+		req.Lessons = 1
+		result, err := f.service.Suggest(ctx, req)
+		if err != nil {
+			t.Fatalf("Suggest failed: %v", err)
+		}
+		// Hugo's slot comes first for following his other lesson
+		if want := []time.Time{at(4, 16), at(4, 15)}; !slices.EqualFunc(starts(result.Suggestions), want, time.Time.Equal) {
+			t.Fatalf("Expected suggestions at %v but got %+v", want, result.Suggestions)
+		}
+		// The motorcycle is all that is free at 15:00
+		if slot := result.Suggestions[1]; slot.InstructorID != f.ana || slot.VehicleID == nil || *slot.VehicleID != f.bike {
+			t.Fatalf("Unexpected slot %+v", slot)
+		}
+	})
+
+	t.Run("past ranges suggest nothing", func(t *testing.T) {
+		f := newFixture(t)
+		f.service.now = func() time.Time { return at(10, 0) }
+		result, err := f.service.Suggest(ctx, f.request())
+		if err != nil {
+			t.Fatalf("Suggest failed: %v", err)
+		}
+		if len(result.Suggestions) != 0 || len(result.Plan) != 0 {
+			t.Fatalf("Expected no suggestions but got %+v", result)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		f := newFixture(t)
+		tests := map[string]func(req *Request){
+			"invalid_range":     func(req *Request) { req.To = req.From.AddDate(0, 2, 0) },
+			"invalid_times":     func(req *Request) { req.Times = []TimeRange{{Start: "20:00", End: "15:00"}} },
+			"invalid_weekdays":  func(req *Request) { req.Weekdays = []string{"someday"} },
+			"invalid_time_zone": func(req *Request) { req.TimeZone = "Mars/Olympus" },
+			"invalid_request":   func(req *Request) { req.Lessons = maxLessons + 1 },
+		}
+		for code, change := range tests {
+			req := f.request()
+			change(&req)
+			if _, err := f.service.Suggest(ctx, req); err == nil || apperr.From(err).Code != code {
+				t.Errorf("Expected %s but got %v", code, err)
+			}
+		}
+
+		req := f.request()
+		req.StudentID = primitive.NewObjectID()
+		if _, err := f.service.Suggest(ctx, req); apperr.KindOf(err) != apperr.KindValidation {
+			t.Fatalf("Expected unknown references to fail validation but got %v", err)
+		}
+	})
+}
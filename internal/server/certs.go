@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkInterval is how often handshakes look at the files for a renewal.
+const checkInterval = 10 * time.Second
+
+// CertReloader serves a certificate from disk and loads it again when either
+// file changes. A pair that fails to load is logged and the previous
+// certificate stays in use, so a half-written renewal never breaks HTTPS.
+type CertReloader struct {
+	certFile, keyFile string
+	logger            *slog.Logger
+	now               func() time.Time
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+func NewCertReloader(certFile, keyFile string, logger *slog.Logger) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, logger: logger, now: time.Now}
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.lastCheck) >= checkInterval {
+		r.lastCheck = now
+		modTime, err := r.latestModTime()
+		if err != nil {
+			r.logger.Warn("failed to check TLS certificate", slog.Any("error", err))
+		} else if !modTime.Equal(r.modTime) {
+			if err := r.load(modTime); err != nil {
+				r.logger.Error("failed to reload TLS certificate, keeping the previous one", slog.Any("error", err))
+			} else {
+				r.logger.Info("reloaded TLS certificate", slog.String("cert_file", r.certFile))
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// latestModTime returns the later modification time of the two files.
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat TLS file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (r *CertReloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+)
+
+// writeCert writes a self-signed certificate for commonName and its key.
+func writeCert(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{certFile: {Type: "CERTIFICATE", Bytes: der}, keyFile: {Type: "EC PRIVATE KEY", Bytes: keyDER}} {
+		if err := os.WriteFile(name, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func servedName(t *testing.T, r *CertReloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloaderPicksUpRenewals(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	modTime := time.Now().Add(-time.Hour)
+	writeCert(t, certFile, keyFile, "first", modTime)
+
+	r, err := NewCertReloader(certFile, keyFile, logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	if name := servedName(t, r); name != "first" {
+		t.Fatalf("Expected the first certificate but got %q", name)
+	}
+
+	writeCert(t, certFile, keyFile, "renewed", modTime.Add(time.Minute))
+	if name := servedName(t, r); name != "first" {
+		t.Fatalf("Expected the files not to be checked again straight away but got %q", name)
+	}
+	now = now.Add(checkInterval)
+	if name := servedName(t, r); name != "renewed" {
+		t.Fatalf("Expected the renewed certificate but got %q", name)
+	}
+
+	// A broken renewal keeps the last good certificate
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(checkInterval)
+	if name := servedName(t, r); name != "renewed" {
+		t.Fatalf("Expected the previous certificate to be kept but got %q", name)
+	}
+}
+
+func TestNewCertReloaderFailsOnMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCertReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), logging.Discard()); err == nil {
+		t.Fatal("Expected an error for missing files")
+	}
+}
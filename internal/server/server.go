@@ -0,0 +1,41 @@
+// Package server builds the API's http.Server: its timeouts and, when
+// configured, TLS with certificates that are reloaded as they are renewed.
+package server
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+)
+
+// New returns a server for handler on cfg's address. With TLS configured the
+// certificate is loaded straight away, so a bad pair fails startup rather than
+// the first handshake; call ListenAndServeTLS("", "") to serve it.
+//
+// There is deliberately no read or write timeout on whole requests, which
+// would cut off streaming responses; slow clients are handled by the header
+// timeout and the body size limit.
+func New(cfg config.Config, handler http.Handler, logger *slog.Logger) (*http.Server, error) {
+	srv := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	if !cfg.Server.TLS.Enabled() {
+		return srv, nil
+	}
+
+	certs, err := NewCertReloader(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile, logger)
+	if err != nil {
+		return nil, err
+	}
+	srv.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	}
+	return srv, nil
+}
@@ -0,0 +1,454 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/admins"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/metrics"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"github.com/lucasgarciaf/df-backend-go/internal/webhooks"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Instrument wraps every repository so that each method call is timed and
+// recorded in m under the repository's name and the method name.
+func Instrument(repos Repositories, m *metrics.Metrics) Repositories {
+	return Repositories{
+		Students:     instrumentedStudentRepository{repos.Students, m},
+		Instructors:  instrumentedInstructorRepository{repos.Instructors, m},
+		Admins:       instrumentedAdminRepository{repos.Admins, m},
+		Courses:      instrumentedCourseRepository{repos.Courses, m},
+		Lessons:      instrumentedLessonRepository{repos.Lessons, m},
+		Availability: instrumentedAvailabilityRepository{repos.Availability, m},
+		Vehicles:     instrumentedVehicleRepository{repos.Vehicles, m},
+		Audit:        instrumentedAuditRepository{repos.Audit, m},
+		Outbox:       instrumentedOutbox{repos.Outbox, m},
+		Webhooks:     instrumentedSubscriptionRepository{repos.Webhooks, m},
+		Deliveries:   instrumentedDeliveryRepository{repos.Deliveries, m},
+		Inbox:        instrumentedInboxRepository{repos.Inbox, m},
+		Feeds:        instrumentedFeedRepository{repos.Feeds, m},
+		Syncs:        instrumentedSyncRepository{repos.Syncs, m},
+	}
+}
+
+type instrumentedStudentRepository struct {
+	next    students.StudentRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedStudentRepository) CreateStudent(ctx context.Context, student students.Student) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("students", "CreateStudent", start, err) }(time.Now())
+	return r.next.CreateStudent(ctx, student)
+}
+
+func (r instrumentedStudentRepository) GetStudentByID(ctx context.Context, id primitive.ObjectID) (result *students.Student, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("students", "GetStudentByID", start, err) }(time.Now())
+	return r.next.GetStudentByID(ctx, id)
+}
+
+func (r instrumentedStudentRepository) GetStudentByEmail(ctx context.Context, email string) (result *students.Student, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("students", "GetStudentByEmail", start, err) }(time.Now())
+	return r.next.GetStudentByEmail(ctx, email)
+}
+
+func (r instrumentedStudentRepository) UpdateStudent(ctx context.Context, student students.Student) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("students", "UpdateStudent", start, err) }(time.Now())
+	return r.next.UpdateStudent(ctx, student)
+}
+
+func (r instrumentedStudentRepository) DeleteStudent(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("students", "DeleteStudent", start, err) }(time.Now())
+	return r.next.DeleteStudent(ctx, id)
+}
+
+func (r instrumentedStudentRepository) GetAllStudents(ctx context.Context) (result []students.Student, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("students", "GetAllStudents", start, err) }(time.Now())
+	return r.next.GetAllStudents(ctx)
+}
+
+type instrumentedInstructorRepository struct {
+	next    instructors.InstructorRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedInstructorRepository) CreateInstructor(ctx context.Context, instructor instructors.Instructor) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("instructors", "CreateInstructor", start, err) }(time.Now())
+	return r.next.CreateInstructor(ctx, instructor)
+}
+
+func (r instrumentedInstructorRepository) GetInstructorByEmail(ctx context.Context, email string) (result *instructors.Instructor, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("instructors", "GetInstructorByEmail", start, err) }(time.Now())
+	return r.next.GetInstructorByEmail(ctx, email)
+}
+
+func (r instrumentedInstructorRepository) GetInstructorByID(ctx context.Context, id primitive.ObjectID) (result *instructors.Instructor, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("instructors", "GetInstructorByID", start, err) }(time.Now())
+	return r.next.GetInstructorByID(ctx, id)
+}
+
+func (r instrumentedInstructorRepository) UpdateInstructor(ctx context.Context, instructor instructors.Instructor) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("instructors", "UpdateInstructor", start, err) }(time.Now())
+	return r.next.UpdateInstructor(ctx, instructor)
+}
+
+func (r instrumentedInstructorRepository) DeleteInstructor(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("instructors", "DeleteInstructor", start, err) }(time.Now())
+	return r.next.DeleteInstructor(ctx, id)
+}
+
+type instrumentedAdminRepository struct {
+	next    admins.AdminRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedAdminRepository) CreateAdmin(ctx context.Context, admin admins.Admin) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("admins", "CreateAdmin", start, err) }(time.Now())
+	return r.next.CreateAdmin(ctx, admin)
+}
+
+func (r instrumentedAdminRepository) GetAdminByID(ctx context.Context, id primitive.ObjectID) (result *admins.Admin, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("admins", "GetAdminByID", start, err) }(time.Now())
+	return r.next.GetAdminByID(ctx, id)
+}
+
+func (r instrumentedAdminRepository) GetAdminByEmail(ctx context.Context, email string) (result *admins.Admin, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("admins", "GetAdminByEmail", start, err) }(time.Now())
+	return r.next.GetAdminByEmail(ctx, email)
+}
+
+func (r instrumentedAdminRepository) UpdateAdmin(ctx context.Context, admin admins.Admin) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("admins", "UpdateAdmin", start, err) }(time.Now())
+	return r.next.UpdateAdmin(ctx, admin)
+}
+
+func (r instrumentedAdminRepository) DeleteAdmin(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("admins", "DeleteAdmin", start, err) }(time.Now())
+	return r.next.DeleteAdmin(ctx, id)
+}
+
+type instrumentedCourseRepository struct {
+	next    courses.CourseRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedCourseRepository) CreateCourse(ctx context.Context, course courses.Course) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("courses", "CreateCourse", start, err) }(time.Now())
+	return r.next.CreateCourse(ctx, course)
+}
+
+func (r instrumentedCourseRepository) GetCourseByID(ctx context.Context, id primitive.ObjectID) (result *courses.Course, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("courses", "GetCourseByID", start, err) }(time.Now())
+	return r.next.GetCourseByID(ctx, id)
+}
+
+func (r instrumentedCourseRepository) UpdateCourse(ctx context.Context, course courses.Course) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("courses", "UpdateCourse", start, err) }(time.Now())
+	return r.next.UpdateCourse(ctx, course)
+}
+
+func (r instrumentedCourseRepository) DeleteCourse(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("courses", "DeleteCourse", start, err) }(time.Now())
+	return r.next.DeleteCourse(ctx, id)
+}
+
+type instrumentedLessonRepository struct {
+	next    lessons.LessonRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedLessonRepository) CreateLesson(ctx context.Context, lesson lessons.Lesson) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("lessons", "CreateLesson", start, err) }(time.Now())
+	return r.next.CreateLesson(ctx, lesson)
+}
+
+func (r instrumentedLessonRepository) GetLessonByID(ctx context.Context, id primitive.ObjectID) (result *lessons.Lesson, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("lessons", "GetLessonByID", start, err) }(time.Now())
+	return r.next.GetLessonByID(ctx, id)
+}
+
+func (r instrumentedLessonRepository) ListLessons(ctx context.Context, filter lessons.Filter) (result []lessons.Lesson, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("lessons", "ListLessons", start, err) }(time.Now())
+	return r.next.ListLessons(ctx, filter)
+}
+
+func (r instrumentedLessonRepository) UpdateLesson(ctx context.Context, lesson lessons.Lesson) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("lessons", "UpdateLesson", start, err) }(time.Now())
+	return r.next.UpdateLesson(ctx, lesson)
+}
+
+func (r instrumentedLessonRepository) DeleteLesson(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("lessons", "DeleteLesson", start, err) }(time.Now())
+	return r.next.DeleteLesson(ctx, id)
+}
+
+type instrumentedAvailabilityRepository struct {
+	next    availability.AvailabilityRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedAvailabilityRepository) CreateAvailability(ctx context.Context, availability availability.Availability) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("availability", "CreateAvailability", start, err) }(time.Now())
+	return r.next.CreateAvailability(ctx, availability)
+}
+
+func (r instrumentedAvailabilityRepository) GetAvailabilityByID(ctx context.Context, id primitive.ObjectID) (result *availability.Availability, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("availability", "GetAvailabilityByID", start, err) }(time.Now())
+	return r.next.GetAvailabilityByID(ctx, id)
+}
+
+func (r instrumentedAvailabilityRepository) ListAvailability(ctx context.Context, filter availability.Filter) (result []availability.Availability, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("availability", "ListAvailability", start, err) }(time.Now())
+	return r.next.ListAvailability(ctx, filter)
+}
+
+func (r instrumentedAvailabilityRepository) UpdateAvailability(ctx context.Context, availability availability.Availability) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("availability", "UpdateAvailability", start, err) }(time.Now())
+	return r.next.UpdateAvailability(ctx, availability)
+}
+
+func (r instrumentedAvailabilityRepository) DeleteAvailability(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("availability", "DeleteAvailability", start, err) }(time.Now())
+	return r.next.DeleteAvailability(ctx, id)
+}
+
+type instrumentedVehicleRepository struct {
+	next    vehicles.VehicleRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedVehicleRepository) CreateVehicle(ctx context.Context, vehicle vehicles.Vehicle) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("vehicles", "CreateVehicle", start, err) }(time.Now())
+	return r.next.CreateVehicle(ctx, vehicle)
+}
+
+func (r instrumentedVehicleRepository) GetVehicleByID(ctx context.Context, id primitive.ObjectID) (result *vehicles.Vehicle, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("vehicles", "GetVehicleByID", start, err) }(time.Now())
+	return r.next.GetVehicleByID(ctx, id)
+}
+
+func (r instrumentedVehicleRepository) ListVehicles(ctx context.Context, filter vehicles.Filter) (result []vehicles.Vehicle, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("vehicles", "ListVehicles", start, err) }(time.Now())
+	return r.next.ListVehicles(ctx, filter)
+}
+
+func (r instrumentedVehicleRepository) UpdateVehicle(ctx context.Context, vehicle vehicles.Vehicle) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("vehicles", "UpdateVehicle", start, err) }(time.Now())
+	return r.next.UpdateVehicle(ctx, vehicle)
+}
+
+func (r instrumentedVehicleRepository) DeleteVehicle(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("vehicles", "DeleteVehicle", start, err) }(time.Now())
+	return r.next.DeleteVehicle(ctx, id)
+}
+
+type instrumentedAuditRepository struct {
+	next    audit.AuditRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedAuditRepository) AppendEntry(ctx context.Context, entry audit.Entry) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("audit", "AppendEntry", start, err) }(time.Now())
+	return r.next.AppendEntry(ctx, entry)
+}
+
+func (r instrumentedAuditRepository) ListEntries(ctx context.Context, filter audit.Filter) (result []audit.Entry, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("audit", "ListEntries", start, err) }(time.Now())
+	return r.next.ListEntries(ctx, filter)
+}
+
+type instrumentedOutbox struct {
+	next    events.Outbox
+	metrics *metrics.Metrics
+}
+
+// Transaction is not timed: it is as long as the service call it wraps.
+func (r instrumentedOutbox) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.next.Transaction(ctx, fn)
+}
+
+func (r instrumentedOutbox) Emit(ctx context.Context, payloads ...events.Payload) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("outbox", "Emit", start, err) }(time.Now())
+	return r.next.Emit(ctx, payloads...)
+}
+
+func (r instrumentedOutbox) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) (result []events.Event, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("outbox", "Claim", start, err) }(time.Now())
+	return r.next.Claim(ctx, now, lease, limit)
+}
+
+func (r instrumentedOutbox) MarkDispatched(ctx context.Context, id primitive.ObjectID, at time.Time) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("outbox", "MarkDispatched", start, err) }(time.Now())
+	return r.next.MarkDispatched(ctx, id, at)
+}
+
+func (r instrumentedOutbox) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string, retryAt time.Time) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("outbox", "MarkFailed", start, err) }(time.Now())
+	return r.next.MarkFailed(ctx, id, reason, retryAt)
+}
+
+type instrumentedSubscriptionRepository struct {
+	next    webhooks.SubscriptionRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedSubscriptionRepository) CreateSubscription(ctx context.Context, subscription webhooks.Subscription) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhooks", "CreateSubscription", start, err) }(time.Now())
+	return r.next.CreateSubscription(ctx, subscription)
+}
+
+func (r instrumentedSubscriptionRepository) GetSubscription(ctx context.Context, id primitive.ObjectID) (result *webhooks.Subscription, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhooks", "GetSubscription", start, err) }(time.Now())
+	return r.next.GetSubscription(ctx, id)
+}
+
+func (r instrumentedSubscriptionRepository) ListSubscriptions(ctx context.Context) (result []webhooks.Subscription, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhooks", "ListSubscriptions", start, err) }(time.Now())
+	return r.next.ListSubscriptions(ctx)
+}
+
+func (r instrumentedSubscriptionRepository) UpdateSubscription(ctx context.Context, subscription webhooks.Subscription) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhooks", "UpdateSubscription", start, err) }(time.Now())
+	return r.next.UpdateSubscription(ctx, subscription)
+}
+
+func (r instrumentedSubscriptionRepository) DeleteSubscription(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhooks", "DeleteSubscription", start, err) }(time.Now())
+	return r.next.DeleteSubscription(ctx, id)
+}
+
+type instrumentedDeliveryRepository struct {
+	next    webhooks.DeliveryRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedDeliveryRepository) CreateDelivery(ctx context.Context, delivery webhooks.Delivery) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhook_deliveries", "CreateDelivery", start, err) }(time.Now())
+	return r.next.CreateDelivery(ctx, delivery)
+}
+
+func (r instrumentedDeliveryRepository) GetDelivery(ctx context.Context, id primitive.ObjectID) (result *webhooks.Delivery, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhook_deliveries", "GetDelivery", start, err) }(time.Now())
+	return r.next.GetDelivery(ctx, id)
+}
+
+func (r instrumentedDeliveryRepository) ListDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int) (result []webhooks.Delivery, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhook_deliveries", "ListDeliveries", start, err) }(time.Now())
+	return r.next.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+func (r instrumentedDeliveryRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (result []webhooks.Delivery, err error) {
+	defer func(start time.Time) {
+		r.metrics.ObserveRepository("webhook_deliveries", "ClaimDeliveries", start, err)
+	}(time.Now())
+	return r.next.ClaimDeliveries(ctx, now, lease, limit)
+}
+
+func (r instrumentedDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt webhooks.Attempt, status string, retryAt time.Time) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhook_deliveries", "RecordAttempt", start, err) }(time.Now())
+	return r.next.RecordAttempt(ctx, id, attempt, status, retryAt)
+}
+
+func (r instrumentedDeliveryRepository) DeferDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhook_deliveries", "DeferDelivery", start, err) }(time.Now())
+	return r.next.DeferDelivery(ctx, id, at)
+}
+
+func (r instrumentedDeliveryRepository) ReplayDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("webhook_deliveries", "ReplayDelivery", start, err) }(time.Now())
+	return r.next.ReplayDelivery(ctx, id, at)
+}
+
+type instrumentedInboxRepository struct {
+	next    notifications.InboxRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedInboxRepository) AddNotification(ctx context.Context, notification notifications.Notification) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("notifications", "AddNotification", start, err) }(time.Now())
+	return r.next.AddNotification(ctx, notification)
+}
+
+func (r instrumentedInboxRepository) SentKeys(ctx context.Context, keys []string) (result map[string]bool, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("notifications", "SentKeys", start, err) }(time.Now())
+	return r.next.SentKeys(ctx, keys)
+}
+
+func (r instrumentedInboxRepository) ListNotifications(ctx context.Context, query notifications.Query) (result []notifications.Notification, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("notifications", "ListNotifications", start, err) }(time.Now())
+	return r.next.ListNotifications(ctx, query)
+}
+
+func (r instrumentedInboxRepository) CountUnread(ctx context.Context, email, role string) (result int, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("notifications", "CountUnread", start, err) }(time.Now())
+	return r.next.CountUnread(ctx, email, role)
+}
+
+func (r instrumentedInboxRepository) MarkRead(ctx context.Context, email, role string, id primitive.ObjectID, at time.Time) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("notifications", "MarkRead", start, err) }(time.Now())
+	return r.next.MarkRead(ctx, email, role, id, at)
+}
+
+func (r instrumentedInboxRepository) MarkAllRead(ctx context.Context, email, role string, at time.Time) (result int, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("notifications", "MarkAllRead", start, err) }(time.Now())
+	return r.next.MarkAllRead(ctx, email, role, at)
+}
+
+type instrumentedFeedRepository struct {
+	next    calendar.FeedRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedFeedRepository) SaveFeed(ctx context.Context, feed calendar.Feed) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_feeds", "SaveFeed", start, err) }(time.Now())
+	return r.next.SaveFeed(ctx, feed)
+}
+
+func (r instrumentedFeedRepository) GetFeedByTokenHash(ctx context.Context, hash string) (result *calendar.Feed, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_feeds", "GetFeedByTokenHash", start, err) }(time.Now())
+	return r.next.GetFeedByTokenHash(ctx, hash)
+}
+
+func (r instrumentedFeedRepository) DeleteFeed(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_feeds", "DeleteFeed", start, err) }(time.Now())
+	return r.next.DeleteFeed(ctx, id)
+}
+
+type instrumentedSyncRepository struct {
+	next    calendar.SyncRepository
+	metrics *metrics.Metrics
+}
+
+func (r instrumentedSyncRepository) CreateSync(ctx context.Context, sync calendar.Sync) (result primitive.ObjectID, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_syncs", "CreateSync", start, err) }(time.Now())
+	return r.next.CreateSync(ctx, sync)
+}
+
+func (r instrumentedSyncRepository) GetSyncByID(ctx context.Context, id primitive.ObjectID) (result *calendar.Sync, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_syncs", "GetSyncByID", start, err) }(time.Now())
+	return r.next.GetSyncByID(ctx, id)
+}
+
+func (r instrumentedSyncRepository) ListSyncs(ctx context.Context, instructorID primitive.ObjectID) (result []calendar.Sync, err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_syncs", "ListSyncs", start, err) }(time.Now())
+	return r.next.ListSyncs(ctx, instructorID)
+}
+
+func (r instrumentedSyncRepository) RecordSync(ctx context.Context, id primitive.ObjectID, at time.Time, errMessage string) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_syncs", "RecordSync", start, err) }(time.Now())
+	return r.next.RecordSync(ctx, id, at, errMessage)
+}
+
+func (r instrumentedSyncRepository) DeleteSync(ctx context.Context, id primitive.ObjectID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveRepository("calendar_syncs", "DeleteSync", start, err) }(time.Now())
+	return r.next.DeleteSync(ctx, id)
+}
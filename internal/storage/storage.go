@@ -0,0 +1,104 @@
+// Package storage bundles the repositories of every domain so that the API and
+// the command-line tools can switch between MongoDB and in-memory storage.
+package storage
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/calendar"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/admins"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/availability"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/courses"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/instructors"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/lessons"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/students"
+	"github.com/lucasgarciaf/df-backend-go/internal/domain/vehicles"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"github.com/lucasgarciaf/df-backend-go/internal/webhooks"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type Backend string
+
+const (
+	BackendMongo  Backend = "mongo"
+	BackendMemory Backend = "memory"
+)
+
+func ParseBackend(value string) (Backend, error) {
+	switch Backend(value) {
+	case BackendMongo, BackendMemory:
+		return Backend(value), nil
+	default:
+		return "", fmt.Errorf("unknown storage backend %q, expected %q or %q", value, BackendMongo, BackendMemory)
+	}
+}
+
+type Repositories struct {
+	Students     students.StudentRepository
+	Instructors  instructors.InstructorRepository
+	Admins       admins.AdminRepository
+	Courses      courses.CourseRepository
+	Lessons      lessons.LessonRepository
+	Availability availability.AvailabilityRepository
+	Vehicles     vehicles.VehicleRepository
+	Audit        audit.AuditRepository
+	Outbox       events.Outbox
+	Webhooks     webhooks.SubscriptionRepository
+	Deliveries   webhooks.DeliveryRepository
+	Inbox        notifications.InboxRepository
+	Feeds        calendar.FeedRepository
+	Syncs        calendar.SyncRepository
+}
+
+// Collections are the MongoDB collections that NewMongo's repositories keep
+// their documents in, for tools that clear them. The schema_migrations
+// collection is not one of them.
+var Collections = []string{
+	"students", "instructors", "admins", "courses", "lessons", "availability", "vehicles",
+	"audit_log", "outbox", "webhook_subscriptions", "webhook_deliveries", "notifications",
+	"calendar_feeds", "calendar_syncs",
+}
+
+func NewMongo(db *mongo.Database, logger *slog.Logger) Repositories {
+	return Repositories{
+		Students:     students.NewMongoStudentRepository(db, logger),
+		Instructors:  instructors.NewMongoInstructorRepository(db),
+		Admins:       admins.NewMongoAdminRepository(db),
+		Courses:      courses.NewMongoCourseRepository(db),
+		Lessons:      lessons.NewMongoLessonRepository(db),
+		Availability: availability.NewMongoAvailabilityRepository(db),
+		Vehicles:     vehicles.NewMongoVehicleRepository(db),
+		Audit:        audit.NewMongoAuditRepository(db),
+		Outbox:       events.NewMongoOutbox(db, logger),
+		Webhooks:     webhooks.NewMongoSubscriptionRepository(db),
+		Deliveries:   webhooks.NewMongoDeliveryRepository(db),
+		Inbox:        notifications.NewMongoInboxRepository(db),
+		Feeds:        calendar.NewMongoFeedRepository(db),
+		Syncs:        calendar.NewMongoSyncRepository(db),
+	}
+}
+
+// NewMemory returns empty in-memory repositories. Everything is lost when the
+// process exits.
+func NewMemory() Repositories {
+	return Repositories{
+		Students:     students.NewMemoryStudentRepository(),
+		Instructors:  instructors.NewMemoryInstructorRepository(),
+		Admins:       admins.NewMemoryAdminRepository(),
+		Courses:      courses.NewMemoryCourseRepository(),
+		Lessons:      lessons.NewMemoryLessonRepository(),
+		Availability: availability.NewMemoryAvailabilityRepository(),
+		Vehicles:     vehicles.NewMemoryVehicleRepository(),
+		Audit:        audit.NewMemoryAuditRepository(),
+		Outbox:       events.NewMemoryOutbox(),
+		Webhooks:     webhooks.NewMemorySubscriptionRepository(),
+		Deliveries:   webhooks.NewMemoryDeliveryRepository(),
+		Inbox:        notifications.NewMemoryInboxRepository(),
+		Feeds:        calendar.NewMemoryFeedRepository(),
+		Syncs:        calendar.NewMemorySyncRepository(),
+	}
+}
@@ -0,0 +1,183 @@
+// Package apitest boots the complete API, router and middleware included, on
+// in-memory repositories and a fake Keycloak, so tests can drive it over HTTP
+// without any external service.
+package apitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/health"
+	"github.com/lucasgarciaf/df-backend-go/internal/identity"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/metrics"
+	"github.com/lucasgarciaf/df-backend-go/internal/notifications"
+	"github.com/lucasgarciaf/df-backend-go/internal/realtime"
+	"github.com/lucasgarciaf/df-backend-go/internal/router"
+	"github.com/lucasgarciaf/df-backend-go/internal/storage"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/fakekeycloak"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+)
+
+type Harness struct {
+	t        *testing.T
+	Keycloak *fakekeycloak.Server
+	Repos    storage.Repositories
+	// Outbox holds every domain event the API emitted; nothing dispatches them
+	Outbox *events.MemoryOutbox
+	// Inbox is the one the API's notification routes use
+	Inbox *notifications.Inbox
+	// Realtime is the broker the API's WebSockets subscribe to
+	Realtime *realtime.Hub
+	Handler  http.Handler
+	// Logs holds every record the API logged, as JSON lines at debug level.
+	Logs *bytes.Buffer
+}
+
+func New(t *testing.T) *Harness {
+	t.Helper()
+	return NewWithConfig(t, nil)
+}
+
+// NewWithConfig is New with extra configuration keys, such as tighter rate limits.
+func NewWithConfig(t *testing.T, env map[string]string) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	keycloak := fakekeycloak.New(t)
+	values := map[string]string{"STORAGE": "memory"}
+	for key, value := range env {
+		values[key] = value
+	}
+	cfg, err := config.Parse(values)
+	if err != nil {
+		t.Fatalf("invalid test configuration: %v", err)
+	}
+	cfg.Keycloak = keycloak.Config()
+
+	// Installs the trace context propagator; exporting stays off
+	if _, err := tracing.Setup(context.Background(), cfg.Tracing); err != nil {
+		t.Fatalf("failed to set up tracing: %v", err)
+	}
+
+	logs := &bytes.Buffer{}
+	logger := logging.New(logs, slog.LevelDebug, "json")
+
+	m := metrics.New()
+	memory := storage.NewMemory()
+	repos := storage.Instrument(memory, m)
+	m.Register(metrics.NewBusiness(repos.Lessons, time.Second))
+
+	idp := identity.NewKeycloak(cfg.Keycloak, logger, m)
+	// No caching, so tests see a dependency going down straight away
+	checks := health.NewRegistry(0, logger)
+	checks.Register(health.CheckFunc("keycloak", idp.Ready), time.Second)
+
+	inbox := notifications.NewInbox(repos.Inbox)
+	t.Cleanup(inbox.Close)
+	hub := realtime.NewHub()
+	t.Cleanup(hub.Close)
+
+	r := gin.New()
+	err = router.SetupRouter(r, router.Dependencies{
+		Config:   cfg,
+		Repos:    repos,
+		Identity: idp,
+		Logger:   logger,
+		Metrics:  m,
+		Health:   checks,
+		Inbox:    inbox,
+		Realtime: hub,
+	})
+	if err != nil {
+		t.Fatalf("failed to set up router: %v", err)
+	}
+
+	return &Harness{t: t, Keycloak: keycloak, Repos: repos, Outbox: memory.Outbox.(*events.MemoryOutbox), Inbox: inbox, Realtime: hub, Handler: r, Logs: logs}
+}
+
+type Response struct {
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the JSON body into v, failing the test if it cannot.
+func (r *Response) Decode(t *testing.T, v any) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("failed to decode response %s: %v", r.Body, err)
+	}
+}
+
+// Problem decodes an application/problem+json body.
+func (r *Response) Problem(t *testing.T) map[string]any {
+	t.Helper()
+	if ct := r.Header.Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("expected a problem document but got %q: %s", ct, r.Body)
+	}
+	var problem map[string]any
+	r.Decode(t, &problem)
+	return problem
+}
+
+// Do sends a request with body encoded as JSON. token may be empty.
+func (h *Harness) Do(method, path, token string, body any) *Response {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	h.Handler.ServeHTTP(w, req)
+	return &Response{Code: w.Code, Header: w.Header(), Body: w.Body.Bytes()}
+}
+
+// Expect sends a request like Do and fails the test unless it returns status.
+func (h *Harness) Expect(status int, method, path, token string, body any) *Response {
+	h.t.Helper()
+	resp := h.Do(method, path, token, body)
+	if resp.Code != status {
+		h.t.Fatalf("%s %s: expected status %d but got %d: %s", method, path, status, resp.Code, resp.Body)
+	}
+	return resp
+}
+
+// Login logs in through /login/{role} and returns the access token.
+func (h *Harness) Login(role, email, password string) string {
+	h.t.Helper()
+	resp := h.Expect(http.StatusOK, "POST", "/login/"+role, "", map[string]string{"email": email, "password": password, "role": role})
+	var body struct {
+		Token string `json:"token"`
+	}
+	resp.Decode(h.t, &body)
+	return body.Token
+}
+
+// AdminToken creates an admin directly in the fake Keycloak and logs it in.
+func (h *Harness) AdminToken() string {
+	h.t.Helper()
+	h.Keycloak.AddUser("admin@example.com", "admin-password", identity.RoleAdmin)
+	return h.Login(identity.RoleAdmin, "admin@example.com", "admin-password")
+}
@@ -0,0 +1,390 @@
+// Package fakekeycloak is an httptest stand-in for the parts of the Keycloak
+// API the backend uses: the token, introspection, logout and JWKS endpoints of
+// a realm, and the admin endpoints for users and realm roles. Tokens are real
+// RS256 JWTs signed with a key published on the JWKS endpoint.
+package fakekeycloak
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	Realm        = "drivefluency"
+	ClientID     = "df-backend-go"
+	ClientSecret = "test-secret"
+	keyID        = "fake-key"
+)
+
+type user struct {
+	ID        string   `json:"id"`
+	Username  string   `json:"username"`
+	FirstName string   `json:"firstName"`
+	LastName  string   `json:"lastName"`
+	Email     string   `json:"email"`
+	Enabled   bool     `json:"enabled"`
+	Roles     []string `json:"-"`
+	password  string
+}
+
+type Server struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu      sync.Mutex
+	users   map[string]*user  // keyed by ID
+	refresh map[string]string // refresh token to the access token issued with it
+	revoked map[string]bool   // access tokens invalidated by logout
+	down    bool              // every endpoint answers 503 while set
+}
+
+// New starts a fake Keycloak that is shut down when the test finishes.
+func New(t *testing.T) *Server {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	s := &Server{key: key, users: map[string]*user{}, refresh: map[string]string{}, revoked: map[string]bool{}}
+
+	mux := http.NewServeMux()
+	realm := "/realms/" + Realm + "/protocol/openid-connect"
+	mux.HandleFunc(realm+"/token", s.token)
+	mux.HandleFunc(realm+"/token/introspect", s.introspect)
+	mux.HandleFunc(realm+"/logout", s.logout)
+	mux.HandleFunc(realm+"/certs", s.certs)
+	mux.HandleFunc("/admin/realms/"+Realm+"/", s.admin)
+	mux.HandleFunc("/realms/"+Realm+"/.well-known/openid-configuration", s.wellKnown)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		down := s.down
+		s.mu.Unlock()
+		if down {
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Config returns the settings that point identity.Keycloak at this server.
+func (s *Server) Config() config.KeycloakConfig {
+	return config.KeycloakConfig{URL: s.URL, Realm: Realm, ClientID: ClientID, ClientSecret: ClientSecret, Timeout: 5 * time.Second}
+}
+
+// SetDown simulates an outage: while down, every request fails with 503.
+func (s *Server) SetDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+// AddUser creates a user directly, bypassing the admin API.
+func (s *Server) AddUser(email, password, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := primitive.NewObjectID().Hex()
+	s.users[id] = &user{ID: id, Username: email, Email: email, Enabled: true, Roles: []string{role}, password: password}
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !clientAuthenticated(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized_client"})
+		return
+	}
+
+	var claims jwt.MapClaims
+	switch r.PostForm.Get("grant_type") {
+	case "client_credentials":
+		claims = s.claims("service-account-"+ClientID, "", []string{"realm-admin"})
+	case "password":
+		u := s.findByLogin(r.PostForm.Get("username"))
+		if u == nil || u.password != r.PostForm.Get("password") {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_grant", "error_description": "Invalid user credentials"})
+			return
+		}
+		claims = s.claims(u.ID, u.Email, u.Roles)
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refreshToken := "refresh-" + claims["jti"].(string)
+	s.mu.Lock()
+	s.refresh[refreshToken] = signed
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  signed,
+		"expires_in":    300,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+	})
+}
+
+func (s *Server) claims(subject, email string, roles []string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"jti":          primitive.NewObjectID().Hex(),
+		"iss":          s.URL + "/realms/" + Realm,
+		"sub":          subject,
+		"email":        email,
+		"iat":          now.Unix(),
+		"exp":          now.Add(5 * time.Minute).Unix(),
+		"realm_access": map[string]any{"roles": roles},
+	}
+}
+
+func (s *Server) introspect(w http.ResponseWriter, r *http.Request) {
+	if r.ParseForm() != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !clientAuthenticated(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized_client"})
+		return
+	}
+	token := r.PostForm.Get("token")
+
+	var claims jwt.MapClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return &s.key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+
+	s.mu.Lock()
+	active := err == nil && !s.revoked[token]
+	if active {
+		// Tokens of deleted users are no longer active.
+		if sub, _ := claims["sub"].(string); !strings.HasPrefix(sub, "service-account-") && s.users[sub] == nil {
+			active = false
+		}
+	}
+	s.mu.Unlock()
+
+	if !active {
+		writeJSON(w, http.StatusOK, map[string]any{"active": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"active": true, "sub": claims["sub"], "exp": claims["exp"]})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	if r.ParseForm() != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	access, ok := s.refresh[r.PostForm.Get("refresh_token")]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "Invalid refresh token"})
+		return
+	}
+	delete(s.refresh, r.PostForm.Get("refresh_token"))
+	s.revoked[access] = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) certs(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+		"kid": keyID,
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+func (s *Server) wellKnown(w http.ResponseWriter, r *http.Request) {
+	issuer := s.URL + "/realms/" + Realm
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                 issuer,
+		"token_endpoint":         issuer + "/protocol/openid-connect/token",
+		"introspection_endpoint": issuer + "/protocol/openid-connect/token/introspect",
+		"jwks_uri":               issuer + "/protocol/openid-connect/certs",
+	})
+}
+
+// admin serves the subset of /admin/realms/{realm}/... used by the backend.
+func (s *Server) admin(w http.ResponseWriter, r *http.Request) {
+	if !s.isServiceAccount(r) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "HTTP 401 Unauthorized"})
+		return
+	}
+
+	path := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/realms/"+Realm+"/"), "/")
+	switch {
+	case len(path) == 1 && path[0] == "users" && r.Method == http.MethodPost:
+		s.createUser(w, r)
+	case len(path) == 1 && path[0] == "users" && r.Method == http.MethodGet:
+		s.searchUsers(w, r)
+	case len(path) == 2 && path[0] == "users" && r.Method == http.MethodDelete:
+		s.deleteUser(w, path[1])
+	case len(path) == 2 && path[0] == "roles" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]string{"id": "role-" + path[1], "name": path[1]})
+	case len(path) == 4 && path[0] == "users" && path[2] == "role-mappings" && path[3] == "realm" && r.Method == http.MethodPost:
+		s.mapRoles(w, r, path[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		user
+		Credentials []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"credentials"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.findByLogin(body.Email) != nil || s.findByLogin(body.Username) != nil {
+		writeJSON(w, http.StatusConflict, map[string]string{"errorMessage": "User exists with same username or email"})
+		return
+	}
+
+	u := body.user
+	u.ID = primitive.NewObjectID().Hex()
+	for _, credential := range body.Credentials {
+		if credential.Type == "password" {
+			u.password = credential.Value
+		}
+	}
+
+	s.mu.Lock()
+	s.users[u.ID] = &u
+	s.mu.Unlock()
+
+	w.Header().Set("Location", s.URL+"/admin/realms/"+Realm+"/users/"+u.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Server) searchUsers(w http.ResponseWriter, r *http.Request) {
+	search := strings.ToLower(r.URL.Query().Get("search"))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := []*user{}
+	// Only exact email lookups are made
+	if email := r.URL.Query().Get("email"); email != "" {
+		for _, u := range s.users {
+			if strings.EqualFold(u.Email, email) {
+				found = append(found, u)
+			}
+		}
+		writeJSON(w, http.StatusOK, found)
+		return
+	}
+	for _, u := range s.users {
+		for _, field := range []string{u.Username, u.Email, u.FirstName, u.LastName} {
+			if strings.Contains(strings.ToLower(field), search) {
+				found = append(found, u)
+				break
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, found)
+}
+
+func (s *Server) deleteUser(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users[id] == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+	delete(s.users, id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) mapRoles(w http.ResponseWriter, r *http.Request, id string) {
+	var roles []struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&roles); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.users[id]
+	if u == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+	for _, role := range roles {
+		u.Roles = append(u.Roles, role.Name)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientAuthenticated accepts client credentials sent either with HTTP basic
+// auth or as form fields, like Keycloak does. r.ParseForm must have been called.
+func clientAuthenticated(r *http.Request) bool {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	return id == ClientID && secret == ClientSecret
+}
+
+func (s *Server) isServiceAccount(r *http.Request) bool {
+	var claims jwt.MapClaims
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &claims, func(*jwt.Token) (any, error) {
+		return &s.key.PublicKey, nil
+	})
+	sub, _ := claims["sub"].(string)
+	return err == nil && strings.HasPrefix(sub, "service-account-")
+}
+
+func (s *Server) findByLogin(login string) *user {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if login != "" && (strings.EqualFold(u.Email, login) || strings.EqualFold(u.Username, login)) {
+			return u
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,32 @@
+// Package fakesms is an SMS provider for tests that keeps the messages it is
+// asked to send.
+package fakesms
+
+import (
+	"context"
+	"sync"
+)
+
+type Message struct {
+	To   string
+	Body string
+}
+
+type Provider struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (p *Provider) SendSMS(ctx context.Context, to, body string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, Message{To: to, Body: body})
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (p *Provider) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
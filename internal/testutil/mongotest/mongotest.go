@@ -0,0 +1,50 @@
+// Package mongotest provides throwaway MongoDB databases for tests that need a
+// real server. Those tests are skipped unless MONGODB_TEST_URI is set.
+package mongotest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"github.com/lucasgarciaf/df-backend-go/internal/migrations"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const uriVariable = "MONGODB_TEST_URI"
+
+// Database returns a new, fully migrated database that is dropped when the
+// test finishes.
+func Database(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	uri := os.Getenv(uriVariable)
+	if uri == "" {
+		t.Skipf("%s is not set", uriVariable)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetTimeout(10*time.Second))
+	if err != nil {
+		t.Fatalf("Failed to connect to MongoDB: %v", err)
+	}
+	db := client.Database(fmt.Sprintf("drivefluency_test_%s", primitive.NewObjectID().Hex()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+
+	if _, err := migrations.NewMigrator(db, migrations.All, logging.Discard()).Up(ctx); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
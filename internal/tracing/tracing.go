@@ -0,0 +1,68 @@
+// Package tracing sets up OpenTelemetry. Instrumented code uses the global
+// tracer provider and propagator, which Setup installs once at startup.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Setup installs the W3C trace context propagator and, when tracing is
+// enabled, a tracer provider exporting to the OTLP/HTTP collector. The
+// returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for an instrumented package.
+func Tracer(name string) trace.Tracer {
+	return otel.Tracer("github.com/lucasgarciaf/df-backend-go/" + name)
+}
+
+// End finishes span after recording *err. It is meant to be deferred with a
+// pointer to the function's named error result. Errors caused by the caller,
+// such as a missing document, are recorded without marking the span failed.
+func End(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		if apperr.KindOf(*err).Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	for _, err := range []error{nil, apperr.ResourceNotFound("lesson"), errors.New("boom")} {
+		_, span := tracer.Start(context.Background(), "op")
+		End(span, &err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 ended spans but got %d", len(spans))
+	}
+	expected := []struct {
+		status codes.Code
+		events int
+	}{{codes.Unset, 0}, {codes.Unset, 1}, {codes.Error, 1}}
+	for i, want := range expected {
+		if spans[i].Status().Code != want.status || len(spans[i].Events()) != want.events {
+			t.Errorf("span %d: expected status %v with %d events but got %v with %d",
+				i, want.status, want.events, spans[i].Status().Code, len(spans[i].Events()))
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// batchSize is how many deliveries are claimed at a time.
+	batchSize = 10
+	// maxRetryDelay caps the exponential backoff between attempts.
+	maxRetryDelay = 6 * time.Hour
+	// maxResponse is how much of a response body is kept in the delivery log.
+	maxResponse = 512
+)
+
+var errPrivateAddress = errors.New("the subscription URL points to a private network address")
+
+// message is the JSON body posted to subscribers.
+type message struct {
+	events.Event
+	Data events.Payload `json:"data"`
+}
+
+// Deliverer is the events.Publisher that sends events to webhook subscribers.
+// Publish only queues a delivery for each interested subscription; Run sends
+// them, so that one slow or failing subscriber holds up no one else.
+type Deliverer struct {
+	subscriptions SubscriptionRepository
+	deliveries    DeliveryRepository
+	cfg           config.WebhookConfig
+	client        *http.Client
+	logger        *slog.Logger
+	now           func() time.Time
+}
+
+func NewDeliverer(subscriptions SubscriptionRepository, deliveries DeliveryRepository, cfg config.WebhookConfig, logger *slog.Logger) *Deliverer {
+	dialer := &net.Dialer{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivateURLs {
+		// Checked on the address actually dialled, so DNS cannot point a
+		// public name at an internal service
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Deliverer{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+		cfg:           cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+			// A redirect is reported as the response it is rather than followed
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+func isPublic(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
+
+// Publish queues a delivery of event for every active subscription that wants
+// it. Queuing the same event twice is harmless.
+func (d *Deliverer) Publish(ctx context.Context, event events.Event) error {
+	subscriptions, err := d.subscriptions.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	var body []byte
+	var errs []error
+	for _, subscription := range subscriptions {
+		if !subscription.Wants(event.Type) {
+			continue
+		}
+		if body == nil {
+			if body, err = render(event); err != nil {
+				return err
+			}
+		}
+		_, err := d.deliveries.CreateDelivery(ctx, Delivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        body,
+			Status:         DeliveryPending,
+			NextAttemptAt:  d.now(),
+		})
+		// A conflict means the event was queued by an earlier, partly failed Publish
+		if err != nil && apperr.KindOf(err) != apperr.KindConflict {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func render(event events.Event) ([]byte, error) {
+	payload, err := event.DecodePayload()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(message{Event: event, Data: payload})
+}
+
+// Run sends the deliveries that are due every PollInterval until ctx is
+// cancelled.
+func (d *Deliverer) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DeliverPending(ctx); err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "failed to deliver webhooks", slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending sends the deliveries that are due, a batch at a time, and
+// returns how many succeeded.
+func (d *Deliverer) DeliverPending(ctx context.Context) (int, error) {
+	// Long enough for every request of a batch to time out
+	lease := time.Duration(batchSize+1) * d.cfg.Timeout
+	succeeded := 0
+	for {
+		batch, err := d.deliveries.ClaimDeliveries(ctx, d.now(), lease, batchSize)
+		if err != nil {
+			return succeeded, err
+		}
+		for _, delivery := range batch {
+			if d.deliver(ctx, delivery) {
+				succeeded++
+			}
+		}
+		if len(batch) < batchSize || ctx.Err() != nil {
+			return succeeded, nil
+		}
+	}
+}
+
+func (d *Deliverer) deliver(ctx context.Context, delivery Delivery) bool {
+	ctx, span := tracer.Start(ctx, "Deliverer.deliver", trace.WithAttributes(
+		attribute.String("webhook.delivery_id", delivery.ID.Hex()), attribute.String("event.type", string(delivery.EventType))))
+	var err error
+	defer tracing.End(span, &err)
+
+	attrs := []any{slog.String("delivery_id", delivery.ID.Hex()), slog.String("subscription_id", delivery.SubscriptionID.Hex())}
+	subscription, err := d.subscriptions.GetSubscription(ctx, delivery.SubscriptionID)
+	// A paused subscription's deliveries wait for it to be resumed without
+	// using up their attempts
+	if err == nil && subscription.Status != StatusActive {
+		if err = d.deliveries.DeferDelivery(ctx, delivery.ID, d.now().Add(d.cfg.RetryBase)); err != nil {
+			d.logger.ErrorContext(ctx, "failed to defer webhook delivery", append(attrs, slog.Any("error", err))...)
+		}
+		return false
+	}
+	attempt := Attempt{At: d.now()}
+	if err == nil {
+		attempt, err = d.send(ctx, subscription, delivery)
+	}
+
+	status, retryAt := DeliverySucceeded, time.Time{}
+	if err != nil {
+		failures := delivery.Failures + 1
+		status = DeliveryFailed
+		// A missing subscription will not come back, so neither will the delivery
+		if failures < d.cfg.MaxAttempts && !apperr.IsNotFound(err) {
+			status = DeliveryPending
+			retryAt = d.now().Add(retryDelay(d.cfg.RetryBase, failures))
+		}
+		attempt.Error = err.Error()
+		attrs = append(attrs, slog.Int("failures", failures), slog.Any("error", err))
+		if status == DeliveryFailed {
+			d.logger.ErrorContext(ctx, "giving up on webhook delivery", attrs...)
+		} else {
+			d.logger.WarnContext(ctx, "webhook delivery failed, will retry", append(attrs, slog.Time("retry_at", retryAt))...)
+		}
+	}
+	if recordErr := d.deliveries.RecordAttempt(ctx, delivery.ID, attempt, status, retryAt); recordErr != nil {
+		d.logger.ErrorContext(ctx, "failed to record webhook attempt", append(attrs, slog.Any("record_error", recordErr))...)
+	}
+	return err == nil
+}
+
+// send posts the delivery to the subscription once. The returned attempt is
+// filled in as far as the request got.
+func (d *Deliverer) send(ctx context.Context, subscription *Subscription, delivery Delivery) (Attempt, error) {
+	attempt := Attempt{At: d.now()}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return attempt, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "df-backend-go-webhooks")
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, attempt.At, delivery.Payload))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		return attempt, err
+	}
+	defer resp.Body.Close()
+	attempt.StatusCode = resp.StatusCode
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponse))
+	attempt.Response = string(body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return attempt, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return attempt, nil
+}
+
+// retryDelay doubles base with each failure, up to maxRetryDelay.
+func retryDelay(base time.Duration, failures int) time.Duration {
+	delay := base
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
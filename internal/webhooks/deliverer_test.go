@@ -0,0 +1,250 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/config"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/logging"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// subscriber is a webhook receiver that answers with the next status in line.
+type subscriber struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newSubscriber(t *testing.T, statuses ...int) *subscriber {
+	s := &subscriber{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests = append(s.requests, r)
+		s.bodies = append(s.bodies, body)
+		status := http.StatusNoContent
+		if len(s.statuses) > 0 {
+			status, s.statuses = s.statuses[0], s.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestDeliverer(subscriptions SubscriptionRepository, deliveries DeliveryRepository, now *time.Time) *Deliverer {
+	d := NewDeliverer(subscriptions, deliveries, config.WebhookConfig{PollInterval: time.Second, Timeout: time.Second, RetryBase: time.Minute, MaxAttempts: 3, AllowPrivateURLs: true}, logging.Discard())
+	d.now = func() time.Time { return *now }
+	return d
+}
+
+func lessonBooked(t *testing.T) events.Event {
+	t.Helper()
+	payload := events.LessonBooked{Lesson: events.Lesson{LessonID: primitive.NewObjectID(), Title: "Roundabouts"}}
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events.Event{ID: primitive.NewObjectID(), Type: payload.EventType(), AggregateID: payload.LessonID, OccurredAt: time.Now(), Payload: raw}
+}
+
+func TestDelivererSendsSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	subscriptions, deliveries := NewMemorySubscriptionRepository(), NewMemoryDeliveryRepository()
+	now := time.Now()
+	d := newTestDeliverer(subscriptions, deliveries, &now)
+
+	bookings := newSubscriber(t)
+	everything := newSubscriber(t)
+	paused := newSubscriber(t)
+	subscriptions.CreateSubscription(ctx, Subscription{URL: bookings.URL, Events: []events.Type{events.TypeLessonBooked}, Status: StatusActive, Secret: "booking-secret"})
+	subscriptions.CreateSubscription(ctx, Subscription{URL: everything.URL, Status: StatusActive, Secret: "other-secret"})
+	subscriptions.CreateSubscription(ctx, Subscription{URL: paused.URL, Status: StatusPaused, Secret: "paused-secret"})
+
+	event := lessonBooked(t)
+	if err := d.Publish(ctx, event); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	// The dispatcher may publish an event again; it is still sent once
+	if err := d.Publish(ctx, event); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	cancelled := event
+	cancelled.ID, cancelled.Type = primitive.NewObjectID(), events.TypeLessonCancelled
+	if err := d.Publish(ctx, cancelled); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+
+	succeeded, err := d.DeliverPending(ctx)
+	if err != nil || succeeded != 3 {
+		t.Fatalf("Expected 3 deliveries but got %d (%v)", succeeded, err)
+	}
+	if len(bookings.requests) != 1 || len(everything.requests) != 2 || len(paused.requests) != 0 {
+		t.Fatalf("Expected the event filters to apply but got %d, %d and %d requests",
+			len(bookings.requests), len(everything.requests), len(paused.requests))
+	}
+
+	req, body := bookings.requests[0], bookings.bodies[0]
+	if err := Verify("booking-secret", req.Header.Get(SignatureHeader), body, now, time.Minute); err != nil {
+		t.Fatalf("Expected a valid signature but got %v", err)
+	}
+	if err := Verify("other-secret", req.Header.Get(SignatureHeader), body, now, time.Minute); err != ErrBadSignature {
+		t.Fatalf("Expected the signature to be bound to the secret but got %v", err)
+	}
+	if req.Header.Get(EventHeader) != "lesson.booked" || req.Header.Get(DeliveryHeader) == "" || req.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected headers %v", req.Header)
+	}
+	var msg struct {
+		ID   primitive.ObjectID `json:"id"`
+		Type events.Type        `json:"type"`
+		Data events.Lesson      `json:"data"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil || msg.ID != event.ID || msg.Type != events.TypeLessonBooked || msg.Data.Title != "Roundabouts" {
+		t.Fatalf("Unexpected body %s (%v)", body, err)
+	}
+}
+
+func TestDelivererRetriesAndGivesUp(t *testing.T) {
+	ctx := context.Background()
+	subscriptions, deliveries := NewMemorySubscriptionRepository(), NewMemoryDeliveryRepository()
+	now := time.Now()
+	d := newTestDeliverer(subscriptions, deliveries, &now)
+
+	flaky := newSubscriber(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	subscriptionID, _ := subscriptions.CreateSubscription(ctx, Subscription{URL: flaky.URL, Status: StatusActive, Secret: "secret"})
+	d.Publish(ctx, lessonBooked(t))
+
+	// Retried after 1m, then 2m, and given up on at the third attempt
+	for i, wait := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		if i > 0 {
+			now = now.Add(wait - time.Second)
+			if succeeded, _ := d.DeliverPending(ctx); len(flaky.requests) != i || succeeded != 0 {
+				t.Fatalf("Expected attempt %d to wait %v", i+1, wait)
+			}
+			now = now.Add(time.Second)
+		}
+		if succeeded, _ := d.DeliverPending(ctx); len(flaky.requests) != i+1 || succeeded != 0 {
+			t.Fatalf("Expected attempt %d to fail but got %d requests", i+1, len(flaky.requests))
+		}
+	}
+	now = now.Add(time.Hour)
+	d.DeliverPending(ctx)
+	log, _ := deliveries.ListDeliveries(ctx, subscriptionID, 0)
+	if len(flaky.requests) != 3 || len(log) != 1 || log[0].Status != DeliveryFailed {
+		t.Fatalf("Expected the delivery to be given up on but got %+v", log)
+	}
+	if codes := []int{log[0].Attempts[0].StatusCode, log[0].Attempts[1].StatusCode, log[0].Attempts[2].StatusCode}; codes[0] != 500 || codes[1] != 502 || codes[2] != 503 {
+		t.Fatalf("Expected the response codes to be logged but got %v", codes)
+	}
+
+	// A replay gets a fresh set of attempts
+	if err := deliveries.ReplayDelivery(ctx, log[0].ID, now); err != nil {
+		t.Fatalf("ReplayDelivery failed: %v", err)
+	}
+	if succeeded, _ := d.DeliverPending(ctx); succeeded != 1 || len(flaky.requests) != 4 {
+		t.Fatalf("Expected the replay to be delivered but got %d requests", len(flaky.requests))
+	}
+	if string(flaky.bodies[3]) != string(flaky.bodies[0]) {
+		t.Fatalf("Expected the replay to send the same body but got %s", flaky.bodies[3])
+	}
+}
+
+func TestDelivererHoldsPausedSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	subscriptions, deliveries := NewMemorySubscriptionRepository(), NewMemoryDeliveryRepository()
+	now := time.Now()
+	d := newTestDeliverer(subscriptions, deliveries, &now)
+
+	receiver := newSubscriber(t)
+	subscription := Subscription{URL: receiver.URL, Status: StatusActive, Secret: "secret"}
+	subscription.ID, _ = subscriptions.CreateSubscription(ctx, subscription)
+	d.Publish(ctx, lessonBooked(t))
+	subscription.Status = StatusPaused
+	subscriptions.UpdateSubscription(ctx, subscription)
+
+	// Paused for longer than every attempt would take
+	for range 10 {
+		if succeeded, err := d.DeliverPending(ctx); err != nil || succeeded != 0 {
+			t.Fatalf("Expected nothing to be delivered but got %d (%v)", succeeded, err)
+		}
+		now = now.Add(time.Hour)
+	}
+	log, _ := deliveries.ListDeliveries(ctx, subscription.ID, 0)
+	if len(receiver.requests) != 0 || len(log) != 1 || log[0].Status != DeliveryPending || len(log[0].Attempts) != 0 {
+		t.Fatalf("Expected the delivery to wait without attempts but got %+v", log)
+	}
+
+	subscription.Status = StatusActive
+	subscriptions.UpdateSubscription(ctx, subscription)
+	if succeeded, _ := d.DeliverPending(ctx); succeeded != 1 || len(receiver.requests) != 1 {
+		t.Fatalf("Expected the delivery to be sent once resumed but got %d requests", len(receiver.requests))
+	}
+}
+
+func TestDelivererFailsDeletedSubscriptions(t *testing.T) {
+	ctx := context.Background()
+	subscriptions, deliveries := NewMemorySubscriptionRepository(), NewMemoryDeliveryRepository()
+	now := time.Now()
+	d := newTestDeliverer(subscriptions, deliveries, &now)
+
+	receiver := newSubscriber(t)
+	subscriptionID, _ := subscriptions.CreateSubscription(ctx, Subscription{URL: receiver.URL, Status: StatusActive, Secret: "secret"})
+	d.Publish(ctx, lessonBooked(t))
+	subscriptions.DeleteSubscription(ctx, subscriptionID)
+
+	d.DeliverPending(ctx)
+	log, _ := deliveries.ListDeliveries(ctx, subscriptionID, 0)
+	if len(receiver.requests) != 0 || len(log) != 1 || log[0].Status != DeliveryFailed || log[0].Attempts[0].Error == "" {
+		t.Fatalf("Expected the delivery to fail straight away but got %+v", log)
+	}
+}
+
+func TestDelivererRefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	subscriptions, deliveries := NewMemorySubscriptionRepository(), NewMemoryDeliveryRepository()
+	d := NewDeliverer(subscriptions, deliveries, config.WebhookConfig{Timeout: time.Second, RetryBase: time.Minute, MaxAttempts: 3}, logging.Discard())
+
+	receiver := newSubscriber(t)
+	subscriptionID, _ := subscriptions.CreateSubscription(ctx, Subscription{URL: receiver.URL, Status: StatusActive, Secret: "secret"})
+	d.Publish(ctx, lessonBooked(t))
+
+	if succeeded, err := d.DeliverPending(ctx); err != nil || succeeded != 0 {
+		t.Fatalf("Expected the delivery to fail but got %d (%v)", succeeded, err)
+	}
+	log, _ := deliveries.ListDeliveries(ctx, subscriptionID, 0)
+	if len(receiver.requests) != 0 || len(log) != 1 || len(log[0].Attempts) != 1 || !strings.Contains(log[0].Attempts[0].Error, errPrivateAddress.Error()) {
+		t.Fatalf("Expected the loopback subscriber not to be posted to but got %+v", log)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"lesson.booked"}`)
+	header := Sign("secret", at, body)
+
+	if err := Verify("secret", header, body, at.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Fatalf("Expected the signature to verify but got %v", err)
+	}
+	if err := Verify("secret", header, []byte(`{"type":"lesson.cancelled"}`), at, 5*time.Minute); err != ErrBadSignature {
+		t.Fatalf("Expected a tampered body to be rejected but got %v", err)
+	}
+	if err := Verify("secret", header, body, at.Add(10*time.Minute), 5*time.Minute); err != ErrStaleSignature {
+		t.Fatalf("Expected an old signature to be rejected but got %v", err)
+	}
+	if err := Verify("secret", "v1=abc", body, at, 5*time.Minute); err != ErrMissingSignature {
+		t.Fatalf("Expected a malformed header to be rejected but got %v", err)
+	}
+}
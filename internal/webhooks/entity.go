@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A subscription is active unless an admin has paused it. Paused
+// subscriptions are not sent new events, and the deliveries they already have
+// wait until they are resumed.
+const (
+	StatusActive = "active"
+	StatusPaused = "paused"
+)
+
+// Subscription asks for the events of the listed types to be posted to URL.
+type Subscription struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL         string             `bson:"url" json:"url"`
+	Description string             `bson:"description" json:"description,omitempty"`
+	// Events are the event types sent; empty means all of them
+	Events []events.Type `bson:"events" json:"events"`
+	Status string        `bson:"status" json:"status"`
+	// Secret signs the payloads. It is shown once, when the subscription is created.
+	Secret    string    `bson:"secret" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// Wants reports whether events of type typ are sent to the subscription.
+func (s Subscription) Wants(typ events.Type) bool {
+	if s.Status != StatusActive {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, want := range s.Events {
+		if want == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is one event sent to one subscription, with every attempt made so
+// far. There is at most one delivery per event and subscription.
+type Delivery struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscription_id" json:"subscription_id"`
+	EventID        primitive.ObjectID `bson:"event_id" json:"event_id"`
+	EventType      events.Type        `bson:"event_type" json:"event_type"`
+	// Payload is the exact body posted, kept so that replays send the same bytes
+	Payload  []byte    `bson:"payload" json:"-"`
+	Status   string    `bson:"status" json:"status"`
+	Attempts []Attempt `bson:"attempts" json:"attempts"`
+	// Failures counts the failed attempts since the delivery was created or last replayed
+	Failures      int       `bson:"failures" json:"-"`
+	NextAttemptAt time.Time `bson:"next_attempt_at" json:"next_attempt_at"`
+	LockedUntil   time.Time `bson:"locked_until" json:"-"`
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+}
+
+// Attempt is one POST of a delivery to its subscriber.
+type Attempt struct {
+	At time.Time `bson:"at" json:"at"`
+	// StatusCode is the response status, or zero if no response was received
+	StatusCode int   `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	DurationMS int64 `bson:"duration_ms" json:"duration_ms"`
+	// Response is the start of the response body
+	Response string `bson:"response,omitempty" json:"response,omitempty"`
+	Error    string `bson:"error,omitempty" json:"error,omitempty"`
+}
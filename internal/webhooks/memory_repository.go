@@ -0,0 +1,190 @@
+package webhooks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/memstore"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemorySubscriptionRepository keeps subscriptions in memory with the same
+// semantics as MongoSubscriptionRepository. It is meant for tests and local
+// development.
+type MemorySubscriptionRepository struct {
+	store *memstore.Store[Subscription]
+}
+
+func NewMemorySubscriptionRepository() *MemorySubscriptionRepository {
+	return &MemorySubscriptionRepository{
+		store: memstore.New[Subscription](nil),
+	}
+}
+
+func (r *MemorySubscriptionRepository) CreateSubscription(ctx context.Context, subscription Subscription) (primitive.ObjectID, error) {
+	subscription.ID = primitive.NewObjectID()
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = time.Now()
+	if _, err := r.store.Insert(subscription.ID, subscription); err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	return subscription.ID, nil
+}
+
+func (r *MemorySubscriptionRepository) GetSubscription(ctx context.Context, id primitive.ObjectID) (*Subscription, error) {
+	subscription, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("webhook")
+	}
+	return &subscription, nil
+}
+
+func (r *MemorySubscriptionRepository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	return r.store.Find(nil), nil
+}
+
+func (r *MemorySubscriptionRepository) UpdateSubscription(ctx context.Context, subscription Subscription) error {
+	current, ok := r.store.Get(subscription.ID)
+	if !ok {
+		return apperr.ResourceNotFound("webhook")
+	}
+	current.URL = subscription.URL
+	current.Description = subscription.Description
+	current.Events = subscription.Events
+	current.Status = subscription.Status
+	current.UpdatedAt = time.Now()
+	found, _, err := r.store.Set(subscription.ID, current)
+	if err != nil {
+		return apperr.Internal(err)
+	}
+	if !found {
+		return apperr.ResourceNotFound("webhook")
+	}
+	return nil
+}
+
+func (r *MemorySubscriptionRepository) DeleteSubscription(ctx context.Context, id primitive.ObjectID) error {
+	if !r.store.Delete(id) {
+		return apperr.ResourceNotFound("webhook")
+	}
+	return nil
+}
+
+// MemoryDeliveryRepository keeps deliveries in memory with the same semantics
+// as MongoDeliveryRepository, including the unique event and subscription
+// index. It is meant for tests and local development.
+type MemoryDeliveryRepository struct {
+	// mu makes claiming and updating a delivery atomic, like the single
+	// document updates MongoDB does
+	mu    sync.Mutex
+	store *memstore.Store[Delivery]
+}
+
+func NewMemoryDeliveryRepository() *MemoryDeliveryRepository {
+	return &MemoryDeliveryRepository{
+		store: memstore.New[Delivery](func(a, b Delivery) bool {
+			return a.SubscriptionID == b.SubscriptionID && a.EventID == b.EventID
+		}),
+	}
+}
+
+func (r *MemoryDeliveryRepository) CreateDelivery(ctx context.Context, delivery Delivery) (primitive.ObjectID, error) {
+	delivery.ID = primitive.NewObjectID()
+	delivery.CreatedAt = time.Now()
+	if delivery.Attempts == nil {
+		delivery.Attempts = []Attempt{}
+	}
+	ok, err := r.store.Insert(delivery.ID, delivery)
+	if err != nil {
+		return primitive.NilObjectID, apperr.Internal(err)
+	}
+	if !ok {
+		return primitive.NilObjectID, apperr.ResourceConflict("webhook_delivery")
+	}
+	return delivery.ID, nil
+}
+
+func (r *MemoryDeliveryRepository) GetDelivery(ctx context.Context, id primitive.ObjectID) (*Delivery, error) {
+	delivery, ok := r.store.Get(id)
+	if !ok {
+		return nil, apperr.ResourceNotFound("webhook_delivery")
+	}
+	return &delivery, nil
+}
+
+func (r *MemoryDeliveryRepository) ListDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, n int) ([]Delivery, error) {
+	deliveries := r.store.Find(func(delivery Delivery) bool {
+		return delivery.SubscriptionID == subscriptionID
+	})
+	for i, j := 0, len(deliveries)-1; i < j; i, j = i+1, j-1 {
+		deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+	}
+	if len(deliveries) > limit(n) {
+		deliveries = deliveries[:limit(n)]
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryDeliveryRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, n int) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := r.store.Find(func(delivery Delivery) bool {
+		return delivery.Status == DeliveryPending && !delivery.NextAttemptAt.After(now) && !delivery.LockedUntil.After(now)
+	})
+	if len(due) > n {
+		due = due[:n]
+	}
+	for i := range due {
+		due[i].LockedUntil = now.Add(lease)
+		if _, _, err := r.store.Set(due[i].ID, due[i]); err != nil {
+			return nil, apperr.Internal(err)
+		}
+	}
+	return due, nil
+}
+
+func (r *MemoryDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt Attempt, status string, retryAt time.Time) error {
+	return r.update(id, func(delivery *Delivery) {
+		delivery.Attempts = append(delivery.Attempts, attempt)
+		delivery.Status = status
+		delivery.LockedUntil = time.Time{}
+		if status != DeliverySucceeded {
+			delivery.Failures++
+		}
+		if status == DeliveryPending {
+			delivery.NextAttemptAt = retryAt
+		}
+	})
+}
+
+func (r *MemoryDeliveryRepository) DeferDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return r.update(id, func(delivery *Delivery) {
+		delivery.NextAttemptAt = at
+		delivery.LockedUntil = time.Time{}
+	})
+}
+
+func (r *MemoryDeliveryRepository) ReplayDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	return r.update(id, func(delivery *Delivery) {
+		delivery.Status = DeliveryPending
+		delivery.Failures = 0
+		delivery.NextAttemptAt = at
+		delivery.LockedUntil = time.Time{}
+	})
+}
+
+func (r *MemoryDeliveryRepository) update(id primitive.ObjectID, apply func(delivery *Delivery)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.store.Get(id)
+	if !ok {
+		return apperr.ResourceNotFound("webhook_delivery")
+	}
+	apply(&delivery)
+	if _, _, err := r.store.Set(id, delivery); err != nil {
+		return apperr.Internal(err)
+	}
+	return nil
+}
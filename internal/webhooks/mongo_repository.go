@@ -0,0 +1,193 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoSubscriptionRepository struct {
+	db *mongo.Collection
+}
+
+func NewMongoSubscriptionRepository(db *mongo.Database) *MongoSubscriptionRepository {
+	return &MongoSubscriptionRepository{
+		db: db.Collection("webhook_subscriptions"),
+	}
+}
+
+func (r *MongoSubscriptionRepository) CreateSubscription(ctx context.Context, subscription Subscription) (primitive.ObjectID, error) {
+	subscription.ID = primitive.NewObjectID()
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = time.Now()
+	if _, err := r.db.InsertOne(ctx, subscription); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "webhook")
+	}
+	return subscription.ID, nil
+}
+
+func (r *MongoSubscriptionRepository) GetSubscription(ctx context.Context, id primitive.ObjectID) (*Subscription, error) {
+	var subscription Subscription
+	if err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&subscription); err != nil {
+		return nil, apperr.FromMongo(err, "webhook")
+	}
+	return &subscription, nil
+}
+
+func (r *MongoSubscriptionRepository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	cursor, err := r.db.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, apperr.FromMongo(err, "webhook")
+	}
+	defer cursor.Close(ctx)
+
+	var subscriptions []Subscription
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, apperr.FromMongo(err, "webhook")
+	}
+	return subscriptions, nil
+}
+
+func (r *MongoSubscriptionRepository) UpdateSubscription(ctx context.Context, subscription Subscription) error {
+	update := bson.M{"$set": bson.M{
+		"url":         subscription.URL,
+		"description": subscription.Description,
+		"events":      subscription.Events,
+		"status":      subscription.Status,
+		"updated_at":  time.Now(),
+	}}
+	result, err := r.db.UpdateByID(ctx, subscription.ID, update)
+	if err != nil {
+		return apperr.FromMongo(err, "webhook")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "webhook")
+	}
+	return nil
+}
+
+func (r *MongoSubscriptionRepository) DeleteSubscription(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.db.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return apperr.FromMongo(err, "webhook")
+	}
+	if result.DeletedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "webhook")
+	}
+	return nil
+}
+
+type MongoDeliveryRepository struct {
+	db *mongo.Collection
+}
+
+func NewMongoDeliveryRepository(db *mongo.Database) *MongoDeliveryRepository {
+	return &MongoDeliveryRepository{
+		db: db.Collection("webhook_deliveries"),
+	}
+}
+
+func (r *MongoDeliveryRepository) CreateDelivery(ctx context.Context, delivery Delivery) (primitive.ObjectID, error) {
+	delivery.ID = primitive.NewObjectID()
+	delivery.CreatedAt = time.Now()
+	if delivery.Attempts == nil {
+		delivery.Attempts = []Attempt{}
+	}
+	if _, err := r.db.InsertOne(ctx, delivery); err != nil {
+		return primitive.NilObjectID, apperr.FromMongo(err, "webhook_delivery")
+	}
+	return delivery.ID, nil
+}
+
+func (r *MongoDeliveryRepository) GetDelivery(ctx context.Context, id primitive.ObjectID) (*Delivery, error) {
+	var delivery Delivery
+	if err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(&delivery); err != nil {
+		return nil, apperr.FromMongo(err, "webhook_delivery")
+	}
+	return &delivery, nil
+}
+
+func (r *MongoDeliveryRepository) ListDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, n int) ([]Delivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit(n)))
+	cursor, err := r.db.Find(ctx, bson.M{"subscription_id": subscriptionID}, opts)
+	if err != nil {
+		return nil, apperr.FromMongo(err, "webhook_delivery")
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []Delivery
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, apperr.FromMongo(err, "webhook_delivery")
+	}
+	return deliveries, nil
+}
+
+func (r *MongoDeliveryRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, n int) ([]Delivery, error) {
+	filter := bson.M{
+		"status":          DeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"locked_until":    bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}}).SetReturnDocument(options.After)
+
+	// One at a time, so that each delivery is claimed by a single instance
+	var deliveries []Delivery
+	for len(deliveries) < n {
+		var claimed Delivery
+		err := r.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&claimed)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return deliveries, apperr.FromMongo(err, "webhook_delivery")
+		}
+		deliveries = append(deliveries, claimed)
+	}
+	return deliveries, nil
+}
+
+func (r *MongoDeliveryRepository) RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt Attempt, status string, retryAt time.Time) error {
+	set := bson.M{"status": status, "locked_until": time.Time{}}
+	update := bson.M{"$push": bson.M{"attempts": attempt}}
+	if status != DeliverySucceeded {
+		update["$inc"] = bson.M{"failures": 1}
+	}
+	if status == DeliveryPending {
+		set["next_attempt_at"] = retryAt
+	}
+	update["$set"] = set
+	return r.update(ctx, id, update)
+}
+
+func (r *MongoDeliveryRepository) DeferDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{"next_attempt_at": at, "locked_until": time.Time{}}}
+	return r.update(ctx, id, update)
+}
+
+func (r *MongoDeliveryRepository) ReplayDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"status":          DeliveryPending,
+		"failures":        0,
+		"next_attempt_at": at,
+		"locked_until":    time.Time{},
+	}}
+	return r.update(ctx, id, update)
+}
+
+func (r *MongoDeliveryRepository) update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	result, err := r.db.UpdateByID(ctx, id, update)
+	if err != nil {
+		return apperr.FromMongo(err, "webhook_delivery")
+	}
+	if result.MatchedCount == 0 {
+		return apperr.FromMongo(mongo.ErrNoDocuments, "webhook_delivery")
+	}
+	return nil
+}
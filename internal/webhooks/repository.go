@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultLimit and MaxLimit bound how many deliveries ListDeliveries returns.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+type SubscriptionRepository interface {
+	CreateSubscription(ctx context.Context, subscription Subscription) (primitive.ObjectID, error)
+	GetSubscription(ctx context.Context, id primitive.ObjectID) (*Subscription, error)
+	// ListSubscriptions returns every subscription, oldest first.
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	// UpdateSubscription replaces the URL, description, event filter and status.
+	// The secret is kept.
+	UpdateSubscription(ctx context.Context, subscription Subscription) error
+	DeleteSubscription(ctx context.Context, id primitive.ObjectID) error
+}
+
+type DeliveryRepository interface {
+	// CreateDelivery fails with a conflict if the event has already been
+	// queued for the subscription.
+	CreateDelivery(ctx context.Context, delivery Delivery) (primitive.ObjectID, error)
+	GetDelivery(ctx context.Context, id primitive.ObjectID) (*Delivery, error)
+	// ListDeliveries returns a subscription's deliveries, newest first. A limit
+	// of zero means DefaultLimit.
+	ListDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int) ([]Delivery, error)
+	// ClaimDeliveries returns up to limit pending deliveries that are due at
+	// now, oldest first, and hides them from other claims for lease.
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	// RecordAttempt appends an attempt and moves the delivery to status. A
+	// pending delivery is claimed again from retryAt.
+	RecordAttempt(ctx context.Context, id primitive.ObjectID, attempt Attempt, status string, retryAt time.Time) error
+	// DeferDelivery releases a claimed delivery to be claimed again from at,
+	// without recording an attempt.
+	DeferDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	// ReplayDelivery makes a delivery pending again from at, whatever its
+	// status, with a fresh allowance of attempts.
+	ReplayDelivery(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+func limit(n int) int {
+	if n <= 0 {
+		return DefaultLimit
+	}
+	return min(n, MaxLimit)
+}
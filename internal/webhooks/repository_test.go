@@ -0,0 +1,172 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/testutil/mongotest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryRepositories(t *testing.T) {
+	testRepositories(t, func(t *testing.T) (SubscriptionRepository, DeliveryRepository) {
+		return NewMemorySubscriptionRepository(), NewMemoryDeliveryRepository()
+	})
+}
+
+func TestMongoRepositories(t *testing.T) {
+	testRepositories(t, func(t *testing.T) (SubscriptionRepository, DeliveryRepository) {
+		db := mongotest.Database(t)
+		return NewMongoSubscriptionRepository(db), NewMongoDeliveryRepository(db)
+	})
+}
+
+// testRepositories is the contract every SubscriptionRepository and
+// DeliveryRepository must satisfy.
+func testRepositories(t *testing.T, newRepos func(t *testing.T) (SubscriptionRepository, DeliveryRepository)) {
+	ctx := context.Background()
+	// Mongo stores times with millisecond precision
+	now := time.Now().Truncate(time.Millisecond)
+
+	t.Run("subscriptions", func(t *testing.T) {
+		subscriptions, _ := newRepos(t)
+		id, err := subscriptions.CreateSubscription(ctx, Subscription{
+			URL: "https://example.com/hook", Description: "Accounting", Events: []events.Type{events.TypeLessonBooked},
+			Status: StatusActive, Secret: "whsec_test",
+		})
+		if err != nil {
+			t.Fatalf("CreateSubscription failed: %v", err)
+		}
+
+		update := Subscription{ID: id, URL: "https://example.com/v2", Status: StatusPaused}
+		if err := subscriptions.UpdateSubscription(ctx, update); err != nil {
+			t.Fatalf("UpdateSubscription failed: %v", err)
+		}
+		got, err := subscriptions.GetSubscription(ctx, id)
+		if err != nil {
+			t.Fatalf("GetSubscription failed: %v", err)
+		}
+		// Everything is replaced but the secret
+		if got.URL != update.URL || got.Description != "" || len(got.Events) != 0 || got.Status != StatusPaused || got.Secret != "whsec_test" {
+			t.Fatalf("Unexpected subscription %+v", got)
+		}
+
+		all, err := subscriptions.ListSubscriptions(ctx)
+		if err != nil || len(all) != 1 || all[0].ID != id {
+			t.Fatalf("Expected the one subscription but got %+v (%v)", all, err)
+		}
+		if err := subscriptions.DeleteSubscription(ctx, id); err != nil {
+			t.Fatalf("DeleteSubscription failed: %v", err)
+		}
+		if _, err := subscriptions.GetSubscription(ctx, id); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected not found but got %v", err)
+		}
+		if err := subscriptions.UpdateSubscription(ctx, update); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected not found but got %v", err)
+		}
+	})
+
+	t.Run("deliveries are unique per event", func(t *testing.T) {
+		_, deliveries := newRepos(t)
+		delivery := Delivery{SubscriptionID: primitive.NewObjectID(), EventID: primitive.NewObjectID(), Status: DeliveryPending, NextAttemptAt: now}
+		if _, err := deliveries.CreateDelivery(ctx, delivery); err != nil {
+			t.Fatalf("CreateDelivery failed: %v", err)
+		}
+		if _, err := deliveries.CreateDelivery(ctx, delivery); apperr.KindOf(err) != apperr.KindConflict {
+			t.Fatalf("Expected a conflict but got %v", err)
+		}
+		delivery.SubscriptionID = primitive.NewObjectID()
+		if _, err := deliveries.CreateDelivery(ctx, delivery); err != nil {
+			t.Fatalf("Expected another subscription to get the event but got %v", err)
+		}
+	})
+
+	t.Run("claim, retry and replay", func(t *testing.T) {
+		_, deliveries := newRepos(t)
+		subscriptionID := primitive.NewObjectID()
+		id, err := deliveries.CreateDelivery(ctx, Delivery{
+			SubscriptionID: subscriptionID, EventID: primitive.NewObjectID(), EventType: events.TypeLessonBooked,
+			Payload: []byte(`{"type":"lesson.booked"}`), Status: DeliveryPending, NextAttemptAt: now,
+		})
+		if err != nil {
+			t.Fatalf("CreateDelivery failed: %v", err)
+		}
+
+		claimed, err := deliveries.ClaimDeliveries(ctx, now, time.Minute, 10)
+		if err != nil || len(claimed) != 1 || claimed[0].ID != id || string(claimed[0].Payload) != `{"type":"lesson.booked"}` {
+			t.Fatalf("Expected the delivery to be claimed but got %+v (%v)", claimed, err)
+		}
+		if claimed, _ := deliveries.ClaimDeliveries(ctx, now, time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected the claim to hide the delivery but got %+v", claimed)
+		}
+
+		// Deferring a delivery keeps its attempts and failures
+		if err := deliveries.DeferDelivery(ctx, id, now.Add(time.Minute)); err != nil {
+			t.Fatalf("DeferDelivery failed: %v", err)
+		}
+		if claimed, _ := deliveries.ClaimDeliveries(ctx, now, time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected nothing due before the deferral but got %+v", claimed)
+		}
+		claimed, err = deliveries.ClaimDeliveries(ctx, now.Add(time.Minute), time.Minute, 10)
+		if err != nil || len(claimed) != 1 || claimed[0].Failures != 0 || len(claimed[0].Attempts) != 0 {
+			t.Fatalf("Expected the deferred delivery without attempts but got %+v (%v)", claimed, err)
+		}
+
+		failed := Attempt{At: now, StatusCode: 500, DurationMS: 12, Error: "subscriber responded with status 500"}
+		if err := deliveries.RecordAttempt(ctx, id, failed, DeliveryPending, now.Add(time.Hour)); err != nil {
+			t.Fatalf("RecordAttempt failed: %v", err)
+		}
+		if claimed, _ := deliveries.ClaimDeliveries(ctx, now.Add(time.Minute), time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected nothing due before the retry but got %+v", claimed)
+		}
+		if err := deliveries.RecordAttempt(ctx, id, failed, DeliveryFailed, time.Time{}); err != nil {
+			t.Fatalf("RecordAttempt failed: %v", err)
+		}
+		got, err := deliveries.GetDelivery(ctx, id)
+		if err != nil || got.Status != DeliveryFailed || got.Failures != 2 || len(got.Attempts) != 2 || got.Attempts[0].StatusCode != 500 || got.Attempts[0].Error != failed.Error {
+			t.Fatalf("Unexpected delivery %+v (%v)", got, err)
+		}
+
+		if err := deliveries.ReplayDelivery(ctx, id, now.Add(2*time.Hour)); err != nil {
+			t.Fatalf("ReplayDelivery failed: %v", err)
+		}
+		claimed, err = deliveries.ClaimDeliveries(ctx, now.Add(2*time.Hour), time.Minute, 10)
+		if err != nil || len(claimed) != 1 || claimed[0].Failures != 0 || len(claimed[0].Attempts) != 2 {
+			t.Fatalf("Expected the replayed delivery with its log but got %+v (%v)", claimed, err)
+		}
+		if err := deliveries.RecordAttempt(ctx, id, Attempt{At: now, StatusCode: 204}, DeliverySucceeded, time.Time{}); err != nil {
+			t.Fatalf("RecordAttempt failed: %v", err)
+		}
+
+		log, err := deliveries.ListDeliveries(ctx, subscriptionID, 0)
+		if err != nil || len(log) != 1 || log[0].Status != DeliverySucceeded || log[0].Attempts[2].StatusCode != 204 {
+			t.Fatalf("Unexpected delivery log %+v (%v)", log, err)
+		}
+		if log, _ := deliveries.ListDeliveries(ctx, primitive.NewObjectID(), 0); len(log) != 0 {
+			t.Fatalf("Expected no deliveries for another subscription but got %+v", log)
+		}
+		if err := deliveries.ReplayDelivery(ctx, primitive.NewObjectID(), now); !apperr.IsNotFound(err) {
+			t.Fatalf("Expected not found but got %v", err)
+		}
+	})
+
+	t.Run("newest first", func(t *testing.T) {
+		_, deliveries := newRepos(t)
+		subscriptionID := primitive.NewObjectID()
+		var ids []primitive.ObjectID
+		for range 3 {
+			id, err := deliveries.CreateDelivery(ctx, Delivery{SubscriptionID: subscriptionID, EventID: primitive.NewObjectID(), Status: DeliveryPending, NextAttemptAt: now})
+			if err != nil {
+				t.Fatalf("CreateDelivery failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		log, err := deliveries.ListDeliveries(ctx, subscriptionID, 2)
+		if err != nil || len(log) != 2 || log[0].ID != ids[2] || log[1].ID != ids[1] {
+			t.Fatalf("Expected the two newest deliveries but got %+v (%v)", log, err)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+// Package webhooks posts domain events to the partner systems that subscribe
+// to them. Admins manage the subscriptions through Service; the Deliverer
+// queues a delivery per subscription for every event the Dispatcher hands it
+// and sends them, signed, retrying with exponential backoff.
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/lucasgarciaf/df-backend-go/internal/apperr"
+	"github.com/lucasgarciaf/df-backend-go/internal/audit"
+	"github.com/lucasgarciaf/df-backend-go/internal/events"
+	"github.com/lucasgarciaf/df-backend-go/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var tracer = tracing.Tracer("internal/webhooks")
+
+var (
+	ErrInvalidURL    = apperr.Invalid("invalid_url", "url must be an absolute http or https URL")
+	ErrInvalidStatus = apperr.Invalid("invalid_status", `status must be "active" or "paused"`)
+)
+
+type Service struct {
+	subscriptions SubscriptionRepository
+	deliveries    DeliveryRepository
+	audit         audit.Recorder
+	now           func() time.Time
+}
+
+func NewService(subscriptions SubscriptionRepository, deliveries DeliveryRepository, auditor audit.Recorder) *Service {
+	return &Service{subscriptions: subscriptions, deliveries: deliveries, audit: auditor, now: time.Now}
+}
+
+// CreateSubscription stores a new subscription with a freshly generated
+// secret and returns it. This is the only time the secret is handed out.
+func (s *Service) CreateSubscription(ctx context.Context, subscription Subscription) (_ *Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateSubscription")
+	defer tracing.End(span, &err)
+	if subscription.Status == "" {
+		subscription.Status = StatusActive
+	}
+	if err := validate(subscription); err != nil {
+		return nil, err
+	}
+	subscription.Secret, err = newSecret()
+	if err != nil {
+		return nil, apperr.Internal(err)
+	}
+	id, err := s.subscriptions.CreateSubscription(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, audit.ActionCreate, "webhook", id, nil, subscription)
+	subscription.ID = id
+	return &subscription, nil
+}
+
+func (s *Service) GetSubscription(ctx context.Context, id primitive.ObjectID) (_ *Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetSubscription")
+	defer tracing.End(span, &err)
+	return s.subscriptions.GetSubscription(ctx, id)
+}
+
+func (s *Service) ListSubscriptions(ctx context.Context) (_ []Subscription, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListSubscriptions")
+	defer tracing.End(span, &err)
+	return s.subscriptions.ListSubscriptions(ctx)
+}
+
+// UpdateSubscription replaces everything but the secret.
+func (s *Service) UpdateSubscription(ctx context.Context, subscription Subscription) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateSubscription")
+	defer tracing.End(span, &err)
+	if subscription.Status == "" {
+		subscription.Status = StatusActive
+	}
+	if err := validate(subscription); err != nil {
+		return err
+	}
+	before, err := s.subscriptions.GetSubscription(ctx, subscription.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.subscriptions.UpdateSubscription(ctx, subscription); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionUpdate, "webhook", subscription.ID, before, subscription)
+	return nil
+}
+
+// DeleteSubscription removes a subscription. Its pending deliveries fail the
+// next time they are tried.
+func (s *Service) DeleteSubscription(ctx context.Context, id primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteSubscription")
+	defer tracing.End(span, &err)
+	before, err := s.subscriptions.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.subscriptions.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionDelete, "webhook", id, before, nil)
+	return nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, subscriptionID primitive.ObjectID, limit int) (_ []Delivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries")
+	defer tracing.End(span, &err)
+	if _, err := s.subscriptions.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	return s.deliveries.ListDeliveries(ctx, subscriptionID, limit)
+}
+
+// ReplayDelivery sends a delivery of the subscription again as soon as
+// possible, whether it succeeded or was given up on.
+func (s *Service) ReplayDelivery(ctx context.Context, subscriptionID, deliveryID primitive.ObjectID) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ReplayDelivery")
+	defer tracing.End(span, &err)
+	delivery, err := s.deliveries.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery.SubscriptionID != subscriptionID {
+		return apperr.ResourceNotFound("webhook_delivery")
+	}
+	if err := s.deliveries.ReplayDelivery(ctx, deliveryID, s.now()); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, audit.ActionReplay, "webhook_delivery", deliveryID, nil, nil)
+	return nil
+}
+
+func validate(subscription Subscription) error {
+	u, err := url.Parse(subscription.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	if subscription.Status != StatusActive && subscription.Status != StatusPaused {
+		return ErrInvalidStatus
+	}
+	for _, typ := range subscription.Events {
+		if !slices.Contains(events.Types, typ) {
+			return apperr.Invalid("unknown_event", fmt.Sprintf("unknown event type %q", typ)).WithDetail("event_types", events.Types)
+		}
+	}
+	return nil
+}